@@ -0,0 +1,108 @@
+// cmd/raven-discover/dhcp.go - DHCP pool parsing and matching
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// dhcpPool is an inclusive range of IPv4 addresses handed out by DHCP
+type dhcpPool struct {
+	start, end net.IP // 4-byte form
+
+	// Legacy "100-200" pools match on the last octet of any network
+	lastOctetOnly bool
+}
+
+// dhcpPools is the set of pools given with -dhcp
+type dhcpPools []dhcpPool
+
+// parseDHCPPools parses a comma-separated list of pools. Each pool is a full
+// address range (192.168.1.100-192.168.1.200), a CIDR block (10.0.0.0/25)
+// or a last-octet range (100-200) that applies to every network.
+func parseDHCPPools(spec string) (dhcpPools, error) {
+	var pools dhcpPools
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pool, err := parseDHCPPool(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DHCP pool %q: %w", entry, err)
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func parseDHCPPool(entry string) (dhcpPool, error) {
+	if strings.Contains(entry, "/") {
+		_, ipnet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return dhcpPool{}, err
+		}
+		start := ipnet.IP.To4()
+		if start == nil {
+			return dhcpPool{}, fmt.Errorf("only IPv4 networks are supported")
+		}
+		end := make(net.IP, len(start))
+		for i := range start {
+			end[i] = start[i] | ^ipnet.Mask[i]
+		}
+		return dhcpPool{start: start, end: end}, nil
+	}
+
+	parts := strings.Split(entry, "-")
+	if len(parts) != 2 {
+		return dhcpPool{}, fmt.Errorf("expected low-high, a CIDR block or a last-octet range")
+	}
+	low, high := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	if lowOctet, err := strconv.Atoi(low); err == nil {
+		highOctet, err := strconv.Atoi(high)
+		if err != nil || lowOctet < 0 || highOctet > 255 || lowOctet > highOctet {
+			return dhcpPool{}, fmt.Errorf("last-octet range must be within 0-255 and low <= high")
+		}
+		return dhcpPool{
+			start:         net.IPv4(0, 0, 0, byte(lowOctet)).To4(),
+			end:           net.IPv4(0, 0, 0, byte(highOctet)).To4(),
+			lastOctetOnly: true,
+		}, nil
+	}
+
+	start, end := net.ParseIP(low).To4(), net.ParseIP(high).To4()
+	if start == nil || end == nil {
+		return dhcpPool{}, fmt.Errorf("range ends must be IPv4 addresses")
+	}
+	if bytes.Compare(start, end) > 0 {
+		return dhcpPool{}, fmt.Errorf("range start is after its end")
+	}
+	return dhcpPool{start: start, end: end}, nil
+}
+
+// contains reports whether ipv4 falls in any pool
+func (pools dhcpPools) contains(ipv4 string) bool {
+	ip := net.ParseIP(ipv4).To4()
+	if ip == nil {
+		return false
+	}
+
+	for _, pool := range pools {
+		if pool.lastOctetOnly {
+			if ip[3] >= pool.start[3] && ip[3] <= pool.end[3] {
+				return true
+			}
+			continue
+		}
+		if bytes.Compare(ip, pool.start) >= 0 && bytes.Compare(ip, pool.end) <= 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,11 @@
 package main
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -81,6 +83,7 @@ type PortService struct {
 	Product string `xml:"product,attr"`
 	Version string `xml:"version,attr"`
 	Method  string `xml:"method,attr"`
+	Tunnel  string `xml:"tunnel,attr"`
 	Conf    int    `xml:"conf,attr"`
 }
 
@@ -158,6 +161,9 @@ type CheckConfig struct {
 	Options   map[string]interface{}   `yaml:"options"`
 }
 
+// status receives progress messages; stderr when the config goes to stdout
+var status io.Writer = os.Stdout
+
 // Port service mapping for check generation
 var serviceChecks = map[int]CheckTemplate{
 	22: {
@@ -231,30 +237,66 @@ var serviceChecks = map[int]CheckTemplate{
 			"program": "/usr/lib/nagios/plugins/check_http",
 			"options": []string{"-S", "-C", "30,15"},
 		},
+		PreferHostname: true,
 	},
 }
 
+// defaultPorts are scanned unless -ports is given
+const defaultPorts = "22,23,25,80,123,161,162,443"
+
+// checkKey groups hosts into one generated check: the port, plus the
+// detected service when service detection picked the template
+type checkKey struct {
+	port    int
+	service string
+}
+
 type CheckTemplate struct {
 	Type    string
 	Name    string
 	Timeout string
 	Options map[string]interface{}
+	// Check against the hostname rather than the IP (e.g. for TLS SNI)
+	// when every host on the port has a PTR name
+	PreferHostname bool
 }
 
 func main() {
 	var (
-		network     = flag.String("network", "", "CIDR network to scan (e.g., 192.168.1.0/24)")
+		network     = flag.String("network", "", "CIDR networks to scan, comma-separated (e.g., 192.168.1.0/24,10.0.0.0/24)")
+		parallel    = flag.Int("parallel", 4, "Maximum number of -network ranges scanned at once")
 		xmlFile     = flag.String("xml", "", "Use existing nmap XML file instead of scanning")
-		output      = flag.String("output", "config.yaml", "Output configuration file")
+		output      = flag.String("output", "config.yaml", "Output configuration file, or - for stdout")
+		format      = flag.String("format", "yaml", "Output format: yaml or json (json can be posted to /api/config/import)")
 		group       = flag.String("group", "discovered", "Group name for discovered hosts")
-		dhcpRange   = flag.String("dhcp", "100-200", "DHCP range (e.g., 100-200) - hosts in this range won't have static IP configured")
+		dhcpRange   = flag.String("dhcp", "100-200", "Comma-separated DHCP pools: address ranges (192.168.1.100-192.168.1.200), CIDR blocks or last-octet ranges (100-200) - hosts in a pool won't have static IP configured")
 		nmapPath    = flag.String("nmap", "/usr/bin/nmap", "Path to nmap binary")
 		enabled     = flag.Bool("enabled", true, "Mark discovered hosts as enabled")
 		osDetection = flag.Bool("os", false, "Enable OS detection (requires root)")
+		services    = flag.Bool("services", false, "Enable service/version detection so checks match the detected service rather than just the port")
+		ports       = flag.String("ports", defaultPorts, "Ports to scan (nmap -p syntax)")
 		verbose     = flag.Bool("verbose", false, "Verbose output")
+		resolve     = flag.Bool("resolve", false, "Reverse-DNS hosts that nmap returned without a hostname")
+		resolveWait = flag.Duration("resolve-timeout", 2*time.Second, "Timeout for each -resolve lookup")
+		reconcileIt = flag.Bool("reconcile", false, "Compare discovered hosts against -existing instead of generating a config")
+		existing    = flag.String("existing", "", "Existing configuration file to reconcile against")
+		updateTags  = flag.Bool("update-tags", false, "With -reconcile, set tags.last_seen on matched hosts in the -existing file")
 	)
 	flag.Parse()
 
+	if *reconcileIt && *existing == "" {
+		log.Fatal("-reconcile requires -existing config file")
+	}
+
+	if *format != "yaml" && *format != "json" {
+		log.Fatalf("Invalid -format %q: must be yaml or json", *format)
+	}
+
+	// Keep stdout clean for the configuration when writing it there
+	if *output == "-" {
+		status = os.Stderr
+	}
+
 	if *network == "" && *xmlFile == "" {
 		// Try to detect local network
 		detected := detectLocalNetwork()
@@ -262,45 +304,78 @@ func main() {
 			log.Fatal("No network specified and couldn't detect local network. Use -network flag.")
 		}
 		*network = detected
-		fmt.Printf("Auto-detected network: %s\n", *network)
+		fmt.Fprintf(status, "Auto-detected network: %s\n", *network)
 	}
 
-	var nmapData []byte
-	var err error
+	var nmapRun NmapRun
 
 	if *xmlFile != "" {
-		fmt.Printf("Reading nmap XML from: %s\n", *xmlFile)
-		nmapData, err = os.ReadFile(*xmlFile)
+		fmt.Fprintf(status, "Reading nmap XML from: %s\n", *xmlFile)
+		nmapData, err := os.ReadFile(*xmlFile)
 		if err != nil {
 			log.Fatalf("Failed to read XML file: %v", err)
 		}
+
+		// Parse nmap XML
+		if err := xml.Unmarshal(nmapData, &nmapRun); err != nil {
+			log.Fatalf("Failed to parse nmap XML: %v", err)
+		}
 	} else {
-		fmt.Printf("Scanning network: %s\n", *network)
-		nmapData, err = runNmapScan(*network, *nmapPath, *osDetection, *verbose)
-		if err != nil {
-			log.Fatalf("Failed to run nmap: %v", err)
+		networks := splitNetworks(*network)
+		fmt.Fprintf(status, "Scanning networks: %s\n", strings.Join(networks, ", "))
+		results := scanNetworks(networks, *parallel, func(network string) ([]byte, error) {
+			return runNmapScan(network, *nmapPath, *ports, *osDetection, *services, *verbose)
+		})
+
+		// Keep going with the ranges that worked, but say which didn't
+		failed := 0
+		for _, result := range results {
+			if result.err != nil {
+				failed++
+				log.Printf("Scan of %s failed: %v", result.network, result.err)
+			}
 		}
+
+		merged := mergeScans(results)
+		if merged == nil {
+			log.Fatalf("All %d network scans failed", len(results))
+		}
+		if failed > 0 {
+			fmt.Fprintf(status, "Using results from %d of %d networks\n", len(results)-failed, len(results))
+		}
+		nmapRun = *merged
 	}
 
-	// Parse nmap XML
-	var nmapRun NmapRun
-	if err := xml.Unmarshal(nmapData, &nmapRun); err != nil {
-		log.Fatalf("Failed to parse nmap XML: %v", err)
+	if *resolve {
+		resolved := newReverseResolver(*resolveWait).enrich(&nmapRun)
+		fmt.Fprintf(status, "Resolved %d hostnames with reverse DNS\n", resolved)
 	}
 
-	// Parse DHCP range
-	dhcpLow, dhcpHigh := parseDHCPRange(*dhcpRange)
+	if *reconcileIt {
+		runReconcile(&nmapRun, *existing, *updateTags)
+		return
+	}
+
+	// Parse DHCP pools
+	dhcp, err := parseDHCPPools(*dhcpRange)
+	if err != nil {
+		log.Fatalf("Invalid -dhcp: %v", err)
+	}
 
 	// Generate configuration
-	config := generateConfig(&nmapRun, *group, dhcpLow, dhcpHigh, *enabled)
+	config := generateConfig(&nmapRun, *group, dhcp, *enabled)
 
 	// Write configuration
-	if err := writeConfig(config, *output); err != nil {
+	if err := writeConfig(config, *output, *format); err != nil {
 		log.Fatalf("Failed to write configuration: %v", err)
 	}
 
-	fmt.Printf("\nConfiguration written to: %s\n", *output)
-	fmt.Printf("Discovered %d hosts and generated %d checks\n", len(config.Hosts), len(config.Checks))
+	destination := *output
+	if destination == "-" {
+		destination = "stdout"
+	}
+	fmt.Fprintf(status, "\nConfiguration written to: %s\n", destination)
+	fmt.Fprintf(status, "Discovered %d hosts and generated %d checks\n", len(config.Hosts), len(config.Checks))
 }
 
 func detectLocalNetwork() string {
@@ -330,24 +405,28 @@ func detectLocalNetwork() string {
 	return ""
 }
 
-func runNmapScan(network, nmapPath string, osDetection, verbose bool) ([]byte, error) {
+func runNmapScan(network, nmapPath, ports string, osDetection, services, verbose bool) ([]byte, error) {
 	args := []string{
 		"--system-dns",
 		"-oX", "-",
-		"-p", "22,23,25,80,123,161,162,443",
+		"-p", ports,
 	}
 
 	if osDetection {
 		args = append(args, "-O")
 	}
 
+	if services {
+		args = append(args, "-sV")
+	}
+
 	if verbose {
 		args = append(args, "-v")
 	}
 
 	args = append(args, network)
 
-	fmt.Printf("Running: %s %s\n", nmapPath, strings.Join(args, " "))
+	fmt.Fprintf(status, "Running: %s %s\n", nmapPath, strings.Join(args, " "))
 
 	cmd := exec.Command(nmapPath, args...)
 	output, err := cmd.Output()
@@ -364,23 +443,7 @@ func runNmapScan(network, nmapPath string, osDetection, verbose bool) ([]byte, e
 	return output, nil
 }
 
-func parseDHCPRange(dhcpRange string) (int, int) {
-	parts := strings.Split(dhcpRange, "-")
-	if len(parts) != 2 {
-		return 100, 200 // Default range
-	}
-
-	low, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-	high, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-
-	if err1 != nil || err2 != nil {
-		return 100, 200 // Default range
-	}
-
-	return low, high
-}
-
-func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabled bool) *Config {
+func generateConfig(nmapRun *NmapRun, group string, dhcp dhcpPools, enabled bool) *Config {
 	config := &Config{
 		Server: ServerConfig{
 			Port:         ":8000",
@@ -415,8 +478,10 @@ func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabl
 	}
 
 	var hosts []HostConfig
-	portHosts := make(map[int][]string)
+	checkHosts := make(map[checkKey][]string)
+	templates := make(map[checkKey]CheckTemplate)
 	allHosts := make([]string, 0)
+	namedHosts := make(map[string]bool)
 
 	// Process discovered hosts
 	for _, host := range nmapRun.Hosts {
@@ -424,15 +489,21 @@ func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabl
 			continue
 		}
 
-		hostConfig := processHost(host, group, dhcpLow, dhcpHigh, enabled)
+		hostConfig := processHost(host, group, dhcp, enabled)
 		if hostConfig != nil {
 			hosts = append(hosts, *hostConfig)
 			allHosts = append(allHosts, hostConfig.ID)
+			if hostConfig.Hostname != "" {
+				namedHosts[hostConfig.ID] = true
+			}
 
-			// Track which hosts have which ports open
+			// Track which hosts have which ports (and services) open
 			for _, port := range host.Ports {
 				if port.State.State == "open" {
-					portHosts[port.PortID] = append(portHosts[port.PortID], hostConfig.ID)
+					service, template := checkTemplateFor(port.PortID, port.Service)
+					key := checkKey{port: port.PortID, service: service}
+					checkHosts[key] = append(checkHosts[key], hostConfig.ID)
+					templates[key] = template
 				}
 			}
 		}
@@ -467,34 +538,38 @@ func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabl
 	}
 
 	// Generate port-specific checks
-	var ports []int
-	for port := range portHosts {
-		ports = append(ports, port)
+	var keys []checkKey
+	for key := range checkHosts {
+		keys = append(keys, key)
 	}
-	sort.Ints(ports)
-
-	for _, port := range ports {
-		hostList := portHosts[port]
-		if len(hostList) == 0 {
-			continue
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].port != keys[j].port {
+			return keys[i].port < keys[j].port
 		}
-
-		checkTemplate, exists := serviceChecks[port]
-		if !exists {
-			// Generic TCP check for unknown ports
-			checkTemplate = CheckTemplate{
-				Type:    "nagios",
-				Name:    fmt.Sprintf("Port %d Check", port),
-				Timeout: "10s",
-				Options: map[string]interface{}{
-					"program": "/usr/lib/nagios/plugins/check_tcp",
-					"options": []string{"-p", strconv.Itoa(port)},
-				},
+		return keys[i].service < keys[j].service
+	})
+
+	for _, key := range keys {
+		port := key.port
+		hostList := checkHosts[key]
+		checkTemplate := templates[key]
+
+		options := checkTemplate.Options
+		if checkTemplate.PreferHostname && allNamed(hostList, namedHosts) {
+			options = make(map[string]interface{}, len(checkTemplate.Options)+1)
+			for key, value := range checkTemplate.Options {
+				options[key] = value
 			}
+			options["target"] = "hostname"
+		}
+
+		checkID := fmt.Sprintf("port-%d-check", port)
+		if key.service != "" {
+			checkID = fmt.Sprintf("port-%d-%s-check", port, key.service)
 		}
 
 		portCheck := CheckConfig{
-			ID:   fmt.Sprintf("port-%d-check", port),
+			ID:   checkID,
 			Name: fmt.Sprintf("%s (Port %d)", checkTemplate.Name, port),
 			Type: checkTemplate.Type,
 			Hosts: hostList,
@@ -507,7 +582,7 @@ func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabl
 			Threshold: 2,
 			Timeout:   checkTemplate.Timeout,
 			Enabled:   true,
-			Options:   checkTemplate.Options,
+			Options:   options,
 		}
 		checks = append(checks, portCheck)
 	}
@@ -516,7 +591,17 @@ func generateConfig(nmapRun *NmapRun, group string, dhcpLow, dhcpHigh int, enabl
 	return config
 }
 
-func processHost(host Host, group string, dhcpLow, dhcpHigh int, enabled bool) *HostConfig {
+// allNamed reports whether every host ID has a hostname
+func allNamed(hostIDs []string, namedHosts map[string]bool) bool {
+	for _, id := range hostIDs {
+		if !namedHosts[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func processHost(host Host, group string, dhcp dhcpPools, enabled bool) *HostConfig {
 	var ipv4, hostname string
 
 	// Get IP address
@@ -546,8 +631,8 @@ func processHost(host Host, group string, dhcpLow, dhcpHigh int, enabled bool) *
 		displayName = strings.Split(hostname, ".")[0]
 	}
 
-	// Check if IP is in DHCP range
-	isDHCP := isInDHCPRange(ipv4, dhcpLow, dhcpHigh)
+	// Check if IP is in a DHCP pool
+	isDHCP := dhcp.contains(ipv4)
 
 	tags := make(map[string]string)
 	
@@ -608,33 +693,42 @@ func generateHostID(ipv4, hostname string) string {
 	return fmt.Sprintf("host-%s", strings.ReplaceAll(ipv4, ".", "-"))
 }
 
-func isInDHCPRange(ipv4 string, dhcpLow, dhcpHigh int) bool {
-	parts := strings.Split(ipv4, ".")
-	if len(parts) != 4 {
-		return false
-	}
-
-	lastOctet, err := strconv.Atoi(parts[3])
-	if err != nil {
-		return false
-	}
-
-	return lastOctet >= dhcpLow && lastOctet <= dhcpHigh
-}
-
-func writeConfig(config *Config, filename string) error {
+// writeConfig writes the configuration as YAML (with a header comment) or
+// JSON to filename, or to stdout when filename is "-"
+func writeConfig(config *Config, filename, format string) error {
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal YAML: %w", err)
 	}
 
-	// Add header comment
-	header := fmt.Sprintf("# Raven Network Monitoring Configuration\n# Generated by raven-discover on %s\n# Contains %d hosts and %d checks\n\n",
-		time.Now().Format("2006-01-02 15:04:05"),
-		len(config.Hosts),
-		len(config.Checks))
+	var finalData []byte
+	if format == "json" {
+		// Round-trip through YAML so keys match the config format
+		var generic interface{}
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return fmt.Errorf("failed to convert to JSON: %w", err)
+		}
+		finalData, err = json.MarshalIndent(generic, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		finalData = append(finalData, '\n')
+	} else {
+		// Add header comment
+		header := fmt.Sprintf("# Raven Network Monitoring Configuration\n# Generated by raven-discover on %s\n# Contains %d hosts and %d checks\n\n",
+			time.Now().Format("2006-01-02 15:04:05"),
+			len(config.Hosts),
+			len(config.Checks))
+
+		finalData = append([]byte(header), data...)
+	}
 
-	finalData := append([]byte(header), data...)
+	if filename == "-" {
+		if _, err := os.Stdout.Write(finalData); err != nil {
+			return fmt.Errorf("failed to write to stdout: %w", err)
+		}
+		return nil
+	}
 
 	if err := os.WriteFile(filename, finalData, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
@@ -0,0 +1,269 @@
+// cmd/raven-discover/reconcile.go - Compare discovered hosts against an existing configuration
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DiscoveredHost is the subset of an nmap host used for reconciliation
+type DiscoveredHost struct {
+	IPv4      string
+	Hostnames []string
+}
+
+// ReconcileMatch pairs a configured host with what was seen on the network
+type ReconcileMatch struct {
+	Configured HostConfig
+	Discovered DiscoveredHost
+}
+
+// ReconcileReport is the result of comparing a scan to a configuration
+type ReconcileReport struct {
+	Matched            []ReconcileMatch
+	MissingFromNetwork []HostConfig
+	NewOnNetwork       []DiscoveredHost
+}
+
+// reconcile compares discovered hosts against configured hosts. A configured
+// host matches when its IPv4, hostname or generated host ID lines up with a
+// discovered host.
+func reconcile(nmapRun *NmapRun, configured []HostConfig) *ReconcileReport {
+	report := &ReconcileReport{}
+	seen := make(map[int]bool)
+
+	for _, discovered := range discoveredHosts(nmapRun) {
+		matched := false
+		for i, host := range configured {
+			if seen[i] || !hostMatches(host, discovered) {
+				continue
+			}
+			seen[i] = true
+			matched = true
+			report.Matched = append(report.Matched, ReconcileMatch{Configured: host, Discovered: discovered})
+			break
+		}
+		if !matched {
+			report.NewOnNetwork = append(report.NewOnNetwork, discovered)
+		}
+	}
+
+	for i, host := range configured {
+		if !seen[i] {
+			report.MissingFromNetwork = append(report.MissingFromNetwork, host)
+		}
+	}
+
+	sort.Slice(report.Matched, func(i, j int) bool {
+		return report.Matched[i].Configured.ID < report.Matched[j].Configured.ID
+	})
+	sort.Slice(report.MissingFromNetwork, func(i, j int) bool {
+		return report.MissingFromNetwork[i].ID < report.MissingFromNetwork[j].ID
+	})
+	sort.Slice(report.NewOnNetwork, func(i, j int) bool {
+		return report.NewOnNetwork[i].IPv4 < report.NewOnNetwork[j].IPv4
+	})
+
+	return report
+}
+
+func runReconcile(nmapRun *NmapRun, existing string, updateTags bool) {
+	configured, err := loadExistingHosts(existing)
+	if err != nil {
+		log.Fatalf("Failed to load existing configuration: %v", err)
+	}
+
+	report := reconcile(nmapRun, configured)
+	printReconcileReport(report)
+
+	if updateTags && len(report.Matched) > 0 {
+		if err := updateLastSeenTags(existing, report, time.Now()); err != nil {
+			log.Fatalf("Failed to update tags: %v", err)
+		}
+		fmt.Printf("\nUpdated last_seen on %d hosts in: %s\n", len(report.Matched), existing)
+	}
+}
+
+func discoveredHosts(nmapRun *NmapRun) []DiscoveredHost {
+	var hosts []DiscoveredHost
+
+	for _, host := range nmapRun.Hosts {
+		if host.Status.State != "" && host.Status.State != "up" {
+			continue
+		}
+
+		discovered := DiscoveredHost{}
+		for _, addr := range host.Addresses {
+			if addr.AddrType == "ipv4" {
+				discovered.IPv4 = addr.Addr
+				break
+			}
+		}
+		if discovered.IPv4 == "" {
+			continue
+		}
+
+		for _, hn := range host.Hostnames {
+			if hn.Name != "" {
+				discovered.Hostnames = append(discovered.Hostnames, hn.Name)
+			}
+		}
+
+		hosts = append(hosts, discovered)
+	}
+
+	return hosts
+}
+
+func hostMatches(host HostConfig, discovered DiscoveredHost) bool {
+	if host.IPv4 != "" && host.IPv4 == discovered.IPv4 {
+		return true
+	}
+
+	for _, name := range discovered.Hostnames {
+		if host.Hostname != "" && strings.EqualFold(host.Hostname, name) {
+			return true
+		}
+		if strings.EqualFold(host.ID, generateHostID(discovered.IPv4, name)) {
+			return true
+		}
+	}
+
+	// Hosts discovered in the DHCP range are configured without an IP
+	return len(discovered.Hostnames) == 0 && host.ID == generateHostID(discovered.IPv4, "")
+}
+
+func printReconcileReport(report *ReconcileReport) {
+	fmt.Printf("\nMatched (%d):\n", len(report.Matched))
+	for _, match := range report.Matched {
+		fmt.Printf("  %-20s %-15s %s\n", match.Configured.ID, match.Discovered.IPv4, strings.Join(match.Discovered.Hostnames, ","))
+	}
+
+	fmt.Printf("\nMissing from network (%d):\n", len(report.MissingFromNetwork))
+	for _, host := range report.MissingFromNetwork {
+		lastSeen := host.Tags["last_seen"]
+		if lastSeen == "" {
+			lastSeen = "never"
+		}
+		fmt.Printf("  %-20s %-15s %-30s last seen: %s\n", host.ID, host.IPv4, host.Hostname, lastSeen)
+	}
+
+	fmt.Printf("\nNew on network (%d):\n", len(report.NewOnNetwork))
+	for _, host := range report.NewOnNetwork {
+		fmt.Printf("  %-20s %-15s %s\n", generateHostID(host.IPv4, firstHostname(host.Hostnames)), host.IPv4, strings.Join(host.Hostnames, ","))
+	}
+}
+
+func firstHostname(hostnames []string) string {
+	if len(hostnames) == 0 {
+		return ""
+	}
+	return hostnames[0]
+}
+
+// loadExistingHosts reads the hosts section of an existing configuration.
+// Include directories are not followed.
+func loadExistingHosts(filename string) ([]HostConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var existing struct {
+		Hosts []HostConfig `yaml:"hosts"`
+	}
+	if err := yaml.Unmarshal(data, &existing); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return existing.Hosts, nil
+}
+
+// updateLastSeenTags sets tags.last_seen on matched hosts in place. The file
+// is edited as a YAML node tree so comments and unrelated sections survive.
+func updateLastSeenTags(filename string, report *ReconcileReport, seenAt time.Time) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return fmt.Errorf("config is empty")
+	}
+
+	matched := make(map[string]bool)
+	for _, match := range report.Matched {
+		matched[match.Configured.ID] = true
+	}
+
+	hosts := mappingValue(doc.Content[0], "hosts")
+	if hosts == nil || hosts.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config has no hosts list")
+	}
+
+	timestamp := seenAt.Format(time.RFC3339)
+	for _, host := range hosts.Content {
+		id := mappingValue(host, "id")
+		if id == nil || !matched[id.Value] {
+			continue
+		}
+
+		tags := mappingValue(host, "tags")
+		if tags == nil || tags.Kind != yaml.MappingNode {
+			tags = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(host, "tags", tags)
+		}
+		setMappingValue(tags, "last_seen", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: timestamp})
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to stat config: %w", err)
+	}
+
+	if err := os.WriteFile(filename, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
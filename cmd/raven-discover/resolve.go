@@ -0,0 +1,75 @@
+// cmd/raven-discover/resolve.go - Reverse DNS for hosts nmap didn't name
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// reverseResolver looks up PTR names with a per-lookup timeout, caching
+// results (including misses) so each address is only asked about once
+type reverseResolver struct {
+	timeout time.Duration
+	cache   map[string]string // IP -> name, "" when there is none
+}
+
+func newReverseResolver(timeout time.Duration) *reverseResolver {
+	return &reverseResolver{
+		timeout: timeout,
+		cache:   make(map[string]string),
+	}
+}
+
+// lookup returns the first PTR name for ip without the trailing dot, or ""
+func (r *reverseResolver) lookup(ip string) string {
+	if name, cached := r.cache[ip]; cached {
+		return name
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var name string
+	if names, err := net.DefaultResolver.LookupAddr(ctx, ip); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+	r.cache[ip] = name
+	return name
+}
+
+// enrich adds a PTR hostname to each up host that nmap returned without a
+// name, and returns how many were resolved
+func (r *reverseResolver) enrich(nmapRun *NmapRun) int {
+	resolved := 0
+
+	for i := range nmapRun.Hosts {
+		host := &nmapRun.Hosts[i]
+		if host.Status.State != "up" || hasHostname(host) {
+			continue
+		}
+
+		for _, addr := range host.Addresses {
+			if addr.AddrType != "ipv4" {
+				continue
+			}
+			if name := r.lookup(addr.Addr); name != "" {
+				host.Hostnames = append(host.Hostnames, Hostname{Name: name, Type: "PTR"})
+				resolved++
+			}
+			break
+		}
+	}
+
+	return resolved
+}
+
+func hasHostname(host *Host) bool {
+	for _, hn := range host.Hostnames {
+		if hn.Name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+// cmd/raven-discover/scan.go - Scanning several network ranges in parallel
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// rangeScan is the outcome of scanning one -network range
+type rangeScan struct {
+	network string
+	run     *NmapRun
+	err     error
+}
+
+// splitNetworks parses the comma-separated -network list
+func splitNetworks(spec string) []string {
+	var networks []string
+	for _, network := range strings.Split(spec, ",") {
+		if network = strings.TrimSpace(network); network != "" {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// scanNetworks runs scan on each range with at most parallel scans at once.
+// Results come back in the order of networks, whatever order they finish in.
+func scanNetworks(networks []string, parallel int, scan func(network string) ([]byte, error)) []rangeScan {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]rangeScan, len(networks))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, network := range networks {
+		wg.Add(1)
+		go func(i int, network string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := rangeScan{network: network}
+			data, err := scan(network)
+			if err == nil {
+				var run NmapRun
+				if err = xml.Unmarshal(data, &run); err != nil {
+					err = fmt.Errorf("failed to parse nmap XML: %w", err)
+				} else {
+					result.run = &run
+				}
+			}
+			result.err = err
+			results[i] = result
+		}(i, network)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// mergeScans combines the successful scans into one run, in range order.
+// A host found by overlapping ranges is kept once, from the first range.
+func mergeScans(results []rangeScan) *NmapRun {
+	var merged *NmapRun
+	seen := make(map[string]bool)
+
+	for _, result := range results {
+		if result.run == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &NmapRun{
+				Scanner:  result.run.Scanner,
+				Args:     result.run.Args,
+				Start:    result.run.Start,
+				StartStr: result.run.StartStr,
+				Version:  result.run.Version,
+				ScanInfo: result.run.ScanInfo,
+			}
+		}
+
+		for _, host := range result.run.Hosts {
+			key := hostKey(host)
+			if key != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Hosts = append(merged.Hosts, host)
+		}
+	}
+
+	return merged
+}
+
+// hostKey identifies a host across ranges by its IPv4 address
+func hostKey(host Host) string {
+	for _, addr := range host.Addresses {
+		if addr.AddrType == "ipv4" {
+			return addr.Addr
+		}
+	}
+	return ""
+}
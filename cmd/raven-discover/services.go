@@ -0,0 +1,113 @@
+// cmd/raven-discover/services.go - Choosing check templates from detected services
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// httpServers picks a product-specific HTTP check from nmap's product string.
+// The check also expects the product in the Server header, so a different
+// server answering on the port is reported.
+var httpServers = []struct {
+	product string // Lower-case substring of the nmap product
+	key     string
+	name    string
+	header  string // Expected in the Server response header
+}{
+	{"nginx", "nginx", "Nginx", "nginx"},
+	{"apache", "apache", "Apache", "Apache"},
+	{"iis", "iis", "IIS", "Microsoft-IIS"},
+	{"lighttpd", "lighttpd", "lighttpd", "lighttpd"},
+	{"caddy", "caddy", "Caddy", "Caddy"},
+}
+
+// serviceDetected reports whether nmap identified the service by probing it
+// (-sV) rather than guessing from the port number
+func serviceDetected(svc PortService) bool {
+	return svc.Method == "probed" && svc.Name != ""
+}
+
+// checkTemplateFor picks the check for an open port. A detected service
+// chooses the template (and a key that keeps e.g. nginx and Apache hosts on
+// the same port in separate checks); otherwise it falls back to the
+// port-number mapping, then to a generic TCP check.
+func checkTemplateFor(port int, svc PortService) (string, CheckTemplate) {
+	if serviceDetected(svc) {
+		if key, template, ok := serviceTemplate(port, svc); ok {
+			return key, template
+		}
+	}
+
+	if template, exists := serviceChecks[port]; exists {
+		return "", template
+	}
+
+	return "", CheckTemplate{
+		Type:    "nagios",
+		Name:    fmt.Sprintf("Port %d Check", port),
+		Timeout: "10s",
+		Options: map[string]interface{}{
+			"program": "/usr/lib/nagios/plugins/check_tcp",
+			"options": []string{"-p", strconv.Itoa(port)},
+		},
+	}
+}
+
+// serviceTemplate returns the template for a detected service, or false for
+// services without one
+func serviceTemplate(port int, svc PortService) (string, CheckTemplate, bool) {
+	portArg := strconv.Itoa(port)
+	product := strings.ToLower(svc.Product)
+
+	nagios := func(name, timeout, program string, options ...string) CheckTemplate {
+		return CheckTemplate{
+			Type:    "nagios",
+			Name:    name,
+			Timeout: timeout,
+			Options: map[string]interface{}{
+				"program": "/usr/lib/nagios/plugins/" + program,
+				"options": append([]string{"-p", portArg}, options...),
+			},
+		}
+	}
+
+	switch {
+	case svc.Name == "https" || (svc.Name == "http" && svc.Tunnel == "ssl"):
+		template := nagios("HTTPS Service", "15s", "check_http", "-S", "-C", "30,15")
+		template.PreferHostname = true
+		return "https", template, true
+
+	case svc.Name == "http":
+		for _, server := range httpServers {
+			if strings.Contains(product, server.product) {
+				return server.key, nagios(server.name+" HTTP Service", "15s", "check_http", "-d", "Server: "+server.header), true
+			}
+		}
+		return "http", nagios("HTTP Service", "15s", "check_http"), true
+
+	case svc.Name == "ssh":
+		return "ssh", nagios("SSH Service", "10s", "check_ssh"), true
+
+	case svc.Name == "smtp":
+		return "smtp", nagios("SMTP Service", "10s", "check_smtp"), true
+
+	case svc.Name == "mysql":
+		if strings.Contains(product, "mariadb") {
+			return "mariadb", nagios("MariaDB Service", "10s", "check_tcp"), true
+		}
+		return "mysql", nagios("MySQL Service", "10s", "check_tcp"), true
+
+	case svc.Name == "postgresql":
+		return "postgresql", nagios("PostgreSQL Service", "10s", "check_tcp"), true
+
+	case svc.Name == "redis":
+		return "redis", nagios("Redis Service", "10s", "check_tcp", "-E", "-s", `PING\r\n`, "-e", "+PONG"), true
+
+	case svc.Name == "mongodb":
+		return "mongodb", nagios("MongoDB Service", "10s", "check_tcp"), true
+	}
+
+	return "", CheckTemplate{}, false
+}
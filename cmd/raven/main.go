@@ -2,6 +2,7 @@ package main
 
 import (
     "context"
+    "errors"
     "flag"
     "fmt"
     "os"
@@ -17,13 +18,36 @@ import (
     "raven2/internal/web"
 )
 
+// Exit codes for database startup failures, so systemd restart policies can
+// tell a lock (retrying may succeed) from corruption (needs -repair), a
+// database written by a newer Raven or a data directory it can't write to
+const (
+    exitDatabaseLocked  = 75 // EX_TEMPFAIL
+    exitDatabaseCorrupt = 65 // EX_DATAERR, also used for a newer schema
+    exitDataDir         = 73 // EX_CANTCREAT
+)
+
 func main() {
     configFile := flag.String("config", "config.yaml", "Configuration file path")
     version := flag.Bool("version", false, "Show version information")
+    repair := flag.Bool("repair", false, "Move a corrupted database aside, rebuild it from the readable records and exit")
+    migrateConfig := flag.Bool("migrate-config", false, "Write the -config file upgraded to the current config version to stdout and exit")
     flag.Parse()
 
     if *version {
-        fmt.Printf("Raven Network Monitoring v2.0.0\nBuild: %s\n", getBuildInfo())
+        fmt.Printf("Raven Network Monitoring v2.0.0\nBuild: %s\nDatabase schema: %d\n", getBuildInfo(), database.SchemaVersion)
+        os.Exit(0)
+    }
+
+    if *migrateConfig {
+        upgraded, notes, err := config.MigrateFile(*configFile)
+        if err != nil {
+            logrus.Fatalf("Failed to migrate config: %v", err)
+        }
+        for _, note := range notes {
+            fmt.Fprintf(os.Stderr, "upgraded %s\n", note)
+        }
+        os.Stdout.Write(upgraded)
         os.Exit(0)
     }
 
@@ -36,21 +60,44 @@ func main() {
     // Setup logging
     setupLogging(cfg.Logging)
 
+    for _, warning := range cfg.Warnings() {
+        logrus.Warnf("Config: %s", warning)
+    }
+
+    if *repair {
+        summary, err := database.RepairBoltStore(cfg.Database.Path, cfg.Database.Files())
+        if err != nil {
+            logrus.Fatalf("Failed to repair database: %v", err)
+        }
+        logrus.Infof("Database repaired: %s", summary)
+        os.Exit(0)
+    }
+
     logrus.WithFields(logrus.Fields{
-        "config_file": *configFile,
-        "port":        cfg.Server.Port,
-        "workers":     cfg.Server.Workers,
+        "config_file":   cfg.Path(),
+        "include_files": len(cfg.IncludeFiles()),
+        "port":          cfg.Server.Port,
+        "workers":       cfg.Server.Workers,
     }).Info("Starting Raven monitoring system")
 
     // Initialize database
-    store, err := database.NewExtendedBoltStore(cfg.Database.Path)
+    store, err := database.NewExtendedBoltStore(cfg.Database.Path, cfg.Database.Files())
     if err != nil {
-        logrus.Fatalf("Failed to initialize database: %v", err)
+        logrus.Errorf("Failed to initialize database: %v", err)
+        switch {
+        case errors.Is(err, database.ErrDatabaseLocked):
+            os.Exit(exitDatabaseLocked)
+        case errors.Is(err, database.ErrDatabaseCorrupt), errors.Is(err, database.ErrSchemaTooNew):
+            os.Exit(exitDatabaseCorrupt)
+        case errors.Is(err, database.ErrDataDirNotWritable):
+            os.Exit(exitDataDir)
+        }
+        os.Exit(1)
     }
     defer store.Close()
 
     // Initialize metrics
-    metricsCollector := metrics.NewCollector(store)
+    metricsCollector := metrics.NewCollector(store, cfg.Prometheus)
 
     // Initialize monitoring engine
     engine, err := monitoring.NewEngine(cfg, store, metricsCollector)
@@ -80,6 +127,9 @@ func main() {
 
     // Graceful shutdown
     cancel()
+
+    // Record results from checks still running before the store closes
+    engine.Stop()
     
     // Give services time to shutdown
     time.Sleep(2 * time.Second)
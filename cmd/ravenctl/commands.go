@@ -0,0 +1,273 @@
+// cmd/ravenctl/commands.go - The hosts, checks, alerts, status and config commands
+package main
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "raven2/internal/config"
+    "raven2/internal/database"
+    "raven2/pkg/client"
+)
+
+// command runs one ravenctl command against the API
+type command struct {
+    api      *client.Client
+    out      *printer
+    host     string // -host
+    severity string // -severity
+}
+
+func (c *command) run(ctx context.Context, args []string) error {
+    switch args[0] {
+    case "hosts":
+        return c.hosts(ctx, args[1:])
+    case "checks":
+        return c.checks(ctx, args[1:])
+    case "alerts":
+        return c.alerts(ctx, args[1:])
+    case "status":
+        if len(args) != 2 {
+            return usagef("status takes a host ID")
+        }
+        return c.status(ctx, args[1])
+    case "config":
+        return c.config(ctx, args[1:])
+    }
+    return usagef("unknown command %q", args[0])
+}
+
+func (c *command) hosts(ctx context.Context, args []string) error {
+    switch {
+    case len(args) == 1 && args[0] == "list":
+        hosts, err := c.api.Hosts(ctx)
+        if err != nil {
+            return err
+        }
+        sort.Slice(hosts, func(i, j int) bool { return hosts[i].ID < hosts[j].ID })
+        return c.out.print(hosts, func(t *tabwriter.Writer) {
+            fmt.Fprintln(t, "ID\tNAME\tGROUP\tENABLED\tSTATUS\tFOR\tCHECKS")
+            for _, host := range hosts {
+                fmt.Fprintf(t, "%s\t%s\t%s\t%t\t%s\t%s\t%d\n", host.ID, host.Name, host.Group, host.Enabled, host.Status, orDash(host.StateDurationText), host.CheckCount)
+            }
+        })
+
+    case len(args) == 2 && args[0] == "get":
+        host, err := c.api.Host(ctx, args[1])
+        if err != nil {
+            return err
+        }
+        return c.out.print(host, func(t *tabwriter.Writer) {
+            fmt.Fprintf(t, "ID:\t%s\n", host.ID)
+            fmt.Fprintf(t, "Name:\t%s\n", host.Name)
+            fmt.Fprintf(t, "Display name:\t%s\n", orDash(host.DisplayName))
+            fmt.Fprintf(t, "IPv4:\t%s\n", orDash(host.IPv4))
+            fmt.Fprintf(t, "Hostname:\t%s\n", orDash(host.Hostname))
+            fmt.Fprintf(t, "Group:\t%s\n", host.Group)
+            fmt.Fprintf(t, "Enabled:\t%t\n", host.Enabled)
+            fmt.Fprintf(t, "Status:\t%s for %s\n", host.Status, orDash(host.StateDurationText))
+            fmt.Fprintf(t, "Checks:\t%d\n", host.CheckCount)
+            fmt.Fprintf(t, "Last check:\t%s\n", formatTime(host.LastCheck))
+            fmt.Fprintf(t, "Next check:\t%s\n", formatTime(host.NextCheck))
+            fmt.Fprintf(t, "Tags:\t%s\n", formatTags(host.Tags))
+        })
+
+    case len(args) >= 2 && (args[0] == "disable" || args[0] == "enable"):
+        enabled := args[0] == "enable"
+        var updated []*database.Host
+        for _, id := range args[1:] {
+            host, err := c.api.SetHostEnabled(ctx, id, enabled)
+            if err != nil {
+                return fmt.Errorf("%s: %w", id, err)
+            }
+            updated = append(updated, host)
+        }
+        return c.out.print(updated, func(t *tabwriter.Writer) {
+            for _, host := range updated {
+                fmt.Fprintf(t, "%s %sd\n", host.ID, args[0])
+            }
+        })
+    }
+    return usagef("expected hosts list, get <host>, disable <host>... or enable <host>...")
+}
+
+func (c *command) checks(ctx context.Context, args []string) error {
+    switch {
+    case len(args) == 1 && args[0] == "list":
+        checks, err := c.api.Checks(ctx)
+        if err != nil {
+            return err
+        }
+        sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+        return c.out.print(checks, func(t *tabwriter.Writer) {
+            fmt.Fprintln(t, "ID\tNAME\tTYPE\tENABLED\tHOSTS")
+            for _, check := range checks {
+                fmt.Fprintf(t, "%s\t%s\t%s\t%t\t%s\n", check.ID, check.Name, check.Type, check.Enabled, orDash(strings.Join(check.Hosts, ",")))
+            }
+        })
+
+    case len(args) == 2 && args[0] == "run":
+        hosts, err := c.api.RunCheck(ctx, args[1], c.host)
+        if err != nil {
+            return err
+        }
+        result := map[string]interface{}{"check": args[1], "hosts": hosts}
+        return c.out.print(result, func(t *tabwriter.Writer) {
+            fmt.Fprintf(t, "Queued %s on %s\n", args[1], orDash(strings.Join(hosts, ", ")))
+        })
+    }
+    return usagef("expected checks list or run <check> [-host <host>]")
+}
+
+func (c *command) alerts(ctx context.Context, args []string) error {
+    switch {
+    case len(args) == 1 && args[0] == "list":
+        alerts, err := c.api.Alerts(ctx, c.severity)
+        if err != nil {
+            return err
+        }
+        return c.out.print(alerts, func(t *tabwriter.Writer) {
+            fmt.Fprintln(t, "SEVERITY\tHOST\tCHECK\tFOR\tSNOOZED UNTIL\tMESSAGE")
+            for _, alert := range alerts {
+                snoozed := "-"
+                if alert.SnoozedUntil != nil {
+                    snoozed = formatTime(*alert.SnoozedUntil)
+                }
+                fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\n", alert.Severity, alert.Host, alert.Check, orDash(alert.StateDurationText), snoozed, firstLine(alert.Message))
+            }
+        })
+
+    case len(args) == 4 && args[0] == "snooze":
+        duration, err := time.ParseDuration(args[3])
+        if err != nil {
+            return usagef("invalid duration %q, e.g. 2h", args[3])
+        }
+        snooze, err := c.api.SnoozeAlert(ctx, args[1], args[2], duration)
+        if err != nil {
+            return err
+        }
+        return c.out.print(snooze, func(t *tabwriter.Writer) {
+            fmt.Fprintf(t, "Snoozed %s/%s until %s\n", snooze.HostID, snooze.CheckID, formatTime(snooze.Until))
+        })
+
+    case len(args) == 3 && args[0] == "unsnooze":
+        if err := c.api.UnsnoozeAlert(ctx, args[1], args[2]); err != nil {
+            return err
+        }
+        result := map[string]string{"host_id": args[1], "check_id": args[2]}
+        return c.out.print(result, func(t *tabwriter.Writer) {
+            fmt.Fprintf(t, "Cleared the snooze on %s/%s\n", args[1], args[2])
+        })
+
+    case len(args) >= 1 && args[0] == "ack":
+        return usagef("Raven has no alert acknowledgements; use alerts snooze <host> <check> <duration>")
+    }
+    return usagef("expected alerts list, snooze <host> <check> <duration> or unsnooze <host> <check>")
+}
+
+func (c *command) status(ctx context.Context, hostID string) error {
+    // An unknown host is an error, not an empty list
+    if _, err := c.api.Host(ctx, hostID); err != nil {
+        return err
+    }
+    statuses, err := c.api.HostStatus(ctx, hostID)
+    if err != nil {
+        return err
+    }
+    sort.Slice(statuses, func(i, j int) bool { return statuses[i].CheckID < statuses[j].CheckID })
+
+    return c.out.print(statuses, func(t *tabwriter.Writer) {
+        fmt.Fprintln(t, "CHECK\tSTATE\tFOR\tCHECKED\tOUTPUT")
+        for _, status := range statuses {
+            state := strings.ToUpper(database.StateName(status.ExitCode))
+            switch {
+            case status.ObserveOnly:
+                state += " (observe only)"
+            case status.OutOfPeriod:
+                state += " (out of period)"
+            case status.Overdue:
+                state += " (overdue)"
+            }
+            fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\n", status.CheckName, state, age(status.LastStateChange), formatTime(status.Timestamp), firstLine(status.Output))
+        }
+    })
+}
+
+func (c *command) config(ctx context.Context, args []string) error {
+    switch {
+    case len(args) <= 2 && len(args) > 0 && args[0] == "validate":
+        // Checked locally, so a config can be validated before the server
+        // is pointed at it
+        file := "config.yaml"
+        if len(args) == 2 {
+            file = args[1]
+        }
+        cfg, err := config.Load(file)
+        if err != nil {
+            return err
+        }
+        warnings := cfg.Warnings()
+        result := map[string]interface{}{
+            "config_file":   cfg.Path(),
+            "include_files": cfg.IncludeFiles(),
+            "hosts":         len(cfg.Hosts),
+            "checks":        len(cfg.Checks),
+            "warnings":      warnings,
+        }
+        return c.out.print(result, func(t *tabwriter.Writer) {
+            fmt.Fprintf(t, "%s is valid: %d hosts, %d checks, %d include files\n", cfg.Path(), len(cfg.Hosts), len(cfg.Checks), len(cfg.IncludeFiles()))
+            for _, warning := range warnings {
+                fmt.Fprintf(t, "warning: %s\n", warning)
+            }
+        })
+
+    case len(args) == 1 && args[0] == "reload":
+        result, err := c.api.ReloadConfig(ctx)
+        if err != nil {
+            return err
+        }
+        return c.out.print(result, func(t *tabwriter.Writer) {
+            fmt.Fprintln(t, result.Message)
+            if !result.Changed || result.Changes == nil {
+                fmt.Fprintln(t, "No changes")
+            } else {
+                printChangeSet(t, "Groups", result.Changes.Groups)
+                printChangeSet(t, "Hosts", result.Changes.Hosts)
+                printChangeSet(t, "Checks", result.Changes.Checks)
+                if len(result.Changes.Settings) > 0 {
+                    fmt.Fprintf(t, "Settings:\t%s\n", strings.Join(result.Changes.Settings, ", "))
+                }
+                if len(result.Changes.RestartRequired) > 0 {
+                    fmt.Fprintf(t, "Restart required:\t%s\n", strings.Join(result.Changes.RestartRequired, ", "))
+                }
+            }
+            for _, warning := range result.Warnings {
+                fmt.Fprintf(t, "warning: %s\n", warning)
+            }
+        })
+    }
+    return usagef("expected config validate [file] or config reload")
+}
+
+func printChangeSet(t *tabwriter.Writer, name string, set config.ChangeSet) {
+    if set.Empty() {
+        return
+    }
+    var parts []string
+    for _, part := range []struct {
+        verb string
+        ids  []string
+    }{{"added", set.Added}, {"changed", set.Changed}, {"removed", set.Removed}} {
+        if len(part.ids) > 0 {
+            parts = append(parts, part.verb+" "+strings.Join(part.ids, ", "))
+        }
+    }
+    for from, to := range set.Renamed {
+        parts = append(parts, fmt.Sprintf("renamed %s to %s", from, to))
+    }
+    fmt.Fprintf(t, "%s:\t%s\n", name, strings.Join(parts, "; "))
+}
@@ -0,0 +1,181 @@
+// cmd/ravenctl/main.go - Command line client for the Raven API
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+    "raven2/pkg/client"
+)
+
+// Exit codes, so scripts can tell a failed call from a mistyped command
+const (
+    exitOK    = 0
+    exitError = 1 // The API returned an error, or the server couldn't be reached
+    exitUsage = 2
+)
+
+const usage = `Usage: ravenctl [flags] <command> [arguments]
+
+Commands:
+  hosts list
+  hosts get <host>
+  hosts disable <host>...
+  hosts enable <host>...
+  checks list
+  checks run <check> [-host <host>]
+  alerts list [-severity critical|warning|unknown]
+  alerts snooze <host> <check> <duration>
+  alerts unsnooze <host> <check>
+  status <host>
+  config validate [file]
+  config reload
+
+Flags:
+`
+
+// settings are where the server is and how to authenticate, from
+// ~/.raven.yaml, then RAVEN_URL and RAVEN_TOKEN, then flags
+type settings struct {
+    URL   string `yaml:"url"`
+    Token string `yaml:"token"`
+}
+
+// usageError is a command line mistake; it exits with exitUsage
+type usageError struct {
+    message string
+}
+
+func (e *usageError) Error() string {
+    return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+    return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+    flags := flag.NewFlagSet("ravenctl", flag.ContinueOnError)
+    flags.Usage = func() {
+        fmt.Fprint(flags.Output(), usage)
+        flags.PrintDefaults()
+    }
+    serverURL := flags.String("server", "", "Raven server URL (default $RAVEN_URL, the settings file, or "+client.DefaultURL+")")
+    token := flags.String("token", "", "Bearer token sent to the server (default $RAVEN_TOKEN or the settings file)")
+    settingsFile := flags.String("settings", "~/.raven.yaml", "Settings file with url and token")
+    output := flags.String("o", "table", "Output format: table or json")
+    timeout := flags.Duration("timeout", 30*time.Second, "How long to wait for the server")
+    hostFlag := flags.String("host", "", "checks run: run on this host only")
+    severity := flags.String("severity", "", "alerts list: only this severity")
+
+    args, err := parseInterspersed(flags, os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        os.Exit(exitOK)
+    }
+    if err != nil {
+        os.Exit(exitUsage)
+    }
+    if *output != "table" && *output != "json" {
+        fmt.Fprintf(os.Stderr, "ravenctl: -o must be table or json\n")
+        os.Exit(exitUsage)
+    }
+    if len(args) == 0 {
+        flags.Usage()
+        os.Exit(exitUsage)
+    }
+
+    resolved, err := loadSettings(*settingsFile)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ravenctl: %v\n", err)
+        os.Exit(exitUsage)
+    }
+    if *serverURL != "" {
+        resolved.URL = *serverURL
+    }
+    if *token != "" {
+        resolved.Token = *token
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+    defer cancel()
+
+    cmd := &command{
+        api:      client.New(resolved.URL, resolved.Token),
+        out:      newPrinter(os.Stdout, *output == "json"),
+        host:     *hostFlag,
+        severity: *severity,
+    }
+    err = cmd.run(ctx, args)
+
+    var usageErr *usageError
+    switch {
+    case err == nil:
+        os.Exit(exitOK)
+    case errors.As(err, &usageErr):
+        fmt.Fprintf(os.Stderr, "ravenctl: %v\n\n", err)
+        flags.Usage()
+        os.Exit(exitUsage)
+    default:
+        fmt.Fprintf(os.Stderr, "ravenctl: %v\n", err)
+        os.Exit(exitError)
+    }
+}
+
+// parseInterspersed parses flags wherever they appear among the arguments,
+// so "hosts list -o json" works as well as "-o json hosts list", and
+// returns the rest
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+    var positional []string
+    for {
+        if err := flags.Parse(args); err != nil {
+            return nil, err
+        }
+        args = flags.Args()
+        if len(args) == 0 {
+            return positional, nil
+        }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+}
+
+// loadSettings reads the settings file, if there is one, then the
+// environment
+func loadSettings(path string) (settings, error) {
+    resolved := settings{URL: client.DefaultURL}
+
+    if strings.HasPrefix(path, "~/") {
+        if home, err := os.UserHomeDir(); err == nil {
+            path = filepath.Join(home, path[2:])
+        }
+    }
+    data, err := os.ReadFile(path)
+    switch {
+    case err == nil:
+        var file settings
+        if err := yaml.Unmarshal(data, &file); err != nil {
+            return resolved, fmt.Errorf("invalid settings file %s: %w", path, err)
+        }
+        if file.URL != "" {
+            resolved.URL = file.URL
+        }
+        resolved.Token = file.Token
+    case !os.IsNotExist(err):
+        return resolved, fmt.Errorf("failed to read settings file: %w", err)
+    }
+
+    if value := os.Getenv("RAVEN_URL"); value != "" {
+        resolved.URL = value
+    }
+    if value := os.Getenv("RAVEN_TOKEN"); value != "" {
+        resolved.Token = value
+    }
+    return resolved, nil
+}
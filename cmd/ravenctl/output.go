@@ -0,0 +1,92 @@
+// cmd/ravenctl/output.go - Table and JSON output
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+// maxOutputColumn caps how much of a message or output a table shows
+const maxOutputColumn = 80
+
+// printer writes a result as JSON, or as a table drawn by the command
+type printer struct {
+    w    io.Writer
+    json bool
+}
+
+func newPrinter(w io.Writer, asJSON bool) *printer {
+    return &printer{w: w, json: asJSON}
+}
+
+// print writes value as indented JSON, or calls table to lay it out in
+// aligned columns
+func (p *printer) print(value interface{}, table func(t *tabwriter.Writer)) error {
+    if p.json {
+        encoder := json.NewEncoder(p.w)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(value)
+    }
+
+    t := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+    table(t)
+    return t.Flush()
+}
+
+func orDash(value string) string {
+    if value == "" {
+        return "-"
+    }
+    return value
+}
+
+// formatTime shows a time in the local zone, or "-" if unset
+func formatTime(t time.Time) string {
+    if t.IsZero() {
+        return "-"
+    }
+    return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// age is how long ago t was, e.g. "3h 12m", or "-" if unset
+func age(t time.Time) string {
+    if t.IsZero() {
+        return "-"
+    }
+    d := time.Since(t)
+    switch {
+    case d < time.Minute:
+        return fmt.Sprintf("%.0fs", d.Seconds())
+    case d < time.Hour:
+        return fmt.Sprintf("%.0fm", d.Minutes())
+    case d < 24*time.Hour:
+        return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+    }
+    return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+}
+
+// firstLine keeps a table row to one line of bounded width
+func firstLine(text string) string {
+    if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+        text = text[:i]
+    }
+    text = strings.ReplaceAll(text, "\t", " ")
+    if runes := []rune(text); len(runes) > maxOutputColumn {
+        text = string(runes[:maxOutputColumn-3]) + "..."
+    }
+    return orDash(text)
+}
+
+func formatTags(tags map[string]string) string {
+    pairs := make([]string, 0, len(tags))
+    for key, value := range tags {
+        pairs = append(pairs, key+"="+value)
+    }
+    sort.Strings(pairs)
+    return orDash(strings.Join(pairs, ", "))
+}
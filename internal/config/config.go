@@ -5,22 +5,36 @@ import (
     "fmt"
     "os"
     "path/filepath"
+    "strconv"
     "strings"
     "time"
 
-    "gopkg.in/yaml.v3"
+    "raven2/internal/database"
 )
 
 type Config struct {
+    Version    int              `yaml:"version"` // Config format version (missing = 1, see CurrentVersion)
     Server     ServerConfig     `yaml:"server"`
     Web        WebConfig        `yaml:"web"`
     Database   DatabaseConfig   `yaml:"database"`
     Prometheus PrometheusConfig `yaml:"prometheus"`
     Monitoring MonitoringConfig `yaml:"monitoring"`
     Logging    LoggingConfig    `yaml:"logging"`
+    Groups     []GroupConfig    `yaml:"groups"`
     Hosts      []HostConfig     `yaml:"hosts"`
     Checks     []CheckConfig    `yaml:"checks"`
     Include    IncludeConfig    `yaml:"include"`
+
+    // Values resolved from ${ENV_VAR} / file:// references, kept for redaction
+    secrets map[string]bool
+
+    // Where Load read the config from, so it can be read again
+    path         string
+    includeDir   string
+    includeFiles []string
+
+    // Deprecated forms upgraded while loading, with file and line
+    migrationNotes []string
 }
 
 type IncludeConfig struct {
@@ -35,39 +49,87 @@ type ServerConfig struct {
     PluginDir    string        `yaml:"plugin_dir"`
     ReadTimeout  time.Duration `yaml:"read_timeout"`
     WriteTimeout time.Duration `yaml:"write_timeout"`
+    JobQueueSize int           `yaml:"job_queue_size"` // Max jobs waiting for a worker (default 1000)
+    JobAging     time.Duration `yaml:"job_aging"`      // Queue wait that counts as one priority level (default 30s)
 }
 
 type WebConfig struct {
-    AssetsDir    string   `yaml:"assets_dir"`
-    StaticDir    string   `yaml:"static_dir"`
-    ServeStatic  bool     `yaml:"serve_static"`
-    Root         string   `yaml:"root"`
-    Files        []string `yaml:"files"`
-    HeaderLink   string   `yaml:"header_link"`
+    AssetsDir        string   `yaml:"assets_dir"`
+    StaticDir        string   `yaml:"static_dir"`
+    ServeStatic      bool     `yaml:"serve_static"`
+    Root             string   `yaml:"root"`
+    Files            []string `yaml:"files"`
+    HeaderLink       string   `yaml:"header_link"`
+    HistoryMaxPoints int      `yaml:"history_max_points"` // Status history points returned before downsampling (default 1000)
 }
 
 type DatabaseConfig struct {
     Type              string        `yaml:"type"`
-    Path              string        `yaml:"path"`
+    Path              string        `yaml:"path"`      // Relative paths are under data_dir
+    DataDir           string        `yaml:"data_dir"`  // Holds the database (default: the directory of path)
+    DirMode           string        `yaml:"dir_mode"`  // Octal permissions for directories Raven creates (default 0755)
+    FileMode          string        `yaml:"file_mode"` // Octal permissions for files Raven creates (default 0600)
     BackupInterval    time.Duration `yaml:"backup_interval"`
     CleanupInterval   time.Duration `yaml:"cleanup_interval"`
     HistoryRetention  time.Duration `yaml:"history_retention"`
+    ArchiveRetention  time.Duration `yaml:"archive_retention"` // How long hourly rollups of purged history are kept
     CompactInterval   time.Duration `yaml:"compact_interval"`
+    BatchWrites       bool          `yaml:"batch_writes"`  // Coalesce status writes (false = synchronous writes)
+    BatchWindow       time.Duration `yaml:"batch_window"`  // Max time a status waits before being written
+    BatchSize         int           `yaml:"batch_size"`    // Flush early once this many statuses are pending
+    DedupWindow       time.Duration `yaml:"dedup_window"`  // Fold identical results into the previous history entry this long (0 = off)
+    HistoryDedupe     bool          `yaml:"history_dedupe"` // Fold unchanged OK results into the previous history entry, numbers ignored
 }
 
 type PrometheusConfig struct {
     Enabled     bool   `yaml:"enabled"`
     MetricsPath string `yaml:"metrics_path"`
     PushGateway string `yaml:"push_gateway"`
+    HostLabel   string `yaml:"host_label"` // "name" (default) or "id" - value used for the host label
+
+    // Upper bounds in seconds for raven_check_duration_seconds
+    DurationBuckets []float64 `yaml:"duration_buckets"`
 }
 
+// DefaultDurationBuckets spans fast ping checks through slow nagios plugins
+var DefaultDurationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
 type MonitoringConfig struct {
-    DefaultInterval   time.Duration `yaml:"default_interval"`
-    MaxRetries        int           `yaml:"max_retries"`
-    Timeout           time.Duration `yaml:"timeout"`
-    BatchSize         int           `yaml:"batch_size"`
-    DefaultThreshold  int           `yaml:"default_threshold"`  // Default soft fail threshold
-    SoftFailEnabled   bool          `yaml:"soft_fail_enabled"`  // Global soft fail enable/disable
+    DefaultInterval  time.Duration            `yaml:"default_interval"`
+    MaxRetries       int                      `yaml:"max_retries"`
+    Timeout          time.Duration            `yaml:"timeout"`
+    BatchSize        int                      `yaml:"batch_size"`
+    DefaultThreshold int                      `yaml:"default_threshold"`   // Default soft fail threshold
+    SoftFailEnabled  bool                     `yaml:"soft_fail_enabled"`   // Global soft fail enable/disable
+    SelfChecks       SelfChecksConfig         `yaml:"self_checks"`
+    StripControl     bool                     `yaml:"strip_control_chars"` // Remove control characters and terminal escapes from plugin output before storing it
+    MaxOutputBytes   int                      `yaml:"max_output_bytes"`    // Output and long output are each truncated to this size when stored (default 64 KiB)
+    TypeIntervals    map[string]time.Duration `yaml:"type_intervals"`      // Default interval by check type, before default_interval
+    OverdueFactor    float64                  `yaml:"overdue_factor"`      // A check is overdue after this many expected intervals without a result (default 2)
+}
+
+// IntervalFor returns the default interval for checks of a type: its
+// type_intervals entry, or default_interval
+func (m *MonitoringConfig) IntervalFor(checkType string) time.Duration {
+    if interval, exists := m.TypeIntervals[checkType]; exists {
+        return interval
+    }
+    return m.DefaultInterval
+}
+
+// SelfChecksConfig enables checks on Raven's own health. They run through
+// the normal scheduler against the reserved "raven-self" host.
+type SelfChecksConfig struct {
+    Enabled              bool          `yaml:"enabled"`
+    Interval             time.Duration `yaml:"interval"`                  // Default 1m
+    QueueWarning         float64       `yaml:"queue_warning"`             // Job queue fill, percent (default 50)
+    QueueCritical        float64       `yaml:"queue_critical"`            // Default 90
+    DeferredWarning      int           `yaml:"deferred_warning"`          // Jobs deferred since the last run (default 1)
+    DeferredCritical     int           `yaml:"deferred_critical"`         // Default 100
+    DatabaseSizeWarning  int64         `yaml:"database_size_warning_mb"`  // 0 = no limit
+    DatabaseSizeCritical int64         `yaml:"database_size_critical_mb"` // 0 = no limit
+    GoroutineWarning     int           `yaml:"goroutine_warning"`         // Default 1000
+    GoroutineCritical    int           `yaml:"goroutine_critical"`        // Default 5000
 }
 
 type LoggingConfig struct {
@@ -75,6 +137,16 @@ type LoggingConfig struct {
     Format string `yaml:"format"`
 }
 
+// GroupConfig attaches metadata to a host group. Groups referenced by hosts
+// but not defined here are created with defaults.
+type GroupConfig struct {
+    ID          string            `yaml:"id"`
+    DisplayName string            `yaml:"display_name"`
+    Description string            `yaml:"description"`
+    DefaultTags map[string]string `yaml:"default_tags"` // Applied to member hosts that don't set the tag
+    SortOrder   int               `yaml:"sort_order"`   // Lower sorts first
+}
+
 type HostConfig struct {
     ID          string            `yaml:"id"`
     Name        string            `yaml:"name"`
@@ -84,6 +156,7 @@ type HostConfig struct {
     Group       string            `yaml:"group"`
     Enabled     bool              `yaml:"enabled"`
     Tags        map[string]string `yaml:"tags"`
+    PreviousIDs []string          `yaml:"previous_ids"` // Old IDs whose record and history move to this host
 }
 
 type CheckConfig struct {
@@ -93,10 +166,18 @@ type CheckConfig struct {
     Hosts           []string                 `yaml:"hosts"`
     Interval        map[string]time.Duration `yaml:"interval"`
     Threshold       int                      `yaml:"threshold"`         // Soft fail threshold (overrides default)
+    HostThresholds  map[string]int           `yaml:"host_thresholds"`   // Per-host soft fail thresholds (override threshold), keyed by host ID
     SoftFailEnabled *bool                    `yaml:"soft_fail_enabled"` // Per-check soft fail override (nil = use global)
+    SoftFailMode    string                   `yaml:"soft_fail_mode"`    // "failures" (default) or "symmetric" to also delay recovery
     Timeout         time.Duration            `yaml:"timeout"`
     Enabled         bool                     `yaml:"enabled"`
     Options         map[string]interface{}   `yaml:"options"`
+    Retention       time.Duration            `yaml:"retention"`         // History retention for this check (0 = use database.history_retention)
+    ExitCodeMap     map[int]int              `yaml:"exit_code_map"`     // Remap plugin exit codes, e.g. {3: 1} treats unknown as warning
+    Priority        int                      `yaml:"priority"`          // Execution priority, higher runs first (default 0)
+    ObserveOnly     bool                     `yaml:"observe_only"`      // Dark launch: record results without alerting
+    Periods         []database.Period        `yaml:"periods"`           // Weekly windows the check runs in (none = always)
+    PreviousIDs     []string                 `yaml:"previous_ids"`      // Old IDs whose record and history move to this check
 }
 
 // PartialConfig represents a partial configuration that can be merged
@@ -107,16 +188,23 @@ type PartialConfig struct {
     Prometheus *PrometheusConfig `yaml:"prometheus,omitempty"`
     Monitoring *MonitoringConfig `yaml:"monitoring,omitempty"`
     Logging    *LoggingConfig    `yaml:"logging,omitempty"`
+    Groups     []GroupConfig     `yaml:"groups,omitempty"`
     Hosts      []HostConfig      `yaml:"hosts,omitempty"`
     Checks     []CheckConfig     `yaml:"checks,omitempty"`
 }
 
 func Load(filename string) (*Config, error) {
+    // Reloads must find the same file even if the working directory changed
+    if abs, err := filepath.Abs(filename); err == nil {
+        filename = abs
+    }
+
     // Load the main config file
     config, err := loadConfigFile(filename)
     if err != nil {
         return nil, fmt.Errorf("failed to load main config file: %w", err)
     }
+    config.path = filename
 
     // Process includes if enabled
     if config.Include.Enabled && config.Include.Directory != "" {
@@ -125,6 +213,11 @@ func Load(filename string) (*Config, error) {
         }
     }
 
+    // Resolve ${ENV_VAR} and file:// secret references
+    if err := resolveSecrets(config); err != nil {
+        return nil, fmt.Errorf("invalid configuration: %w", err)
+    }
+
     // Set defaults
     setDefaults(config)
 
@@ -136,6 +229,23 @@ func Load(filename string) (*Config, error) {
     return config, nil
 }
 
+// Path is the absolute path of the main config file, or "" for a config
+// that wasn't read from disk
+func (c *Config) Path() string {
+    return c.path
+}
+
+// IncludeDir is the resolved include directory, or "" if includes are off
+func (c *Config) IncludeDir() string {
+    return c.includeDir
+}
+
+// IncludeFiles lists the include files merged into the config, in the order
+// they were applied
+func (c *Config) IncludeFiles() []string {
+    return c.includeFiles
+}
+
 func loadConfigFile(filename string) (*Config, error) {
     data, err := os.ReadFile(filename)
     if err != nil {
@@ -143,9 +253,12 @@ func loadConfigFile(filename string) (*Config, error) {
     }
 
     var config Config
-    if err := yaml.Unmarshal(data, &config); err != nil {
-        return nil, fmt.Errorf("failed to parse YAML: %w", err)
+    notes, err := parseMigrated(filename, data, &config)
+    if err != nil {
+        return nil, err
     }
+    config.migrationNotes = notes
+    config.Version = CurrentVersion
 
     return &config, nil
 }
@@ -162,6 +275,7 @@ func loadIncludes(config *Config, baseDir string) error {
     if _, err := os.Stat(includeDir); os.IsNotExist(err) {
         return fmt.Errorf("include directory does not exist: %s", includeDir)
     }
+    config.includeDir = includeDir
 
     // Set default pattern if not specified
     pattern := config.Include.Pattern
@@ -201,6 +315,7 @@ func loadIncludes(config *Config, baseDir string) error {
         if err := loadAndMergeInclude(config, match); err != nil {
             return fmt.Errorf("failed to load include file %s: %w", match, err)
         }
+        config.includeFiles = append(config.includeFiles, match)
     }
 
     return nil
@@ -213,9 +328,11 @@ func loadAndMergeInclude(config *Config, filename string) error {
     }
 
     var partial PartialConfig
-    if err := yaml.Unmarshal(data, &partial); err != nil {
-        return fmt.Errorf("failed to parse include file YAML: %w", err)
+    notes, err := parseMigrated(filename, data, &partial)
+    if err != nil {
+        return err
     }
+    config.migrationNotes = append(config.migrationNotes, notes...)
 
     // Merge the partial config into the main config
     mergePartialConfig(config, &partial)
@@ -224,6 +341,21 @@ func loadAndMergeInclude(config *Config, filename string) error {
 }
 
 func mergePartialConfig(config *Config, partial *PartialConfig) {
+    // Merge groups (a group with the same ID replaces the earlier one)
+    for _, group := range partial.Groups {
+        replaced := false
+        for i := range config.Groups {
+            if config.Groups[i].ID == group.ID {
+                config.Groups[i] = group
+                replaced = true
+                break
+            }
+        }
+        if !replaced {
+            config.Groups = append(config.Groups, group)
+        }
+    }
+
     // Merge hosts (append to existing)
     if len(partial.Hosts) > 0 {
         config.Hosts = append(config.Hosts, partial.Hosts...)
@@ -272,7 +404,7 @@ func mergeChecks(config *Config, newChecks []CheckConfig) {
             // Check if this is a partial definition (only ID and hosts specified)
             if isPartialCheckDefinition(newCheck) {
                 // Append hosts to existing check
-                appendHostsToCheck(existingCheck, newCheck.Hosts)
+                appendHostsToCheck(existingCheck, newCheck.Hosts, newCheck.HostThresholds)
             } else {
                 // This is a full check definition, replace the existing one
                 *existingCheck = newCheck
@@ -286,7 +418,8 @@ func mergeChecks(config *Config, newChecks []CheckConfig) {
 }
 
 func isPartialCheckDefinition(check CheckConfig) bool {
-    // Check if only ID and hosts are specified (all other fields are zero values)
+    // Check if only ID and hosts (and optionally their host_thresholds) are
+    // specified (all other fields are zero values)
     return check.ID != "" &&
            len(check.Hosts) > 0 &&
            check.Name == "" &&
@@ -296,10 +429,16 @@ func isPartialCheckDefinition(check CheckConfig) bool {
            check.Timeout == 0 &&
            !check.Enabled &&
            len(check.Options) == 0 &&
-           check.SoftFailEnabled == nil
+           check.SoftFailEnabled == nil &&
+           check.SoftFailMode == "" &&
+           check.Retention == 0 &&
+           len(check.ExitCodeMap) == 0 &&
+           check.Priority == 0 &&
+           !check.ObserveOnly &&
+           len(check.Periods) == 0
 }
 
-func appendHostsToCheck(existingCheck *CheckConfig, newHosts []string) {
+func appendHostsToCheck(existingCheck *CheckConfig, newHosts []string, hostThresholds map[string]int) {
     // Create a set of existing hosts for quick lookup
     existingHosts := make(map[string]bool)
     for _, host := range existingCheck.Hosts {
@@ -312,6 +451,13 @@ func appendHostsToCheck(existingCheck *CheckConfig, newHosts []string) {
             existingCheck.Hosts = append(existingCheck.Hosts, host)
         }
     }
+
+    if len(hostThresholds) > 0 && existingCheck.HostThresholds == nil {
+        existingCheck.HostThresholds = make(map[string]int, len(hostThresholds))
+    }
+    for host, threshold := range hostThresholds {
+        existingCheck.HostThresholds[host] = threshold
+    }
 }
 
 func mergeServerConfig(main *ServerConfig, partial *ServerConfig) {
@@ -330,6 +476,12 @@ func mergeServerConfig(main *ServerConfig, partial *ServerConfig) {
     if partial.WriteTimeout != 0 {
         main.WriteTimeout = partial.WriteTimeout
     }
+    if partial.JobQueueSize != 0 {
+        main.JobQueueSize = partial.JobQueueSize
+    }
+    if partial.JobAging != 0 {
+        main.JobAging = partial.JobAging
+    }
 }
 
 func mergeWebConfig(main *WebConfig, partial *WebConfig) {
@@ -345,6 +497,9 @@ func mergeWebConfig(main *WebConfig, partial *WebConfig) {
     if partial.HeaderLink != "" {
         main.HeaderLink = partial.HeaderLink
     }
+    if partial.HistoryMaxPoints != 0 {
+        main.HistoryMaxPoints = partial.HistoryMaxPoints
+    }
     main.ServeStatic = partial.ServeStatic
     
     if len(partial.Files) > 0 {
@@ -359,6 +514,15 @@ func mergeDatabaseConfig(main *DatabaseConfig, partial *DatabaseConfig) {
     if partial.Path != "" {
         main.Path = partial.Path
     }
+    if partial.DataDir != "" {
+        main.DataDir = partial.DataDir
+    }
+    if partial.DirMode != "" {
+        main.DirMode = partial.DirMode
+    }
+    if partial.FileMode != "" {
+        main.FileMode = partial.FileMode
+    }
     if partial.BackupInterval != 0 {
         main.BackupInterval = partial.BackupInterval
     }
@@ -368,9 +532,27 @@ func mergeDatabaseConfig(main *DatabaseConfig, partial *DatabaseConfig) {
     if partial.HistoryRetention != 0 {
         main.HistoryRetention = partial.HistoryRetention
     }
+    if partial.ArchiveRetention != 0 {
+        main.ArchiveRetention = partial.ArchiveRetention
+    }
     if partial.CompactInterval != 0 {
         main.CompactInterval = partial.CompactInterval
     }
+    if partial.BatchWrites {
+        main.BatchWrites = true
+    }
+    if partial.BatchWindow != 0 {
+        main.BatchWindow = partial.BatchWindow
+    }
+    if partial.BatchSize != 0 {
+        main.BatchSize = partial.BatchSize
+    }
+    if partial.DedupWindow != 0 {
+        main.DedupWindow = partial.DedupWindow
+    }
+    if partial.HistoryDedupe {
+        main.HistoryDedupe = true
+    }
 }
 
 func mergePrometheusConfig(main *PrometheusConfig, partial *PrometheusConfig) {
@@ -381,6 +563,12 @@ func mergePrometheusConfig(main *PrometheusConfig, partial *PrometheusConfig) {
     if partial.PushGateway != "" {
         main.PushGateway = partial.PushGateway
     }
+    if partial.HostLabel != "" {
+        main.HostLabel = partial.HostLabel
+    }
+    if len(partial.DurationBuckets) > 0 {
+        main.DurationBuckets = partial.DurationBuckets
+    }
 }
 
 func mergeMonitoringConfig(main *MonitoringConfig, partial *MonitoringConfig) {
@@ -401,6 +589,24 @@ func mergeMonitoringConfig(main *MonitoringConfig, partial *MonitoringConfig) {
     }
     // For boolean, always take partial value
     main.SoftFailEnabled = partial.SoftFailEnabled
+    if partial.SelfChecks.Enabled {
+        main.SelfChecks = partial.SelfChecks
+    }
+    if partial.StripControl {
+        main.StripControl = true
+    }
+    if partial.MaxOutputBytes != 0 {
+        main.MaxOutputBytes = partial.MaxOutputBytes
+    }
+    if partial.OverdueFactor != 0 {
+        main.OverdueFactor = partial.OverdueFactor
+    }
+    for checkType, interval := range partial.TypeIntervals {
+        if main.TypeIntervals == nil {
+            main.TypeIntervals = make(map[string]time.Duration)
+        }
+        main.TypeIntervals[checkType] = interval
+    }
 }
 
 func mergeLoggingConfig(main *LoggingConfig, partial *LoggingConfig) {
@@ -420,13 +626,33 @@ func setDefaults(cfg *Config) {
     if cfg.Server.Workers == 0 {
         cfg.Server.Workers = 3
     }
+    if cfg.Server.JobQueueSize == 0 {
+        cfg.Server.JobQueueSize = 1000
+    }
+    if cfg.Server.JobAging == 0 {
+        cfg.Server.JobAging = 30 * time.Second
+    }
     
     // Database defaults
     if cfg.Database.Type == "" {
         cfg.Database.Type = "boltdb"
     }
     if cfg.Database.Path == "" {
-        cfg.Database.Path = "./data/raven.db"
+        cfg.Database.Path = "raven.db"
+        if cfg.Database.DataDir == "" {
+            cfg.Database.DataDir = "./data"
+        }
+    }
+    if cfg.Database.DataDir == "" {
+        cfg.Database.DataDir = filepath.Dir(cfg.Database.Path)
+    } else if !filepath.IsAbs(cfg.Database.Path) {
+        cfg.Database.Path = filepath.Join(cfg.Database.DataDir, cfg.Database.Path)
+    }
+    if cfg.Database.BatchWindow == 0 {
+        cfg.Database.BatchWindow = 200 * time.Millisecond
+    }
+    if cfg.Database.BatchSize == 0 {
+        cfg.Database.BatchSize = 100
     }
     
     // Web defaults
@@ -439,6 +665,9 @@ func setDefaults(cfg *Config) {
     if cfg.Web.HeaderLink == "" {
         cfg.Web.HeaderLink = "https://github.com/John-MustangGT/raven2"
     }
+    if cfg.Web.HistoryMaxPoints == 0 {
+        cfg.Web.HistoryMaxPoints = 1000
+    }
     
     // Include defaults
     if cfg.Include.Pattern == "" {
@@ -455,11 +684,25 @@ func setDefaults(cfg *Config) {
     if cfg.Monitoring.Timeout == 0 {
         cfg.Monitoring.Timeout = 30 * time.Second
     }
+    if cfg.Monitoring.MaxOutputBytes == 0 {
+        cfg.Monitoring.MaxOutputBytes = 64 * 1024
+    }
+    if cfg.Monitoring.OverdueFactor == 0 {
+        cfg.Monitoring.OverdueFactor = 2
+    }
+    setSelfCheckDefaults(&cfg.Monitoring.SelfChecks)
+    setGroupDefaults(cfg)
     
     // Prometheus defaults
     if cfg.Prometheus.MetricsPath == "" {
         cfg.Prometheus.MetricsPath = "/metrics"
     }
+    if cfg.Prometheus.HostLabel == "" {
+        cfg.Prometheus.HostLabel = "name"
+    }
+    if len(cfg.Prometheus.DurationBuckets) == 0 {
+        cfg.Prometheus.DurationBuckets = DefaultDurationBuckets
+    }
     
     // Logging defaults
     if cfg.Logging.Level == "" {
@@ -470,10 +713,171 @@ func setDefaults(cfg *Config) {
     }
 }
 
+// setGroupDefaults creates groups that hosts reference but the config doesn't
+// define, and applies each group's default tags to its member hosts without
+// overwriting tags the host sets itself
+func setGroupDefaults(cfg *Config) {
+    for _, host := range cfg.Hosts {
+        if host.Group != "" && cfg.findGroup(host.Group) == nil {
+            cfg.Groups = append(cfg.Groups, GroupConfig{ID: host.Group})
+        }
+    }
+
+    for i := range cfg.Groups {
+        if cfg.Groups[i].DisplayName == "" {
+            cfg.Groups[i].DisplayName = cfg.Groups[i].ID
+        }
+    }
+
+    for i := range cfg.Hosts {
+        host := &cfg.Hosts[i]
+        host.Tags = cfg.GroupTags(host.Group, host.Tags)
+    }
+}
+
+// GroupTags returns tags with the group's default tags added where tags
+// doesn't already set them. tags is returned unchanged if there are none.
+func (c *Config) GroupTags(groupID string, tags map[string]string) map[string]string {
+    group := c.findGroup(groupID)
+    if group == nil || len(group.DefaultTags) == 0 {
+        return tags
+    }
+
+    merged := make(map[string]string, len(group.DefaultTags)+len(tags))
+    for key, value := range group.DefaultTags {
+        merged[key] = value
+    }
+    for key, value := range tags {
+        merged[key] = value
+    }
+    return merged
+}
+
+func (c *Config) findGroup(id string) *GroupConfig {
+    for i := range c.Groups {
+        if c.Groups[i].ID == id {
+            return &c.Groups[i]
+        }
+    }
+    return nil
+}
+
+// Warnings reports configuration that is valid but probably unintended
+func (c *Config) Warnings() []string {
+    used := make(map[string]bool)
+    for _, host := range c.Hosts {
+        used[host.Group] = true
+    }
+
+    var warnings []string
+    for _, note := range c.migrationNotes {
+        warnings = append(warnings, "deprecated: "+note+" (raven -migrate-config writes the upgraded file)")
+    }
+    for _, group := range c.Groups {
+        if !used[group.ID] {
+            warnings = append(warnings, fmt.Sprintf("group '%s' is defined but no host uses it", group.ID))
+        }
+    }
+    return warnings
+}
+
+func setSelfCheckDefaults(sc *SelfChecksConfig) {
+    if sc.Interval == 0 {
+        sc.Interval = time.Minute
+    }
+    if sc.QueueWarning == 0 {
+        sc.QueueWarning = 50
+    }
+    if sc.QueueCritical == 0 {
+        sc.QueueCritical = 90
+    }
+    if sc.DeferredWarning == 0 {
+        sc.DeferredWarning = 1
+    }
+    if sc.DeferredCritical == 0 {
+        sc.DeferredCritical = 100
+    }
+    if sc.GoroutineWarning == 0 {
+        sc.GoroutineWarning = 1000
+    }
+    if sc.GoroutineCritical == 0 {
+        sc.GoroutineCritical = 5000
+    }
+}
+
+func validateSelfChecks(sc *SelfChecksConfig) error {
+    if sc.Interval < 0 {
+        return fmt.Errorf("monitoring.self_checks.interval must be positive")
+    }
+    if sc.QueueWarning < 0 || sc.QueueWarning > sc.QueueCritical || sc.QueueCritical > 100 {
+        return fmt.Errorf("monitoring.self_checks queue thresholds must satisfy 0 <= queue_warning <= queue_critical <= 100")
+    }
+    if sc.DeferredWarning < 0 || sc.DeferredWarning > sc.DeferredCritical {
+        return fmt.Errorf("monitoring.self_checks.deferred_warning must be between 0 and deferred_critical")
+    }
+    if sc.DatabaseSizeWarning < 0 || sc.DatabaseSizeCritical < 0 ||
+        (sc.DatabaseSizeCritical > 0 && sc.DatabaseSizeWarning > sc.DatabaseSizeCritical) {
+        return fmt.Errorf("monitoring.self_checks database size limits must not be negative and warning must not exceed critical")
+    }
+    if sc.GoroutineWarning < 0 || sc.GoroutineWarning > sc.GoroutineCritical {
+        return fmt.Errorf("monitoring.self_checks.goroutine_warning must be between 0 and goroutine_critical")
+    }
+    return nil
+}
+
+// validateDataFiles checks the database is kept in the data directory and
+// that the permissions let Raven use what it creates
+func validateDataFiles(db *DatabaseConfig) error {
+    if rel, err := filepath.Rel(db.DataDir, db.Path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+        return fmt.Errorf("database.path %s is outside database.data_dir %s", db.Path, db.DataDir)
+    }
+    dirMode, err := parseFileMode(db.DirMode, database.DefaultDirMode)
+    if err != nil {
+        return fmt.Errorf("database.dir_mode: %w", err)
+    }
+    if dirMode&0700 != 0700 {
+        return fmt.Errorf("database.dir_mode %04o must give the owner read, write and search permission", dirMode)
+    }
+    fileMode, err := parseFileMode(db.FileMode, database.DefaultFileMode)
+    if err != nil {
+        return fmt.Errorf("database.file_mode: %w", err)
+    }
+    if fileMode&0600 != 0600 {
+        return fmt.Errorf("database.file_mode %04o must give the owner read and write permission", fileMode)
+    }
+    return nil
+}
+
+// parseFileMode reads octal permissions such as "0640", or returns def for ""
+func parseFileMode(value string, def os.FileMode) (os.FileMode, error) {
+    if value == "" {
+        return def, nil
+    }
+    mode, err := strconv.ParseUint(value, 8, 32)
+    if err != nil || mode > 0777 {
+        return 0, fmt.Errorf("invalid permissions %q, expected octal such as \"0640\"", value)
+    }
+    return os.FileMode(mode), nil
+}
+
+// Files returns where the store keeps its database and the permissions of
+// the files it creates. The modes were checked when the config was loaded.
+func (d *DatabaseConfig) Files() database.FileOptions {
+    dirMode, _ := parseFileMode(d.DirMode, database.DefaultDirMode)
+    fileMode, _ := parseFileMode(d.FileMode, database.DefaultFileMode)
+    return database.FileOptions{DataDir: d.DataDir, DirMode: dirMode, FileMode: fileMode}
+}
+
 func validate(cfg *Config) error {
     if cfg.Server.Workers < 1 {
         return fmt.Errorf("server.workers must be at least 1")
     }
+    if cfg.Server.JobQueueSize < 0 {
+        return fmt.Errorf("server.job_queue_size must not be negative")
+    }
+    if cfg.Server.JobAging < 0 {
+        return fmt.Errorf("server.job_aging must not be negative")
+    }
     if cfg.Database.Type != "boltdb" {
         return fmt.Errorf("only boltdb is supported currently")
     }
@@ -485,6 +889,47 @@ func validate(cfg *Config) error {
     if cfg.Monitoring.DefaultInterval <= 0 {
         return fmt.Errorf("monitoring.default_interval must be positive")
     }
+    if cfg.Monitoring.OverdueFactor < 1 {
+        return fmt.Errorf("monitoring.overdue_factor must be at least 1")
+    }
+    if cfg.Monitoring.MaxOutputBytes < 0 {
+        return fmt.Errorf("monitoring.max_output_bytes must not be negative")
+    }
+    for checkType, interval := range cfg.Monitoring.TypeIntervals {
+        if interval <= 0 {
+            return fmt.Errorf("monitoring.type_intervals.%s must be positive", checkType)
+        }
+    }
+    if err := validateSelfChecks(&cfg.Monitoring.SelfChecks); err != nil {
+        return err
+    }
+    if cfg.Database.HistoryRetention < 0 {
+        return fmt.Errorf("database.history_retention must not be negative")
+    }
+    if cfg.Database.ArchiveRetention < 0 {
+        return fmt.Errorf("database.archive_retention must not be negative")
+    }
+    if cfg.Database.BatchWindow < 0 || cfg.Database.BatchSize < 0 {
+        return fmt.Errorf("database.batch_window and database.batch_size must not be negative")
+    }
+    if cfg.Database.DedupWindow < 0 {
+        return fmt.Errorf("database.dedup_window must not be negative")
+    }
+    if err := validateDataFiles(&cfg.Database); err != nil {
+        return err
+    }
+    
+    if cfg.Prometheus.HostLabel != "name" && cfg.Prometheus.HostLabel != "id" {
+        return fmt.Errorf("prometheus.host_label must be \"name\" or \"id\"")
+    }
+    for i, bucket := range cfg.Prometheus.DurationBuckets {
+        if bucket <= 0 {
+            return fmt.Errorf("prometheus.duration_buckets must be positive")
+        }
+        if i > 0 && bucket <= cfg.Prometheus.DurationBuckets[i-1] {
+            return fmt.Errorf("prometheus.duration_buckets must be in increasing order")
+        }
+    }
     
     // Validate web configuration
     if cfg.Web.Root == "" {
@@ -497,6 +942,9 @@ func validate(cfg *Config) error {
             return fmt.Errorf("web.header_link must be a valid URL")
         }
     }
+    if cfg.Web.HistoryMaxPoints < 0 {
+        return fmt.Errorf("web.history_max_points must not be negative")
+    }
     
     // If assets_dir is specified, validate it exists
     if cfg.Web.AssetsDir != "" {
@@ -526,6 +974,18 @@ func validate(cfg *Config) error {
         }
     }
     
+    // Validate groups
+    groupIDs := make(map[string]bool)
+    for _, group := range cfg.Groups {
+        if group.ID == "" {
+            return fmt.Errorf("groups entry is missing an id")
+        }
+        if groupIDs[group.ID] {
+            return fmt.Errorf("duplicate group ID: %s", group.ID)
+        }
+        groupIDs[group.ID] = true
+    }
+    
     // Validate for duplicate host IDs
     hostIDs := make(map[string]bool)
     for _, host := range cfg.Hosts {
@@ -534,12 +994,51 @@ func validate(cfg *Config) error {
         }
         hostIDs[host.ID] = true
     }
+    renamedHosts := make(map[string]string)
+    for _, host := range cfg.Hosts {
+        if err := validatePreviousIDs("host", host.ID, host.PreviousIDs, hostIDs, renamedHosts); err != nil {
+            return err
+        }
+    }
+    checkIDs := make(map[string]bool)
+    for _, check := range cfg.Checks {
+        checkIDs[check.ID] = true
+    }
+    renamedChecks := make(map[string]string)
+    for _, check := range cfg.Checks {
+        if err := validatePreviousIDs("check", check.ID, check.PreviousIDs, checkIDs, renamedChecks); err != nil {
+            return err
+        }
+    }
     
     // Validate check configurations
-    for _, check := range cfg.Checks {
+    for i := range cfg.Checks {
+        check := &cfg.Checks[i]
         if check.Threshold < 0 {
             return fmt.Errorf("check '%s' has invalid threshold: %d (must be >= 0)", check.ID, check.Threshold)
         }
+        if check.Retention < 0 {
+            return fmt.Errorf("check '%s' has invalid retention: %s (must be >= 0)", check.ID, check.Retention)
+        }
+        for from, to := range check.ExitCodeMap {
+            if from < 0 || to < 0 || to > 3 {
+                return fmt.Errorf("check '%s' has invalid exit_code_map entry %d: %d (codes must be >= 0 and map to 0-3)", check.ID, from, to)
+            }
+        }
+        if err := database.ValidatePeriods(check.Periods); err != nil {
+            return fmt.Errorf("check '%s' has invalid %w", check.ID, err)
+        }
+        if err := database.ValidateHostThresholds(check.Hosts, check.HostThresholds); err != nil {
+            return fmt.Errorf("check '%s' has invalid %w", check.ID, err)
+        }
+        if check.SoftFailMode != "" && check.SoftFailMode != "failures" && check.SoftFailMode != "symmetric" {
+            return fmt.Errorf("check '%s' has invalid soft_fail_mode: %s (must be failures or symmetric)", check.ID, check.SoftFailMode)
+        }
+        // A threshold of 1 confirms every result immediately, so an explicit
+        // soft_fail_enabled: true would otherwise be silently ignored
+        if check.IsSoftFailEnabled(false) && check.GetEffectiveThreshold(cfg.Monitoring.DefaultThreshold) < 2 {
+            return fmt.Errorf("check '%s' enables soft fail with threshold 1 (soft fail needs a threshold of at least 2)", check.ID)
+        }
         if check.Timeout <= 0 {
             check.Timeout = cfg.Monitoring.Timeout // Use default if not specified
         }
@@ -559,28 +1058,117 @@ func validate(cfg *Config) error {
         }
         
         // Validate intervals
-        if len(check.Interval) == 0 {
-            // Set default intervals if not specified
-            check.Interval = map[string]time.Duration{
-                "ok":       cfg.Monitoring.DefaultInterval,
-                "warning":  cfg.Monitoring.DefaultInterval / 2,
-                "critical": cfg.Monitoring.DefaultInterval / 4,
-                "unknown":  cfg.Monitoring.DefaultInterval,
-            }
-        }
-        
-        // Ensure all required intervals are present
-        requiredStates := []string{"ok", "warning", "critical", "unknown"}
-        for _, state := range requiredStates {
-            if _, exists := check.Interval[state]; !exists {
-                check.Interval[state] = cfg.Monitoring.DefaultInterval
-            }
+        intervals, err := NormalizeIntervals(check.Interval, cfg.Monitoring.IntervalFor(check.Type))
+        if err != nil {
+            return fmt.Errorf("check '%s' has %w", check.ID, err)
         }
+        check.Interval = intervals
     }
     
     return nil
 }
 
+// validatePreviousIDs rejects previous_ids that are still in use or that
+// more than one entry claims, recording each in claimed
+func validatePreviousIDs(kind, id string, previousIDs []string, current map[string]bool, claimed map[string]string) error {
+    for _, previousID := range previousIDs {
+        if previousID == "" {
+            return fmt.Errorf("%s '%s' has an empty previous_ids entry", kind, id)
+        }
+        if current[previousID] {
+            return fmt.Errorf("%s '%s' lists previous ID %s, which is still in use", kind, id, previousID)
+        }
+        if other, exists := claimed[previousID]; exists {
+            return fmt.Errorf("%s '%s' lists previous ID %s, already listed by %s", kind, id, previousID, other)
+        }
+        claimed[previousID] = id
+    }
+    return nil
+}
+
+// IntervalStates are the only keys the scheduler consults in a check's interval map
+var IntervalStates = []string{"ok", "warning", "critical", "unknown"}
+
+// NormalizeIntervals rejects unknown interval keys and fills in missing states.
+// An empty map gets the default interval with faster re-checks for warning and
+// critical; otherwise missing states use the default interval. Shared by config
+// loading and the API so the two can't diverge.
+func NormalizeIntervals(intervals map[string]time.Duration, defaultInterval time.Duration) (map[string]time.Duration, error) {
+    if len(intervals) == 0 {
+        return map[string]time.Duration{
+            "ok":       defaultInterval,
+            "warning":  defaultInterval / 2,
+            "critical": defaultInterval / 4,
+            "unknown":  defaultInterval,
+        }, nil
+    }
+
+    normalized := make(map[string]time.Duration, len(IntervalStates))
+    for state, interval := range intervals {
+        if !isIntervalState(state) {
+            return nil, fmt.Errorf("unknown interval state %q (valid: %s)", state, strings.Join(IntervalStates, ", "))
+        }
+        if interval < 0 {
+            return nil, fmt.Errorf("interval for %s must not be negative", state)
+        }
+        normalized[state] = interval
+    }
+
+    for _, state := range IntervalStates {
+        if _, exists := normalized[state]; !exists {
+            normalized[state] = defaultInterval
+        }
+    }
+
+    return normalized, nil
+}
+
+func isIntervalState(state string) bool {
+    for _, s := range IntervalStates {
+        if s == state {
+            return true
+        }
+    }
+    return false
+}
+
+// ValidateInventory checks a host/check inventory with the same rules used
+// at load time, taking the global settings from c
+func (c *Config) ValidateInventory(hosts []HostConfig, checks []CheckConfig) error {
+    candidate := *c
+    candidate.Hosts = hosts
+    candidate.Checks = checks
+    return validate(&candidate)
+}
+
+// ConfiguredIDs are the host and check IDs a configuration accounts for
+type ConfiguredIDs struct {
+    Hosts  map[string]bool
+    Checks map[string]bool
+}
+
+// ConfiguredIDs returns the configured host and check IDs along with their
+// previous_ids, whose records are migrated rather than abandoned
+func (c *Config) ConfiguredIDs() ConfiguredIDs {
+    ids := ConfiguredIDs{
+        Hosts:  make(map[string]bool),
+        Checks: make(map[string]bool),
+    }
+    for _, host := range c.Hosts {
+        ids.Hosts[host.ID] = true
+        for _, id := range host.PreviousIDs {
+            ids.Hosts[id] = true
+        }
+    }
+    for _, check := range c.Checks {
+        ids.Checks[check.ID] = true
+        for _, id := range check.PreviousIDs {
+            ids.Checks[id] = true
+        }
+    }
+    return ids
+}
+
 // GetEffectiveThreshold returns the effective threshold for a check
 // considering both check-level and global defaults
 func (c *CheckConfig) GetEffectiveThreshold(globalDefault int) int {
@@ -0,0 +1,189 @@
+// internal/config/diff.go - Differences between a running and a reloaded config
+package config
+
+import (
+    "reflect"
+    "sort"
+)
+
+// ChangeSet lists the IDs added, changed and removed between two configs
+type ChangeSet struct {
+    Added   []string          `json:"added"`
+    Changed []string          `json:"changed"`
+    Removed []string          `json:"removed"`
+    Renamed map[string]string `json:"renamed,omitempty"` // Old ID to new, for entries listing the old one in previous_ids
+}
+
+// Empty reports whether nothing was added, changed, removed or renamed
+func (c *ChangeSet) Empty() bool {
+    return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0 && len(c.Renamed) == 0
+}
+
+// Changes describes how a reloaded config differs from the running one
+type Changes struct {
+    Groups          ChangeSet `json:"groups"`
+    Hosts           ChangeSet `json:"hosts"`
+    Checks          ChangeSet `json:"checks"`
+    Settings        []string  `json:"settings"`         // Changed settings that apply straight away
+    RestartRequired []string  `json:"restart_required"` // Changed settings that need a restart
+}
+
+// Empty reports whether the two configs were the same
+func (c *Changes) Empty() bool {
+    return c.Groups.Empty() && c.Hosts.Empty() && c.Checks.Empty() &&
+        len(c.Settings) == 0 && len(c.RestartRequired) == 0
+}
+
+// Diff compares the running config with a newly loaded one
+func Diff(old, updated *Config) *Changes {
+    changes := &Changes{
+        Settings:        []string{},
+        RestartRequired: []string{},
+    }
+
+    oldGroups := make(map[string]interface{}, len(old.Groups))
+    for _, group := range old.Groups {
+        oldGroups[group.ID] = group
+    }
+    newGroups := make(map[string]interface{}, len(updated.Groups))
+    for _, group := range updated.Groups {
+        newGroups[group.ID] = group
+    }
+    changes.Groups = diffByID(oldGroups, newGroups)
+
+    oldHosts := make(map[string]interface{}, len(old.Hosts))
+    for _, host := range old.Hosts {
+        oldHosts[host.ID] = host
+    }
+    newHosts := make(map[string]interface{}, len(updated.Hosts))
+    hostRenames := make(map[string][]string)
+    for _, host := range updated.Hosts {
+        newHosts[host.ID] = host
+        hostRenames[host.ID] = host.PreviousIDs
+    }
+    changes.Hosts = diffByID(oldHosts, newHosts)
+    changes.Hosts.applyRenames(hostRenames)
+
+    oldChecks := make(map[string]interface{}, len(old.Checks))
+    for _, check := range old.Checks {
+        oldChecks[check.ID] = check
+    }
+    newChecks := make(map[string]interface{}, len(updated.Checks))
+    checkRenames := make(map[string][]string)
+    for _, check := range updated.Checks {
+        newChecks[check.ID] = check
+        checkRenames[check.ID] = check.PreviousIDs
+    }
+    changes.Checks = diffByID(oldChecks, newChecks)
+    changes.Checks.applyRenames(checkRenames)
+
+    // The listener, routes, database handle, metrics and log output are all
+    // set up once at startup
+    restart := func(name string, a, b interface{}) {
+        if !reflect.DeepEqual(a, b) {
+            changes.RestartRequired = append(changes.RestartRequired, name)
+        }
+    }
+    restart("server", old.Server, updated.Server)
+
+    // The history point limit is read on every request
+    oldWeb, newWeb := old.Web, updated.Web
+    oldWeb.HistoryMaxPoints, newWeb.HistoryMaxPoints = 0, 0
+    restart("web", oldWeb, newWeb)
+    restart("prometheus", old.Prometheus, updated.Prometheus)
+    restart("logging", old.Logging, updated.Logging)
+
+    // Retention is read on every purge and the dedup settings are handed to
+    // the store on reload; the rest of the database section is only read when the
+    // store and purge schedule are set up
+    oldDatabase, newDatabase := old.Database, updated.Database
+    oldDatabase.HistoryRetention, newDatabase.HistoryRetention = 0, 0
+    oldDatabase.ArchiveRetention, newDatabase.ArchiveRetention = 0, 0
+    oldDatabase.DedupWindow, newDatabase.DedupWindow = 0, 0
+    oldDatabase.HistoryDedupe, newDatabase.HistoryDedupe = false, false
+    restart("database", oldDatabase, newDatabase)
+
+    if old.Database.HistoryRetention != updated.Database.HistoryRetention {
+        changes.Settings = append(changes.Settings, "database.history_retention")
+    }
+    if old.Database.ArchiveRetention != updated.Database.ArchiveRetention {
+        changes.Settings = append(changes.Settings, "database.archive_retention")
+    }
+    if old.Database.DedupWindow != updated.Database.DedupWindow {
+        changes.Settings = append(changes.Settings, "database.dedup_window")
+    }
+    if old.Database.HistoryDedupe != updated.Database.HistoryDedupe {
+        changes.Settings = append(changes.Settings, "database.history_dedupe")
+    }
+    if old.Web.HistoryMaxPoints != updated.Web.HistoryMaxPoints {
+        changes.Settings = append(changes.Settings, "web.history_max_points")
+    }
+    if !reflect.DeepEqual(old.Monitoring, updated.Monitoring) {
+        changes.Settings = append(changes.Settings, "monitoring")
+    }
+
+    return changes
+}
+
+func diffByID(old, updated map[string]interface{}) ChangeSet {
+    set := ChangeSet{
+        Added:   []string{},
+        Changed: []string{},
+        Removed: []string{},
+    }
+
+    for id, item := range updated {
+        previous, exists := old[id]
+        switch {
+        case !exists:
+            set.Added = append(set.Added, id)
+        case !reflect.DeepEqual(previous, item):
+            set.Changed = append(set.Changed, id)
+        }
+    }
+    for id := range old {
+        if _, exists := updated[id]; !exists {
+            set.Removed = append(set.Removed, id)
+        }
+    }
+
+    sort.Strings(set.Added)
+    sort.Strings(set.Changed)
+    sort.Strings(set.Removed)
+    return set
+}
+
+// applyRenames turns an added ID and a removed one it lists in previous_ids
+// into a rename, so the old entry is migrated rather than deleted
+func (c *ChangeSet) applyRenames(previousIDs map[string][]string) {
+    removed := make(map[string]bool, len(c.Removed))
+    for _, id := range c.Removed {
+        removed[id] = true
+    }
+
+    added := c.Added[:0]
+    for _, id := range c.Added {
+        renamed := false
+        for _, previousID := range previousIDs[id] {
+            if removed[previousID] {
+                if c.Renamed == nil {
+                    c.Renamed = make(map[string]string)
+                }
+                c.Renamed[previousID] = id
+                delete(removed, previousID)
+                renamed = true
+                break
+            }
+        }
+        if !renamed {
+            added = append(added, id)
+        }
+    }
+    c.Added = added
+
+    c.Removed = c.Removed[:0]
+    for id := range removed {
+        c.Removed = append(c.Removed, id)
+    }
+    sort.Strings(c.Removed)
+}
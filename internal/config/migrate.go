@@ -0,0 +1,191 @@
+// internal/config/migrate.go - Upgrading config files written for older config versions
+package config
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config format this build reads. Files without a
+// version: key are version 1.
+//
+// Version 2 reads durations the way the YAML library does: a bare number is
+// nanoseconds, so durations need a unit ("30s"). Version 1 files are
+// upgraded on load:
+//   - a bare number where a duration is expected is taken as seconds
+//   - a check interval given as one duration applies to every state
+const CurrentVersion = 2
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// migrator upgrades one file's YAML tree in place, noting each change with
+// the file and line it came from
+type migrator struct {
+    filename string
+    notes    []string
+}
+
+// parseMigrated reads a config file, upgrades it to the current version and
+// decodes it into out. It returns a note for every deprecated form found.
+func parseMigrated(filename string, data []byte, out interface{}) ([]string, error) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, fmt.Errorf("failed to parse YAML: %w", err)
+    }
+    if len(doc.Content) == 0 {
+        return nil, nil // Empty file
+    }
+
+    m := &migrator{filename: filename}
+    if err := m.migrate(&doc, reflect.TypeOf(out).Elem()); err != nil {
+        return nil, err
+    }
+    if err := doc.Decode(out); err != nil {
+        return nil, fmt.Errorf("failed to parse YAML: %w", err)
+    }
+    return m.notes, nil
+}
+
+// MigrateFile returns a config file (main or include) upgraded to the
+// current version, with its comments kept, and notes on what changed
+func MigrateFile(filename string) ([]byte, []string, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+    }
+
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+    }
+    if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+        return nil, nil, fmt.Errorf("%s is not a YAML mapping", filename)
+    }
+
+    m := &migrator{filename: filename}
+    if err := m.migrate(&doc, reflect.TypeOf(Config{})); err != nil {
+        return nil, nil, err
+    }
+    setVersion(doc.Content[0], CurrentVersion)
+
+    var out bytes.Buffer
+    encoder := yaml.NewEncoder(&out)
+    encoder.SetIndent(2)
+    if err := encoder.Encode(&doc); err != nil {
+        return nil, nil, fmt.Errorf("failed to write YAML: %w", err)
+    }
+    encoder.Close()
+    return out.Bytes(), m.notes, nil
+}
+
+// migrate checks the file's version and applies every upgrade after it
+func (m *migrator) migrate(doc *yaml.Node, root reflect.Type) error {
+    top := doc.Content[0]
+    if top.Kind != yaml.MappingNode {
+        return nil // Let decoding report it
+    }
+
+    version := 1
+    if node := mappingValue(top, "version"); node != nil {
+        parsed, err := strconv.Atoi(node.Value)
+        if err != nil || parsed < 1 {
+            return fmt.Errorf("%s:%d: invalid config version %q", m.filename, node.Line, node.Value)
+        }
+        version = parsed
+    }
+    if version > CurrentVersion {
+        return fmt.Errorf("%s: config version %d is newer than this Raven supports (%d); upgrade Raven or use an older config", m.filename, version, CurrentVersion)
+    }
+
+    if version < 2 {
+        m.upgradeDurations(top, root, "")
+    }
+    return nil
+}
+
+// upgradeDurations walks the tree alongside the type it decodes into and
+// rewrites version 1 durations: bare numbers become seconds and a scalar
+// interval becomes a map covering every state
+func (m *migrator) upgradeDurations(node *yaml.Node, t reflect.Type, path string) {
+    for t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+
+    switch {
+    case t == durationType:
+        if node.Kind == yaml.ScalarNode && node.Tag == "!!int" {
+            m.note(node, "%s: bare number %s is read as %ss; write it with a unit", path, node.Value, node.Value)
+            node.Value += "s"
+            node.Tag = "!!str"
+        }
+
+    case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+        for i := 0; i+1 < len(node.Content); i += 2 {
+            key := node.Content[i].Value
+            for f := 0; f < t.NumField(); f++ {
+                field := t.Field(f)
+                if field.IsExported() && yamlName(field) == key {
+                    m.upgradeDurations(node.Content[i+1], field.Type, joinPath(path, key))
+                    break
+                }
+            }
+        }
+
+    case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+        for i, item := range node.Content {
+            m.upgradeDurations(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+        }
+
+    case t.Kind() == reflect.Map && t.Elem() == durationType && node.Kind == yaml.ScalarNode:
+        m.upgradeDurations(node, durationType, path)
+        m.note(node, "%s: a single duration (%s) now applies to every state; write it as a map of %s", path, node.Value, strings.Join(IntervalStates, "/"))
+        value := *node
+        node.Kind, node.Tag, node.Value, node.Style = yaml.MappingNode, "!!map", "", 0
+        node.Content = nil
+        for _, state := range IntervalStates {
+            stateValue := value
+            node.Content = append(node.Content,
+                &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: state},
+                &stateValue)
+        }
+
+    case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+        for i := 0; i+1 < len(node.Content); i += 2 {
+            m.upgradeDurations(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+        }
+    }
+}
+
+func (m *migrator) note(node *yaml.Node, format string, args ...interface{}) {
+    m.notes = append(m.notes, fmt.Sprintf("%s:%d: ", m.filename, node.Line)+fmt.Sprintf(format, args...))
+}
+
+// mappingValue returns the value for key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if node.Content[i].Value == key {
+            return node.Content[i+1]
+        }
+    }
+    return nil
+}
+
+// setVersion sets the version: key, adding it at the top if missing
+func setVersion(node *yaml.Node, version int) {
+    value := strconv.Itoa(version)
+    if existing := mappingValue(node, "version"); existing != nil {
+        existing.Value, existing.Tag = value, "!!int"
+        return
+    }
+    node.Content = append([]*yaml.Node{
+        {Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+        {Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+    }, node.Content...)
+}
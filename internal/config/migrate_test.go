@@ -0,0 +1,112 @@
+// internal/config/migrate_test.go - Loading version 1 config files
+package config
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// loadFixture copies a testdata config into a temporary directory, with
+// the database pointed there too, and loads it
+func loadFixture(t *testing.T, name string) (*Config, string) {
+    t.Helper()
+    data, err := os.ReadFile(filepath.Join("testdata", name))
+    if err != nil {
+        t.Fatal(err)
+    }
+    dir := t.TempDir()
+    path := filepath.Join(dir, name)
+    data = []byte(strings.Replace(string(data), "PLACEHOLDER", filepath.Join(dir, "raven.db"), 1))
+    if err := os.WriteFile(path, data, 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    cfg, err := Load(path)
+    if err != nil {
+        t.Fatalf("Load(%s): %v", name, err)
+    }
+    return cfg, path
+}
+
+// comparable is a config's exported settings, leaving out the file paths
+func comparable(t *testing.T, cfg *Config) string {
+    t.Helper()
+    copied := *cfg
+    copied.Database.Path, copied.Database.DataDir = "", ""
+    data, err := json.Marshal(&copied)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return string(data)
+}
+
+func TestLoadVersion1Config(t *testing.T) {
+    v1, path := loadFixture(t, "v1.yaml")
+    v2, _ := loadFixture(t, "v2.yaml")
+
+    if got, want := comparable(t, v1), comparable(t, v2); got != want {
+        t.Errorf("version 1 config loaded as\n%s\nwant\n%s", got, want)
+    }
+    if v1.Version != CurrentVersion {
+        t.Errorf("Version = %d, want %d", v1.Version, CurrentVersion)
+    }
+    if v1.Database.CleanupInterval != time.Hour || v1.Checks[0].Interval["critical"] != time.Minute {
+        t.Errorf("cleanup_interval %s, critical interval %s; want bare numbers read as seconds",
+            v1.Database.CleanupInterval, v1.Checks[0].Interval["critical"])
+    }
+
+    // Each upgrade is reported with where it was found
+    warnings := strings.Join(v1.Warnings(), "\n")
+    for _, want := range []string{path + ":6: server.read_timeout", path + ":26: checks[0].interval: a single duration"} {
+        if !strings.Contains(warnings, want) {
+            t.Errorf("warnings missing %q:\n%s", want, warnings)
+        }
+    }
+    if warnings := v2.Warnings(); len(warnings) != 0 {
+        t.Errorf("current format config has warnings: %v", warnings)
+    }
+}
+
+func TestMigrateFileMatchesLoad(t *testing.T) {
+    v1, path := loadFixture(t, "v1.yaml")
+
+    upgraded, notes, err := MigrateFile(path)
+    if err != nil {
+        t.Fatalf("MigrateFile: %v", err)
+    }
+    if len(notes) == 0 {
+        t.Error("MigrateFile made no notes on a version 1 file")
+    }
+    if !strings.Contains(string(upgraded), "# A config written before versioning") {
+        t.Errorf("upgraded file lost its comments:\n%s", upgraded)
+    }
+
+    if err := os.WriteFile(path, upgraded, 0600); err != nil {
+        t.Fatal(err)
+    }
+    migrated, err := Load(path)
+    if err != nil {
+        t.Fatalf("Load(upgraded): %v", err)
+    }
+    if got, want := comparable(t, migrated), comparable(t, v1); got != want {
+        t.Errorf("upgraded file loaded as\n%s\nwant\n%s", got, want)
+    }
+    if warnings := migrated.Warnings(); len(warnings) != 0 {
+        t.Errorf("upgraded file still has warnings: %v", warnings)
+    }
+}
+
+func TestNewerConfigVersionIsRefused(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte("version: 3\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    _, err := Load(path)
+    if err == nil || !strings.Contains(err.Error(), "newer than this Raven supports") {
+        t.Errorf("Load of a version 3 file = %v, want it refused", err)
+    }
+}
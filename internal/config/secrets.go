@@ -0,0 +1,205 @@
+// internal/config/secrets.go - Resolve secrets from environment variables and files
+package config
+
+import (
+    "fmt"
+    "os"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+const (
+    // RedactedValue replaces resolved secrets in anything echoed back to users
+    RedactedValue = "***set***"
+
+    fileSecretPrefix = "file://"
+)
+
+var envSecretPattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// resolveSecrets walks every string in the loaded configuration and replaces
+// values of the form ${ENV_VAR} with the environment variable and file://path
+// with the trimmed content of the file. Resolved values are remembered so they
+// can be redacted from API output.
+func resolveSecrets(cfg *Config) error {
+    cfg.secrets = make(map[string]bool)
+    return cfg.resolveValue(reflect.ValueOf(cfg).Elem(), "")
+}
+
+func (c *Config) resolveValue(v reflect.Value, path string) error {
+    switch v.Kind() {
+    case reflect.Ptr:
+        if v.IsNil() {
+            return nil
+        }
+        return c.resolveValue(v.Elem(), path)
+
+    case reflect.Struct:
+        t := v.Type()
+        for i := 0; i < v.NumField(); i++ {
+            field := t.Field(i)
+            if field.PkgPath != "" {
+                continue // unexported
+            }
+            if err := c.resolveValue(v.Field(i), joinPath(path, yamlName(field))); err != nil {
+                return err
+            }
+        }
+
+    case reflect.String:
+        resolved, err := c.resolveString(v.String(), path)
+        if err != nil {
+            return err
+        }
+        if v.CanSet() {
+            v.SetString(resolved)
+        }
+
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            if err := c.resolveValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+                return err
+            }
+        }
+
+    case reflect.Map:
+        if v.IsNil() {
+            return nil
+        }
+        iter := v.MapRange()
+        for iter.Next() {
+            key := iter.Key()
+            elemPath := joinPath(path, fmt.Sprint(key.Interface()))
+
+            // Map values are not addressable, so resolve into a copy
+            elem := reflect.New(v.Type().Elem()).Elem()
+            elem.Set(iter.Value())
+            if err := c.resolveValue(elem, elemPath); err != nil {
+                return err
+            }
+            v.SetMapIndex(key, elem)
+        }
+
+    case reflect.Interface:
+        if v.IsNil() {
+            return nil
+        }
+        elem := reflect.New(v.Elem().Type()).Elem()
+        elem.Set(v.Elem())
+        if err := c.resolveValue(elem, path); err != nil {
+            return err
+        }
+        if v.CanSet() {
+            v.Set(elem)
+        }
+    }
+
+    return nil
+}
+
+func (c *Config) resolveString(value, path string) (string, error) {
+    if match := envSecretPattern.FindStringSubmatch(value); match != nil {
+        resolved, ok := os.LookupEnv(match[1])
+        if !ok {
+            return "", fmt.Errorf("%s references undefined environment variable %s", path, match[1])
+        }
+        c.rememberSecret(resolved)
+        return resolved, nil
+    }
+
+    if strings.HasPrefix(value, fileSecretPrefix) {
+        filename := strings.TrimPrefix(value, fileSecretPrefix)
+        data, err := os.ReadFile(filename)
+        if err != nil {
+            return "", fmt.Errorf("%s references unreadable secret file %s: %w", path, filename, err)
+        }
+        resolved := strings.TrimSpace(string(data))
+        c.rememberSecret(resolved)
+        return resolved, nil
+    }
+
+    return value, nil
+}
+
+func (c *Config) rememberSecret(value string) {
+    if value != "" {
+        c.secrets[value] = true
+    }
+}
+
+// IsSecret reports whether value was resolved from an environment variable
+// or secret file
+func (c *Config) IsSecret(value string) bool {
+    return value != "" && c.secrets[value]
+}
+
+// Redact replaces any resolved secret values contained in free text, such
+// as a file preview or a logged path. Longer secrets go first so one that
+// contains another is replaced whole. Structured values should use
+// IsSecret or RedactOptions instead, which only match whole values.
+func (c *Config) Redact(s string) string {
+    secrets := make([]string, 0, len(c.secrets))
+    for secret := range c.secrets {
+        secrets = append(secrets, secret)
+    }
+    sort.Slice(secrets, func(i, j int) bool {
+        if len(secrets[i]) != len(secrets[j]) {
+            return len(secrets[i]) > len(secrets[j])
+        }
+        return secrets[i] < secrets[j]
+    })
+    for _, secret := range secrets {
+        s = strings.ReplaceAll(s, secret, RedactedValue)
+    }
+    return s
+}
+
+// RedactOptions returns a copy of check options with every string that was
+// resolved from a secret replaced by RedactedValue. Secrets are always
+// whole values, since only a value that is entirely ${VAR} or file://path
+// is resolved, so nothing else is touched.
+func (c *Config) RedactOptions(options map[string]interface{}) map[string]interface{} {
+    if options == nil {
+        return nil
+    }
+    redacted := make(map[string]interface{}, len(options))
+    for key, value := range options {
+        redacted[key] = c.redactValue(value)
+    }
+    return redacted
+}
+
+func (c *Config) redactValue(value interface{}) interface{} {
+    switch v := value.(type) {
+    case string:
+        if c.IsSecret(v) {
+            return RedactedValue
+        }
+    case map[string]interface{}:
+        return c.RedactOptions(v)
+    case []interface{}:
+        redacted := make([]interface{}, len(v))
+        for i, elem := range v {
+            redacted[i] = c.redactValue(elem)
+        }
+        return redacted
+    }
+    return value
+}
+
+func yamlName(field reflect.StructField) string {
+    name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+    if name == "" {
+        return strings.ToLower(field.Name)
+    }
+    return name
+}
+
+func joinPath(base, name string) string {
+    if base == "" {
+        return name
+    }
+    return base + "." + name
+}
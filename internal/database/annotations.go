@@ -0,0 +1,176 @@
+// internal/database/annotations.go - Notes attached to individual status entries
+package database
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "go.etcd.io/bbolt"
+)
+
+// AnnotationsBucket holds annotations keyed by statusID:annotationID
+var AnnotationsBucket = []byte("annotations")
+
+// Annotation is a note attached to a status entry, e.g. during incident review
+type Annotation struct {
+    ID        string    `json:"id"`
+    StatusID  string    `json:"status_id"`
+    Author    string    `json:"author"`
+    Text      string    `json:"text"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// UnmarshalJSON converts the timestamp to UTC (see toUTC)
+func (a *Annotation) UnmarshalJSON(data []byte) error {
+    type annotationAlias Annotation
+    if err := json.Unmarshal(data, (*annotationAlias)(a)); err != nil {
+        return err
+    }
+    toUTC(&a.Timestamp)
+    return nil
+}
+
+// AddAnnotation attaches an annotation to an existing status history entry
+func (s *BoltStore) AddAnnotation(ctx context.Context, annotation *Annotation) error {
+    if annotation.ID == "" {
+        annotation.ID = uuid.New().String()
+    }
+    if annotation.Timestamp.IsZero() {
+        annotation.Timestamp = time.Now().UTC()
+    }
+
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        if !statusExists(tx, annotation.StatusID) {
+            return fmt.Errorf("status not found")
+        }
+
+        data, err := json.Marshal(annotation)
+        if err != nil {
+            return fmt.Errorf("failed to marshal annotation: %w", err)
+        }
+
+        key := annotation.StatusID + ":" + annotation.ID
+        return tx.Bucket(AnnotationsBucket).Put([]byte(key), data)
+    })
+}
+
+// GetAnnotations returns the annotations for a status entry, oldest first
+func (s *BoltStore) GetAnnotations(ctx context.Context, statusID string) ([]Annotation, error) {
+    annotations := []Annotation{}
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        annotations = append(annotations, readAnnotations(tx, statusID)...)
+        return nil
+    })
+
+    return annotations, err
+}
+
+// GetAnnotationsForStatuses returns annotations for several status entries
+// in one read, keyed by status ID. Statuses without annotations are omitted.
+func (s *BoltStore) GetAnnotationsForStatuses(ctx context.Context, statusIDs []string) (map[string][]Annotation, error) {
+    result := make(map[string][]Annotation)
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        for _, statusID := range statusIDs {
+            if annotations := readAnnotations(tx, statusID); len(annotations) > 0 {
+                result[statusID] = annotations
+            }
+        }
+        return nil
+    })
+
+    return result, err
+}
+
+// DeleteAnnotation removes a single annotation from a status entry
+func (s *BoltStore) DeleteAnnotation(ctx context.Context, statusID, annotationID string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(AnnotationsBucket)
+        key := []byte(statusID + ":" + annotationID)
+        if b.Get(key) == nil {
+            return fmt.Errorf("annotation not found")
+        }
+        return b.Delete(key)
+    })
+}
+
+func readAnnotations(tx *bbolt.Tx, statusID string) []Annotation {
+    var annotations []Annotation
+
+    prefix := statusID + ":"
+    c := tx.Bucket(AnnotationsBucket).Cursor()
+    for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+        var annotation Annotation
+        if err := json.Unmarshal(v, &annotation); err != nil {
+            continue
+        }
+        annotations = append(annotations, annotation)
+    }
+
+    sort.Slice(annotations, func(i, j int) bool {
+        return annotations[i].Timestamp.Before(annotations[j].Timestamp)
+    })
+
+    return annotations
+}
+
+// statusExists reports whether a status ID is present in history. Status IDs
+// are not indexed, so this scans the history bucket.
+func statusExists(tx *bbolt.Tx, statusID string) bool {
+    if statusID == "" {
+        return false
+    }
+
+    needle := []byte(statusID)
+    c := tx.Bucket(StatusHistBucket).Cursor()
+    for k, v := c.First(); k != nil; k, v = c.Next() {
+        if bytes.Contains(v, needle) && statusIDOf(v) == statusID {
+            return true
+        }
+    }
+    return false
+}
+
+// deleteStatusAnnotations removes the annotations of a status entry that is
+// being purged, given its stored JSON
+func deleteStatusAnnotations(tx *bbolt.Tx, statusData []byte) {
+    b := tx.Bucket(AnnotationsBucket)
+    if b == nil {
+        return
+    }
+    if k, _ := b.Cursor().First(); k == nil {
+        return // Nothing annotated, skip decoding the status
+    }
+
+    statusID := statusIDOf(statusData)
+    if statusID == "" {
+        return
+    }
+
+    var keys [][]byte
+    prefix := statusID + ":"
+    c := b.Cursor()
+    for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+        keys = append(keys, copyBytes(k))
+    }
+    for _, key := range keys {
+        b.Delete(key)
+    }
+}
+
+func statusIDOf(statusData []byte) string {
+    var ref struct {
+        ID string `json:"id"`
+    }
+    if err := json.Unmarshal(statusData, &ref); err != nil {
+        return ""
+    }
+    return ref.ID
+}
@@ -0,0 +1,224 @@
+// internal/database/archive.go - Hourly rollups of history removed by retention
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "math"
+    "strings"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "go.etcd.io/bbolt"
+)
+
+// StatusArchiveBucket holds rollups keyed by hostID:checkID:unixhour
+var StatusArchiveBucket = []byte("status_archive")
+
+// StatusRollup summarises one hour of history for a host/check. The sample
+// and total fields make rollups mergeable when an hour is purged in parts.
+type StatusRollup struct {
+    HostID        string    `json:"host_id"`
+    CheckID       string    `json:"check_id"`
+    Hour          time.Time `json:"hour"`
+    Samples       int       `json:"samples"`
+    OKSamples     int       `json:"ok_samples"`
+    WorstState    int       `json:"worst_state"`
+    OKPercent     float64   `json:"ok_percent"`
+    TotalDuration float64   `json:"total_duration_ms"`
+    AvgDuration   float64   `json:"avg_duration_ms"`
+    MaxDuration   float64   `json:"max_duration_ms"`
+}
+
+// add folds a status into the rollup, counting the repeats deduplicated
+// into it as samples of their own
+func (r *StatusRollup) add(status *Status) {
+    if r.Samples == 0 || StateSeverity(status.ExitCode) > StateSeverity(r.WorstState) {
+        r.WorstState = status.ExitCode
+    }
+    samples := 1 + status.Repeats
+    r.Samples += samples
+    if status.ExitCode == StateOK {
+        r.OKSamples += samples
+    }
+    r.TotalDuration += status.Duration * float64(samples)
+    if status.Duration > r.MaxDuration {
+        r.MaxDuration = status.Duration
+    }
+
+    r.OKPercent = float64(r.OKSamples) / float64(r.Samples) * 100
+    r.AvgDuration = r.TotalDuration / float64(r.Samples)
+}
+
+// merge folds another rollup for the same hour into this one
+func (r *StatusRollup) merge(other *StatusRollup) {
+    if other.Samples == 0 {
+        return
+    }
+    if r.Samples == 0 || StateSeverity(other.WorstState) > StateSeverity(r.WorstState) {
+        r.WorstState = other.WorstState
+    }
+    r.Samples += other.Samples
+    r.OKSamples += other.OKSamples
+    r.TotalDuration += other.TotalDuration
+    if other.MaxDuration > r.MaxDuration {
+        r.MaxDuration = other.MaxDuration
+    }
+
+    r.OKPercent = float64(r.OKSamples) / float64(r.Samples) * 100
+    r.AvgDuration = r.TotalDuration / float64(r.Samples)
+}
+
+func rollupKey(hostID, checkID string, hour time.Time) []byte {
+    return []byte(fmt.Sprintf("%s:%s:%d", hostID, checkID, hour.Unix()))
+}
+
+// rollupCollector gathers statuses into hourly rollups within a transaction
+type rollupCollector map[string]*StatusRollup
+
+func (rc rollupCollector) add(data []byte) {
+    var status Status
+    if err := json.Unmarshal(data, &status); err != nil {
+        return
+    }
+
+    for hour, samples := range hourlySamples(&status) {
+        key := string(rollupKey(status.HostID, status.CheckID, hour))
+
+        rollup, ok := rc[key]
+        if !ok {
+            rollup = &StatusRollup{HostID: status.HostID, CheckID: status.CheckID, Hour: hour}
+            rc[key] = rollup
+        }
+        part := status
+        part.Repeats = samples - 1
+        rollup.add(&part)
+    }
+}
+
+// hourlySamples spreads a history entry's samples over the hours they came
+// in. The repeats folded into an entry are taken as evenly spaced between
+// its timestamp and LastSeen, so a run that crosses an hour counts in each.
+func hourlySamples(status *Status) map[time.Time]int {
+    first := status.Timestamp.UTC()
+    samples := 1 + status.Repeats
+    if status.LastSeen == nil || samples == 1 || !status.LastSeen.After(first) {
+        return map[time.Time]int{first.Truncate(time.Hour): samples}
+    }
+
+    step := float64(status.LastSeen.Sub(first)) / float64(samples-1)
+    counts := make(map[time.Time]int)
+    counted := 0
+    for hour := first.Truncate(time.Hour); counted < samples; hour = hour.Add(time.Hour) {
+        // Samples that came in before the end of this hour
+        before := int(math.Ceil(float64(hour.Add(time.Hour).Sub(first)) / step))
+        if before > samples {
+            before = samples
+        }
+        if before > counted {
+            counts[hour] = before - counted
+            counted = before
+        }
+    }
+    return counts
+}
+
+// store merges the collected rollups into the archive bucket
+func (rc rollupCollector) store(tx *bbolt.Tx) error {
+    b := tx.Bucket(StatusArchiveBucket)
+    if b == nil {
+        return nil
+    }
+
+    for key, rollup := range rc {
+        if existing := b.Get([]byte(key)); existing != nil {
+            var stored StatusRollup
+            if err := json.Unmarshal(existing, &stored); err == nil {
+                stored.merge(rollup)
+                rollup = &stored
+            }
+        }
+
+        data, err := json.Marshal(rollup)
+        if err != nil {
+            return fmt.Errorf("failed to marshal rollup: %w", err)
+        }
+        if err := b.Put([]byte(key), data); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// GetStatusArchive returns the hourly rollups for a host/check since the
+// given time, oldest first
+func (s *ExtendedBoltStore) GetStatusArchive(ctx context.Context, hostID, checkID string, since time.Time) ([]StatusRollup, error) {
+    var rollups []StatusRollup
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(StatusArchiveBucket)
+        if b == nil {
+            return nil
+        }
+
+        prefix := fmt.Sprintf("%s:%s:", hostID, checkID)
+        c := b.Cursor()
+        for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+            var rollup StatusRollup
+            if err := json.Unmarshal(v, &rollup); err != nil {
+                continue
+            }
+            // Include the hour that contains since
+            if rollup.Hour.Add(time.Hour).After(since) {
+                rollups = append(rollups, rollup)
+            }
+        }
+        return nil
+    })
+
+    return rollups, err
+}
+
+// DeleteStatusArchiveBefore removes rollups for hours that ended before cutoffTime
+func (s *ExtendedBoltStore) DeleteStatusArchiveBefore(ctx context.Context, cutoffTime time.Time) (int, error) {
+    deletedCount := 0
+
+    err := s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(StatusArchiveBucket)
+        if b == nil {
+            return nil
+        }
+
+        var keysToDelete [][]byte
+        c := b.Cursor()
+        for k, _ := c.First(); k != nil; k, _ = c.Next() {
+            _, _, hour, ok := parseHistoryKey(k)
+            if !ok {
+                continue
+            }
+            if time.Unix(hour, 0).Add(time.Hour).Before(cutoffTime) {
+                keysToDelete = append(keysToDelete, copyBytes(k))
+            }
+        }
+
+        for _, key := range keysToDelete {
+            if err := b.Delete(key); err != nil {
+                logrus.WithError(err).Error("Failed to delete archive entry")
+                continue
+            }
+            deletedCount++
+        }
+        return nil
+    })
+
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete expired archive: %w", err)
+    }
+
+    if deletedCount > 0 {
+        logrus.WithField("deleted_count", deletedCount).Info("Deleted expired archive rollups")
+    }
+
+    return deletedCount, nil
+}
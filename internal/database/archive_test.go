@@ -0,0 +1,159 @@
+// internal/database/archive_test.go - Hourly rollups across hour edges and folded runs
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+var archiveHour = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// at returns a time minutes after the start of archiveHour
+func at(minutes int) time.Time {
+    return archiveHour.Add(time.Duration(minutes) * time.Minute)
+}
+
+// run is a history entry folding repeats results, five minutes apart,
+// starting minutes after archiveHour
+func run(minutes, repeats, exitCode int) Status {
+    lastSeen := at(minutes + 5*repeats)
+    return Status{
+        HostID:    "web-01",
+        CheckID:   "ping",
+        ExitCode:  exitCode,
+        Duration:  100,
+        Timestamp: at(minutes),
+        Repeats:   repeats,
+        LastSeen:  &lastSeen,
+    }
+}
+
+func TestHourlySamples(t *testing.T) {
+    single := run(30, 0, StateOK)
+    single.LastSeen = nil
+    unknownEnd := run(50, 4, StateOK)
+    unknownEnd.LastSeen = nil
+
+    tests := []struct {
+        name   string
+        status Status
+        want   map[time.Time]int
+    }{
+        {"single result", single, map[time.Time]int{at(0): 1}},
+        {"run within the hour", run(5, 9, StateOK), map[time.Time]int{at(0): 10}},
+        {"run across the hour", run(50, 11, StateOK), map[time.Time]int{at(0): 2, at(60): 10}},
+        {"run ending on the hour", run(50, 2, StateOK), map[time.Time]int{at(0): 2, at(60): 1}},
+        {"run across three hours", run(55, 24, StateOK), map[time.Time]int{at(0): 1, at(60): 12, at(120): 12}},
+        {"repeats without last seen", unknownEnd, map[time.Time]int{at(0): 5}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := hourlySamples(&tt.status)
+            if len(got) != len(tt.want) {
+                t.Fatalf("hourlySamples = %v, want %v", got, tt.want)
+            }
+            for hour, samples := range tt.want {
+                if got[hour] != samples {
+                    t.Errorf("hourlySamples = %v, want %v", got, tt.want)
+                    break
+                }
+            }
+        })
+    }
+}
+
+func TestRollupsAcrossHourEdge(t *testing.T) {
+    single := func(minutes, exitCode int, duration float64) Status {
+        return Status{HostID: "web-01", CheckID: "ping", ExitCode: exitCode, Duration: duration, Timestamp: at(minutes)}
+    }
+
+    tests := []struct {
+        name   string
+        purges [][]Status // Each purge archives its statuses in one transaction
+        want   []StatusRollup
+    }{
+        {
+            name:   "either side of the hour",
+            purges: [][]Status{{single(59, StateOK, 100), single(60, StateCritical, 300)}},
+            want: []StatusRollup{
+                {Hour: at(0), Samples: 1, OKSamples: 1, WorstState: StateOK, OKPercent: 100, AvgDuration: 100, MaxDuration: 100},
+                {Hour: at(60), Samples: 1, WorstState: StateCritical, AvgDuration: 300, MaxDuration: 300},
+            },
+        },
+        {
+            name:   "one hour purged in two parts",
+            purges: [][]Status{{single(10, StateOK, 100)}, {single(40, StateWarning, 300), single(70, StateOK, 200)}},
+            want: []StatusRollup{
+                {Hour: at(0), Samples: 2, OKSamples: 1, WorstState: StateWarning, OKPercent: 50, AvgDuration: 200, MaxDuration: 300},
+                {Hour: at(60), Samples: 1, OKSamples: 1, WorstState: StateOK, OKPercent: 100, AvgDuration: 200, MaxDuration: 200},
+            },
+        },
+        {
+            name:   "folded run across the hour",
+            purges: [][]Status{{run(50, 11, StateOK)}},
+            want: []StatusRollup{
+                {Hour: at(0), Samples: 2, OKSamples: 2, WorstState: StateOK, OKPercent: 100, AvgDuration: 100, MaxDuration: 100},
+                {Hour: at(60), Samples: 10, OKSamples: 10, WorstState: StateOK, OKPercent: 100, AvgDuration: 100, MaxDuration: 100},
+            },
+        },
+        {
+            name:   "folded run merged with a later purge",
+            purges: [][]Status{{run(50, 11, StateCritical)}, {single(58, StateOK, 100)}},
+            want: []StatusRollup{
+                {Hour: at(0), Samples: 3, OKSamples: 1, WorstState: StateCritical, OKPercent: 100.0 / 3, AvgDuration: 100, MaxDuration: 100},
+                {Hour: at(60), Samples: 10, WorstState: StateCritical, AvgDuration: 100, MaxDuration: 100},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            store := newTestStore(t, filepath.Join(t.TempDir(), "raven.db")).(*BoltStore)
+            defer store.Close()
+            extended := &ExtendedBoltStore{BoltStore: store}
+
+            for _, purge := range tt.purges {
+                err := store.db.Update(func(tx *bbolt.Tx) error {
+                    rollups := make(rollupCollector)
+                    for i := range purge {
+                        data, err := json.Marshal(&purge[i])
+                        if err != nil {
+                            return err
+                        }
+                        rollups.add(data)
+                    }
+                    return rollups.store(tx)
+                })
+                if err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            got, err := extended.GetStatusArchive(context.Background(), "web-01", "ping", time.Time{})
+            if err != nil {
+                t.Fatalf("GetStatusArchive: %v", err)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("GetStatusArchive = %d rollups, want %d: %+v", len(got), len(tt.want), got)
+            }
+            for i, want := range tt.want {
+                g := got[i]
+                if !g.Hour.Equal(want.Hour) || g.Samples != want.Samples || g.OKSamples != want.OKSamples ||
+                    g.WorstState != want.WorstState || !near(g.OKPercent, want.OKPercent) ||
+                    !near(g.AvgDuration, want.AvgDuration) || g.MaxDuration != want.MaxDuration {
+                    t.Errorf("rollup %d = %+v, want %+v", i, g, want)
+                }
+            }
+        })
+    }
+}
+
+func near(a, b float64) bool {
+    return a-b < 1e-9 && b-a < 1e-9
+}
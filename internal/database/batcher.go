@@ -0,0 +1,230 @@
+// internal/database/batcher.go - Write-behind batching for status updates
+package database
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+// StatusBatcher coalesces status writes into a single transaction per window.
+//
+// Crash safety: statuses are held in memory until the next flush, so an
+// unclean shutdown (kill -9, power loss) loses at most the statuses queued
+// during the last window. A clean shutdown flushes everything pending.
+//
+// A batch that fails to write is retried one status at a time, and statuses
+// that still fail go back at the front of the queue for the next flush, up
+// to maxRetainedBatches batches' worth.
+type StatusBatcher struct {
+    store   Store
+    window  time.Duration
+    maxSize int
+
+    flushMu   sync.Mutex // Held while a batch is written, so Discard can wait it out
+    mu        sync.Mutex
+    pending   []*Status
+    started   bool
+    lastFlush time.Time // When a batch was last written
+
+    flushCh   chan struct{}
+    done      chan struct{}
+    stopped   chan struct{}
+    closeOnce sync.Once
+}
+
+// maxRetainedBatches is how many batches' worth of failed statuses are kept
+// for retrying; beyond that the oldest are dropped
+const maxRetainedBatches = 10
+
+// NewStatusBatcher creates a batcher that flushes every window or once
+// maxSize statuses are pending, whichever comes first
+func NewStatusBatcher(store Store, window time.Duration, maxSize int) *StatusBatcher {
+    if window <= 0 {
+        window = 200 * time.Millisecond
+    }
+    if maxSize <= 0 {
+        maxSize = 100
+    }
+
+    return &StatusBatcher{
+        store:   store,
+        window:  window,
+        maxSize: maxSize,
+        pending: make([]*Status, 0, maxSize),
+        flushCh: make(chan struct{}, 1),
+        done:    make(chan struct{}),
+        stopped: make(chan struct{}),
+    }
+}
+
+// Start runs the flush loop until ctx is cancelled or Close is called
+func (b *StatusBatcher) Start(ctx context.Context) {
+    b.mu.Lock()
+    b.started = true
+    b.mu.Unlock()
+
+    go func() {
+        defer close(b.stopped)
+
+        ticker := time.NewTicker(b.window)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ctx.Done():
+                b.flush()
+                return
+            case <-b.done:
+                b.flush()
+                return
+            case <-ticker.C:
+                b.flush()
+            case <-b.flushCh:
+                b.flush()
+            }
+        }
+    }()
+
+    logrus.WithFields(logrus.Fields{
+        "window":   b.window,
+        "max_size": b.maxSize,
+    }).Info("Started status write batching")
+}
+
+// Add queues a status for the next batch
+func (b *StatusBatcher) Add(status *Status) {
+    b.mu.Lock()
+    b.pending = append(b.pending, status)
+    full := len(b.pending) >= b.maxSize
+    b.mu.Unlock()
+
+    if full {
+        select {
+        case b.flushCh <- struct{}{}:
+        default:
+        }
+    }
+}
+
+// Close flushes pending statuses and stops the flush loop
+func (b *StatusBatcher) Close() {
+    b.mu.Lock()
+    started := b.started
+    b.mu.Unlock()
+
+    if !started {
+        b.flush()
+        return
+    }
+
+    b.closeOnce.Do(func() {
+        close(b.done)
+    })
+    <-b.stopped
+
+    // The loop may have stopped with its context before the last Adds
+    b.flush()
+
+    b.mu.Lock()
+    lost := len(b.pending)
+    b.mu.Unlock()
+    if lost > 0 {
+        logrus.WithField("count", lost).Error("Dropped statuses that could not be written before shutdown")
+    }
+}
+
+// Discard drops pending statuses that match, e.g. those of a deleted host,
+// and returns how many it dropped. It waits for a batch being written, so
+// none of the matching statuses lands after it returns.
+func (b *StatusBatcher) Discard(match func(*Status) bool) int {
+    b.flushMu.Lock()
+    defer b.flushMu.Unlock()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    kept := b.pending[:0]
+    for _, status := range b.pending {
+        if !match(status) {
+            kept = append(kept, status)
+        }
+    }
+    dropped := len(b.pending) - len(kept)
+    b.pending = kept
+    return dropped
+}
+
+// LastFlush returns when a batch was last written, or zero if none has been
+func (b *StatusBatcher) LastFlush() time.Time {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.lastFlush
+}
+
+func (b *StatusBatcher) flush() {
+    b.flushMu.Lock()
+    defer b.flushMu.Unlock()
+
+    b.mu.Lock()
+    if len(b.pending) == 0 {
+        b.mu.Unlock()
+        return
+    }
+    batch := b.pending
+    b.pending = make([]*Status, 0, b.maxSize)
+    b.mu.Unlock()
+
+    start := time.Now()
+    if err := b.store.UpdateStatusBatch(context.Background(), batch); err != nil {
+        logrus.WithError(err).WithField("count", len(batch)).Warn("Failed to write status batch, writing statuses one at a time")
+        b.retry(batch)
+        return
+    }
+
+    b.mu.Lock()
+    b.lastFlush = time.Now()
+    b.mu.Unlock()
+
+    logrus.WithFields(logrus.Fields{
+        "count":    len(batch),
+        "duration": time.Since(start),
+    }).Debug("Flushed status batch")
+}
+
+// retry writes a failed batch one status at a time, so one bad status
+// doesn't lose the rest, and requeues the statuses that still fail
+func (b *StatusBatcher) retry(batch []*Status) {
+    var failed []*Status
+    var lastErr error
+    for _, status := range batch {
+        if err := b.store.UpdateStatus(context.Background(), status); err != nil {
+            failed = append(failed, status)
+            lastErr = err
+        }
+    }
+
+    b.mu.Lock()
+    if len(failed) < len(batch) {
+        b.lastFlush = time.Now()
+    }
+    if len(failed) == 0 {
+        b.mu.Unlock()
+        return
+    }
+
+    // Failed statuses are older than anything added since, so they go first
+    b.pending = append(failed, b.pending...)
+    dropped := 0
+    if limit := b.maxSize * maxRetainedBatches; len(b.pending) > limit {
+        dropped = len(b.pending) - limit
+        b.pending = append(make([]*Status, 0, b.maxSize), b.pending[dropped:]...)
+    }
+    b.mu.Unlock()
+
+    logrus.WithError(lastErr).WithFields(logrus.Fields{
+        "failed":  len(failed),
+        "dropped": dropped,
+    }).Error("Failed to write statuses, keeping them for the next flush")
+}
@@ -0,0 +1,186 @@
+// internal/database/batcher_test.go - Batched status writes, failures and shutdown
+package database
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "path/filepath"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+func newTestStore(t testing.TB, path string) Store {
+    t.Helper()
+    logrus.SetLevel(logrus.ErrorLevel)
+    store, err := NewBoltStore(path, FileOptions{})
+    if err != nil {
+        t.Fatalf("NewBoltStore: %v", err)
+    }
+    return store
+}
+
+func testStatus(hostID string, i int) *Status {
+    return &Status{
+        HostID:    hostID,
+        CheckID:   "ping",
+        Output:    fmt.Sprintf("result %d", i),
+        Timestamp: time.Now().UTC(),
+    }
+}
+
+// failingStore fails batch writes, and single writes while failSingle is set
+type failingStore struct {
+    Store
+    failSingle atomic.Bool
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (s *failingStore) UpdateStatusBatch(ctx context.Context, statuses []*Status) error {
+    return errWriteFailed
+}
+
+func (s *failingStore) UpdateStatus(ctx context.Context, status *Status) error {
+    if s.failSingle.Load() {
+        return errWriteFailed
+    }
+    return s.Store.UpdateStatus(ctx, status)
+}
+
+func latestHosts(t *testing.T, store Store) map[string]bool {
+    t.Helper()
+    statuses, err := store.GetStatus(context.Background(), StatusFilters{})
+    if err != nil {
+        t.Fatalf("GetStatus: %v", err)
+    }
+    hosts := make(map[string]bool)
+    for _, status := range statuses {
+        hosts[status.HostID] = true
+    }
+    return hosts
+}
+
+func TestBatcherFallsBackToSingleWrites(t *testing.T) {
+    store := &failingStore{Store: newTestStore(t, filepath.Join(t.TempDir(), "raven.db"))}
+    defer store.Close()
+
+    batcher := NewStatusBatcher(store, time.Hour, 100)
+    batcher.Add(testStatus("web-01", 0))
+    batcher.Add(testStatus("db-01", 0))
+    batcher.flush()
+
+    if hosts := latestHosts(t, store); !hosts["web-01"] || !hosts["db-01"] {
+        t.Errorf("statuses after a failed batch = %v, want both written singly", hosts)
+    }
+    if batcher.LastFlush().IsZero() {
+        t.Error("LastFlush not set after the statuses were written")
+    }
+}
+
+func TestBatcherRequeuesFailedStatuses(t *testing.T) {
+    store := &failingStore{Store: newTestStore(t, filepath.Join(t.TempDir(), "raven.db"))}
+    defer store.Close()
+
+    batcher := NewStatusBatcher(store, time.Hour, 2)
+    store.failSingle.Store(true)
+    for i := 0; i < 3*maxRetainedBatches; i++ {
+        batcher.Add(testStatus(fmt.Sprintf("host-%02d", i), i))
+    }
+    batcher.flush()
+
+    // Only the newest statuses are kept for retrying
+    if len(batcher.pending) != 2*maxRetainedBatches {
+        t.Fatalf("pending after failure = %d, want %d", len(batcher.pending), 2*maxRetainedBatches)
+    }
+    if first := batcher.pending[0].HostID; first != fmt.Sprintf("host-%02d", maxRetainedBatches) {
+        t.Errorf("oldest retained status = %s, want the oldest ones dropped first", first)
+    }
+
+    store.failSingle.Store(false)
+    batcher.Add(testStatus("late", 0))
+    batcher.flush()
+
+    hosts := latestHosts(t, store)
+    if len(hosts) != 2*maxRetainedBatches+1 || !hosts["late"] {
+        t.Errorf("written after recovery = %d hosts, want every retained status and the new one", len(hosts))
+    }
+    if len(batcher.pending) != 0 {
+        t.Errorf("pending after recovery = %d, want 0", len(batcher.pending))
+    }
+}
+
+// Statuses flushed before an unclean stop are on disk; Close writes the
+// rest
+func TestBatcherCrashSafety(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "raven.db")
+    store := newTestStore(t, path)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    batcher := NewStatusBatcher(store, 10*time.Millisecond, 100)
+    batcher.Start(ctx)
+    batcher.Add(testStatus("flushed", 0))
+
+    deadline := time.Now().Add(5 * time.Second)
+    for batcher.LastFlush().IsZero() {
+        if time.Now().After(deadline) {
+            t.Fatal("batcher never flushed")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+
+    // Stop without Close, as a crash would, and reopen the database
+    cancel()
+    <-batcher.stopped
+    store.Close()
+
+    store = newTestStore(t, path)
+    if hosts := latestHosts(t, store); !hosts["flushed"] {
+        t.Error("flushed status missing after reopening the database")
+    }
+
+    // A clean shutdown writes what is pending however long the window
+    batcher = NewStatusBatcher(store, time.Hour, 100)
+    batcher.Start(context.Background())
+    batcher.Add(testStatus("pending", 0))
+    batcher.Close()
+    store.Close()
+
+    store = newTestStore(t, path)
+    defer store.Close()
+    if hosts := latestHosts(t, store); !hosts["pending"] {
+        t.Error("status pending at Close missing after reopening the database")
+    }
+}
+
+func BenchmarkStatusWrites(b *testing.B) {
+    b.Run("per-write", func(b *testing.B) {
+        store := newTestStore(b, filepath.Join(b.TempDir(), "raven.db"))
+        defer store.Close()
+
+        b.ResetTimer()
+        for i := 0; i < b.N; i++ {
+            if err := store.UpdateStatus(context.Background(), testStatus(fmt.Sprintf("host-%d", i%100), i)); err != nil {
+                b.Fatal(err)
+            }
+        }
+    })
+
+    b.Run("batched", func(b *testing.B) {
+        store := newTestStore(b, filepath.Join(b.TempDir(), "raven.db"))
+        defer store.Close()
+
+        batcher := NewStatusBatcher(store, time.Hour, 100)
+        b.ResetTimer()
+        for i := 0; i < b.N; i++ {
+            batcher.Add(testStatus(fmt.Sprintf("host-%d", i%100), i))
+            if (i+1)%100 == 0 {
+                batcher.flush()
+            }
+        }
+        batcher.Close()
+    })
+}
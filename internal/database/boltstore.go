@@ -4,13 +4,15 @@ package database
 import (
     "context"
     "encoding/json"
-    "path/filepath"
     "fmt"
     "os"
+    "sort"
     "strings"
+    "sync/atomic"
     "time"
 
     "github.com/google/uuid"
+    "github.com/sirupsen/logrus"
     "go.etcd.io/bbolt"
 )
 
@@ -23,41 +25,64 @@ var (
 )
 
 type BoltStore struct {
-    db   *bbolt.DB
-    path string
+    db          *bbolt.DB
+    path        string
+    files       FileOptions
+    dedupWindow atomic.Int64 // time.Duration; 0 stores every result in history
+    dedupeOK    atomic.Bool  // Fold unchanged OK results regardless of the window
+    maxOutput   atomic.Int64 // Bytes kept of output and long output; 0 keeps everything
 }
 
-func NewBoltStore(path string) (Store, error) {
-    // Create directory if it doesn't exist
-    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-        return nil, fmt.Errorf("failed to create data directory: %w", err)
+func NewBoltStore(path string, files FileOptions) (Store, error) {
+    files = files.withDefaults(path)
+
+    // Create the data directory if it doesn't exist, and fail now rather
+    // than on the first write if the service user can't write to it
+    if err := prepareDataDir(path, files); err != nil {
+        return nil, err
     }
 
-    db, err := bbolt.Open(path, 0600, &bbolt.Options{
-        Timeout: 1 * time.Second,
-    })
+    db, err := openBolt(path, files.FileMode)
     if err != nil {
-        return nil, fmt.Errorf("failed to open BoltDB: %w", err)
+        return nil, err
     }
 
-    store := &BoltStore{db: db, path: path}
+    store := &BoltStore{db: db, path: path, files: files}
+
+    if err := store.checkSchemaVersion(); err != nil {
+        db.Close()
+        return nil, err
+    }
 
     if err := store.initBuckets(); err != nil {
         db.Close()
         return nil, fmt.Errorf("failed to initialize buckets: %w", err)
     }
 
+    if err := store.migrate(); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    writePIDFile(path, files.FileMode)
     return store, nil
 }
 
 func (s *BoltStore) initBuckets() error {
     return s.db.Update(func(tx *bbolt.Tx) error {
-        buckets := [][]byte{HostsBucket, ChecksBucket, StatusBucket, StatusHistBucket, MetaBucket}
-        for _, bucket := range buckets {
+        // Every database has had a meta bucket, so only a new one lacks it
+        created := tx.Bucket(MetaBucket) == nil
+
+        for _, bucket := range allBuckets {
             if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
                 return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
             }
         }
+
+        // A new database is written in the current layout from the start
+        if created {
+            return writeSchemaVersion(tx, SchemaVersion)
+        }
         return nil
     })
 }
@@ -111,8 +136,8 @@ func (s *BoltStore) CreateHost(ctx context.Context, host *Host) error {
     if host.ID == "" {
         host.ID = uuid.New().String()
     }
-    host.CreatedAt = time.Now()
-    host.UpdatedAt = time.Now()
+    host.CreatedAt = time.Now().UTC()
+    host.UpdatedAt = time.Now().UTC()
 
     return s.db.Update(func(tx *bbolt.Tx) error {
         b := tx.Bucket(HostsBucket)
@@ -127,7 +152,7 @@ func (s *BoltStore) CreateHost(ctx context.Context, host *Host) error {
 }
 
 func (s *BoltStore) UpdateHost(ctx context.Context, host *Host) error {
-    host.UpdatedAt = time.Now()
+    host.UpdatedAt = time.Now().UTC()
 
     return s.db.Update(func(tx *bbolt.Tx) error {
         b := tx.Bucket(HostsBucket)
@@ -141,6 +166,76 @@ func (s *BoltStore) UpdateHost(ctx context.Context, host *Host) error {
     })
 }
 
+// CreateHostInChecks creates a host and adds it to the given checks in a
+// single transaction, returning the IDs of the checks that were changed
+func (s *BoltStore) CreateHostInChecks(ctx context.Context, host *Host, checkIDs []string) ([]string, error) {
+    if host.ID == "" {
+        host.ID = uuid.New().String()
+    }
+    now := time.Now().UTC()
+    host.CreatedAt = now
+    host.UpdatedAt = now
+
+    var updated []string
+    err := s.db.Update(func(tx *bbolt.Tx) error {
+        hb := tx.Bucket(HostsBucket)
+        if hb.Get([]byte(host.ID)) != nil {
+            return fmt.Errorf("host already exists")
+        }
+
+        data, err := json.Marshal(host)
+        if err != nil {
+            return fmt.Errorf("failed to marshal host: %w", err)
+        }
+        if err := hb.Put([]byte(host.ID), data); err != nil {
+            return err
+        }
+
+        cb := tx.Bucket(ChecksBucket)
+        for _, checkID := range checkIDs {
+            v := cb.Get([]byte(checkID))
+            if v == nil {
+                return fmt.Errorf("check not found: %s", checkID)
+            }
+
+            var check Check
+            if err := json.Unmarshal(v, &check); err != nil {
+                return fmt.Errorf("failed to unmarshal check %s: %w", checkID, err)
+            }
+
+            member := false
+            for _, hostID := range check.Hosts {
+                if hostID == host.ID {
+                    member = true
+                    break
+                }
+            }
+            if member {
+                continue
+            }
+
+            check.Hosts = append(check.Hosts, host.ID)
+            check.UpdatedAt = now
+
+            data, err := json.Marshal(check)
+            if err != nil {
+                return fmt.Errorf("failed to marshal check: %w", err)
+            }
+            if err := cb.Put([]byte(check.ID), data); err != nil {
+                return err
+            }
+            updated = append(updated, check.ID)
+        }
+
+        return nil
+    })
+
+    if err != nil {
+        return nil, err
+    }
+    return updated, nil
+}
+
 func (s *BoltStore) DeleteHost(ctx context.Context, id string) error {
     return s.db.Update(func(tx *bbolt.Tx) error {
         b := tx.Bucket(HostsBucket)
@@ -148,6 +243,65 @@ func (s *BoltStore) DeleteHost(ctx context.Context, id string) error {
     })
 }
 
+// RemoveHostsFromChecks drops the given hosts from every check's host list
+// in a single transaction, returning the checks that were changed
+func (s *BoltStore) RemoveHostsFromChecks(ctx context.Context, hostIDs []string) ([]Check, error) {
+    remove := make(map[string]bool, len(hostIDs))
+    for _, id := range hostIDs {
+        remove[id] = true
+    }
+    now := time.Now().UTC()
+
+    var updated []Check
+    err := s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(ChecksBucket)
+        if err := b.ForEach(func(k, v []byte) error {
+            var check Check
+            if err := json.Unmarshal(v, &check); err != nil {
+                return fmt.Errorf("failed to unmarshal check %s: %w", k, err)
+            }
+
+            hosts := make([]string, 0, len(check.Hosts))
+            for _, hostID := range check.Hosts {
+                if !remove[hostID] {
+                    hosts = append(hosts, hostID)
+                }
+            }
+            if len(hosts) == len(check.Hosts) {
+                return nil
+            }
+
+            check.Hosts = hosts
+            for hostID := range check.HostThresholds {
+                if remove[hostID] {
+                    delete(check.HostThresholds, hostID)
+                }
+            }
+            check.UpdatedAt = now
+            updated = append(updated, check)
+            return nil
+        }); err != nil {
+            return err
+        }
+
+        for _, check := range updated {
+            data, err := json.Marshal(check)
+            if err != nil {
+                return fmt.Errorf("failed to marshal check: %w", err)
+            }
+            if err := b.Put([]byte(check.ID), data); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+
+    if err != nil {
+        return nil, err
+    }
+    return updated, nil
+}
+
 func (s *BoltStore) GetChecks(ctx context.Context) ([]Check, error) {
     var checks []Check
 
@@ -188,8 +342,8 @@ func (s *BoltStore) CreateCheck(ctx context.Context, check *Check) error {
     if check.ID == "" {
         check.ID = uuid.New().String()
     }
-    check.CreatedAt = time.Now()
-    check.UpdatedAt = time.Now()
+    check.CreatedAt = time.Now().UTC()
+    check.UpdatedAt = time.Now().UTC()
 
     return s.db.Update(func(tx *bbolt.Tx) error {
         b := tx.Bucket(ChecksBucket)
@@ -203,69 +357,302 @@ func (s *BoltStore) CreateCheck(ctx context.Context, check *Check) error {
     })
 }
 
+// GetStatus returns current statuses matching the filters. A host filter
+// seeks to the host's keys and a since filter walks the time index (oldest
+// first); otherwise the whole bucket is read in key order.
 func (s *BoltStore) GetStatus(ctx context.Context, filters StatusFilters) ([]Status, error) {
     var statuses []Status
 
+    // add applies the remaining filters and reports whether to keep going
+    add := func(status *Status) bool {
+        if filters.HostID != "" && status.HostID != filters.HostID {
+            return true
+        }
+        if filters.CheckID != "" && status.CheckID != filters.CheckID {
+            return true
+        }
+        if filters.ExitCode != nil && status.ExitCode != *filters.ExitCode {
+            return true
+        }
+        if filters.Since != nil && status.Timestamp.Before(*filters.Since) {
+            return true
+        }
+
+        statuses = append(statuses, *status)
+        return filters.Limit <= 0 || len(statuses) < filters.Limit
+    }
+
     err := s.db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket(StatusBucket)
-        return b.ForEach(func(k, v []byte) error {
+        if filters.HostID == "" && filters.Since != nil {
+            forEachStatusSince(tx, *filters.Since, add)
+            return nil
+        }
+
+        c := tx.Bucket(StatusBucket).Cursor()
+        k, v := c.First()
+        prefix := ""
+        if filters.HostID != "" {
+            prefix = filters.HostID + ":"
+            k, v = c.Seek([]byte(prefix))
+        }
+        for ; k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
             var status Status
             if err := json.Unmarshal(v, &status); err != nil {
-                return nil // Skip malformed entries
+                continue // Skip malformed entries
             }
+            if !add(&status) {
+                break
+            }
+        }
+        return nil
+    })
 
-            // Apply filters
-            if filters.HostID != "" && status.HostID != filters.HostID {
-                return nil
+    return statuses, err
+}
+
+// GetLatestStatus returns the current status for a host/check pair, read
+// directly by key from the current status bucket
+func (s *BoltStore) GetLatestStatus(ctx context.Context, hostID, checkID string) (*Status, error) {
+    var status Status
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        v := tx.Bucket(StatusBucket).Get([]byte(fmt.Sprintf("%s:%s", hostID, checkID)))
+        if v == nil {
+            return fmt.Errorf("status not found")
+        }
+        return json.Unmarshal(v, &status)
+    })
+
+    if err != nil {
+        return nil, err
+    }
+    return &status, nil
+}
+
+// GetLatestStatuses returns the current status of every check on a host,
+// newest first
+func (s *BoltStore) GetLatestStatuses(ctx context.Context, hostID string) ([]Status, error) {
+    var statuses []Status
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        c := tx.Bucket(StatusBucket).Cursor()
+        prefix := hostID + ":"
+
+        for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+            var status Status
+            if err := json.Unmarshal(v, &status); err != nil {
+                continue // Skip malformed entries
             }
-            if filters.CheckID != "" && status.CheckID != filters.CheckID {
-                return nil
+            // Host IDs may themselves contain ':', so the prefix alone isn't enough
+            if status.HostID != hostID {
+                continue
             }
-            if filters.ExitCode != nil && status.ExitCode != *filters.ExitCode {
-                return nil
+            statuses = append(statuses, status)
+        }
+        return nil
+    })
+
+    sort.Slice(statuses, func(i, j int) bool {
+        return statuses[i].Timestamp.After(statuses[j].Timestamp)
+    })
+
+    return statuses, err
+}
+
+// GetStatusBatch returns the current status for each host/check pair in one
+// read transaction. The result lines up with pairs; missing entries are nil.
+func (s *BoltStore) GetStatusBatch(ctx context.Context, pairs []HostCheckPair) ([]*Status, error) {
+    statuses := make([]*Status, len(pairs))
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(StatusBucket)
+        for i, pair := range pairs {
+            v := b.Get([]byte(fmt.Sprintf("%s:%s", pair.HostID, pair.CheckID)))
+            if v == nil {
+                continue
             }
 
-            statuses = append(statuses, status)
-            
-            if filters.Limit > 0 && len(statuses) >= filters.Limit {
-                return fmt.Errorf("limit_reached")
+            var status Status
+            if err := json.Unmarshal(v, &status); err != nil {
+                continue // Skip malformed entries
             }
+            statuses[i] = &status
+        }
+        return nil
+    })
 
-            return nil
-        })
+    return statuses, err
+}
+
+func (s *BoltStore) UpdateStatus(ctx context.Context, status *Status) error {
+    s.limitOutput(status)
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        return putStatus(tx, status, s.historyDedup())
+    })
+}
+
+// UpdateStatusBatch stores several statuses in a single transaction
+func (s *BoltStore) UpdateStatusBatch(ctx context.Context, statuses []*Status) error {
+    if len(statuses) == 0 {
+        return nil
+    }
+
+    for _, status := range statuses {
+        s.limitOutput(status)
+    }
+    dedup := s.historyDedup()
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        for _, status := range statuses {
+            if err := putStatus(tx, status, dedup); err != nil {
+                return err
+            }
+        }
+        return nil
     })
+}
+
+// SetDedupWindow sets how long identical results are folded into the
+// previous history entry instead of adding new ones (0 turns it off)
+func (s *BoltStore) SetDedupWindow(window time.Duration) {
+    s.dedupWindow.Store(int64(window))
+}
+
+// DedupWindow returns the current history dedup window
+func (s *BoltStore) DedupWindow() time.Duration {
+    return time.Duration(s.dedupWindow.Load())
+}
+
+// SetHistoryDedupe sets whether OK results whose output only differs in
+// numbers from the latest history entry are folded into it, however long
+// the run lasts
+func (s *BoltStore) SetHistoryDedupe(enabled bool) {
+    s.dedupeOK.Store(enabled)
+}
 
-    if err != nil && err.Error() == "limit_reached" {
-        err = nil
+// SetMaxOutputBytes sets how much of a result's output and long output is
+// stored, each (0 keeps everything)
+func (s *BoltStore) SetMaxOutputBytes(limit int) {
+    s.maxOutput.Store(int64(limit))
+}
+
+// limitOutput truncates a status's output and long output to the configured
+// size, so one plugin dumping megabytes can't bloat the database
+func (s *BoltStore) limitOutput(status *Status) {
+    limit := int(s.maxOutput.Load())
+    if limit <= 0 {
+        return
     }
 
-    return statuses, err
+    output, cutOutput := TruncateOutput(status.Output, limit)
+    longOutput, cutLongOutput := TruncateOutput(status.LongOutput, limit)
+    if !cutOutput && !cutLongOutput {
+        return
+    }
+    logrus.WithFields(logrus.Fields{
+        "host":        status.HostID,
+        "check":       status.CheckID,
+        "output":      len(status.Output),
+        "long_output": len(status.LongOutput),
+        "limit":       limit,
+    }).Warn("Truncated check output before storing it")
+    status.Output, status.LongOutput = output, longOutput
 }
 
-func (s *BoltStore) UpdateStatus(ctx context.Context, status *Status) error {
+// historyDedup is when a result is folded into the previous history entry
+type historyDedup struct {
+    window   time.Duration // Identical results within this long (0 = off)
+    steadyOK bool          // Unchanged OK results, numbers ignored
+}
+
+func (s *BoltStore) historyDedup() historyDedup {
+    return historyDedup{window: s.DedupWindow(), steadyOK: s.dedupeOK.Load()}
+}
+
+// putStatus writes a status to the current status and history buckets. The
+// current status is always replaced; a result that repeats the latest history
+// entry under dedup only bumps that entry's repeat count.
+func putStatus(tx *bbolt.Tx, status *Status, dedup historyDedup) error {
     if status.ID == "" {
         status.ID = uuid.New().String()
     }
 
-    return s.db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket(StatusBucket)
-        
-        // Store current status
-        key := fmt.Sprintf("%s:%s", status.HostID, status.CheckID)
-        data, err := json.Marshal(status)
-        if err != nil {
-            return fmt.Errorf("failed to marshal status: %w", err)
+    b := tx.Bucket(StatusBucket)
+
+    // Store current status
+    key := fmt.Sprintf("%s:%s", status.HostID, status.CheckID)
+    data, err := json.Marshal(status)
+    if err != nil {
+        return fmt.Errorf("failed to marshal status: %w", err)
+    }
+
+    if previous := b.Get([]byte(key)); previous != nil {
+        if err := unindexStatus(tx, []byte(key), previous); err != nil {
+            return err
         }
+    }
+    if err := b.Put([]byte(key), data); err != nil {
+        return err
+    }
+    if err := indexStatus(tx, []byte(key), data); err != nil {
+        return err
+    }
 
-        if err := b.Put([]byte(key), data); err != nil {
+    // Also store in history
+    hb := tx.Bucket(StatusHistBucket)
+    if dedup.window > 0 || dedup.steadyOK {
+        if folded, err := foldIntoHistory(hb, status, dedup); folded || err != nil {
             return err
         }
+    }
+    histKey := fmt.Sprintf("%s:%s:%d", status.HostID, status.CheckID, status.Timestamp.Unix())
+    return hb.Put([]byte(histKey), data)
+}
 
-        // Also store in history
-        hb := tx.Bucket(StatusHistBucket)
-        histKey := fmt.Sprintf("%s:%s:%d", status.HostID, status.CheckID, status.Timestamp.Unix())
-        return hb.Put([]byte(histKey), data)
-    })
+// foldIntoHistory counts status as a repeat of the latest history entry for
+// its host/check when both states and the output match and the entry is
+// younger than the window, or, with steadyOK, when both are OK and the output
+// matches apart from numbers. State changes therefore always get their own
+// entry.
+func foldIntoHistory(hb *bbolt.Bucket, status *Status, dedup historyDedup) (bool, error) {
+    prefix := fmt.Sprintf("%s:%s:", status.HostID, status.CheckID)
+
+    // Keys end in the unix time, so the last one under the prefix is newest
+    c := hb.Cursor()
+    k, v := c.Seek([]byte(prefix[:len(prefix)-1] + ";"))
+    if k == nil {
+        k, v = c.Last()
+    } else {
+        k, v = c.Prev()
+    }
+    if k == nil || !strings.HasPrefix(string(k), prefix) {
+        return false, nil
+    }
+
+    var previous Status
+    if err := json.Unmarshal(v, &previous); err != nil {
+        return false, nil
+    }
+    if previous.ExitCode != status.ExitCode || previous.RawExitCode != status.RawExitCode ||
+        !status.Timestamp.After(previous.Timestamp) {
+        return false, nil
+    }
+    identical := previous.Output == status.Output && previous.LongOutput == status.LongOutput &&
+        status.Timestamp.Sub(previous.Timestamp) < dedup.window
+    steady := dedup.steadyOK && status.ExitCode == StateOK &&
+        SteadyOutput(previous.Output, previous.LongOutput) == SteadyOutput(status.Output, status.LongOutput)
+    if !identical && !steady {
+        return false, nil
+    }
+
+    previous.Repeats++
+    lastSeen := status.Timestamp
+    previous.LastSeen = &lastSeen
+
+    data, err := json.Marshal(&previous)
+    if err != nil {
+        return false, fmt.Errorf("failed to marshal status: %w", err)
+    }
+    return true, hb.Put(copyBytes(k), data)
 }
 
 func (s *BoltStore) GetStatusHistory(ctx context.Context, hostID, checkID string, since time.Time) ([]Status, error) {
@@ -295,7 +682,7 @@ func (s *BoltStore) GetStatusHistory(ctx context.Context, hostID, checkID string
 }
 
 func (s *BoltStore) UpdateCheck(ctx context.Context, check *Check) error {
-    check.UpdatedAt = time.Now()
+    check.UpdatedAt = time.Now().UTC()
 
     return s.db.Update(func(tx *bbolt.Tx) error {
         b := tx.Bucket(ChecksBucket)
@@ -319,12 +706,9 @@ func (s *BoltStore) DeleteCheck(ctx context.Context, id string) error {
 func (s *BoltStore) DeleteStatus(ctx context.Context, hostID, checkID string) error {
     return s.db.Update(func(tx *bbolt.Tx) error {
         // Delete from current status bucket
-        statusBucket := tx.Bucket(StatusBucket)
-        if statusBucket != nil {
-            key := fmt.Sprintf("%s:%s", hostID, checkID)
-            if err := statusBucket.Delete([]byte(key)); err != nil {
-                return fmt.Errorf("failed to delete current status: %w", err)
-            }
+        key := fmt.Sprintf("%s:%s", hostID, checkID)
+        if _, err := deleteCurrentStatus(tx, []byte(key)); err != nil {
+            return fmt.Errorf("failed to delete current status: %w", err)
         }
         
         // Also delete from history bucket if it exists
@@ -335,11 +719,12 @@ func (s *BoltStore) DeleteStatus(ctx context.Context, hostID, checkID string) er
             
             // Collect keys to delete
             var keysToDelete [][]byte
-            for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+            for k, v := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = cursor.Next() {
                 // Make a copy of the key since BoltDB reuses the slice
                 keyCopy := make([]byte, len(k))
                 copy(keyCopy, k)
                 keysToDelete = append(keysToDelete, keyCopy)
+                deleteStatusAnnotations(tx, v)
             }
             
             // Delete collected keys
@@ -382,6 +767,7 @@ func (s *BoltStore) GetDatabaseStats(ctx context.Context) (*DatabaseStats, error
 }
 
 func (s *BoltStore) Close() error {
+    os.Remove(pidFilePath(s.path))
     return s.db.Close()
 }
 
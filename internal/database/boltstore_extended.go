@@ -7,6 +7,7 @@ import (
     "encoding/json"
     "fmt"
     "os"
+    "strconv"
     "strings"
     "time"
 
@@ -20,8 +21,8 @@ type ExtendedBoltStore struct {
 }
 
 // NewExtendedBoltStore creates a new extended BoltDB store
-func NewExtendedBoltStore(path string) (ExtendedStore, error) {
-    baseStore, err := NewBoltStore(path)
+func NewExtendedBoltStore(path string, files FileOptions) (ExtendedStore, error) {
+    baseStore, err := NewBoltStore(path, files)
     if err != nil {
         return nil, err
     }
@@ -35,26 +36,23 @@ func NewExtendedBoltStore(path string) (ExtendedStore, error) {
 func (s *ExtendedBoltStore) DeleteStatus(ctx context.Context, hostID, checkID string) error {
     return s.db.Update(func(tx *bbolt.Tx) error {
         // Delete from current status bucket
-        statusBucket := tx.Bucket(StatusBucket)
-        if statusBucket != nil {
-            key := fmt.Sprintf("%s:%s", hostID, checkID)
-            if err := statusBucket.Delete([]byte(key)); err != nil {
-                return fmt.Errorf("failed to delete current status: %w", err)
-            }
+        key := fmt.Sprintf("%s:%s", hostID, checkID)
+        if _, err := deleteCurrentStatus(tx, []byte(key)); err != nil {
+            return fmt.Errorf("failed to delete current status: %w", err)
         }
         
         return nil
     })
 }
 
-// DeleteStatusByHostCheck removes all status entries for a host-check combination
+// DeleteStatusByHostCheck removes all status entries for a host-check
+// combination: current status, history and archived rollups
 func (s *ExtendedBoltStore) DeleteStatusByHostCheck(ctx context.Context, hostID, checkID string) error {
     return s.db.Update(func(tx *bbolt.Tx) error {
         // Delete from current status
-        statusBucket := tx.Bucket(StatusBucket)
-        if statusBucket != nil {
-            key := fmt.Sprintf("%s:%s", hostID, checkID)
-            statusBucket.Delete([]byte(key))
+        key := fmt.Sprintf("%s:%s", hostID, checkID)
+        if _, err := deleteCurrentStatus(tx, []byte(key)); err != nil {
+            return err
         }
         
         // Delete from history
@@ -66,8 +64,9 @@ func (s *ExtendedBoltStore) DeleteStatusByHostCheck(ctx context.Context, hostID,
             var keysToDelete [][]byte
             cursor := historyBucket.Cursor()
             
-            for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+            for k, v := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = cursor.Next() {
                 keysToDelete = append(keysToDelete, copyBytes(k))
+                deleteStatusAnnotations(tx, v)
             }
             
             // Delete collected keys
@@ -81,6 +80,20 @@ func (s *ExtendedBoltStore) DeleteStatusByHostCheck(ctx context.Context, hostID,
                 "history_deleted": len(keysToDelete),
             }).Debug("Deleted status history entries")
         }
+
+        // Delete archived rollups
+        if archiveBucket := tx.Bucket(StatusArchiveBucket); archiveBucket != nil {
+            prefix := fmt.Sprintf("%s:%s:", hostID, checkID)
+
+            var keysToDelete [][]byte
+            cursor := archiveBucket.Cursor()
+            for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+                keysToDelete = append(keysToDelete, copyBytes(k))
+            }
+            for _, key := range keysToDelete {
+                archiveBucket.Delete(key)
+            }
+        }
         
         return nil
     })
@@ -115,6 +128,7 @@ func (s *ExtendedBoltStore) DeleteStatusHistoryBefore(ctx context.Context, cutof
             
             if status.Timestamp.Before(cutoffTime) {
                 keysToDelete = append(keysToDelete, copyBytes(k))
+                deleteStatusAnnotations(tx, v)
             }
         }
         
@@ -142,22 +156,102 @@ func (s *ExtendedBoltStore) DeleteStatusHistoryBefore(ctx context.Context, cutof
     return deletedCount, nil
 }
 
+// DeleteStatusHistoryByRetention removes historical status entries using a
+// per-check retention, falling back to defaultRetention for checks without
+// an override. A retention of 0 keeps entries forever.
+func (s *ExtendedBoltStore) DeleteStatusHistoryByRetention(ctx context.Context, defaultRetention time.Duration, checkRetention map[string]time.Duration) (int, error) {
+    now := time.Now()
+    deletedByCheck := make(map[string]int)
+    deletedCount := 0
+
+    err := s.db.Update(func(tx *bbolt.Tx) error {
+        historyBucket := tx.Bucket(StatusHistBucket)
+        if historyBucket == nil {
+            return nil
+        }
+
+        // Group expired keys by check so each check's cutoff is applied once
+        keysByCheck := make(map[string][][]byte)
+        rollups := make(rollupCollector)
+        cursor := historyBucket.Cursor()
+
+        for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+            _, checkID, timestamp, ok := parseHistoryKey(k)
+            if !ok {
+                continue
+            }
+
+            retention := defaultRetention
+            if r, exists := checkRetention[checkID]; exists && r > 0 {
+                retention = r
+            }
+            if retention <= 0 {
+                continue
+            }
+
+            // A folded run expires with its last result, not its first
+            if historyLastSeen(v, timestamp).Before(now.Add(-retention)) {
+                keysByCheck[checkID] = append(keysByCheck[checkID], copyBytes(k))
+                rollups.add(v)
+                deleteStatusAnnotations(tx, v) // Annotations follow their status out
+            }
+        }
+
+        // Keep a downsampled copy before the raw entries go
+        if err := rollups.store(tx); err != nil {
+            return fmt.Errorf("failed to archive history: %w", err)
+        }
+
+        for checkID, keys := range keysByCheck {
+            for _, key := range keys {
+                if err := historyBucket.Delete(key); err != nil {
+                    logrus.WithError(err).Error("Failed to delete history entry")
+                    continue
+                }
+                deletedByCheck[checkID]++
+                deletedCount++
+            }
+        }
+
+        return nil
+    })
+
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete expired history: %w", err)
+    }
+
+    for checkID, count := range deletedByCheck {
+        logrus.WithFields(logrus.Fields{
+            "check_id":      checkID,
+            "deleted_count": count,
+        }).Debug("Deleted expired history entries for check")
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "deleted_count":     deletedCount,
+        "default_retention": defaultRetention,
+        "check_overrides":   len(checkRetention),
+    }).Info("Applied history retention policy")
+
+    return deletedCount, nil
+}
+
 // BulkDeleteStatuses efficiently deletes multiple host-check status combinations
 func (s *ExtendedBoltStore) BulkDeleteStatuses(ctx context.Context, hostCheckPairs []HostCheckPair) (int, error) {
     deletedCount := 0
     
     err := s.db.Update(func(tx *bbolt.Tx) error {
-        statusBucket := tx.Bucket(StatusBucket)
         historyBucket := tx.Bucket(StatusHistBucket)
         
         for _, pair := range hostCheckPairs {
             // Delete from current status
-            if statusBucket != nil {
-                key := fmt.Sprintf("%s:%s", pair.HostID, pair.CheckID)
-                if statusBucket.Get([]byte(key)) != nil {
-                    statusBucket.Delete([]byte(key))
-                    deletedCount++
-                }
+            key := fmt.Sprintf("%s:%s", pair.HostID, pair.CheckID)
+            deleted, err := deleteCurrentStatus(tx, []byte(key))
+            if err != nil {
+                return err
+            }
+            if deleted {
+                deletedCount++
             }
             
             // Delete from history
@@ -166,8 +260,9 @@ func (s *ExtendedBoltStore) BulkDeleteStatuses(ctx context.Context, hostCheckPai
                 cursor := historyBucket.Cursor()
                 
                 var historyKeysToDelete [][]byte
-                for k, _ := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = cursor.Next() {
+                for k, v := cursor.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = cursor.Next() {
                     historyKeysToDelete = append(historyKeysToDelete, copyBytes(k))
+                    deleteStatusAnnotations(tx, v)
                 }
                 
                 for _, key := range historyKeysToDelete {
@@ -215,8 +310,16 @@ func (s *ExtendedBoltStore) GetDatabaseStats(ctx context.Context) (*DatabaseStat
         if historyBucket := tx.Bucket(StatusHistBucket); historyBucket != nil {
             stats.TotalHistorySize = historyBucket.Stats().KeyN
             
-            // Find oldest and newest entries
+            // Break history down per check
+            stats.HistoryByCheck = make(map[string]int)
             cursor := historyBucket.Cursor()
+            for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+                if _, checkID, _, ok := parseHistoryKey(k); ok {
+                    stats.HistoryByCheck[checkID]++
+                }
+            }
+            
+            // Find oldest and newest entries
             
             // Get oldest (first entry)
             if k, v := cursor.First(); k != nil && v != nil {
@@ -262,12 +365,13 @@ func (s *ExtendedBoltStore) CompactDatabase(ctx context.Context) error {
     backupPath := s.path + ".compact.tmp"
     
     // Create new database
-    newDB, err := bbolt.Open(backupPath, 0600, &bbolt.Options{
+    newDB, err := bbolt.Open(backupPath, s.files.FileMode, &bbolt.Options{
         Timeout: 1 * time.Second,
     })
     if err != nil {
         return fmt.Errorf("failed to create compact database: %w", err)
     }
+    setCreatedMode(backupPath, s.files.FileMode)
     
     defer func() {
         newDB.Close()
@@ -276,8 +380,7 @@ func (s *ExtendedBoltStore) CompactDatabase(ctx context.Context) error {
     
     // Initialize buckets in new database
     err = newDB.Update(func(tx *bbolt.Tx) error {
-        buckets := [][]byte{HostsBucket, ChecksBucket, StatusBucket, StatusHistBucket, MetaBucket}
-        for _, bucket := range buckets {
+        for _, bucket := range allBuckets {
             if _, err := tx.CreateBucket(bucket); err != nil {
                 return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
             }
@@ -291,9 +394,7 @@ func (s *ExtendedBoltStore) CompactDatabase(ctx context.Context) error {
     // Copy data from old to new database
     err = s.db.View(func(oldTx *bbolt.Tx) error {
         return newDB.Update(func(newTx *bbolt.Tx) error {
-            buckets := [][]byte{HostsBucket, ChecksBucket, StatusBucket, StatusHistBucket, MetaBucket}
-            
-            for _, bucketName := range buckets {
+            for _, bucketName := range allBuckets {
                 oldBucket := oldTx.Bucket(bucketName)
                 newBucket := newTx.Bucket(bucketName)
                 
@@ -328,7 +429,7 @@ func (s *ExtendedBoltStore) CompactDatabase(ctx context.Context) error {
     }
     
     // Reopen the compacted database
-    s.db, err = bbolt.Open(oldPath, 0600, &bbolt.Options{
+    s.db, err = bbolt.Open(oldPath, s.files.FileMode, &bbolt.Options{
         Timeout: 1 * time.Second,
     })
     if err != nil {
@@ -339,6 +440,39 @@ func (s *ExtendedBoltStore) CompactDatabase(ctx context.Context) error {
     return nil
 }
 
+// historyLastSeen returns when a history entry's last result came in: its
+// LastSeen if it folds a run, otherwise the timestamp from its key
+func historyLastSeen(data []byte, timestamp int64) time.Time {
+    var entry struct {
+        LastSeen *time.Time `json:"last_seen"`
+    }
+    if err := json.Unmarshal(data, &entry); err == nil && entry.LastSeen != nil {
+        return *entry.LastSeen
+    }
+    return time.Unix(timestamp, 0)
+}
+
+// parseHistoryKey splits a history key of the form hostID:checkID:unixtime
+func parseHistoryKey(k []byte) (string, string, int64, bool) {
+    key := string(k)
+
+    last := strings.LastIndex(key, ":")
+    if last <= 0 {
+        return "", "", 0, false
+    }
+    middle := strings.LastIndex(key[:last], ":")
+    if middle <= 0 {
+        return "", "", 0, false
+    }
+
+    timestamp, err := strconv.ParseInt(key[last+1:], 10, 64)
+    if err != nil {
+        return "", "", 0, false
+    }
+
+    return key[:middle], key[middle+1 : last], timestamp, true
+}
+
 // copyBytes creates a copy of a byte slice
 func copyBytes(b []byte) []byte {
     if b == nil {
@@ -0,0 +1,218 @@
+// internal/database/boltstore_test.go - Latest status selection, history dedupe and retention
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "go.etcd.io/bbolt"
+)
+
+func TestLatestStatusSelection(t *testing.T) {
+    store := newTestStore(t, filepath.Join(t.TempDir(), "raven.db"))
+    defer store.Close()
+    ctx := context.Background()
+    base := time.Now().UTC().Add(-time.Hour)
+
+    // Several results per check, the newest written last, with the checks'
+    // latest results at different times
+    latest := map[string]time.Time{}
+    for c, checkID := range []string{"ping", "http", "disk"} {
+        for i := 0; i < 4; i++ {
+            timestamp := base.Add(time.Duration(i*10+c) * time.Minute)
+            status := &Status{
+                HostID:    "web",
+                CheckID:   checkID,
+                ExitCode:  i % 3,
+                Output:    fmt.Sprintf("%s result %d", checkID, i),
+                Timestamp: timestamp,
+            }
+            if err := store.UpdateStatus(ctx, status); err != nil {
+                t.Fatal(err)
+            }
+            latest[checkID] = timestamp
+        }
+    }
+    // A host whose ID starts with the first host's key prefix
+    if err := store.UpdateStatus(ctx, &Status{HostID: "web:02", CheckID: "ping", Timestamp: base.Add(2 * time.Hour)}); err != nil {
+        t.Fatal(err)
+    }
+
+    for checkID, want := range latest {
+        status, err := store.GetLatestStatus(ctx, "web", checkID)
+        if err != nil {
+            t.Fatalf("GetLatestStatus(%s): %v", checkID, err)
+        }
+        if !status.Timestamp.Equal(want) || status.Output != checkID+" result 3" {
+            t.Errorf("GetLatestStatus(%s) = %s at %s, want result 3 at %s", checkID, status.Output, status.Timestamp, want)
+        }
+    }
+    if _, err := store.GetLatestStatus(ctx, "web", "missing"); err == nil {
+        t.Error("GetLatestStatus for a check without results returned no error")
+    }
+
+    statuses, err := store.GetLatestStatuses(ctx, "web")
+    if err != nil {
+        t.Fatalf("GetLatestStatuses: %v", err)
+    }
+    var order []string
+    for _, status := range statuses {
+        if status.HostID != "web" {
+            t.Errorf("GetLatestStatuses(web) returned a status for %s", status.HostID)
+        }
+        if !status.Timestamp.Equal(latest[status.CheckID]) {
+            t.Errorf("GetLatestStatuses(web) %s at %s, want %s", status.CheckID, status.Timestamp, latest[status.CheckID])
+        }
+        order = append(order, status.CheckID)
+    }
+    if fmt.Sprint(order) != "[disk http ping]" {
+        t.Errorf("GetLatestStatuses(web) order = %v, want newest first [disk http ping]", order)
+    }
+
+    // The since filter only keeps results at or after it
+    since := base.Add(31 * time.Minute)
+    statuses, err = store.GetStatus(ctx, StatusFilters{HostID: "web", Since: &since})
+    if err != nil {
+        t.Fatalf("GetStatus: %v", err)
+    }
+    if len(statuses) != 2 {
+        t.Errorf("GetStatus(web, since) = %d statuses, want http and disk", len(statuses))
+    }
+}
+
+// steadyState writes days of one-minute results for a host/check, OK with
+// varying numbers apart from one critical result a day, ending now
+func steadyState(t *testing.T, store Store, days int) {
+    t.Helper()
+    start := time.Now().UTC().Truncate(time.Minute).Add(-time.Duration(days) * 24 * time.Hour)
+
+    batch := make([]*Status, 0, 100)
+    for minute := 0; minute < days*24*60; minute++ {
+        status := &Status{
+            HostID:    "web-01",
+            CheckID:   "ping",
+            ExitCode:  StateOK,
+            Output:    fmt.Sprintf("PING OK - Packet loss = 0%%, RTA = %d.%02d ms", minute%3, minute%100),
+            PerfData:  fmt.Sprintf("rta=%d.%02dms;100;500;0 pl=0%%;20;60;0", minute%3, minute%100),
+            Duration:  float64(20 + minute%7),
+            Timestamp: start.Add(time.Duration(minute) * time.Minute),
+        }
+        if minute%(24*60) == 720 {
+            status.ExitCode = StateCritical
+            status.Output = "PING CRITICAL - Packet loss = 100%"
+        }
+        batch = append(batch, status)
+        if len(batch) == cap(batch) {
+            if err := store.UpdateStatusBatch(context.Background(), batch); err != nil {
+                t.Fatal(err)
+            }
+            batch = batch[:0]
+        }
+    }
+    if err := store.UpdateStatusBatch(context.Background(), batch); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestHistoryDedupeStorageReduction(t *testing.T) {
+    // What is left of each store after a purge down to two days and a
+    // compaction
+    logrus.SetLevel(logrus.ErrorLevel)
+    measure := func(dedupe bool) (entries int, size int64) {
+        path := filepath.Join(t.TempDir(), "raven.db")
+        store, err := NewExtendedBoltStore(path, FileOptions{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer store.Close()
+        store.SetHistoryDedupe(dedupe)
+
+        steadyState(t, store, 3)
+        if _, err := store.DeleteStatusHistoryByRetention(context.Background(), 48*time.Hour, nil); err != nil {
+            t.Fatal(err)
+        }
+        if err := store.CompactDatabase(context.Background()); err != nil {
+            t.Fatal(err)
+        }
+
+        stats, err := store.GetDatabaseStats(context.Background())
+        if err != nil {
+            t.Fatal(err)
+        }
+        info, err := os.Stat(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return stats.TotalHistorySize, info.Size()
+    }
+
+    plainEntries, plainSize := measure(false)
+    dedupeEntries, dedupeSize := measure(true)
+    t.Logf("history after purge: %d entries, %d bytes without dedupe; %d entries, %d bytes with it",
+        plainEntries, plainSize, dedupeEntries, dedupeSize)
+
+    if plainEntries < 2*24*60-1 {
+        t.Errorf("without dedupe %d entries kept, want every result of the last two days", plainEntries)
+    }
+    // An OK run and a critical result a day
+    if dedupeEntries > 6 {
+        t.Errorf("with dedupe %d entries kept, want the runs between critical results", dedupeEntries)
+    }
+    if dedupeSize*4 > plainSize {
+        t.Errorf("with dedupe the database is %d bytes, want under a quarter of %d", dedupeSize, plainSize)
+    }
+}
+
+func TestRetentionKeepsRecentlySeenRuns(t *testing.T) {
+    store := newTestStore(t, filepath.Join(t.TempDir(), "raven.db")).(*BoltStore)
+    defer store.Close()
+    extended := &ExtendedBoltStore{BoltStore: store}
+
+    now := time.Now().UTC()
+    recent := now.Add(-10 * time.Minute)
+    stale := now.Add(-90 * time.Minute)
+    entries := map[string]Status{
+        "old":         {CheckID: "old", Timestamp: now.Add(-2 * time.Hour)},
+        "old run":     {CheckID: "old-run", Timestamp: now.Add(-3 * time.Hour), Repeats: 10, LastSeen: &stale},
+        "ongoing run": {CheckID: "ongoing-run", Timestamp: now.Add(-3 * time.Hour), Repeats: 34, LastSeen: &recent},
+        "new":         {CheckID: "new", Timestamp: recent},
+    }
+    err := store.db.Update(func(tx *bbolt.Tx) error {
+        for _, status := range entries {
+            status.HostID = "web-01"
+            data, err := json.Marshal(&status)
+            if err != nil {
+                return err
+            }
+            key := fmt.Sprintf("%s:%s:%d", status.HostID, status.CheckID, status.Timestamp.Unix())
+            if err := tx.Bucket(StatusHistBucket).Put([]byte(key), data); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := extended.DeleteStatusHistoryByRetention(context.Background(), time.Hour, nil); err != nil {
+        t.Fatalf("DeleteStatusHistoryByRetention: %v", err)
+    }
+
+    for name, status := range entries {
+        history, err := store.GetStatusHistory(context.Background(), "web-01", status.CheckID, time.Time{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        kept := len(history) > 0
+        if want := name == "ongoing run" || name == "new"; kept != want {
+            t.Errorf("%s: kept = %v, want %v", name, kept, want)
+        }
+    }
+}
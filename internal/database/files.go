@@ -0,0 +1,83 @@
+// internal/database/files.go - Where the database lives and the permissions of the files written beside it
+package database
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "github.com/sirupsen/logrus"
+)
+
+// ErrDataDirNotWritable means the data directory couldn't be created or
+// written to, usually because the service user doesn't own it
+var ErrDataDirNotWritable = errors.New("data directory not writable")
+
+// Permissions used when the config doesn't set them
+const (
+    DefaultDirMode  os.FileMode = 0755
+    DefaultFileMode os.FileMode = 0600
+)
+
+// FileOptions say where a store keeps its database and the permissions of
+// what it creates: the data directory, the database, its pid file, and the
+// compaction and repair copies. Files and directories that already exist
+// keep their permissions.
+type FileOptions struct {
+    DataDir  string      // Holds the database; "" = the database's directory
+    DirMode  os.FileMode // 0 = DefaultDirMode
+    FileMode os.FileMode // 0 = DefaultFileMode
+}
+
+// withDefaults fills in the data directory and modes left unset
+func (o FileOptions) withDefaults(path string) FileOptions {
+    if o.DataDir == "" {
+        o.DataDir = filepath.Dir(path)
+    }
+    if o.DirMode == 0 {
+        o.DirMode = DefaultDirMode
+    }
+    if o.FileMode == 0 {
+        o.FileMode = DefaultFileMode
+    }
+    return o
+}
+
+// prepareDataDir creates the data directory and the database's directory if
+// they are missing, and checks that a file can be created in each
+func prepareDataDir(path string, files FileOptions) error {
+    for _, dir := range []string{files.DataDir, filepath.Dir(path)} {
+        if err := makeDir(dir, files.DirMode); err != nil {
+            return err
+        }
+        probe, err := os.CreateTemp(dir, ".raven-write-check-*")
+        if err != nil {
+            return fmt.Errorf("%w: %s: %v", ErrDataDirNotWritable, dir, err)
+        }
+        probe.Close()
+        os.Remove(probe.Name())
+    }
+    return nil
+}
+
+// makeDir creates a missing directory, and any missing parents, with mode.
+// The directory itself gets mode exactly rather than as narrowed by the
+// umask.
+func makeDir(dir string, mode os.FileMode) error {
+    if _, err := os.Stat(dir); err == nil {
+        return nil
+    }
+    if err := os.MkdirAll(dir, mode); err != nil {
+        return fmt.Errorf("%w: %s: %v", ErrDataDirNotWritable, dir, err)
+    }
+    return os.Chmod(dir, mode)
+}
+
+// setCreatedMode gives a file the store just created its configured mode,
+// which the umask may have narrowed
+func setCreatedMode(path string, mode os.FileMode) {
+    if err := os.Chmod(path, mode); err != nil {
+        logrus.WithError(err).WithField("path", path).Warn("Failed to set file permissions")
+    }
+}
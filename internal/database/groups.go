@@ -0,0 +1,111 @@
+// internal/database/groups.go - Host group metadata
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+// GroupsBucket holds group metadata keyed by group ID
+var GroupsBucket = []byte("groups")
+
+// Group describes a host group. Hosts reference it by ID in Host.Group.
+type Group struct {
+    ID          string            `json:"id"`
+    DisplayName string            `json:"display_name"`
+    Description string            `json:"description"`
+    DefaultTags map[string]string `json:"default_tags"`
+    SortOrder   int               `json:"sort_order"`
+    CreatedAt   time.Time         `json:"created_at"`
+    UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// UnmarshalJSON converts timestamps to UTC (see toUTC)
+func (g *Group) UnmarshalJSON(data []byte) error {
+    type groupAlias Group
+    if err := json.Unmarshal(data, (*groupAlias)(g)); err != nil {
+        return err
+    }
+    toUTC(&g.CreatedAt, &g.UpdatedAt)
+    return nil
+}
+
+// GetGroups returns all groups by sort order, then ID
+func (s *BoltStore) GetGroups(ctx context.Context) ([]Group, error) {
+    groups := []Group{}
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        return tx.Bucket(GroupsBucket).ForEach(func(k, v []byte) error {
+            var group Group
+            if err := json.Unmarshal(v, &group); err != nil {
+                return fmt.Errorf("failed to unmarshal group %s: %w", k, err)
+            }
+            groups = append(groups, group)
+            return nil
+        })
+    })
+
+    sort.Slice(groups, func(i, j int) bool {
+        if groups[i].SortOrder != groups[j].SortOrder {
+            return groups[i].SortOrder < groups[j].SortOrder
+        }
+        return groups[i].ID < groups[j].ID
+    })
+
+    return groups, err
+}
+
+func (s *BoltStore) GetGroup(ctx context.Context, id string) (*Group, error) {
+    var group Group
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        data := tx.Bucket(GroupsBucket).Get([]byte(id))
+        if data == nil {
+            return fmt.Errorf("group not found")
+        }
+        return json.Unmarshal(data, &group)
+    })
+
+    if err != nil {
+        return nil, err
+    }
+    return &group, nil
+}
+
+// SaveGroup creates or replaces a group, keeping the original CreatedAt
+func (s *BoltStore) SaveGroup(ctx context.Context, group *Group) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(GroupsBucket)
+
+        now := time.Now().UTC()
+        group.CreatedAt = now
+        if data := b.Get([]byte(group.ID)); data != nil {
+            var existing Group
+            if err := json.Unmarshal(data, &existing); err == nil {
+                group.CreatedAt = existing.CreatedAt
+            }
+        }
+        group.UpdatedAt = now
+
+        data, err := json.Marshal(group)
+        if err != nil {
+            return fmt.Errorf("failed to marshal group: %w", err)
+        }
+        return b.Put([]byte(group.ID), data)
+    })
+}
+
+func (s *BoltStore) DeleteGroup(ctx context.Context, id string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(GroupsBucket)
+        if b.Get([]byte(id)) == nil {
+            return fmt.Errorf("group not found")
+        }
+        return b.Delete([]byte(id))
+    })
+}
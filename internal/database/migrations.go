@@ -0,0 +1,117 @@
+// internal/database/migrations.go - Versioning the database layout and upgrading older databases at startup
+package database
+
+import (
+    "errors"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "go.etcd.io/bbolt"
+)
+
+// ErrSchemaTooNew means the database was written by a newer Raven whose
+// layout this build doesn't understand
+var ErrSchemaTooNew = errors.New("database schema too new")
+
+// schemaVersionKey holds the database layout version in MetaBucket, as a
+// decimal string. Databases written before versioning have none and are
+// version 0.
+var schemaVersionKey = []byte("schema_version")
+
+// migration upgrades the database from the previous schema version. It runs
+// in one transaction with the version bump, so a failed migration leaves the
+// database as it was. Migrations must be safe to run again: a repair that
+// can't read the meta bucket starts over from version 0.
+type migration struct {
+    name string
+    run  func(tx *bbolt.Tx) error
+}
+
+// migrations are applied in order; migrations[i] upgrades version i to i+1.
+// Only ever append to the list.
+var migrations = []migration{
+    {"index current statuses by time", migrateStatusTimeIndex},
+}
+
+// SchemaVersion is the database layout this build reads and writes
+var SchemaVersion = len(migrations)
+
+// readSchemaVersion returns the stored schema version, 0 if there is none
+func readSchemaVersion(tx *bbolt.Tx) (int, error) {
+    b := tx.Bucket(MetaBucket)
+    if b == nil {
+        return 0, nil
+    }
+    v := b.Get(schemaVersionKey)
+    if v == nil {
+        return 0, nil
+    }
+    version, err := strconv.Atoi(string(v))
+    if err != nil || version < 0 {
+        return 0, fmt.Errorf("%w: invalid %s %q (run raven -repair)", ErrDatabaseCorrupt, schemaVersionKey, v)
+    }
+    return version, nil
+}
+
+func writeSchemaVersion(tx *bbolt.Tx, version int) error {
+    return tx.Bucket(MetaBucket).Put(schemaVersionKey, []byte(strconv.Itoa(version)))
+}
+
+// checkSchemaVersion refuses a database newer than this build, before
+// anything is written to it
+func (s *BoltStore) checkSchemaVersion() error {
+    return s.db.View(func(tx *bbolt.Tx) error {
+        version, err := readSchemaVersion(tx)
+        if err != nil {
+            return err
+        }
+        if version > SchemaVersion {
+            return fmt.Errorf("%w: %s is at schema version %d but this Raven supports up to %d; upgrade Raven or restore a backup taken before the upgrade", ErrSchemaTooNew, s.path, version, SchemaVersion)
+        }
+        return nil
+    })
+}
+
+// migrate applies the migrations the database hasn't had yet, one
+// transaction each
+func (s *BoltStore) migrate() error {
+    var version int
+    if err := s.db.View(func(tx *bbolt.Tx) (err error) {
+        version, err = readSchemaVersion(tx)
+        return err
+    }); err != nil {
+        return err
+    }
+    if version >= SchemaVersion {
+        return nil
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "from": version,
+        "to":   SchemaVersion,
+    }).Info("Migrating database schema")
+
+    for ; version < SchemaVersion; version++ {
+        m := migrations[version]
+        started := time.Now()
+
+        err := s.db.Update(func(tx *bbolt.Tx) error {
+            if err := m.run(tx); err != nil {
+                return err
+            }
+            return writeSchemaVersion(tx, version+1)
+        })
+        if err != nil {
+            return fmt.Errorf("database migration %d (%s) failed: %w", version+1, m.name, err)
+        }
+
+        logrus.WithFields(logrus.Fields{
+            "version":   version + 1,
+            "migration": m.name,
+            "took":      time.Since(started).Round(time.Millisecond),
+        }).Info("Applied database migration")
+    }
+    return nil
+}
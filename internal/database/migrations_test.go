@@ -0,0 +1,123 @@
+// internal/database/migrations_test.go - Upgrading old databases and refusing newer ones
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "path/filepath"
+    "strconv"
+    "testing"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+// writeFixture creates a bare database at path and lets fill write to it,
+// the way an older or newer Raven would have left it
+func writeFixture(t *testing.T, path string, fill func(tx *bbolt.Tx) error) {
+    t.Helper()
+    db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := db.Update(fill); err != nil {
+        t.Fatal(err)
+    }
+    if err := db.Close(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestMigrationBuildsStatusTimeIndex(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "raven.db")
+    now := time.Now().UTC()
+
+    // A version 0 database: a meta bucket with no schema version, current
+    // statuses and no time index
+    writeFixture(t, path, func(tx *bbolt.Tx) error {
+        if _, err := tx.CreateBucket(MetaBucket); err != nil {
+            return err
+        }
+        b, err := tx.CreateBucket(StatusBucket)
+        if err != nil {
+            return err
+        }
+        for i, hostID := range []string{"web-01", "db-01"} {
+            data, err := json.Marshal(&Status{
+                HostID:    hostID,
+                CheckID:   "ping",
+                ExitCode:  i,
+                Timestamp: now.Add(-time.Duration(i) * time.Minute),
+            })
+            if err != nil {
+                return err
+            }
+            if err := b.Put([]byte(hostID+":ping"), data); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+
+    store, err := NewBoltStore(path, FileOptions{})
+    if err != nil {
+        t.Fatalf("NewBoltStore: %v", err)
+    }
+    defer store.Close()
+
+    since := now.Add(-time.Hour)
+    statuses, err := store.GetStatus(context.Background(), StatusFilters{Since: &since})
+    if err != nil {
+        t.Fatalf("GetStatus: %v", err)
+    }
+    if len(statuses) != 2 {
+        t.Fatalf("GetStatus(since) = %d statuses, want both migrated statuses", len(statuses))
+    }
+
+    recent := now.Add(-30 * time.Second)
+    statuses, err = store.GetStatus(context.Background(), StatusFilters{Since: &recent})
+    if err != nil {
+        t.Fatalf("GetStatus: %v", err)
+    }
+    if len(statuses) != 1 || statuses[0].HostID != "web-01" {
+        t.Errorf("GetStatus(recent) = %+v, want only web-01", statuses)
+    }
+
+    var version int
+    store.(*BoltStore).db.View(func(tx *bbolt.Tx) (err error) {
+        version, err = readSchemaVersion(tx)
+        return err
+    })
+    if version != SchemaVersion {
+        t.Errorf("schema version after migration = %d, want %d", version, SchemaVersion)
+    }
+}
+
+func TestNewerSchemaIsRefused(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "raven.db")
+    writeFixture(t, path, func(tx *bbolt.Tx) error {
+        b, err := tx.CreateBucket(MetaBucket)
+        if err != nil {
+            return err
+        }
+        return b.Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion+1)))
+    })
+
+    store, err := NewBoltStore(path, FileOptions{})
+    if err == nil {
+        store.Close()
+        t.Fatal("NewBoltStore opened a database from a newer schema")
+    }
+    if !errors.Is(err, ErrSchemaTooNew) {
+        t.Errorf("NewBoltStore error = %v, want ErrSchemaTooNew", err)
+    }
+
+    // Nothing was written to it
+    writeFixture(t, path, func(tx *bbolt.Tx) error {
+        if tx.Bucket(HostsBucket) != nil {
+            t.Error("refused database had buckets created in it")
+        }
+        return nil
+    })
+}
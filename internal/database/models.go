@@ -2,6 +2,7 @@
 package database
 
 import (
+    "encoding/json"
     "time"
 )
 
@@ -18,30 +19,108 @@ type Host struct {
     UpdatedAt   time.Time         `json:"updated_at"`
 }
 
+// UnmarshalJSON converts timestamps to UTC (see toUTC)
+func (h *Host) UnmarshalJSON(data []byte) error {
+    type hostAlias Host
+    if err := json.Unmarshal(data, (*hostAlias)(h)); err != nil {
+        return err
+    }
+    toUTC(&h.CreatedAt, &h.UpdatedAt)
+    return nil
+}
+
 type Check struct {
-    ID        string                   `json:"id"`
-    Name      string                   `json:"name"`
-    Type      string                   `json:"type"`
-    Hosts     []string                 `json:"hosts"`
-    Interval  map[string]time.Duration `json:"interval"`
-    Threshold int                      `json:"threshold"`
-    Timeout   time.Duration            `json:"timeout"`
-    Enabled   bool                     `json:"enabled"`
-    Options   map[string]interface{}   `json:"options"`
-    CreatedAt time.Time                `json:"created_at"`
-    UpdatedAt time.Time                `json:"updated_at"`
+    ID              string                   `json:"id"`
+    Name            string                   `json:"name"`
+    Type            string                   `json:"type"`
+    Hosts           []string                 `json:"hosts"`
+    Interval        map[string]time.Duration `json:"interval"`
+    Threshold       int                      `json:"threshold"`
+    HostThresholds  map[string]int           `json:"host_thresholds,omitempty"`   // Per-host overrides of Threshold, keyed by host ID
+    SoftFailEnabled *bool                    `json:"soft_fail_enabled,omitempty"` // nil = use monitoring.soft_fail_enabled
+    SoftFailMode    string                   `json:"soft_fail_mode,omitempty"`    // "failures" (default) or "symmetric"
+    Timeout         time.Duration            `json:"timeout"`
+    Enabled         bool                     `json:"enabled"`
+    Options         map[string]interface{}   `json:"options"`
+    Retention       time.Duration            `json:"retention"`                   // 0 = use global history retention
+    ExitCodeMap     map[int]int              `json:"exit_code_map,omitempty"`     // Remap plugin exit codes before state handling
+    Priority        int                      `json:"priority"`                    // Higher runs first when workers are busy (0 = normal)
+    ObserveOnly     bool                     `json:"observe_only"`                // Record results but leave out of alerts and rollups
+    Periods         []Period                 `json:"periods,omitempty"`           // Only run inside these weekly windows (none = always)
+    CreatedAt       time.Time                `json:"created_at"`
+    UpdatedAt       time.Time                `json:"updated_at"`
+}
+
+// UnmarshalJSON converts timestamps to UTC (see toUTC)
+func (c *Check) UnmarshalJSON(data []byte) error {
+    type checkAlias Check
+    if err := json.Unmarshal(data, (*checkAlias)(c)); err != nil {
+        return err
+    }
+    toUTC(&c.CreatedAt, &c.UpdatedAt)
+    return nil
+}
+
+// EffectiveRetention returns the history retention for this check, falling
+// back to the global database retention (0 = keep forever)
+func (c *Check) EffectiveRetention(globalRetention time.Duration) time.Duration {
+    if c.Retention > 0 {
+        return c.Retention
+    }
+    return globalRetention
 }
 
 type Status struct {
-    ID         string    `json:"id"`
-    HostID     string    `json:"host_id"`
-    CheckID    string    `json:"check_id"`
-    ExitCode   int       `json:"exit_code"`
-    Output     string    `json:"output"`
-    PerfData   string    `json:"perf_data"`
-    LongOutput string    `json:"long_output"`
-    Duration   float64   `json:"duration_ms"`
-    Timestamp  time.Time `json:"timestamp"`
+    ID              string                 `json:"id"`
+    HostID          string                 `json:"host_id"`
+    CheckID         string                 `json:"check_id"`
+    ExitCode        int                    `json:"exit_code"`                  // Reported state (after soft fail)
+    RawExitCode     int                    `json:"raw_exit_code"`              // What the plugin actually returned
+    Output          string                 `json:"output"`
+    PerfData        string                 `json:"perf_data"`
+    LongOutput      string                 `json:"long_output"`
+    Duration        float64                `json:"duration_ms"`
+    Timestamp       time.Time              `json:"timestamp"`
+    LastStateChange time.Time              `json:"last_state_change"`          // When the reported state last changed
+    OutputHash      string                 `json:"output_hash,omitempty"`      // OutputFingerprint of the plugin's output
+    PrevOutputHash  string                 `json:"prev_output_hash,omitempty"` // Set when the output changed since the previous result
+    Extracted       map[string]interface{} `json:"extracted,omitempty"`        // Values from the check's extract rules, nil where a rule didn't match
+    Repeats         int                    `json:"repeats,omitempty"`          // History only: identical results folded into this entry by the dedup window
+    LastSeen        *time.Time             `json:"last_seen,omitempty"`        // History only: when the last folded result came in
+}
+
+// UnmarshalJSON defaults RawExitCode to ExitCode for records stored before
+// the raw plugin result was persisted, and converts timestamps to UTC
+func (s *Status) UnmarshalJSON(data []byte) error {
+    type statusAlias Status
+    aux := struct {
+        *statusAlias
+        RawExitCode *int `json:"raw_exit_code"`
+    }{statusAlias: (*statusAlias)(s)}
+
+    if err := json.Unmarshal(data, &aux); err != nil {
+        return err
+    }
+
+    if aux.RawExitCode != nil {
+        s.RawExitCode = *aux.RawExitCode
+    } else {
+        s.RawExitCode = s.ExitCode
+    }
+    toUTC(&s.Timestamp, &s.LastStateChange)
+    if s.LastSeen != nil {
+        toUTC(s.LastSeen)
+    }
+    return nil
+}
+
+// toUTC converts decoded timestamps to UTC, which is how the API reports
+// them. Records stored before timestamps were written in UTC carry the
+// server's local offset.
+func toUTC(times ...*time.Time) {
+    for _, t := range times {
+        *t = t.UTC()
+    }
 }
 
 type HostFilters struct {
@@ -0,0 +1,81 @@
+// internal/database/output.go - Normalizing and fingerprinting check output
+package database
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "regexp"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Terminal escape sequences: CSI (colours, cursor movement) and OSC (titles,
+// hyperlinks), plus any other two-character escape
+var terminalEscapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-_]`)
+
+var numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// OutputLines joins a result's output and long output and normalizes line
+// endings and trailing whitespace, so cosmetic differences don't count as
+// changes
+func OutputLines(output, longOutput string) []string {
+    text := output
+    if longOutput != "" {
+        text += "\n" + longOutput
+    }
+
+    lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+    for i, line := range lines {
+        lines[i] = strings.TrimRight(line, " \t\r")
+    }
+    for len(lines) > 0 && lines[len(lines)-1] == "" {
+        lines = lines[:len(lines)-1]
+    }
+    return lines
+}
+
+// OutputFingerprint is a short hash of the normalized output, stored with
+// each result so changes in what a plugin reports are easy to spot
+func OutputFingerprint(output, longOutput string) string {
+    sum := sha256.Sum256([]byte(strings.Join(OutputLines(output, longOutput), "\n")))
+    return hex.EncodeToString(sum[:8])
+}
+
+// SteadyOutput is the normalized output with every number replaced by #, so
+// results that only differ in measured values such as a round-trip time
+// compare equal
+func SteadyOutput(output, longOutput string) string {
+    return numberPattern.ReplaceAllString(strings.Join(OutputLines(output, longOutput), "\n"), "#")
+}
+
+// TruncateOutput cuts text to at most limit bytes, on a character boundary,
+// and appends a marker saying how much was dropped. It reports whether
+// anything was cut.
+func TruncateOutput(text string, limit int) (string, bool) {
+    if len(text) <= limit {
+        return text, false
+    }
+
+    cut := limit
+    for cut > 0 && !utf8.RuneStart(text[cut]) {
+        cut--
+    }
+    return text[:cut] + fmt.Sprintf("... [truncated %d bytes]", len(text)-cut), true
+}
+
+// StripControl removes terminal escape sequences and control characters
+// from plugin output, keeping newlines and tabs. Windows line endings become
+// plain newlines and invalid UTF-8 is replaced.
+func StripControl(text string) string {
+    text = strings.ToValidUTF8(text, "\uFFFD")
+    text = strings.ReplaceAll(text, "\r\n", "\n")
+    text = terminalEscapePattern.ReplaceAllString(text, "")
+    return strings.Map(func(r rune) rune {
+        if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+            return r
+        }
+        return -1
+    }, text)
+}
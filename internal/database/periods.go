@@ -0,0 +1,168 @@
+// internal/database/periods.go - Weekly time windows a check runs in
+package database
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Period is a weekly window in which a check runs. Windows can't cross
+// midnight; use one that ends at 24:00 and another that starts at 00:00.
+type Period struct {
+    Days     []string `json:"days,omitempty" yaml:"days"`         // mon..sun, empty = every day
+    Start    string   `json:"start" yaml:"start"`                 // HH:MM
+    End      string   `json:"end" yaml:"end"`                     // HH:MM, up to 24:00
+    Timezone string   `json:"timezone,omitempty" yaml:"timezone"` // IANA name, empty = server time zone
+}
+
+var periodDays = map[string]time.Weekday{
+    "sun": time.Sunday,
+    "mon": time.Monday,
+    "tue": time.Tuesday,
+    "wed": time.Wednesday,
+    "thu": time.Thursday,
+    "fri": time.Friday,
+    "sat": time.Saturday,
+}
+
+// period is a Period parsed for evaluation
+type period struct {
+    days       map[time.Weekday]bool // nil = every day
+    start, end int                   // Minutes since midnight
+    location   *time.Location
+}
+
+func (p *Period) parse() (*period, error) {
+    parsed := &period{location: time.Local}
+
+    if len(p.Days) > 0 {
+        parsed.days = make(map[time.Weekday]bool, len(p.Days))
+        for _, name := range p.Days {
+            day, ok := periodDays[strings.ToLower(name)]
+            if !ok {
+                return nil, fmt.Errorf("invalid day %q (must be mon, tue, wed, thu, fri, sat or sun)", name)
+            }
+            parsed.days[day] = true
+        }
+    }
+
+    var err error
+    if parsed.start, err = parseClock(p.Start); err != nil {
+        return nil, fmt.Errorf("invalid start: %w", err)
+    }
+    if parsed.end, err = parseClock(p.End); err != nil {
+        return nil, fmt.Errorf("invalid end: %w", err)
+    }
+    if parsed.end <= parsed.start {
+        return nil, fmt.Errorf("end %s is not after start %s (split windows that cross midnight in two)", p.End, p.Start)
+    }
+
+    if p.Timezone != "" {
+        if parsed.location, err = time.LoadLocation(p.Timezone); err != nil {
+            return nil, fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+        }
+    }
+
+    return parsed, nil
+}
+
+// parseClock reads HH:MM as minutes since midnight, allowing 24:00
+func parseClock(value string) (int, error) {
+    var hour, minute int
+    if n, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || n != 2 || len(value) != 5 {
+        return 0, fmt.Errorf("%q is not HH:MM", value)
+    }
+    if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+        return 0, fmt.Errorf("%q is not a time of day", value)
+    }
+    return hour*60 + minute, nil
+}
+
+func (p *period) onDay(day time.Weekday) bool {
+    return p.days == nil || p.days[day]
+}
+
+func (p *period) contains(t time.Time) bool {
+    local := t.In(p.location)
+    minute := local.Hour()*60 + local.Minute()
+    return p.onDay(local.Weekday()) && minute >= p.start && minute < p.end
+}
+
+// nextStart returns the first time after t that the window opens
+func (p *period) nextStart(t time.Time) time.Time {
+    local := t.In(p.location)
+    for offset := 0; offset <= 7; offset++ {
+        day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, p.location)
+        if !p.onDay(day.Weekday()) {
+            continue
+        }
+        start := day.Add(time.Duration(p.start) * time.Minute)
+        if start.After(t) {
+            return start
+        }
+    }
+    return time.Time{}
+}
+
+// ValidatePeriods checks each window and rejects windows in the same time
+// zone that overlap
+func ValidatePeriods(periods []Period) error {
+    parsed := make([]*period, len(periods))
+    for i := range periods {
+        p, err := periods[i].parse()
+        if err != nil {
+            return fmt.Errorf("periods[%d]: %w", i, err)
+        }
+        parsed[i] = p
+    }
+
+    for i := range parsed {
+        for j := i + 1; j < len(parsed); j++ {
+            if periods[i].Timezone != periods[j].Timezone {
+                continue
+            }
+            if parsed[i].start >= parsed[j].end || parsed[j].start >= parsed[i].end {
+                continue
+            }
+            for day := time.Sunday; day <= time.Saturday; day++ {
+                if parsed[i].onDay(day) && parsed[j].onDay(day) {
+                    return fmt.Errorf("periods[%d] and periods[%d] overlap on %s", i, j, day)
+                }
+            }
+        }
+    }
+
+    return nil
+}
+
+// InPeriod reports whether the check should run at t. Checks without
+// periods always run; periods that don't parse are ignored, as validation
+// keeps them out of the store.
+func (c *Check) InPeriod(t time.Time) bool {
+    if len(c.Periods) == 0 {
+        return true
+    }
+    for i := range c.Periods {
+        if p, err := c.Periods[i].parse(); err == nil && p.contains(t) {
+            return true
+        }
+    }
+    return false
+}
+
+// NextPeriodStart returns when the check's next window opens after t, or the
+// zero time if it has no periods
+func (c *Check) NextPeriodStart(t time.Time) time.Time {
+    var next time.Time
+    for i := range c.Periods {
+        p, err := c.Periods[i].parse()
+        if err != nil {
+            continue
+        }
+        if start := p.nextStart(t); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+            next = start
+        }
+    }
+    return next
+}
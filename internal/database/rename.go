@@ -0,0 +1,188 @@
+// internal/database/rename.go - Moving a host or check to a new ID with its results
+package database
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+// RenameHost moves a host to a new ID in one transaction. Its record,
+// current statuses, history and archive rollups are re-keyed, and checks
+// listing the old ID are pointed at the new one.
+func (s *BoltStore) RenameHost(ctx context.Context, oldID, newID string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(HostsBucket)
+        data := b.Get([]byte(oldID))
+        if data == nil {
+            return fmt.Errorf("host not found")
+        }
+        if b.Get([]byte(newID)) != nil {
+            return fmt.Errorf("host %s already exists", newID)
+        }
+
+        var host Host
+        if err := json.Unmarshal(data, &host); err != nil {
+            return fmt.Errorf("failed to unmarshal host: %w", err)
+        }
+        host.ID = newID
+        host.UpdatedAt = time.Now().UTC()
+        if err := putRenamed(b, oldID, newID, &host); err != nil {
+            return err
+        }
+
+        cb := tx.Bucket(ChecksBucket)
+        var checks []Check
+        if err := cb.ForEach(func(k, v []byte) error {
+            var check Check
+            if err := json.Unmarshal(v, &check); err != nil {
+                return nil
+            }
+            for i, hostID := range check.Hosts {
+                if hostID == oldID {
+                    check.Hosts[i] = newID
+                    if threshold, exists := check.HostThresholds[oldID]; exists {
+                        delete(check.HostThresholds, oldID)
+                        check.HostThresholds[newID] = threshold
+                    }
+                    checks = append(checks, check)
+                    break
+                }
+            }
+            return nil
+        }); err != nil {
+            return err
+        }
+        for i := range checks {
+            if err := putRenamed(cb, checks[i].ID, checks[i].ID, &checks[i]); err != nil {
+                return err
+            }
+        }
+
+        return rekeyResults(tx, func(hostID, checkID string) (string, string, bool) {
+            return newID, checkID, hostID == oldID
+        })
+    })
+}
+
+// RenameCheck moves a check to a new ID in one transaction, re-keying its
+// record, current statuses, history and archive rollups
+func (s *BoltStore) RenameCheck(ctx context.Context, oldID, newID string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(ChecksBucket)
+        data := b.Get([]byte(oldID))
+        if data == nil {
+            return fmt.Errorf("check not found")
+        }
+        if b.Get([]byte(newID)) != nil {
+            return fmt.Errorf("check %s already exists", newID)
+        }
+
+        var check Check
+        if err := json.Unmarshal(data, &check); err != nil {
+            return fmt.Errorf("failed to unmarshal check: %w", err)
+        }
+        check.ID = newID
+        check.UpdatedAt = time.Now().UTC()
+        if err := putRenamed(b, oldID, newID, &check); err != nil {
+            return err
+        }
+
+        return rekeyResults(tx, func(hostID, checkID string) (string, string, bool) {
+            return hostID, newID, checkID == oldID
+        })
+    })
+}
+
+// putRenamed stores record under newID, removing it from oldID
+func putRenamed(b *bbolt.Bucket, oldID, newID string, record interface{}) error {
+    data, err := json.Marshal(record)
+    if err != nil {
+        return fmt.Errorf("failed to marshal %s: %w", newID, err)
+    }
+    if oldID != newID {
+        if err := b.Delete([]byte(oldID)); err != nil {
+            return err
+        }
+    }
+    return b.Put([]byte(newID), data)
+}
+
+// rekeyResults moves the current statuses, history entries and archive
+// rollups that rename matches to their new host/check IDs. Their keys all
+// start with hostID:checkID and their values carry host_id and check_id.
+// Other fields are copied as stored.
+func rekeyResults(tx *bbolt.Tx, rename func(hostID, checkID string) (string, string, bool)) error {
+    type move struct {
+        oldKey, newKey, value []byte
+    }
+
+    for _, name := range [][]byte{StatusBucket, StatusHistBucket, StatusArchiveBucket} {
+        b := tx.Bucket(name)
+        if b == nil {
+            continue
+        }
+
+        var moves []move
+        if err := b.ForEach(func(k, v []byte) error {
+            var record map[string]json.RawMessage
+            if err := json.Unmarshal(v, &record); err != nil {
+                return nil // Skip malformed entries
+            }
+            var hostID, checkID string
+            json.Unmarshal(record["host_id"], &hostID)
+            json.Unmarshal(record["check_id"], &checkID)
+
+            newHostID, newCheckID, ok := rename(hostID, checkID)
+            prefix := hostID + ":" + checkID
+            if !ok || !strings.HasPrefix(string(k), prefix) {
+                return nil
+            }
+
+            record["host_id"], _ = json.Marshal(newHostID)
+            record["check_id"], _ = json.Marshal(newCheckID)
+            data, err := json.Marshal(record)
+            if err != nil {
+                return fmt.Errorf("failed to marshal %s: %w", k, err)
+            }
+            moves = append(moves, move{
+                oldKey: copyBytes(k),
+                newKey: []byte(newHostID + ":" + newCheckID + string(k[len(prefix):])),
+                value:  data,
+            })
+            return nil
+        }); err != nil {
+            return err
+        }
+
+        // Current statuses are also in the time index; the timestamp is
+        // unchanged, so the new value dates both entries
+        current := bytes.Equal(name, StatusBucket)
+        for _, m := range moves {
+            if current {
+                if err := unindexStatus(tx, m.oldKey, m.value); err != nil {
+                    return err
+                }
+            }
+            if err := b.Delete(m.oldKey); err != nil {
+                return err
+            }
+        }
+        for _, m := range moves {
+            if err := b.Put(m.newKey, m.value); err != nil {
+                return err
+            }
+            if current {
+                if err := indexStatus(tx, m.newKey, m.value); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+    return nil
+}
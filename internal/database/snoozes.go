@@ -0,0 +1,107 @@
+// internal/database/snoozes.go - Alerts snoozed until they recover or a deadline passes
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+// SnoozesBucket holds snoozes keyed by hostID:checkID
+var SnoozesBucket = []byte("snoozes")
+
+// Snooze quiets one host/check alert until it recovers or Until passes,
+// whichever comes first
+type Snooze struct {
+    HostID    string    `json:"host_id"`
+    CheckID   string    `json:"check_id"`
+    Until     time.Time `json:"until"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// UnmarshalJSON converts the timestamps to UTC (see toUTC)
+func (s *Snooze) UnmarshalJSON(data []byte) error {
+    type snoozeAlias Snooze
+    if err := json.Unmarshal(data, (*snoozeAlias)(s)); err != nil {
+        return err
+    }
+    toUTC(&s.Until, &s.CreatedAt)
+    return nil
+}
+
+// SetSnooze stores a snooze, replacing any already set for the host/check
+func (s *BoltStore) SetSnooze(ctx context.Context, snooze *Snooze) error {
+    data, err := json.Marshal(snooze)
+    if err != nil {
+        return fmt.Errorf("failed to marshal snooze: %w", err)
+    }
+
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        return tx.Bucket(SnoozesBucket).Put([]byte(snooze.HostID+":"+snooze.CheckID), data)
+    })
+}
+
+// GetSnoozes returns every stored snooze, expired ones included
+func (s *BoltStore) GetSnoozes(ctx context.Context) ([]Snooze, error) {
+    snoozes := []Snooze{}
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        return tx.Bucket(SnoozesBucket).ForEach(func(k, v []byte) error {
+            var snooze Snooze
+            if err := json.Unmarshal(v, &snooze); err != nil {
+                return nil // Skip malformed entries
+            }
+            snoozes = append(snoozes, snooze)
+            return nil
+        })
+    })
+
+    return snoozes, err
+}
+
+// DeleteSnooze removes the snooze on a host/check
+func (s *BoltStore) DeleteSnooze(ctx context.Context, hostID, checkID string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(SnoozesBucket)
+        key := []byte(hostID + ":" + checkID)
+        if b.Get(key) == nil {
+            return fmt.Errorf("snooze not found")
+        }
+        return b.Delete(key)
+    })
+}
+
+// PurgeExpiredSnoozes removes snoozes whose deadline is at or before now
+// and returns them
+func (s *BoltStore) PurgeExpiredSnoozes(ctx context.Context, now time.Time) ([]Snooze, error) {
+    var expired []Snooze
+
+    err := s.db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(SnoozesBucket)
+        var keys [][]byte
+        if err := b.ForEach(func(k, v []byte) error {
+            var snooze Snooze
+            if err := json.Unmarshal(v, &snooze); err != nil {
+                return nil
+            }
+            if !snooze.Until.After(now) {
+                keys = append(keys, copyBytes(k))
+                expired = append(expired, snooze)
+            }
+            return nil
+        }); err != nil {
+            return err
+        }
+        for _, key := range keys {
+            if err := b.Delete(key); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+
+    return expired, err
+}
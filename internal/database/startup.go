@@ -0,0 +1,262 @@
+// internal/database/startup.go - Opening the database with lock and corruption diagnostics
+package database
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "go.etcd.io/bbolt"
+)
+
+var (
+    // ErrDatabaseLocked means another process holds the database file lock
+    ErrDatabaseLocked = errors.New("database locked")
+
+    // ErrDatabaseCorrupt means the database file failed to open or failed
+    // bbolt's consistency check; RepairBoltStore can recover it
+    ErrDatabaseCorrupt = errors.New("database corrupted")
+)
+
+// allBuckets are created in every database
+var allBuckets = [][]byte{HostsBucket, ChecksBucket, StatusBucket, StatusHistBucket, MetaBucket, AnnotationsBucket, StatusArchiveBucket, GroupsBucket, SnoozesBucket, StatusTimeIndexBucket}
+
+// maxCheckErrors limits how many consistency errors are reported
+const maxCheckErrors = 5
+
+// pidFilePath is written next to the database while a store has it open
+func pidFilePath(path string) string {
+    return path + ".pid"
+}
+
+// openBolt opens the database and runs bbolt's consistency check, turning a
+// lock timeout into ErrDatabaseLocked and a bad file (including a bbolt
+// panic) into ErrDatabaseCorrupt. A new file is created with mode. Nothing
+// is left open on error.
+func openBolt(path string, mode os.FileMode) (db *bbolt.DB, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            if db != nil {
+                db.Close()
+                db = nil
+            }
+            err = fmt.Errorf("%w: %s: %v (run raven -repair)", ErrDatabaseCorrupt, path, r)
+        }
+    }()
+
+    _, statErr := os.Stat(path)
+    db, err = bbolt.Open(path, mode, &bbolt.Options{
+        Timeout: 1 * time.Second,
+    })
+    switch {
+    case err == nil:
+        if os.IsNotExist(statErr) {
+            setCreatedMode(path, mode)
+        }
+    case errors.Is(err, bbolt.ErrTimeout):
+        return nil, fmt.Errorf("%w: %s - is another raven instance running? %s", ErrDatabaseLocked, path, lockHolder(path))
+    case errors.Is(err, bbolt.ErrInvalid), errors.Is(err, bbolt.ErrChecksum), errors.Is(err, bbolt.ErrVersionMismatch):
+        return nil, fmt.Errorf("%w: %s: %v (run raven -repair)", ErrDatabaseCorrupt, path, err)
+    default:
+        return nil, fmt.Errorf("failed to open BoltDB: %w", err)
+    }
+
+    if checkErr := checkBolt(db); checkErr != nil {
+        db.Close()
+        return nil, fmt.Errorf("%w: %s: %v (run raven -repair)", ErrDatabaseCorrupt, path, checkErr)
+    }
+
+    return db, nil
+}
+
+// checkBolt reads every record, then runs bbolt's consistency check and
+// joins the first few errors. bbolt's check panics on a damaged page in its
+// own goroutine, where the panic can't be recovered, so the walk (whose
+// panic openBolt does recover) has to find those pages first.
+func checkBolt(db *bbolt.DB) error {
+    var problems []string
+    err := db.View(func(tx *bbolt.Tx) error {
+        if err := tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+            return b.ForEach(func(k, v []byte) error { return nil })
+        }); err != nil {
+            return err
+        }
+
+        for err := range tx.Check() {
+            if len(problems) < maxCheckErrors {
+                problems = append(problems, err.Error())
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    if len(problems) > 0 {
+        return errors.New(strings.Join(problems, "; "))
+    }
+    return nil
+}
+
+// lockHolder describes the process named in the pid file, if any
+func lockHolder(path string) string {
+    data, err := os.ReadFile(pidFilePath(path))
+    if err != nil {
+        return "(no pid file)"
+    }
+
+    pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+    if err != nil {
+        return fmt.Sprintf("(unreadable pid file %s)", pidFilePath(path))
+    }
+
+    if process, err := os.FindProcess(pid); err == nil && process.Signal(syscall.Signal(0)) == nil {
+        return fmt.Sprintf("(pid %d from %s is running)", pid, pidFilePath(path))
+    }
+    return fmt.Sprintf("(stale pid file: pid %d is not running, so another program holds the lock)", pid)
+}
+
+// writePIDFile records this process as the holder of the database
+func writePIDFile(path string, mode os.FileMode) {
+    if err := os.WriteFile(pidFilePath(path), []byte(strconv.Itoa(os.Getpid())+"\n"), mode); err != nil {
+        logrus.WithError(err).Warn("Failed to write database pid file")
+        return
+    }
+    setCreatedMode(pidFilePath(path), mode)
+}
+
+// RepairBoltStore recovers a corrupted database. The bad file is moved aside
+// (to <path>.corrupt-<timestamp>), whatever records can still be read from
+// it are copied into a fresh database with all buckets, and a summary is
+// returned. The new database is created with files.FileMode. It refuses to
+// run while another process holds the lock.
+func RepairBoltStore(path string, files FileOptions) (string, error) {
+    if _, err := os.Stat(path); err != nil {
+        return "", fmt.Errorf("nothing to repair: %w", err)
+    }
+    files = files.withDefaults(path)
+
+    // Fail early on a lock rather than moving a live database aside
+    probe, err := openDamaged(path)
+    if errors.Is(err, bbolt.ErrTimeout) {
+        return "", fmt.Errorf("%w: %s - stop the other raven instance first %s", ErrDatabaseLocked, path, lockHolder(path))
+    }
+    if err == nil {
+        probe.Close()
+    }
+
+    aside := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102T150405"))
+    if err := os.Rename(path, aside); err != nil {
+        return "", fmt.Errorf("failed to move database aside: %w", err)
+    }
+
+    db, err := bbolt.Open(path, files.FileMode, &bbolt.Options{Timeout: 1 * time.Second})
+    if err != nil {
+        return "", fmt.Errorf("failed to create new database: %w", err)
+    }
+    defer db.Close()
+    setCreatedMode(path, files.FileMode)
+
+    if err := db.Update(func(tx *bbolt.Tx) error {
+        for _, bucket := range allBuckets {
+            if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+                return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+            }
+        }
+        return nil
+    }); err != nil {
+        return "", err
+    }
+
+    copied, lost := salvageBolt(aside, db)
+    if err := db.Update(func(tx *bbolt.Tx) error {
+        _, err := buildStatusTimeIndex(tx)
+        return err
+    }); err != nil {
+        lost = append(lost, fmt.Sprintf("%s (%v)", StatusTimeIndexBucket, err))
+    }
+    summary := fmt.Sprintf("moved the damaged database to %s and rebuilt %s: %d records recovered", aside, path, copied)
+    if len(lost) > 0 {
+        summary += fmt.Sprintf(", unreadable: %s", strings.Join(lost, ", "))
+    }
+    return summary, nil
+}
+
+// salvageBolt copies every readable record from the damaged file into db,
+// bucket by bucket, and names the buckets it couldn't read. The status time
+// index isn't copied; the repair rebuilds it from the recovered statuses.
+func salvageBolt(damaged string, db *bbolt.DB) (copied int, lost []string) {
+    old, err := openDamaged(damaged)
+    if err != nil {
+        return 0, []string{"all (" + err.Error() + ")"}
+    }
+    defer old.Close()
+
+    for _, bucket := range allBuckets {
+        if bytes.Equal(bucket, StatusTimeIndexBucket) {
+            continue
+        }
+        count, err := salvageBucket(old, db, bucket)
+        copied += count
+        if err != nil {
+            lost = append(lost, fmt.Sprintf("%s (%v)", bucket, err))
+        }
+    }
+    return copied, lost
+}
+
+// openDamaged opens a possibly corrupted file read-only, turning a bbolt
+// panic into an error
+func openDamaged(path string) (db *bbolt.DB, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = fmt.Errorf("%v", r)
+        }
+    }()
+    return bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+}
+
+// salvageBucket copies one bucket's records, keeping those read before any
+// page bbolt fails on
+func salvageBucket(old, db *bbolt.DB, bucket []byte) (int, error) {
+    var records [][2][]byte
+    readErr := readBucket(old, bucket, &records)
+
+    if err := db.Update(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(bucket)
+        for _, record := range records {
+            if err := b.Put(record[0], record[1]); err != nil {
+                return err
+            }
+        }
+        return nil
+    }); err != nil {
+        return 0, err
+    }
+    return len(records), readErr
+}
+
+func readBucket(db *bbolt.DB, bucket []byte, records *[][2][]byte) (err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = fmt.Errorf("%v", r)
+        }
+    }()
+
+    return db.View(func(tx *bbolt.Tx) error {
+        b := tx.Bucket(bucket)
+        if b == nil {
+            return nil
+        }
+        return b.ForEach(func(k, v []byte) error {
+            *records = append(*records, [2][]byte{copyBytes(k), copyBytes(v)})
+            return nil
+        })
+    })
+}
@@ -0,0 +1,85 @@
+// internal/database/states.go - Exit code to state name mapping
+package database
+
+import "fmt"
+
+// Plugin exit codes, following the Nagios plugin convention
+const (
+    StateOK       = 0
+    StateWarning  = 1
+    StateCritical = 2
+    StateUnknown  = 3
+)
+
+var stateNames = map[int]string{
+    StateOK:       "ok",
+    StateWarning:  "warning",
+    StateCritical: "critical",
+    StateUnknown:  "unknown",
+}
+
+// StateName returns the state name for an exit code. Anything outside the
+// four standard codes is treated as unknown.
+func StateName(exitCode int) string {
+    if name, ok := stateNames[exitCode]; ok {
+        return name
+    }
+    return stateNames[StateUnknown]
+}
+
+// stateSeverity orders states from best to worst; unknown sits between OK
+// and warning so a real failure always outranks it
+var stateSeverity = map[int]int{
+    StateOK:       0,
+    StateUnknown:  1,
+    StateWarning:  2,
+    StateCritical: 3,
+}
+
+// StateSeverity ranks an exit code for "worst state" comparisons. Anything
+// outside the four standard codes ranks as unknown.
+func StateSeverity(exitCode int) int {
+    if severity, ok := stateSeverity[exitCode]; ok {
+        return severity
+    }
+    return stateSeverity[StateUnknown]
+}
+
+// RemapExitCode applies the check's exit code mapping, if any
+func (c *Check) RemapExitCode(exitCode int) int {
+    if mapped, ok := c.ExitCodeMap[exitCode]; ok {
+        return mapped
+    }
+    return exitCode
+}
+
+// ValidateExitCodeMap ensures every mapping targets one of the standard exit codes
+func ValidateExitCodeMap(exitCodeMap map[int]int) error {
+    for from, to := range exitCodeMap {
+        if from < 0 {
+            return fmt.Errorf("exit_code_map has negative exit code %d", from)
+        }
+        if _, ok := stateNames[to]; !ok {
+            return fmt.Errorf("exit_code_map maps %d to %d (must be 0-3)", from, to)
+        }
+    }
+    return nil
+}
+
+// ValidateHostThresholds rejects per-host threshold overrides below 1 or for
+// hosts the check doesn't target
+func ValidateHostThresholds(hosts []string, thresholds map[string]int) error {
+    targeted := make(map[string]bool, len(hosts))
+    for _, hostID := range hosts {
+        targeted[hostID] = true
+    }
+    for hostID, threshold := range thresholds {
+        if !targeted[hostID] {
+            return fmt.Errorf("host_thresholds entry for %s, which isn't one of the check's hosts", hostID)
+        }
+        if threshold < 1 {
+            return fmt.Errorf("host_thresholds entry for %s: %d (must be >= 1)", hostID, threshold)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,132 @@
+// internal/database/status_index.go - Time index over the current status bucket
+package database
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "go.etcd.io/bbolt"
+)
+
+// StatusTimeIndexBucket indexes the current status bucket by result time,
+// so queries with a since filter seek to it instead of reading every status.
+// Keys are the big-endian unix nanosecond timestamp followed by the status
+// key (hostID:checkID); values are empty.
+var StatusTimeIndexBucket = []byte("status_by_time")
+
+// statusTimeKey builds a time index key for a current status
+func statusTimeKey(timestamp time.Time, statusKey []byte) []byte {
+    key := make([]byte, 8, 8+len(statusKey))
+    binary.BigEndian.PutUint64(key, uint64(timestamp.UnixNano()))
+    return append(key, statusKey...)
+}
+
+// statusTimestamp reads just the timestamp of a stored status
+func statusTimestamp(data []byte) (time.Time, bool) {
+    var ref struct {
+        Timestamp time.Time `json:"timestamp"`
+    }
+    if err := json.Unmarshal(data, &ref); err != nil {
+        return time.Time{}, false
+    }
+    return ref.Timestamp, true
+}
+
+// indexStatus adds a stored current status to the time index
+func indexStatus(tx *bbolt.Tx, statusKey, data []byte) error {
+    timestamp, ok := statusTimestamp(data)
+    if !ok {
+        return nil // Malformed statuses are skipped by every reader
+    }
+    return tx.Bucket(StatusTimeIndexBucket).Put(statusTimeKey(timestamp, statusKey), nil)
+}
+
+// unindexStatus removes a current status that is being replaced or deleted
+// from the time index
+func unindexStatus(tx *bbolt.Tx, statusKey, data []byte) error {
+    timestamp, ok := statusTimestamp(data)
+    if !ok {
+        return nil
+    }
+    return tx.Bucket(StatusTimeIndexBucket).Delete(statusTimeKey(timestamp, statusKey))
+}
+
+// deleteCurrentStatus removes a host/check's current status and its time
+// index entry
+func deleteCurrentStatus(tx *bbolt.Tx, statusKey []byte) (bool, error) {
+    b := tx.Bucket(StatusBucket)
+    data := b.Get(statusKey)
+    if data == nil {
+        return false, nil
+    }
+    if err := unindexStatus(tx, statusKey, data); err != nil {
+        return false, err
+    }
+    return true, b.Delete(statusKey)
+}
+
+// forEachStatusSince calls visit with each current status at or after since,
+// oldest first, until it returns false. Index entries whose status has since
+// been replaced or removed are skipped.
+func forEachStatusSince(tx *bbolt.Tx, since time.Time, visit func(status *Status) bool) {
+    b := tx.Bucket(StatusBucket)
+    c := tx.Bucket(StatusTimeIndexBucket).Cursor()
+
+    for k, _ := c.Seek(statusTimeKey(since, nil)); k != nil; k, _ = c.Next() {
+        if len(k) <= 8 {
+            continue
+        }
+        data := b.Get(k[8:])
+        if data == nil {
+            continue
+        }
+        var status Status
+        if err := json.Unmarshal(data, &status); err != nil {
+            continue
+        }
+        if !bytes.Equal(statusTimeKey(status.Timestamp, k[8:]), k) {
+            continue
+        }
+        if !visit(&status) {
+            return
+        }
+    }
+}
+
+// buildStatusTimeIndex indexes every current status, for databases written
+// before the index existed. It returns how many statuses were indexed.
+func buildStatusTimeIndex(tx *bbolt.Tx) (int, error) {
+    indexed := 0
+    err := tx.Bucket(StatusBucket).ForEach(func(k, v []byte) error {
+        if err := indexStatus(tx, k, v); err != nil {
+            return fmt.Errorf("failed to index status %s: %w", k, err)
+        }
+        indexed++
+        return nil
+    })
+    return indexed, err
+}
+
+// migrateStatusTimeIndex (schema version 1) indexes the current statuses
+// of a database written before the index existed
+func migrateStatusTimeIndex(tx *bbolt.Tx) error {
+    // Start from an empty index in case the migration is run again
+    if err := tx.DeleteBucket(StatusTimeIndexBucket); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+        return err
+    }
+    if _, err := tx.CreateBucket(StatusTimeIndexBucket); err != nil {
+        return fmt.Errorf("failed to create bucket %s: %w", StatusTimeIndexBucket, err)
+    }
+
+    indexed, err := buildStatusTimeIndex(tx)
+    if err != nil {
+        return err
+    }
+    logrus.WithField("statuses", indexed).Info("Built status time index")
+    return nil
+}
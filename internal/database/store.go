@@ -14,6 +14,9 @@ type Store interface {
     CreateHost(ctx context.Context, host *Host) error
     UpdateHost(ctx context.Context, host *Host) error
     DeleteHost(ctx context.Context, id string) error
+    CreateHostInChecks(ctx context.Context, host *Host, checkIDs []string) ([]string, error)
+    RemoveHostsFromChecks(ctx context.Context, hostIDs []string) ([]Check, error)
+    RenameHost(ctx context.Context, oldID, newID string) error
 
     // Check operations
     GetChecks(ctx context.Context) ([]Check, error)
@@ -21,13 +24,38 @@ type Store interface {
     CreateCheck(ctx context.Context, check *Check) error
     UpdateCheck(ctx context.Context, check *Check) error
     DeleteCheck(ctx context.Context, id string) error
+    RenameCheck(ctx context.Context, oldID, newID string) error
 
     // Status operations
     GetStatus(ctx context.Context, filters StatusFilters) ([]Status, error)
+    GetLatestStatus(ctx context.Context, hostID, checkID string) (*Status, error)
+    GetLatestStatuses(ctx context.Context, hostID string) ([]Status, error)
+    GetStatusBatch(ctx context.Context, pairs []HostCheckPair) ([]*Status, error)
     UpdateStatus(ctx context.Context, status *Status) error
+    UpdateStatusBatch(ctx context.Context, statuses []*Status) error
     GetStatusHistory(ctx context.Context, hostID, checkID string, since time.Time) ([]Status, error)
     DeleteStatus(ctx context.Context, hostID, checkID string) error
+    SetDedupWindow(window time.Duration)
+    SetHistoryDedupe(enabled bool)
+    SetMaxOutputBytes(limit int)
 
+    // Annotation operations
+    AddAnnotation(ctx context.Context, annotation *Annotation) error
+    GetAnnotations(ctx context.Context, statusID string) ([]Annotation, error)
+    GetAnnotationsForStatuses(ctx context.Context, statusIDs []string) (map[string][]Annotation, error)
+    DeleteAnnotation(ctx context.Context, statusID, annotationID string) error
+
+    // Snooze operations
+    SetSnooze(ctx context.Context, snooze *Snooze) error
+    GetSnoozes(ctx context.Context) ([]Snooze, error)
+    DeleteSnooze(ctx context.Context, hostID, checkID string) error
+    PurgeExpiredSnoozes(ctx context.Context, now time.Time) ([]Snooze, error)
+
+    // Group operations
+    GetGroups(ctx context.Context) ([]Group, error)
+    GetGroup(ctx context.Context, id string) (*Group, error)
+    SaveGroup(ctx context.Context, group *Group) error
+    DeleteGroup(ctx context.Context, id string) error
 
     // Close the database connection
     Close() error
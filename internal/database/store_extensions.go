@@ -13,11 +13,16 @@ type ExtendedStore interface {
     // Alert and status purging operations
     DeleteStatus(ctx context.Context, hostID, checkID string) error
     DeleteStatusHistoryBefore(ctx context.Context, cutoffTime time.Time) (int, error)
+    DeleteStatusHistoryByRetention(ctx context.Context, defaultRetention time.Duration, checkRetention map[string]time.Duration) (int, error)
+    DeleteStatusArchiveBefore(ctx context.Context, cutoffTime time.Time) (int, error)
     DeleteStatusByHostCheck(ctx context.Context, hostID, checkID string) error
     
     // Bulk operations for efficiency
     BulkDeleteStatuses(ctx context.Context, hostCheckPairs []HostCheckPair) (int, error)
     
+    // Archived (hourly rollup) history
+    GetStatusArchive(ctx context.Context, hostID, checkID string, since time.Time) ([]StatusRollup, error)
+
     // Data cleanup operations
     CompactDatabase(ctx context.Context) error
     GetDatabaseStats(ctx context.Context) (*DatabaseStats, error)
@@ -38,4 +43,5 @@ type DatabaseStats struct {
     DatabaseSize       int64         `json:"database_size_bytes"`
     OldestEntry        time.Time     `json:"oldest_entry"`
     NewestEntry        time.Time     `json:"newest_entry"`
+    HistoryByCheck     map[string]int `json:"history_by_check"`
 }
@@ -3,95 +3,361 @@ package metrics
 
 import (
     "context"
+    "net/http"
+    "strconv"
+    "sync"
     "time"
 
     "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
     "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    "raven2/internal/config"
     "raven2/internal/database"
 )
 
-// Prometheus metrics
-var (
-    CheckDuration = promauto.NewHistogramVec(
-        prometheus.HistogramOpts{
-            Name:    "raven_check_duration_seconds",
-            Help:    "Time spent executing checks",
-            Buckets: prometheus.DefBuckets,
-        },
-        []string{"host", "check_type", "status"},
-    )
+type Collector struct {
+    store     database.Store
+    hostLabel string // "name" or "id"
+    registry  *prometheus.Registry
 
-    CheckTotal = promauto.NewCounterVec(
-        prometheus.CounterOpts{
-            Name: "raven_checks_total",
-            Help: "Total number of checks executed",
-        },
-        []string{"host", "check_type", "status"},
-    )
+    checkDuration        *prometheus.HistogramVec
+    checkTotal           *prometheus.CounterVec
+    hostStatus           *prometheus.GaugeVec
+    activeHosts          prometheus.Gauge
+    activeChecks         prometheus.Gauge
+    databaseOperations   *prometheus.CounterVec
+    webSocketConnections prometheus.Gauge
+    checkLastRun         *prometheus.GaugeVec
+    checkLastSuccess     *prometheus.GaugeVec
+    extractedValue       *prometheus.GaugeVec
+    softFailAverted      *prometheus.CounterVec
+    jobsDeferred         prometheus.Counter
+    jobQueueWait         *prometheus.HistogramVec
+    resultQueueDepth     prometheus.Gauge
+    listenerQueueDepth   prometheus.Gauge
+    workerStuck          *prometheus.GaugeVec
 
-    HostStatus = promauto.NewGaugeVec(
-        prometheus.GaugeOpts{
-            Name: "raven_host_status",
-            Help: "Current status of hosts (0=OK, 1=Warning, 2=Critical, 3=Unknown)",
-        },
-        []string{"host", "group", "check_type"},
-    )
+    mu          sync.Mutex
+    series      map[seriesKey]bool      // Label sets written so far, for pruning
+    checkSeries map[checkSeriesKey]bool // Per-check timestamp label sets, for pruning
+}
 
-    ActiveHosts = promauto.NewGauge(
-        prometheus.GaugeOpts{
-            Name: "raven_active_hosts_total",
-            Help: "Number of active hosts being monitored",
-        },
-    )
+// checkSeriesKey identifies the label set of the per-check timestamp gauges
+type checkSeriesKey struct {
+    host    string
+    checkID string
+}
 
-    ActiveChecks = promauto.NewGauge(
-        prometheus.GaugeOpts{
-            Name: "raven_active_checks_total",
-            Help: "Number of active checks configured",
-        },
-    )
+// seriesKey identifies the per-host/check label set shared by the check metrics
+type seriesKey struct {
+    host      string
+    group     string
+    checkType string
+}
 
-    DatabaseOperations = promauto.NewCounterVec(
-        prometheus.CounterOpts{
-            Name: "raven_database_operations_total",
-            Help: "Total database operations performed",
-        },
-        []string{"operation", "status"},
-    )
+// NewCollector creates the Raven metrics and registers them on a registry
+// owned by the collector, so collectors don't clash with each other
+func NewCollector(store database.Store, cfg config.PrometheusConfig) *Collector {
+    buckets := cfg.DurationBuckets
+    if len(buckets) == 0 {
+        buckets = config.DefaultDurationBuckets
+    }
 
-    WebSocketConnections = promauto.NewGauge(
-        prometheus.GaugeOpts{
-            Name: "raven_websocket_connections_active",
-            Help: "Number of active WebSocket connections",
-        },
+    registry := prometheus.NewRegistry()
+    registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
     )
-)
+    factory := promauto.With(registry)
 
-type Collector struct {
-    store database.Store
+    return &Collector{
+        store:     store,
+        hostLabel: cfg.HostLabel,
+        registry:  registry,
+
+        checkDuration: factory.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "raven_check_duration_seconds",
+                Help:    "Time spent executing checks",
+                Buckets: buckets,
+            },
+            []string{"host", "group", "check_type", "status"},
+        ),
+
+        checkTotal: factory.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "raven_checks_total",
+                Help: "Total number of checks executed",
+            },
+            []string{"host", "group", "check_type", "status"},
+        ),
+
+        hostStatus: factory.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "raven_host_status",
+                Help: "Current status of hosts (0=OK, 1=Warning, 2=Critical, 3=Unknown)",
+            },
+            []string{"host", "group", "check_type"},
+        ),
+
+        activeHosts: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "raven_active_hosts_total",
+                Help: "Number of active hosts being monitored",
+            },
+        ),
+
+        activeChecks: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "raven_active_checks_total",
+                Help: "Number of active checks configured",
+            },
+        ),
+
+        databaseOperations: factory.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "raven_database_operations_total",
+                Help: "Total database operations performed",
+            },
+            []string{"operation", "status"},
+        ),
+
+        webSocketConnections: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "raven_websocket_connections_active",
+                Help: "Number of active WebSocket connections",
+            },
+        ),
+
+        checkLastRun: factory.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "raven_check_last_run_timestamp_seconds",
+                Help: "Unix time a check last ran on a host, regardless of result",
+            },
+            []string{"host", "check"},
+        ),
+
+        checkLastSuccess: factory.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "raven_check_last_success_timestamp_seconds",
+                Help: "Unix time a check last returned OK on a host",
+            },
+            []string{"host", "check"},
+        ),
+
+        extractedValue: factory.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "raven_check_extracted_value",
+                Help: "Latest numeric value pulled from a check's output by its extract rules",
+            },
+            []string{"host", "check", "name"},
+        ),
+
+        softFailAverted: factory.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "raven_softfail_averted_total",
+                Help: "Failures that recovered before reaching the soft fail threshold",
+            },
+            []string{"host", "check"},
+        ),
+
+        jobsDeferred: factory.NewCounter(
+            prometheus.CounterOpts{
+                Name: "raven_scheduler_jobs_deferred_total",
+                Help: "Due jobs deferred to the next scheduling pass because the job queue was full",
+            },
+        ),
+
+        jobQueueWait: factory.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "raven_job_queue_wait_seconds",
+                Help:    "Time jobs waited in the queue for a worker, by check priority",
+                Buckets: []float64{0.01, 0.1, 0.5, 1, 5, 15, 30, 60, 120, 300},
+            },
+            []string{"priority"},
+        ),
+
+        resultQueueDepth: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "raven_result_queue_depth",
+                Help: "Finished check results waiting to be recorded",
+            },
+        ),
+
+        listenerQueueDepth: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "raven_listener_queue_depth",
+                Help: "Recorded results waiting to be passed to listeners such as the event stream",
+            },
+        ),
+
+        workerStuck: factory.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "raven_worker_stuck",
+                Help: "Whether a worker's current job has exceeded twice its check timeout (1=stuck)",
+            },
+            []string{"worker"},
+        ),
+
+        series:      make(map[seriesKey]bool),
+        checkSeries: make(map[checkSeriesKey]bool),
+    }
+}
+
+// Handler serves the collector's registry in the Prometheus exposition format
+func (c *Collector) Handler() http.Handler {
+    return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
+
+// HostLabel returns the value used for the host label of a host
+func (c *Collector) HostLabel(host *database.Host) string {
+    if c.hostLabel == "id" {
+        return host.ID
+    }
+    return host.Name
 }
 
-func NewCollector(store database.Store) *Collector {
-    return &Collector{store: store}
+func (c *Collector) RecordCheckResult(host *database.Host, checkType string, exitCode int, duration time.Duration) {
+    key := c.track(host, checkType)
+    status := database.StateName(exitCode)
+    c.checkDuration.WithLabelValues(key.host, key.group, key.checkType, status).Observe(duration.Seconds())
+    c.checkTotal.WithLabelValues(key.host, key.group, key.checkType, status).Inc()
 }
 
-func (c *Collector) RecordCheckResult(host, checkType string, exitCode int, duration time.Duration) {
-    status := getStatusLabel(exitCode)
-    CheckDuration.WithLabelValues(host, checkType, status).Observe(duration.Seconds())
-    CheckTotal.WithLabelValues(host, checkType, status).Inc()
+func (c *Collector) UpdateHostStatus(host *database.Host, checkType string, exitCode int) {
+    key := c.track(host, checkType)
+    c.hostStatus.WithLabelValues(key.host, key.group, key.checkType).Set(float64(exitCode))
 }
 
-func (c *Collector) UpdateHostStatus(host, group, checkType string, exitCode int) {
-    HostStatus.WithLabelValues(host, group, checkType).Set(float64(exitCode))
+// RecordCheckRun updates the last-run timestamp and, when the check
+// returned OK, the last-success timestamp
+func (c *Collector) RecordCheckRun(host *database.Host, checkID string, exitCode int, at time.Time) {
+    key := checkSeriesKey{host: c.HostLabel(host), checkID: checkID}
+
+    c.mu.Lock()
+    c.checkSeries[key] = true
+    c.mu.Unlock()
+
+    c.checkLastRun.WithLabelValues(key.host, key.checkID).Set(float64(at.Unix()))
+    if exitCode == database.StateOK {
+        c.checkLastSuccess.WithLabelValues(key.host, key.checkID).Set(float64(at.Unix()))
+    }
+}
+
+// RecordExtractedValues sets the extracted value gauges from a result.
+// Values that are missing or not numbers drop their series, so a stale
+// reading isn't left behind.
+func (c *Collector) RecordExtractedValues(host *database.Host, checkID string, values map[string]interface{}) {
+    key := checkSeriesKey{host: c.HostLabel(host), checkID: checkID}
+
+    c.mu.Lock()
+    c.checkSeries[key] = true
+    c.mu.Unlock()
+
+    for name, value := range values {
+        if number, ok := value.(float64); ok {
+            c.extractedValue.WithLabelValues(key.host, key.checkID, name).Set(number)
+        } else {
+            c.extractedValue.DeleteLabelValues(key.host, key.checkID, name)
+        }
+    }
+}
+
+// RecordSoftFailAverted counts a failure that recovered before soft fail
+// confirmed it
+func (c *Collector) RecordSoftFailAverted(host *database.Host, checkID string) {
+    key := checkSeriesKey{host: c.HostLabel(host), checkID: checkID}
+
+    c.mu.Lock()
+    c.checkSeries[key] = true
+    c.mu.Unlock()
+
+    c.softFailAverted.WithLabelValues(key.host, key.checkID).Inc()
+}
+
+func (c *Collector) track(host *database.Host, checkType string) seriesKey {
+    key := seriesKey{host: c.HostLabel(host), group: host.Group, checkType: checkType}
+
+    c.mu.Lock()
+    c.series[key] = true
+    c.mu.Unlock()
+
+    return key
+}
+
+// PruneSeries deletes check series for hosts and checks that have been
+// removed or disabled, so stale gauges don't linger forever
+func (c *Collector) PruneSeries(ctx context.Context) error {
+    hosts, err := c.store.GetHosts(ctx, database.HostFilters{})
+    if err != nil {
+        return err
+    }
+    checks, err := c.store.GetChecks(ctx)
+    if err != nil {
+        return err
+    }
+
+    c.pruneSeries(hosts, checks)
+    return nil
+}
+
+func (c *Collector) pruneSeries(hosts []database.Host, checks []database.Check) {
+    enabledHosts := make(map[string]*database.Host)
+    for i := range hosts {
+        if hosts[i].Enabled {
+            enabledHosts[hosts[i].ID] = &hosts[i]
+        }
+    }
+
+    wanted := make(map[seriesKey]bool)
+    wantedChecks := make(map[checkSeriesKey]bool)
+    for _, check := range checks {
+        if !check.Enabled {
+            continue
+        }
+        for _, hostID := range check.Hosts {
+            if host, ok := enabledHosts[hostID]; ok {
+                wanted[seriesKey{host: c.HostLabel(host), group: host.Group, checkType: check.Type}] = true
+                wantedChecks[checkSeriesKey{host: c.HostLabel(host), checkID: check.ID}] = true
+            }
+        }
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    for key := range c.series {
+        if wanted[key] {
+            continue
+        }
+        labels := prometheus.Labels{"host": key.host, "group": key.group, "check_type": key.checkType}
+        c.checkDuration.DeletePartialMatch(labels)
+        c.checkTotal.DeletePartialMatch(labels)
+        c.hostStatus.Delete(labels)
+        delete(c.series, key)
+    }
+
+    for key := range c.checkSeries {
+        if wantedChecks[key] {
+            continue
+        }
+        labels := prometheus.Labels{"host": key.host, "check": key.checkID}
+        c.checkLastRun.Delete(labels)
+        c.checkLastSuccess.Delete(labels)
+        c.extractedValue.DeletePartialMatch(labels)
+        c.softFailAverted.Delete(labels)
+        delete(c.checkSeries, key)
+    }
 }
 
 func (c *Collector) UpdateSystemMetrics(ctx context.Context) error {
     hosts, err := c.store.GetHosts(ctx, database.HostFilters{})
     if err != nil {
-        DatabaseOperations.WithLabelValues("get_hosts", "error").Inc()
+        c.databaseOperations.WithLabelValues("get_hosts", "error").Inc()
         return err
     }
-    DatabaseOperations.WithLabelValues("get_hosts", "success").Inc()
+    c.databaseOperations.WithLabelValues("get_hosts", "success").Inc()
 
     enabledHosts := 0
     for _, host := range hosts {
@@ -99,14 +365,14 @@ func (c *Collector) UpdateSystemMetrics(ctx context.Context) error {
             enabledHosts++
         }
     }
-    ActiveHosts.Set(float64(enabledHosts))
+    c.activeHosts.Set(float64(enabledHosts))
 
     checks, err := c.store.GetChecks(ctx)
     if err != nil {
-        DatabaseOperations.WithLabelValues("get_checks", "error").Inc()
+        c.databaseOperations.WithLabelValues("get_checks", "error").Inc()
         return err
     }
-    DatabaseOperations.WithLabelValues("get_checks", "success").Inc()
+    c.databaseOperations.WithLabelValues("get_checks", "success").Inc()
 
     enabledChecks := 0
     for _, check := range checks {
@@ -114,24 +380,36 @@ func (c *Collector) UpdateSystemMetrics(ctx context.Context) error {
             enabledChecks++
         }
     }
-    ActiveChecks.Set(float64(enabledChecks))
+    c.activeChecks.Set(float64(enabledChecks))
 
+    c.pruneSeries(hosts, checks)
     return nil
 }
 
 func (c *Collector) RecordWebSocketConnection(delta int) {
-    WebSocketConnections.Add(float64(delta))
+    c.webSocketConnections.Add(float64(delta))
+}
+
+func (c *Collector) RecordJobsDeferred(count int) {
+    c.jobsDeferred.Add(float64(count))
+}
+
+func (c *Collector) RecordJobQueueWait(priority int, wait time.Duration) {
+    c.jobQueueWait.WithLabelValues(strconv.Itoa(priority)).Observe(wait.Seconds())
+}
+
+func (c *Collector) UpdateResultQueueDepth(depth int) {
+    c.resultQueueDepth.Set(float64(depth))
+}
+
+func (c *Collector) UpdateListenerQueueDepth(depth int) {
+    c.listenerQueueDepth.Set(float64(depth))
 }
 
-func getStatusLabel(exitCode int) string {
-    switch exitCode {
-    case 0:
-        return "ok"
-    case 1:
-        return "warning"
-    case 2:
-        return "critical"
-    default:
-        return "unknown"
+func (c *Collector) UpdateWorkerStuck(worker string, stuck bool) {
+    value := 0.0
+    if stuck {
+        value = 1
     }
+    c.workerStuck.WithLabelValues(worker).Set(value)
 }
@@ -0,0 +1,111 @@
+// internal/metrics/prometheus_test.go - Pruning series for removed hosts and checks
+package metrics
+
+import (
+    "context"
+    "io"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+// scrape returns the collector's exposition output
+func scrape(t *testing.T, c *Collector) string {
+    t.Helper()
+    recorder := httptest.NewRecorder()
+    c.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+    body, err := io.ReadAll(recorder.Body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return string(body)
+}
+
+func TestPruneSeriesForRemovedHostsAndChecks(t *testing.T) {
+    logrus.SetLevel(logrus.ErrorLevel)
+    store, err := database.NewBoltStore(filepath.Join(t.TempDir(), "raven.db"), database.FileOptions{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer store.Close()
+    ctx := context.Background()
+
+    hosts := []*database.Host{
+        {ID: "web-01", Name: "web-01", Group: "web", Enabled: true},
+        {ID: "db-01", Name: "db-01", Group: "db", Enabled: true},
+    }
+    checks := []*database.Check{
+        {ID: "ping", Name: "Ping", Type: "ping", Hosts: []string{"web-01", "db-01"}, Enabled: true},
+        {ID: "disk", Name: "Disk", Type: "nagios", Hosts: []string{"db-01"}, Enabled: true},
+    }
+    for _, host := range hosts {
+        if err := store.CreateHost(ctx, host); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, check := range checks {
+        if err := store.CreateCheck(ctx, check); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    c := NewCollector(store, config.PrometheusConfig{})
+    for _, check := range checks {
+        for _, hostID := range check.Hosts {
+            host := hosts[0]
+            if hostID == "db-01" {
+                host = hosts[1]
+            }
+            c.RecordCheckResult(host, check.Type, database.StateOK, 20*time.Millisecond)
+            c.UpdateHostStatus(host, check.Type, database.StateOK)
+            c.RecordCheckRun(host, check.ID, database.StateOK, time.Now())
+        }
+    }
+
+    before := scrape(t, c)
+    for _, want := range []string{
+        `raven_checks_total{check_type="ping",group="web",host="web-01",status="ok"} 1`,
+        `raven_host_status{check_type="nagios",group="db",host="db-01"} 0`,
+        `raven_check_last_run_timestamp_seconds{check="ping",host="web-01"}`,
+    } {
+        if !strings.Contains(before, want) {
+            t.Errorf("metrics missing %s", want)
+        }
+    }
+
+    // Delete a host and disable a check, then prune
+    if err := store.DeleteHost(ctx, "web-01"); err != nil {
+        t.Fatal(err)
+    }
+    checks[0].Hosts = []string{"db-01"}
+    checks[1].Enabled = false
+    for _, check := range checks {
+        if err := store.UpdateCheck(ctx, check); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := c.PruneSeries(ctx); err != nil {
+        t.Fatalf("PruneSeries: %v", err)
+    }
+
+    after := scrape(t, c)
+    for _, gone := range []string{`host="web-01"`, `check_type="nagios"`, `check="disk"`} {
+        if strings.Contains(after, gone) {
+            t.Errorf("series with %s left after pruning", gone)
+        }
+    }
+    for _, kept := range []string{
+        `raven_checks_total{check_type="ping",group="db",host="db-01",status="ok"} 1`,
+        `raven_check_last_run_timestamp_seconds{check="ping",host="db-01"}`,
+    } {
+        if !strings.Contains(after, kept) {
+            t.Errorf("series %s pruned with the rest", kept)
+        }
+    }
+}
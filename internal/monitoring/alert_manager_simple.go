@@ -15,11 +15,13 @@ import (
 // SimpleAlertManager handles alert lifecycle and purging for existing engine
 type SimpleAlertManager struct {
     store  database.Store
-    config *config.Config
+    config func() *config.Config // The engine's current config
+
+    snoozesExpired func([]database.Snooze) // Told which snoozes the purge removed
 }
 
 // NewSimpleAlertManager creates a new alert manager that works with existing engine
-func NewSimpleAlertManager(store database.Store, cfg *config.Config) *SimpleAlertManager {
+func NewSimpleAlertManager(store database.Store, cfg func() *config.Config) *SimpleAlertManager {
     return &SimpleAlertManager{
         store:  store,
         config: cfg,
@@ -75,12 +77,12 @@ func (am *SimpleAlertManager) getValidHostCheckCombinations() map[string]bool {
     
     // Build map of valid host IDs
     validHosts := make(map[string]bool)
-    for _, host := range am.config.Hosts {
+    for _, host := range am.config().Hosts {
         validHosts[host.ID] = host.Enabled
     }
     
     // Build map of valid host:check combinations
-    for _, check := range am.config.Checks {
+    for _, check := range am.config().Checks {
         if !check.Enabled {
             continue // Skip disabled checks
         }
@@ -93,6 +95,9 @@ func (am *SimpleAlertManager) getValidHostCheckCombinations() map[string]bool {
             }
         }
     }
+    for _, checkID := range selfCheckIDs(am.config().Monitoring.SelfChecks) {
+        valid[fmt.Sprintf("%s:%s", SelfHostID, checkID)] = true
+    }
     
     logrus.WithField("valid_combinations", len(valid)).Debug("Built valid host:check combinations map")
     
@@ -103,10 +108,11 @@ func (am *SimpleAlertManager) getValidHostCheckCombinations() map[string]bool {
 func (am *SimpleAlertManager) PurgeOrphanedHosts(ctx context.Context) error {
     logrus.Debug("Checking for orphaned hosts in database")
     
-    // Get current hosts from config
-    configHostIDs := make(map[string]bool)
-    for _, host := range am.config.Hosts {
-        configHostIDs[host.ID] = true
+    // Get current hosts from config, keeping any that a rename still
+    // has to migrate
+    configHostIDs := am.config().ConfiguredIDs().Hosts
+    if am.config().Monitoring.SelfChecks.Enabled {
+        configHostIDs[SelfHostID] = true
     }
     
     // Get hosts from database
@@ -145,10 +151,11 @@ func (am *SimpleAlertManager) PurgeOrphanedHosts(ctx context.Context) error {
 func (am *SimpleAlertManager) PurgeOrphanedChecks(ctx context.Context) error {
     logrus.Debug("Checking for orphaned checks in database")
     
-    // Get current checks from config
-    configCheckIDs := make(map[string]bool)
-    for _, check := range am.config.Checks {
-        configCheckIDs[check.ID] = true
+    // Get current checks from config, keeping any that a rename still
+    // has to migrate
+    configCheckIDs := am.config().ConfiguredIDs().Checks
+    for _, checkID := range selfCheckIDs(am.config().Monitoring.SelfChecks) {
+        configCheckIDs[checkID] = true
     }
     
     // Get checks from database
@@ -183,6 +190,42 @@ func (am *SimpleAlertManager) PurgeOrphanedChecks(ctx context.Context) error {
     return nil
 }
 
+// PurgeExpiredHistory removes history entries older than each check's
+// effective retention (check retention, or database.history_retention).
+// Removed entries are kept as hourly rollups until database.archive_retention.
+func (am *SimpleAlertManager) PurgeExpiredHistory(ctx context.Context) (int, error) {
+    extStore, ok := am.store.(database.ExtendedStore)
+    if !ok {
+        logrus.Debug("Store does not support history cleanup, skipping")
+        return 0, nil
+    }
+    
+    checks, err := am.store.GetChecks(ctx)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get checks: %w", err)
+    }
+    
+    defaultRetention := am.config().Database.HistoryRetention
+    checkRetention := make(map[string]time.Duration)
+    for _, check := range checks {
+        checkRetention[check.ID] = check.EffectiveRetention(defaultRetention)
+    }
+    
+    deleted, err := extStore.DeleteStatusHistoryByRetention(ctx, defaultRetention, checkRetention)
+    if err != nil {
+        return deleted, err
+    }
+
+    if am.config().Database.ArchiveRetention > 0 {
+        cutoff := time.Now().Add(-am.config().Database.ArchiveRetention)
+        if _, err := extStore.DeleteStatusArchiveBefore(ctx, cutoff); err != nil {
+            return deleted, err
+        }
+    }
+
+    return deleted, nil
+}
+
 // PurgeAll performs a complete purge of stale data
 func (am *SimpleAlertManager) PurgeAll(ctx context.Context) error {
     logrus.Info("Starting complete alert and configuration purge")
@@ -204,6 +247,16 @@ func (am *SimpleAlertManager) PurgeAll(ctx context.Context) error {
         errors = append(errors, fmt.Sprintf("alert purge failed: %v", err))
     }
     
+    // Apply history retention
+    if _, err := am.PurgeExpiredHistory(ctx); err != nil {
+        errors = append(errors, fmt.Sprintf("history cleanup failed: %v", err))
+    }
+
+    // Drop snoozes past their deadline
+    if err := am.PurgeExpiredSnoozes(ctx); err != nil {
+        errors = append(errors, fmt.Sprintf("snooze cleanup failed: %v", err))
+    }
+    
     if len(errors) > 0 {
         return fmt.Errorf("purge completed with errors: %s", strings.Join(errors, "; "))
     }
@@ -212,6 +265,21 @@ func (am *SimpleAlertManager) PurgeAll(ctx context.Context) error {
     return nil
 }
 
+// PurgeExpiredSnoozes removes snoozes whose deadline has passed
+func (am *SimpleAlertManager) PurgeExpiredSnoozes(ctx context.Context) error {
+    expired, err := am.store.PurgeExpiredSnoozes(ctx, time.Now())
+    if err != nil {
+        return err
+    }
+    if len(expired) > 0 {
+        logrus.WithField("expired_snoozes", len(expired)).Info("Purged expired snoozes")
+        if am.snoozesExpired != nil {
+            am.snoozesExpired(expired)
+        }
+    }
+    return nil
+}
+
 // SchedulePeriodicPurge sets up automatic purging on a schedule
 func (am *SimpleAlertManager) SchedulePeriodicPurge(ctx context.Context, interval time.Duration) {
     // Purge immediately on startup
@@ -0,0 +1,133 @@
+// internal/monitoring/command.go - Running plugin programs with a per-check environment
+package monitoring
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "regexp"
+    "sort"
+    "strings"
+
+    "raven2/internal/database"
+)
+
+// Daemon environment variables plugins inherit; everything else comes from
+// the check's "env" option
+var basePluginEnv = []string{"PATH", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// commandOptionSpecs are offered by plugins that run an external program
+var (
+    envOptionSpec = OptionSpec{
+        Type:        OptionMap,
+        Description: "Environment variables set for the program, on top of PATH, LANG, LC_ALL, TZ and TMPDIR",
+    }
+    cwdOptionSpec = OptionSpec{
+        Type:        OptionString,
+        Description: "Directory the program runs in",
+    }
+)
+
+// validateCommandOptions rejects env entries that aren't NAME: value pairs
+// and a cwd that isn't an existing directory
+func validateCommandOptions(options map[string]interface{}) error {
+    if env, ok := options["env"].(map[string]interface{}); ok {
+        for name, value := range env {
+            if !envNamePattern.MatchString(name) {
+                return fmt.Errorf("invalid env name %q", name)
+            }
+            switch value.(type) {
+            case string, int, int64, float64, bool:
+            default:
+                return fmt.Errorf("env %s must be a string", name)
+            }
+        }
+    }
+
+    if cwd, ok := options["cwd"].(string); ok {
+        info, err := os.Stat(cwd)
+        if err != nil {
+            return fmt.Errorf("invalid cwd: %w", err)
+        }
+        if !info.IsDir() {
+            return fmt.Errorf("invalid cwd: %s is not a directory", cwd)
+        }
+    }
+
+    return nil
+}
+
+// ProgramProblem says why a check's program can't be run, or returns ""
+// when it is an executable file (or found on PATH for a bare name)
+func ProgramProblem(program string) string {
+    if program == "" {
+        return "no program configured"
+    }
+    if _, err := exec.LookPath(program); err != nil {
+        return err.Error()
+    }
+    return ""
+}
+
+// pluginCommand builds the command for a check's program. It gets the base
+// environment plus the check's "env", never the daemon's full environment,
+// and runs in "cwd" when set.
+func pluginCommand(ctx context.Context, program string, args []string, options map[string]interface{}) *exec.Cmd {
+    cmd := exec.CommandContext(ctx, program, args...)
+
+    values := make(map[string]string)
+    for _, name := range basePluginEnv {
+        if value, ok := os.LookupEnv(name); ok {
+            values[name] = value
+        }
+    }
+    if env, ok := options["env"].(map[string]interface{}); ok {
+        for name, value := range env {
+            values[name] = fmt.Sprint(value)
+        }
+    }
+
+    names := make([]string, 0, len(values))
+    for name := range values {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    cmd.Env = make([]string, 0, len(names))
+    for _, name := range names {
+        cmd.Env = append(cmd.Env, name+"="+values[name])
+    }
+
+    if cwd, ok := options["cwd"].(string); ok {
+        cmd.Dir = cwd
+    }
+
+    return cmd
+}
+
+// parsePluginOutput splits Nagios plugin output into the status line, its
+// performance data and the remaining lines
+func parsePluginOutput(output string) (text, perfData, longOutput string) {
+    first, rest, _ := strings.Cut(strings.TrimRight(output, "\n"), "\n")
+    text, perfData, _ = strings.Cut(first, "|")
+    return strings.TrimSpace(text), strings.TrimSpace(perfData), rest
+}
+
+// pluginExitCode reads the plugin's state from how it exited. Codes outside
+// 0-3 and programs that couldn't be run are UNKNOWN.
+func pluginExitCode(err error) (int, error) {
+    if err == nil {
+        return database.StateOK, nil
+    }
+    var exitErr *exec.ExitError
+    if !errors.As(err, &exitErr) {
+        return database.StateUnknown, err
+    }
+    if code := exitErr.ExitCode(); code >= database.StateOK && code <= database.StateUnknown {
+        return code, nil
+    }
+    return database.StateUnknown, nil
+}
@@ -0,0 +1,189 @@
+// internal/monitoring/coverage.go - Which checks cover each host, and which hosts each check covers
+package monitoring
+
+import (
+    "context"
+    "sort"
+    "sync"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// CheckCoverage answers which checks list a host and which hosts a check
+// lists, without loading every check from the store
+type CheckCoverage interface {
+    // ChecksForHost maps the ID of every check listing the host to its name
+    ChecksForHost(hostID string) map[string]string
+    // EnabledCheckCount counts the enabled checks listing the host
+    EnabledCheckCount(hostID string) int
+    // HostsForCheck returns the hosts a check lists, sorted
+    HostsForCheck(checkID string) []string
+}
+
+// coveredCheck is what the index keeps of a check
+type coveredCheck struct {
+    name    string
+    enabled bool
+    hosts   []string
+}
+
+// coverageIndex keeps host-to-check membership in both directions. It is
+// rebuilt from the store whenever the engine syncs its configuration and
+// kept current in between as checks and hosts are saved or deleted.
+type coverageIndex struct {
+    mu         sync.RWMutex
+    checks     map[string]coveredCheck        // Check ID -> check
+    hostChecks map[string]map[string]struct{} // Host ID -> check IDs
+}
+
+func newCoverageIndex() *coverageIndex {
+    return &coverageIndex{
+        checks:     make(map[string]coveredCheck),
+        hostChecks: make(map[string]map[string]struct{}),
+    }
+}
+
+// rebuild replaces the index with the given checks
+func (ci *coverageIndex) rebuild(checks []database.Check) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+
+    ci.checks = make(map[string]coveredCheck, len(checks))
+    ci.hostChecks = make(map[string]map[string]struct{})
+    for i := range checks {
+        ci.add(&checks[i])
+    }
+}
+
+// setCheck adds a check or replaces what the index has for it
+func (ci *coverageIndex) setCheck(check *database.Check) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+
+    ci.remove(check.ID)
+    ci.add(check)
+}
+
+// removeCheck drops a deleted check
+func (ci *coverageIndex) removeCheck(checkID string) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+
+    ci.remove(checkID)
+}
+
+// removeHost drops a deleted host from every check listing it
+func (ci *coverageIndex) removeHost(hostID string) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+
+    for checkID := range ci.hostChecks[hostID] {
+        check := ci.checks[checkID]
+        hosts := make([]string, 0, len(check.hosts))
+        for _, id := range check.hosts {
+            if id != hostID {
+                hosts = append(hosts, id)
+            }
+        }
+        check.hosts = hosts
+        ci.checks[checkID] = check
+    }
+    delete(ci.hostChecks, hostID)
+}
+
+// add indexes a check; the caller holds the write lock
+func (ci *coverageIndex) add(check *database.Check) {
+    covered := coveredCheck{name: check.Name, enabled: check.Enabled}
+    for _, hostID := range check.Hosts {
+        checkIDs, exists := ci.hostChecks[hostID]
+        if !exists {
+            checkIDs = make(map[string]struct{})
+            ci.hostChecks[hostID] = checkIDs
+        }
+        if _, dup := checkIDs[check.ID]; dup {
+            continue
+        }
+        checkIDs[check.ID] = struct{}{}
+        covered.hosts = append(covered.hosts, hostID)
+    }
+    sort.Strings(covered.hosts)
+    ci.checks[check.ID] = covered
+}
+
+// remove unindexes a check; the caller holds the write lock
+func (ci *coverageIndex) remove(checkID string) {
+    for _, hostID := range ci.checks[checkID].hosts {
+        delete(ci.hostChecks[hostID], checkID)
+        if len(ci.hostChecks[hostID]) == 0 {
+            delete(ci.hostChecks, hostID)
+        }
+    }
+    delete(ci.checks, checkID)
+}
+
+func (ci *coverageIndex) ChecksForHost(hostID string) map[string]string {
+    ci.mu.RLock()
+    defer ci.mu.RUnlock()
+
+    names := make(map[string]string, len(ci.hostChecks[hostID]))
+    for checkID := range ci.hostChecks[hostID] {
+        names[checkID] = ci.checks[checkID].name
+    }
+    return names
+}
+
+func (ci *coverageIndex) EnabledCheckCount(hostID string) int {
+    ci.mu.RLock()
+    defer ci.mu.RUnlock()
+
+    count := 0
+    for checkID := range ci.hostChecks[hostID] {
+        if ci.checks[checkID].enabled {
+            count++
+        }
+    }
+    return count
+}
+
+// covers reports whether a check lists a host
+func (ci *coverageIndex) covers(hostID, checkID string) bool {
+    ci.mu.RLock()
+    defer ci.mu.RUnlock()
+
+    _, listed := ci.hostChecks[hostID][checkID]
+    return listed
+}
+
+func (ci *coverageIndex) HostsForCheck(checkID string) []string {
+    ci.mu.RLock()
+    defer ci.mu.RUnlock()
+
+    return append([]string(nil), ci.checks[checkID].hosts...)
+}
+
+// Coverage returns the index of which checks cover which hosts
+func (e *Engine) Coverage() CheckCoverage {
+    return e.coverage
+}
+
+// RebuildCoverage reloads the coverage index from the store. Syncing the
+// configuration does this; call it after changing checks some other way.
+func (e *Engine) RebuildCoverage(ctx context.Context) {
+    checks, err := e.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to rebuild check coverage")
+        return
+    }
+    e.coverage.rebuild(checks)
+}
+
+// CheckSaved updates the coverage index for a created or updated check
+func (e *Engine) CheckSaved(check *database.Check) {
+    e.coverage.setCheck(check)
+}
+
+// CheckDeleted drops a deleted check from the coverage index
+func (e *Engine) CheckDeleted(checkID string) {
+    e.coverage.removeCheck(checkID)
+}
@@ -0,0 +1,83 @@
+// internal/monitoring/coverage_test.go - Coverage lookups cost what the host or check lists, not the inventory
+package monitoring
+
+import (
+    "fmt"
+    "testing"
+
+    "raven2/internal/database"
+)
+
+// coverageChecks builds checks checks over hosts hosts, each check listing
+// perCheck consecutive hosts, so every host is listed by about
+// checks*perCheck/hosts checks
+func coverageChecks(checks, hosts, perCheck int) []database.Check {
+    list := make([]database.Check, checks)
+    for i := range list {
+        list[i] = database.Check{ID: fmt.Sprintf("check-%05d", i), Name: fmt.Sprintf("Check %d", i), Enabled: true}
+        for j := 0; j < perCheck; j++ {
+            list[i].Hosts = append(list[i].Hosts, fmt.Sprintf("host-%05d", (i*perCheck+j)%hosts))
+        }
+    }
+    return list
+}
+
+func TestCoverageIndex(t *testing.T) {
+    index := newCoverageIndex()
+    index.rebuild(coverageChecks(100, 50, 2))
+
+    // 100 checks of 2 hosts over 50 hosts: each host is in 4 checks
+    if got := index.ChecksForHost("host-00007"); len(got) != 4 {
+        t.Errorf("ChecksForHost = %v, want 4 checks", got)
+    }
+    if got := index.HostsForCheck("check-00030"); len(got) != 2 || got[0] != "host-00010" || got[1] != "host-00011" {
+        t.Errorf("HostsForCheck = %v, want [host-00010 host-00011]", got)
+    }
+    if !index.covers("host-00010", "check-00030") || index.covers("host-00012", "check-00030") {
+        t.Error("covers disagrees with HostsForCheck")
+    }
+
+    index.removeHost("host-00010")
+    if got := index.HostsForCheck("check-00030"); len(got) != 1 || got[0] != "host-00011" {
+        t.Errorf("HostsForCheck after removing host-00010 = %v, want [host-00011]", got)
+    }
+    index.removeCheck("check-00030")
+    if index.covers("host-00011", "check-00030") {
+        t.Error("removed check still covers its hosts")
+    }
+}
+
+// BenchmarkCoverage looks up one host's checks and one check's hosts as the
+// inventory grows 100-fold with the same number of checks per host. The
+// time per lookup should stay flat: it depends on the checks listing the
+// host, or the hosts the check lists, not on how many checks exist.
+//
+//    go test ./internal/monitoring -run '^$' -bench Coverage
+func BenchmarkCoverage(b *testing.B) {
+    for _, size := range []struct{ checks, hosts int }{
+        {100, 50},
+        {1000, 500},
+        {10000, 5000},
+    } {
+        index := newCoverageIndex()
+        index.rebuild(coverageChecks(size.checks, size.hosts, 2))
+        name := fmt.Sprintf("%d checks", size.checks)
+
+        b.Run("ChecksForHost/"+name, func(b *testing.B) {
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                if len(index.ChecksForHost("host-00007")) != 4 {
+                    b.Fatal("host-00007 should be in 4 checks")
+                }
+            }
+        })
+        b.Run("HostsForCheck/"+name, func(b *testing.B) {
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                if len(index.HostsForCheck("check-00030")) != 2 {
+                    b.Fatal("check-00030 should list 2 hosts")
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,96 @@
+// internal/monitoring/delete.go - Deleting hosts and checks along with everything recorded about them
+package monitoring
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// DeleteHost deletes a host along with the current status, history and
+// archived rollups of every check it belongs to or has results for, and
+// drops its tracked state and coverage. Results for it that are still
+// running or waiting to be written are dropped. It returns how many current
+// statuses were removed. Callers take the host out of check host lists.
+func (e *Engine) DeleteHost(ctx context.Context, id string) (int, error) {
+    checkIDs := make(map[string]bool)
+    for checkID := range e.coverage.ChecksForHost(id) {
+        checkIDs[checkID] = true
+    }
+    statuses, err := e.store.GetLatestStatuses(ctx, id)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get statuses: %w", err)
+    }
+    for _, status := range statuses {
+        checkIDs[status.CheckID] = true
+    }
+
+    if err := e.store.DeleteHost(ctx, id); err != nil {
+        return 0, err
+    }
+    // Results still running or waiting to be written would bring back
+    // what is deleted below
+    e.scheduler.stopRecording(func() { e.ForgetHost(id) }, func(status *database.Status) bool {
+        return status.HostID == id
+    })
+
+    for checkID := range checkIDs {
+        if err := e.deleteStatuses(ctx, id, checkID); err != nil {
+            return 0, fmt.Errorf("failed to delete statuses for check %s: %w", checkID, err)
+        }
+    }
+    return len(statuses), nil
+}
+
+// DeleteCheck deletes a check along with the current status, history and
+// archived rollups of every host it lists or has results for, and drops
+// its tracked state and coverage
+func (e *Engine) DeleteCheck(ctx context.Context, id string) error {
+    hostIDs := make(map[string]bool)
+    for _, hostID := range e.coverage.HostsForCheck(id) {
+        hostIDs[hostID] = true
+    }
+    statuses, err := e.store.GetStatus(ctx, database.StatusFilters{CheckID: id})
+    if err != nil {
+        return fmt.Errorf("failed to get statuses: %w", err)
+    }
+    for _, status := range statuses {
+        hostIDs[status.HostID] = true
+    }
+
+    if err := e.store.DeleteCheck(ctx, id); err != nil {
+        return err
+    }
+    e.scheduler.stopRecording(func() {
+        e.CheckDeleted(id)
+        e.scheduler.stateTracker.forgetCheck(id)
+    }, func(status *database.Status) bool {
+        return status.CheckID == id
+    })
+
+    for hostID := range hostIDs {
+        if err := e.deleteStatuses(ctx, hostID, id); err != nil {
+            return fmt.Errorf("failed to delete statuses for host %s: %w", hostID, err)
+        }
+    }
+    return nil
+}
+
+// deleteStatuses removes a host/check's results; the extended store also
+// removes archived rollups
+func (e *Engine) deleteStatuses(ctx context.Context, hostID, checkID string) error {
+    if extStore, ok := e.store.(database.ExtendedStore); ok {
+        return extStore.DeleteStatusByHostCheck(ctx, hostID, checkID)
+    }
+    return e.store.DeleteStatus(ctx, hostID, checkID)
+}
+
+// PruneMetricSeries drops Prometheus series for hosts and checks that no
+// longer exist
+func (e *Engine) PruneMetricSeries(ctx context.Context) {
+    if err := e.metrics.PruneSeries(ctx); err != nil {
+        logrus.WithError(err).Warn("Failed to prune metric series")
+    }
+}
@@ -0,0 +1,82 @@
+// internal/monitoring/delete_test.go - Results that finish after a delete don't bring the host back
+package monitoring
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const deleteTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "default"
+    enabled: true
+checks:
+  - id: "ping-check"
+    name: "Ping"
+    type: "ping"
+    hosts: ["web-01"]
+    enabled: true
+`
+
+func TestDeletedHostStaysDeleted(t *testing.T) {
+    for _, batched := range []bool{false, true} {
+        name := map[bool]string{false: "direct writes", true: "batched writes"}[batched]
+        t.Run(name, func(t *testing.T) {
+            engine := newTestEngine(t, deleteTestConfig)
+            ctx := context.Background()
+            s := engine.scheduler
+            s.listeners = newListenerQueue(func(int) {})
+            defer s.listeners.close(time.Second)
+            if batched {
+                // Never flushed by itself, so a queued status waits for Close
+                s.batcher = database.NewStatusBatcher(engine.store, time.Hour, 100)
+            }
+
+            host, err := engine.store.GetHost(ctx, "web-01")
+            if err != nil {
+                t.Fatal(err)
+            }
+            check, err := engine.store.GetCheck(ctx, "ping-check")
+            if err != nil {
+                t.Fatal(err)
+            }
+            result := func(output string) *JobResult {
+                return &JobResult{
+                    Job:    &Job{HostID: host.ID, CheckID: check.ID, Host: host, Check: check},
+                    Result: &CheckResult{ExitCode: database.StateOK, Output: output},
+                }
+            }
+
+            // One result recorded (or queued) before the delete, one from a
+            // check that was still running when it happened
+            s.handleResult(result("before"))
+            if _, err := engine.DeleteHost(ctx, host.ID); err != nil {
+                t.Fatalf("DeleteHost: %v", err)
+            }
+            s.handleResult(result("after"))
+            if s.batcher != nil {
+                s.batcher.Close()
+            }
+
+            if status, err := engine.store.GetLatestStatus(ctx, host.ID, check.ID); err == nil {
+                t.Errorf("status after the delete = %q, want none", status.Output)
+            }
+            history, err := engine.store.GetStatusHistory(ctx, host.ID, check.ID, time.Time{})
+            if err != nil {
+                t.Fatal(err)
+            }
+            if len(history) > 0 {
+                t.Errorf("history after the delete = %d entries, want none", len(history))
+            }
+            if _, tracked := engine.GetStateDetail(host.ID, check.ID); tracked {
+                t.Error("tracked state came back after the delete")
+            }
+        })
+    }
+}
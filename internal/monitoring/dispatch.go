@@ -0,0 +1,70 @@
+// internal/monitoring/dispatch.go - Running result listeners off the result goroutine
+package monitoring
+
+import (
+    "sync/atomic"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+// listenerQueueSize is how many listener calls can wait before new ones are
+// dropped
+const listenerQueueSize = 1000
+
+// listenerDrainTimeout is how long Stop waits for queued listener calls
+// before leaving them behind
+const listenerDrainTimeout = 2 * time.Second
+
+// listenerQueue runs status and soft fail averted listeners, in order, on
+// a goroutine of its own, so a slow listener holds up neither status
+// recording nor shutdown
+type listenerQueue struct {
+    calls   chan func()
+    done    chan struct{} // Closed once run has returned
+    depth   func(int)     // Reports how many calls are waiting
+    dropped atomic.Uint64
+}
+
+func newListenerQueue(depth func(int)) *listenerQueue {
+    q := &listenerQueue{
+        calls: make(chan func(), listenerQueueSize),
+        done:  make(chan struct{}),
+        depth: depth,
+    }
+    go q.run()
+    return q
+}
+
+func (q *listenerQueue) run() {
+    defer close(q.done)
+
+    for call := range q.calls {
+        q.depth(len(q.calls))
+        call()
+    }
+    q.depth(0)
+}
+
+// add queues a listener call, dropping it when the queue is full. Only the
+// result goroutine adds calls.
+func (q *listenerQueue) add(call func()) {
+    select {
+    case q.calls <- call:
+    default:
+        if dropped := q.dropped.Add(1); dropped%100 == 1 {
+            logrus.WithField("dropped", dropped).Warn("Listener queue full, dropping result events")
+        }
+    }
+}
+
+// close stops taking calls and waits up to timeout for the queued ones
+func (q *listenerQueue) close(timeout time.Duration) {
+    close(q.calls)
+
+    select {
+    case <-q.done:
+    case <-time.After(timeout):
+        logrus.WithField("pending", len(q.calls)).Warn("Listeners still busy at shutdown, not waiting for them")
+    }
+}
@@ -0,0 +1,81 @@
+// internal/monitoring/dispatch_test.go - Slow listeners hold up neither statuses nor shutdown
+package monitoring
+
+import (
+    "context"
+    "fmt"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const dispatchTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "default"
+    enabled: true
+checks:
+  - id: "ping-check"
+    name: "Ping"
+    type: "ping"
+    hosts: ["web-01"]
+    enabled: true
+`
+
+func TestSlowListenerDoesNotBlock(t *testing.T) {
+    engine := newTestEngine(t, dispatchTestConfig)
+    ctx := context.Background()
+
+    host, err := engine.store.GetHost(ctx, "web-01")
+    if err != nil {
+        t.Fatal(err)
+    }
+    check, err := engine.store.GetCheck(ctx, "ping-check")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // A notifier stuck on a slow external API
+    release := make(chan struct{})
+    defer close(release)
+    engine.OnStatus(func(status *database.Status) {
+        select {
+        case <-release:
+        case <-time.After(5 * time.Second):
+        }
+    })
+
+    if err := engine.scheduler.Start(ctx); err != nil {
+        t.Fatal(err)
+    }
+    const results = 5
+    for i := 0; i < results; i++ {
+        engine.scheduler.resultQueue <- &JobResult{
+            Job:    &Job{HostID: host.ID, CheckID: check.ID, Host: host, Check: check},
+            Result: &CheckResult{ExitCode: i % 2, Output: fmt.Sprintf("result %d", i)},
+        }
+    }
+
+    // Every status is recorded while the listener is still on the first
+    last := fmt.Sprintf("result %d", results-1)
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        status, err := engine.store.GetLatestStatus(ctx, host.ID, check.ID)
+        if err == nil && status.Output == last {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("latest status behind a slow listener = %+v, want %q", status, last)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    stopping := time.Now()
+    engine.scheduler.Stop()
+    if took := time.Since(stopping); took > listenerDrainTimeout+time.Second {
+        t.Errorf("Stop took %s behind a slow listener, want at most %s", took.Round(time.Millisecond), listenerDrainTimeout)
+    }
+}
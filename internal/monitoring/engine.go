@@ -3,7 +3,10 @@ package monitoring
 
 import (
     "context"
+    "fmt"
+    "strings"
     "sync"
+    "sync/atomic"
     "time"
 
     "github.com/sirupsen/logrus"
@@ -13,20 +16,30 @@ import (
 )
 
 type Engine struct {
-    config    *config.Config
+    config    atomic.Pointer[config.Config] // Replaced whole on reload; read it through Config
     store     database.Store
     metrics   *metrics.Collector
     alertManager *SimpleAlertManager
     scheduler *Scheduler
     plugins   map[string]Plugin
+    pluginAvailability map[string]PluginAvailability // Probed when each plugin is registered
+    pluginsMu sync.RWMutex // Separate from mu so workers never wait on Start/Stop
+    coverage  *coverageIndex
+    snoozes   *snoozeSet
     mu        sync.RWMutex
     running   bool
+
+    listenersMu           sync.RWMutex
+    statusListeners       []func(*database.Status)
+    avertedListeners      []func(*SoftFailAverted)
+    snoozeListeners       []func(*SnoozeCleared)
+    reconfiguredListeners []func(*CheckReconfigured)
 }
 
 type Plugin interface {
     Name() string
     Init(options map[string]interface{}) error
-    Execute(ctx context.Context, host *database.Host) (*CheckResult, error)
+    Execute(ctx context.Context, host *database.Host, options map[string]interface{}) (*CheckResult, error)
 }
 
 type CheckResult struct {
@@ -39,18 +52,31 @@ type CheckResult struct {
 
 func NewEngine(cfg *config.Config, store database.Store, metricsCollector *metrics.Collector) (*Engine, error) {
     engine := &Engine{
-        config:  cfg,
         store:   store,
         metrics: metricsCollector,
         plugins: make(map[string]Plugin),
-        alertManager: NewSimpleAlertManager(store, cfg),
+        pluginAvailability: make(map[string]PluginAvailability),
+        coverage: newCoverageIndex(),
+        snoozes:  newSnoozeSet(),
     }
+    engine.config.Store(cfg)
+    engine.alertManager = NewSimpleAlertManager(store, engine.Config)
+    store.SetDedupWindow(cfg.Database.DedupWindow)
+    store.SetHistoryDedupe(cfg.Database.HistoryDedupe)
+    store.SetMaxOutputBytes(cfg.Monitoring.MaxOutputBytes)
+    engine.alertManager.snoozesExpired = engine.snoozesExpired
+    engine.loadSnoozes(context.Background())
 
     // Initialize plugins
     if err := engine.loadPlugins(); err != nil {
         return nil, err
     }
 
+    // Catch option typos before any check runs
+    if err := engine.validateConfiguredCheckOptions(); err != nil {
+        return nil, err
+    }
+
     // Initialize scheduler
     scheduler := NewScheduler(engine)
     engine.scheduler = scheduler
@@ -74,10 +100,12 @@ func (e *Engine) Start(ctx context.Context) error {
         logrus.WithError(err).Error("Failed to sync config")
         return err
     }
+    hostIDs, checkIDs := e.unconfiguredIDs(ctx)
+    e.warnDroppedIDs(ctx, hostIDs, checkIDs)
 
     purgeInterval := 6 * time.Hour
-    if e.config.Database.CleanupInterval > 0 {
-        purgeInterval = e.config.Database.CleanupInterval
+    if e.Config().Database.CleanupInterval > 0 {
+        purgeInterval = e.Config().Database.CleanupInterval
     }
     e.alertManager.SchedulePeriodicPurge(ctx, purgeInterval)
 
@@ -104,8 +132,11 @@ func (e *Engine) RefreshConfig() error {
 }
 
 func (e *Engine) syncConfig() error {
+    e.syncGroups()
+    e.migrateRenamedIDs(context.Background())
+
     // Sync hosts
-    for _, hostCfg := range e.config.Hosts {
+    for _, hostCfg := range e.Config().Hosts {
         host := &database.Host{
             ID:          hostCfg.ID,
             Name:        hostCfg.Name,
@@ -121,8 +152,8 @@ func (e *Engine) syncConfig() error {
         existing, err := e.store.GetHost(context.Background(), host.ID)
         if err != nil {
             // Host doesn't exist, create it
-            host.CreatedAt = time.Now()
-            host.UpdatedAt = time.Now()
+            host.CreatedAt = time.Now().UTC()
+            host.UpdatedAt = time.Now().UTC()
             if err := e.store.CreateHost(context.Background(), host); err != nil {
                 logrus.WithError(err).WithField("host", host.Name).Error("Failed to create host")
                 continue
@@ -137,7 +168,7 @@ func (e *Engine) syncConfig() error {
             existing.Group = host.Group
             existing.Enabled = host.Enabled
             existing.Tags = host.Tags
-            existing.UpdatedAt = time.Now()
+            existing.UpdatedAt = time.Now().UTC()
             
             if err := e.store.UpdateHost(context.Background(), existing); err != nil {
                 logrus.WithError(err).WithField("host", host.Name).Error("Failed to update host")
@@ -147,25 +178,33 @@ func (e *Engine) syncConfig() error {
     }
 
     // Sync checks
-    for _, checkCfg := range e.config.Checks {
+    for _, checkCfg := range e.Config().Checks {
         check := &database.Check{
-            ID:        checkCfg.ID,
-            Name:      checkCfg.Name,
-            Type:      checkCfg.Type,
-            Hosts:     checkCfg.Hosts,
-            Interval:  checkCfg.Interval,
-            Threshold: checkCfg.Threshold,
-            Timeout:   checkCfg.Timeout,
-            Enabled:   checkCfg.Enabled,
-            Options:   checkCfg.Options,
+            ID:              checkCfg.ID,
+            Name:            checkCfg.Name,
+            Type:            checkCfg.Type,
+            Hosts:           checkCfg.Hosts,
+            Interval:        checkCfg.Interval,
+            Threshold:       checkCfg.Threshold,
+            HostThresholds:  checkCfg.HostThresholds,
+            SoftFailEnabled: checkCfg.SoftFailEnabled,
+            SoftFailMode:    checkCfg.SoftFailMode,
+            Timeout:         checkCfg.Timeout,
+            Enabled:         checkCfg.Enabled,
+            Options:         checkCfg.Options,
+            Retention:       checkCfg.Retention,
+            ExitCodeMap:     checkCfg.ExitCodeMap,
+            Priority:        checkCfg.Priority,
+            ObserveOnly:     checkCfg.ObserveOnly,
+            Periods:         checkCfg.Periods,
         }
 
         // Try to get existing check
         existing, err := e.store.GetCheck(context.Background(), check.ID)
         if err != nil {
             // Check doesn't exist, create it
-            check.CreatedAt = time.Now()
-            check.UpdatedAt = time.Now()
+            check.CreatedAt = time.Now().UTC()
+            check.UpdatedAt = time.Now().UTC()
             if err := e.store.CreateCheck(context.Background(), check); err != nil {
                 logrus.WithError(err).WithField("check", check.Name).Error("Failed to create check")
                 continue
@@ -173,35 +212,137 @@ func (e *Engine) syncConfig() error {
             logrus.WithField("check", check.Name).Info("Created check")
         } else {
             // Update existing check
+            before := *existing
             existing.Name = check.Name
             existing.Type = check.Type
             existing.Hosts = check.Hosts
             existing.Interval = check.Interval
             existing.Threshold = check.Threshold
+            existing.HostThresholds = check.HostThresholds
+            existing.SoftFailEnabled = check.SoftFailEnabled
+            existing.SoftFailMode = check.SoftFailMode
             existing.Timeout = check.Timeout
             existing.Enabled = check.Enabled
             existing.Options = check.Options
-            existing.UpdatedAt = time.Now()
+            existing.Retention = check.Retention
+            existing.ExitCodeMap = check.ExitCodeMap
+            existing.Priority = check.Priority
+            existing.ObserveOnly = check.ObserveOnly
+            existing.Periods = check.Periods
+            existing.UpdatedAt = time.Now().UTC()
             
             if err := e.store.UpdateCheck(context.Background(), existing); err != nil {
                 logrus.WithError(err).WithField("check", check.Name).Error("Failed to update check")
                 continue
             }
+            e.CheckChanged(&before, existing)
         }
     }
 
+    e.syncSelfChecks()
+    e.RebuildCoverage(context.Background())
     return nil
 }
 
 func (e *Engine) loadPlugins() error {
     // Register built-in plugins
-    e.plugins["ping"] = &PingPlugin{}
-    e.plugins["nagios"] = &NagiosPlugin{}
+    e.registerPlugin(&PingPlugin{})
+    e.registerPlugin(&PingSweepPlugin{})
+    e.registerPlugin(&NagiosPlugin{pluginDir: e.Config().Server.PluginDir})
+    e.registerPlugin(&InternalPlugin{engine: e})
     
-    logrus.WithField("plugins", len(e.plugins)).Info("Loaded plugins")
+    logrus.WithField("plugins", len(e.GetPlugins())).Info("Loaded plugins")
     return nil
 }
 
+// GetStateDetail returns the scheduler's authoritative soft/hard state
+// for a host/check combination
+func (e *Engine) GetStateDetail(hostID, checkID string) (*StateDetail, bool) {
+    return e.scheduler.stateTracker.Get(fmt.Sprintf("%s:%s", hostID, checkID))
+}
+
+// GetHostStateDetails returns the scheduler's tracked state for every
+// check on a host, keyed by check ID
+func (e *Engine) GetHostStateDetails(hostID string) map[string]*StateDetail {
+    prefix := hostID + ":"
+    details := make(map[string]*StateDetail)
+
+    for key, detail := range e.scheduler.stateTracker.Snapshot() {
+        if strings.HasPrefix(key, prefix) {
+            details[strings.TrimPrefix(key, prefix)] = detail
+        }
+    }
+    return details
+}
+
+// ForgetHost drops the scheduler's tracked state for a deleted host, so a
+// host later created with the same ID starts fresh, and removes it from the
+// coverage index
+func (e *Engine) ForgetHost(hostID string) {
+    e.scheduler.stateTracker.forgetHost(hostID)
+    e.coverage.removeHost(hostID)
+}
+
+// RunSoon runs every check on a host, or every host of a check, without
+// waiting for its next interval, e.g. once it is enabled again. Pass an
+// empty ID for the side that doesn't apply, or both to run one host's check.
+func (e *Engine) RunSoon(hostID, checkID string) {
+    e.scheduler.runSoon(hostID, checkID)
+}
+
+// GetWorkerDebugInfo returns what each worker is running and has recently run
+func (e *Engine) GetWorkerDebugInfo() []WorkerDebugInfo {
+    return e.scheduler.WorkerDebugInfo()
+}
+
+// OnStatus registers a function called with every new check result, after
+// soft-fail handling and once the result has been handed to the store. With
+// database.batch_writes the status may still be waiting to be written, and
+// the write can fail and be retried, so a listener can see a result before
+// it is saved. Listeners run in order on their own goroutine; a slow one
+// delays later events, which are dropped once 1000 are waiting.
+func (e *Engine) OnStatus(listener func(*database.Status)) {
+    e.listenersMu.Lock()
+    defer e.listenersMu.Unlock()
+
+    e.statusListeners = append(e.statusListeners, listener)
+}
+
+func (e *Engine) notifyStatus(status *database.Status) {
+    e.listenersMu.RLock()
+    defer e.listenersMu.RUnlock()
+
+    for _, listener := range e.statusListeners {
+        listener(status)
+    }
+}
+
+// OnSoftFailAverted registers a function called when a failure recovers
+// before soft fail confirmed it. Listeners run in order with the OnStatus
+// listeners.
+func (e *Engine) OnSoftFailAverted(listener func(*SoftFailAverted)) {
+    e.listenersMu.Lock()
+    defer e.listenersMu.Unlock()
+
+    e.avertedListeners = append(e.avertedListeners, listener)
+}
+
+func (e *Engine) notifySoftFailAverted(averted *SoftFailAverted) {
+    e.listenersMu.RLock()
+    defer e.listenersMu.RUnlock()
+
+    for _, listener := range e.avertedListeners {
+        listener(averted)
+    }
+}
+
+// Config returns the running configuration. A reload swaps in a new one
+// rather than changing it, so callers that read several settings should
+// take it once.
+func (e *Engine) Config() *config.Config {
+    return e.config.Load()
+}
+
 func (e *Engine) GetAlertManager() *SimpleAlertManager {
     return e.alertManager
 }
@@ -217,11 +358,10 @@ func (e *Engine) RefreshConfigWithPurge() error {
         return err
     }
 
-    e.alertManager.config = e.config
-
     if err := e.alertManager.PurgeAll(ctx); err != nil {
         logrus.WithError(err).Warn("Alert purge completed with errors")
     }
+    e.RebuildCoverage(ctx)
 
     return nil
 }
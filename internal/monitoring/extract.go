@@ -0,0 +1,169 @@
+// internal/monitoring/extract.go - Pulling named values out of check output
+package monitoring
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/sirupsen/logrus"
+)
+
+// Types of an extracted value
+const (
+    ExtractNumber = "number"
+    ExtractString = "string"
+)
+
+// extractOptionSpec is offered by plugins whose output is free text
+var extractOptionSpec = OptionSpec{
+    Type:        OptionList,
+    Description: "Rules pulling values out of the output: name, regex, group (default 1) and type (number or string, default number)",
+}
+
+var extractNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Compiled regexes by source, so results don't recompile them
+var extractPatterns sync.Map
+
+// extractRule is one entry of the "extract" check option
+type extractRule struct {
+    name      string
+    pattern   *regexp.Regexp
+    group     int
+    valueType string
+}
+
+// parseExtractRules reads the "extract" option, compiling each regex.
+// A missing option gives no rules.
+func parseExtractRules(value interface{}) ([]extractRule, error) {
+    if value == nil {
+        return nil, nil
+    }
+    entries, ok := value.([]interface{})
+    if !ok {
+        return nil, fmt.Errorf("must be a list of rules")
+    }
+
+    rules := make([]extractRule, 0, len(entries))
+    seen := make(map[string]bool, len(entries))
+    for i, entry := range entries {
+        fields, ok := entry.(map[string]interface{})
+        if !ok {
+            return nil, fmt.Errorf("rule %d is not a mapping", i)
+        }
+
+        rule := extractRule{group: 1, valueType: ExtractNumber}
+
+        rule.name, _ = fields["name"].(string)
+        if !extractNamePattern.MatchString(rule.name) {
+            return nil, fmt.Errorf("rule %d has invalid name %q (letters, digits and underscores)", i, rule.name)
+        }
+        if seen[rule.name] {
+            return nil, fmt.Errorf("rule %d repeats name %s", i, rule.name)
+        }
+        seen[rule.name] = true
+
+        source, _ := fields["regex"].(string)
+        if source == "" {
+            return nil, fmt.Errorf("rule %s has no regex", rule.name)
+        }
+        pattern, err := compileExtractPattern(source)
+        if err != nil {
+            return nil, fmt.Errorf("rule %s has invalid regex: %w", rule.name, err)
+        }
+        rule.pattern = pattern
+
+        switch group := fields["group"].(type) {
+        case nil:
+            if pattern.NumSubexp() == 0 {
+                rule.group = 0
+            }
+        case int:
+            rule.group = group
+        case float64:
+            rule.group = int(group)
+        case string:
+            if rule.group = pattern.SubexpIndex(group); rule.group < 0 {
+                return nil, fmt.Errorf("rule %s has no group named %s", rule.name, group)
+            }
+        default:
+            return nil, fmt.Errorf("rule %s has invalid group %v", rule.name, group)
+        }
+        if rule.group < 0 || rule.group > pattern.NumSubexp() {
+            return nil, fmt.Errorf("rule %s has group %d but its regex has %d", rule.name, rule.group, pattern.NumSubexp())
+        }
+
+        if valueType, ok := fields["type"].(string); ok {
+            rule.valueType = valueType
+        }
+        if rule.valueType != ExtractNumber && rule.valueType != ExtractString {
+            return nil, fmt.Errorf("rule %s has invalid type %v (must be %s or %s)", rule.name, fields["type"], ExtractNumber, ExtractString)
+        }
+
+        rules = append(rules, rule)
+    }
+
+    return rules, nil
+}
+
+func compileExtractPattern(source string) (*regexp.Regexp, error) {
+    if cached, ok := extractPatterns.Load(source); ok {
+        return cached.(*regexp.Regexp), nil
+    }
+    pattern, err := regexp.Compile(source)
+    if err != nil {
+        return nil, err
+    }
+    extractPatterns.Store(source, pattern)
+    return pattern, nil
+}
+
+// validateExtractOption rejects "extract" rules that don't parse
+func validateExtractOption(options map[string]interface{}) error {
+    if _, err := parseExtractRules(options["extract"]); err != nil {
+        return fmt.Errorf("invalid extract: %w", err)
+    }
+    return nil
+}
+
+// extractValues applies a check's extraction rules to its output. A rule
+// that doesn't match, or matches something that isn't a number when one is
+// wanted, gives a nil value rather than failing the check.
+func extractValues(checkID string, options map[string]interface{}, output, longOutput string) map[string]interface{} {
+    rules, err := parseExtractRules(options["extract"])
+    if err != nil {
+        logrus.WithError(err).WithField("check", checkID).Warn("Skipping invalid extract rules")
+        return nil
+    }
+    if len(rules) == 0 {
+        return nil
+    }
+
+    text := output
+    if longOutput != "" {
+        text += "\n" + longOutput
+    }
+
+    values := make(map[string]interface{}, len(rules))
+    for _, rule := range rules {
+        values[rule.name] = nil
+
+        match := rule.pattern.FindStringSubmatch(text)
+        if match == nil {
+            continue
+        }
+        value := strings.TrimSpace(match[rule.group])
+
+        if rule.valueType == ExtractString {
+            values[rule.name] = value
+            continue
+        }
+        if number, err := strconv.ParseFloat(value, 64); err == nil {
+            values[rule.name] = number
+        }
+    }
+    return values
+}
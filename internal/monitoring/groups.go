@@ -0,0 +1,64 @@
+// internal/monitoring/groups.go - Keeping host group metadata in the store
+package monitoring
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// syncGroups saves the groups from the config (including those created for
+// hosts that reference an undefined group), so the config stays the source
+// of truth for their metadata
+func (e *Engine) syncGroups() {
+    ctx := context.Background()
+
+    for _, groupCfg := range e.Config().Groups {
+        group := &database.Group{
+            ID:          groupCfg.ID,
+            DisplayName: groupCfg.DisplayName,
+            Description: groupCfg.Description,
+            DefaultTags: groupCfg.DefaultTags,
+            SortOrder:   groupCfg.SortOrder,
+        }
+        if err := e.store.SaveGroup(ctx, group); err != nil {
+            logrus.WithError(err).WithField("group", group.ID).Error("Failed to save group")
+        }
+    }
+}
+
+// ApplyGroup prepares a host created or changed outside the config: its
+// group is created with defaults if it doesn't exist yet, and the group's
+// default tags are added where the host doesn't set them
+func (e *Engine) ApplyGroup(ctx context.Context, host *database.Host) error {
+    if host.Group == "" {
+        return nil
+    }
+
+    group, err := e.store.GetGroup(ctx, host.Group)
+    if err != nil {
+        group = &database.Group{
+            ID:          host.Group,
+            DisplayName: host.Group,
+        }
+        if err := e.store.SaveGroup(ctx, group); err != nil {
+            return fmt.Errorf("failed to create group %s: %w", host.Group, err)
+        }
+        logrus.WithField("group", host.Group).Info("Created group")
+    }
+
+    if len(group.DefaultTags) > 0 {
+        if host.Tags == nil {
+            host.Tags = make(map[string]string, len(group.DefaultTags))
+        }
+        for key, value := range group.DefaultTags {
+            if _, exists := host.Tags[key]; !exists {
+                host.Tags[key] = value
+            }
+        }
+    }
+
+    return nil
+}
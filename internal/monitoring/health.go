@@ -0,0 +1,110 @@
+// internal/monitoring/health.go - The engine's own view of whether it is keeping up
+package monitoring
+
+import (
+    "fmt"
+    "time"
+)
+
+// Engine health states
+const (
+    HealthHealthy  = "healthy"
+    HealthDegraded = "degraded"
+    HealthStopped  = "stopped"
+)
+
+// healthQueueLimit is how full a queue can get, as a fraction of its
+// capacity, before the engine reports itself degraded
+const healthQueueLimit = 0.9
+
+// EngineHealth is a snapshot of the scheduler, its workers and its queues
+type EngineHealth struct {
+    Status           string     `json:"status"` // "healthy", "degraded" or "stopped"
+    Problems         []string   `json:"problems,omitempty"`
+    Running          bool       `json:"running"`
+    Workers          int        `json:"workers"`
+    BusyWorkers      int        `json:"busy_workers"`
+    LastPass         *time.Time `json:"last_pass,omitempty"`    // When the last scheduling pass finished
+    LastPassDuration float64    `json:"last_pass_duration_ms"`
+    JobQueue         int        `json:"job_queue"`
+    JobQueueCapacity int        `json:"job_queue_capacity"`
+    ResultQueue      int        `json:"result_queue"`
+    ResultQueueCap   int        `json:"result_queue_capacity"`
+    LastStoreWrite   *time.Time `json:"last_store_write,omitempty"` // When a status was last written
+}
+
+// Health reports whether the scheduler is running, completing its passes
+// and keeping its queues clear. It is degraded when no pass has finished
+// within three ticks, or either queue is nearly full.
+func (e *Engine) Health() EngineHealth {
+    s := e.scheduler
+    now := time.Now()
+
+    s.mu.RLock()
+    running := s.running
+    workers := s.workers
+    startedAt := s.startedAt
+    lastPass, lastPassTook := s.lastPass, s.lastPassTook
+    results := s.resultQueue
+    batcher := s.batcher
+    s.mu.RUnlock()
+
+    health := EngineHealth{
+        Status:           HealthHealthy,
+        Running:          running,
+        Workers:          len(workers),
+        LastPassDuration: float64(lastPassTook.Microseconds()) / 1000,
+        JobQueue:         s.jobQueue.Len(),
+        JobQueueCapacity: s.jobQueue.Cap(),
+        ResultQueue:      len(results),
+        ResultQueueCap:   cap(results),
+    }
+    for _, worker := range workers {
+        if worker.busy() {
+            health.BusyWorkers++
+        }
+    }
+    if !lastPass.IsZero() {
+        passed := lastPass.UTC()
+        health.LastPass = &passed
+    }
+
+    lastWrite := time.Time{}
+    if batcher != nil {
+        lastWrite = batcher.LastFlush()
+    } else if nanos := s.lastWrite.Load(); nanos != 0 {
+        lastWrite = time.Unix(0, nanos)
+    }
+    if !lastWrite.IsZero() {
+        written := lastWrite.UTC()
+        health.LastStoreWrite = &written
+    }
+
+    if !running {
+        health.Status = HealthStopped
+        return health
+    }
+
+    // The first pass waits a full tick after Start
+    since := lastPass
+    if since.Before(startedAt) {
+        since = startedAt
+    }
+    if stalled := now.Sub(since); stalled > 3*scheduleTick {
+        health.Problems = append(health.Problems, fmt.Sprintf("no scheduling pass for %s", stalled.Round(time.Second)))
+    }
+    if nearlyFull(health.JobQueue, health.JobQueueCapacity) {
+        health.Problems = append(health.Problems, fmt.Sprintf("job queue %d/%d full", health.JobQueue, health.JobQueueCapacity))
+    }
+    if nearlyFull(health.ResultQueue, health.ResultQueueCap) {
+        health.Problems = append(health.Problems, fmt.Sprintf("result queue %d/%d full", health.ResultQueue, health.ResultQueueCap))
+    }
+    if len(health.Problems) > 0 {
+        health.Status = HealthDegraded
+    }
+    return health
+}
+
+func nearlyFull(length, capacity int) bool {
+    return capacity > 0 && float64(length) >= float64(capacity)*healthQueueLimit
+}
@@ -0,0 +1,48 @@
+// internal/monitoring/helpers_test.go - An engine over a temporary database for tests
+package monitoring
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/config"
+    "raven2/internal/database"
+    "raven2/internal/metrics"
+)
+
+// newTestEngine loads configYAML, with the database pointed at a temporary
+// directory, and builds a synced engine over it without starting it
+func newTestEngine(t *testing.T, configYAML string) *Engine {
+    t.Helper()
+
+    dir := t.TempDir()
+    path := filepath.Join(dir, "config.yaml")
+    document := fmt.Sprintf("database:\n  path: %q\nlogging:\n  level: error\n%s", filepath.Join(dir, "raven.db"), configYAML)
+    if err := os.WriteFile(path, []byte(document), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    cfg, err := config.Load(path)
+    if err != nil {
+        t.Fatalf("config.Load: %v", err)
+    }
+    store, err := database.NewExtendedBoltStore(cfg.Database.Path, cfg.Database.Files())
+    if err != nil {
+        t.Fatalf("NewExtendedBoltStore: %v", err)
+    }
+    t.Cleanup(func() { store.Close() })
+
+    logrus.SetLevel(logrus.ErrorLevel)
+
+    engine, err := NewEngine(cfg, store, metrics.NewCollector(store, cfg.Prometheus))
+    if err != nil {
+        t.Fatalf("NewEngine: %v", err)
+    }
+    if err := engine.RefreshConfig(); err != nil {
+        t.Fatalf("RefreshConfig: %v", err)
+    }
+    return engine
+}
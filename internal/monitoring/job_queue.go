@@ -0,0 +1,133 @@
+// internal/monitoring/job_queue.go - Priority queue feeding the workers
+package monitoring
+
+import (
+    "container/heap"
+    "sync"
+    "time"
+)
+
+// JobQueue hands out jobs highest priority first, FIFO within a priority.
+// Waiting jobs age: every aging interval in the queue counts as one more
+// priority level, so low priority jobs still run when the queue stays busy.
+type JobQueue struct {
+    mu       sync.Mutex
+    items    jobHeap
+    capacity int
+    seq      uint64
+    ready    chan struct{}
+    aging    time.Duration
+    epoch    time.Time
+}
+
+type queuedJob struct {
+    job  *Job
+    seq  uint64
+    rank float64 // Priority less the aging credit of a later arrival
+}
+
+// NewJobQueue creates a queue holding at most capacity jobs, ageing them one
+// priority level per aging interval
+func NewJobQueue(capacity int, aging time.Duration) *JobQueue {
+    return &JobQueue{
+        capacity: capacity,
+        ready:    make(chan struct{}, 1),
+        aging:    aging,
+        epoch:    time.Now(),
+    }
+}
+
+// Push queues a job, returning false if the queue is full
+func (q *JobQueue) Push(job *Job) bool {
+    q.mu.Lock()
+    if len(q.items) >= q.capacity {
+        q.mu.Unlock()
+        return false
+    }
+    q.seq++
+    job.QueuedAt = time.Now()
+
+    // Comparing priority + wait/aging between two jobs at any moment is the
+    // same as comparing priority - arrival/aging, which doesn't change while
+    // they wait, so the heap order holds
+    rank := float64(job.Priority)
+    if q.aging > 0 {
+        rank -= float64(job.QueuedAt.Sub(q.epoch)) / float64(q.aging)
+    }
+    heap.Push(&q.items, &queuedJob{job: job, seq: q.seq, rank: rank})
+    q.mu.Unlock()
+
+    q.signal()
+    return true
+}
+
+// Pop blocks until a job is available or quit is signalled
+func (q *JobQueue) Pop(quit <-chan bool) (*Job, bool) {
+    for {
+        q.mu.Lock()
+        if len(q.items) > 0 {
+            item := heap.Pop(&q.items).(*queuedJob)
+            more := len(q.items) > 0
+            q.mu.Unlock()
+
+            // Wake another waiting worker if work remains
+            if more {
+                q.signal()
+            }
+            return item.job, true
+        }
+        q.mu.Unlock()
+
+        select {
+        case <-q.ready:
+        case <-quit:
+            return nil, false
+        }
+    }
+}
+
+// Len returns the number of queued jobs
+func (q *JobQueue) Len() int {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return len(q.items)
+}
+
+// Cap returns the maximum number of queued jobs
+func (q *JobQueue) Cap() int {
+    return q.capacity
+}
+
+func (q *JobQueue) signal() {
+    select {
+    case q.ready <- struct{}{}:
+    default:
+    }
+}
+
+// jobHeap implements heap.Interface ordered by aged priority, then arrival
+type jobHeap []*queuedJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+    if h[i].rank != h[j].rank {
+        return h[i].rank > h[j].rank
+    }
+    return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) {
+    *h = append(*h, x.(*queuedJob))
+}
+
+func (h *jobHeap) Pop() interface{} {
+    old := *h
+    n := len(old)
+    item := old[n-1]
+    old[n-1] = nil
+    *h = old[:n-1]
+    return item
+}
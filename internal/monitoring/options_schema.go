@@ -0,0 +1,199 @@
+// internal/monitoring/options_schema.go - Per-plugin validation of check options
+package monitoring
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// OptionType is the expected type of a check option value
+type OptionType string
+
+const (
+    OptionString OptionType = "string"
+    OptionInt    OptionType = "int"
+    OptionNumber OptionType = "number"
+    OptionBool   OptionType = "bool"
+    OptionList   OptionType = "list"
+    OptionMap    OptionType = "map"
+)
+
+// OptionSpec describes a single check option
+type OptionSpec struct {
+    Type        OptionType `json:"type"`
+    Required    bool       `json:"required"`
+    Description string     `json:"description"`
+}
+
+// OptionsSchema maps option names to their specs
+type OptionsSchema map[string]OptionSpec
+
+// OptionsSchemaProvider is implemented by plugins that declare which options
+// they accept. Plugins without a schema accept any options.
+type OptionsSchemaProvider interface {
+    OptionsSchema() OptionsSchema
+}
+
+// Validate checks options against the schema, reporting every unknown,
+// missing or mistyped key at once
+func (schema OptionsSchema) Validate(options map[string]interface{}) error {
+    var unknown, missing, mistyped []string
+
+    for key, value := range options {
+        spec, ok := schema[key]
+        if !ok {
+            unknown = append(unknown, key)
+            continue
+        }
+        if !matchesOptionType(spec.Type, value) {
+            mistyped = append(mistyped, fmt.Sprintf("%s (expected %s)", key, spec.Type))
+        }
+    }
+
+    for key, spec := range schema {
+        if _, ok := options[key]; spec.Required && !ok {
+            missing = append(missing, key)
+        }
+    }
+
+    var problems []string
+    if len(unknown) > 0 {
+        sort.Strings(unknown)
+        problems = append(problems, fmt.Sprintf("unknown options: %s (valid: %s)",
+            strings.Join(unknown, ", "), strings.Join(schema.keys(), ", ")))
+    }
+    if len(missing) > 0 {
+        sort.Strings(missing)
+        problems = append(problems, "missing required options: "+strings.Join(missing, ", "))
+    }
+    if len(mistyped) > 0 {
+        sort.Strings(mistyped)
+        problems = append(problems, "invalid option types: "+strings.Join(mistyped, ", "))
+    }
+
+    if len(problems) > 0 {
+        return fmt.Errorf("%s", strings.Join(problems, "; "))
+    }
+    return nil
+}
+
+func (schema OptionsSchema) keys() []string {
+    keys := make([]string, 0, len(schema))
+    for key := range schema {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// matchesOptionType accepts the representations YAML and JSON decoding
+// produce; numbers may also be quoted strings
+func matchesOptionType(optionType OptionType, value interface{}) bool {
+    switch optionType {
+    case OptionString:
+        _, ok := value.(string)
+        return ok
+    case OptionBool:
+        _, ok := value.(bool)
+        return ok
+    case OptionInt:
+        switch v := value.(type) {
+        case int, int64:
+            return true
+        case float64:
+            return v == math.Trunc(v)
+        case string:
+            _, err := strconv.Atoi(v)
+            return err == nil
+        }
+        return false
+    case OptionNumber:
+        switch v := value.(type) {
+        case int, int64, float64:
+            return true
+        case string:
+            _, err := strconv.ParseFloat(v, 64)
+            return err == nil
+        }
+        return false
+    case OptionList:
+        switch value.(type) {
+        case []interface{}, []string:
+            return true
+        }
+        return false
+    case OptionMap:
+        _, ok := value.(map[string]interface{})
+        return ok
+    }
+    return true
+}
+
+// optionInt reads an int option in any form matchesOptionType accepts
+func optionInt(value interface{}) (int, bool) {
+    switch v := value.(type) {
+    case int:
+        return v, true
+    case int64:
+        return int(v), true
+    case float64:
+        if v == math.Trunc(v) {
+            return int(v), true
+        }
+    case string:
+        n, err := strconv.Atoi(v)
+        return n, err == nil
+    }
+    return 0, false
+}
+
+// ValidateCheckOptions validates options against the schema of the plugin
+// for checkType. Unknown check types and plugins without a schema pass.
+func (e *Engine) ValidateCheckOptions(checkType string, options map[string]interface{}) error {
+    plugin, exists := e.GetPlugin(checkType)
+    if !exists {
+        return nil
+    }
+
+    provider, ok := plugin.(OptionsSchemaProvider)
+    if !ok {
+        return nil
+    }
+
+    schema := provider.OptionsSchema()
+    if err := schema.Validate(options); err != nil {
+        return err
+    }
+    if _, ok := schema["target"]; ok {
+        if err := validateTargetOption(options); err != nil {
+            return err
+        }
+    }
+    if _, ok := schema["env"]; ok {
+        if err := validateCommandOptions(options); err != nil {
+            return err
+        }
+    }
+    if _, ok := schema["cidr"]; ok {
+        if _, err := parseSweepOptions(options); err != nil {
+            return err
+        }
+    }
+    if _, ok := schema["extract"]; ok {
+        return validateExtractOption(options)
+    }
+    return nil
+}
+
+// validateConfiguredCheckOptions validates every check in the loaded config
+func (e *Engine) validateConfiguredCheckOptions() error {
+    for _, check := range e.Config().Checks {
+        if err := e.ValidateCheckOptions(check.Type, check.Options); err != nil {
+            return fmt.Errorf("check '%s' has invalid options: %w", check.ID, err)
+        }
+    }
+    return nil
+}
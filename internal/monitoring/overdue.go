@@ -0,0 +1,69 @@
+// internal/monitoring/overdue.go - Spotting host/checks that haven't run as often as their interval expects
+package monitoring
+
+import (
+    "time"
+
+    "raven2/internal/database"
+)
+
+// OverdueSince reports whether a host/check has gone more than
+// monitoring.overdue_factor times its expected interval without a result,
+// and when it became overdue. The wait is measured from the later of its
+// last result and the scheduler's start, so checks still waiting for their
+// smeared first run after a restart aren't flagged. Disabled checks and
+// hosts, and checks outside their periods, are never overdue.
+func (e *Engine) OverdueSince(check *database.Check, host *database.Host, now time.Time) (time.Time, bool) {
+    if !check.Enabled || !host.Enabled || !check.InPeriod(now) {
+        return time.Time{}, false
+    }
+
+    s := e.scheduler
+    s.mu.RLock()
+    started := s.startedAt
+    s.mu.RUnlock()
+    if started.IsZero() {
+        return time.Time{}, false
+    }
+
+    s.stateTracker.mu.RLock()
+    info, exists := s.stateTracker.states[host.ID+":"+check.ID]
+    var state StateInfo
+    if exists {
+        state = *info
+    }
+    s.stateTracker.mu.RUnlock()
+    if !exists {
+        return time.Time{}, false // Not picked up by a scheduling pass yet
+    }
+
+    since := state.LastCheckTime
+    if since.Before(started) {
+        since = started
+    }
+    allowed := time.Duration(float64(s.checkInterval(check, &state)) * e.Config().Monitoring.OverdueFactor)
+    overdueAt := since.Add(allowed)
+    if now.Before(overdueAt) {
+        return time.Time{}, false
+    }
+    return overdueAt.UTC(), true
+}
+
+// HostOverdue returns when each overdue check on a host became overdue,
+// keyed by check ID. checks holds every check by ID, so callers listing
+// many hosts read them once.
+func (e *Engine) HostOverdue(host *database.Host, checks map[string]*database.Check) map[string]time.Time {
+    now := time.Now()
+    overdue := make(map[string]time.Time)
+
+    for checkID := range e.coverage.ChecksForHost(host.ID) {
+        check, ok := checks[checkID]
+        if !ok {
+            continue
+        }
+        if since, ok := e.OverdueSince(check, host, now); ok {
+            overdue[checkID] = since
+        }
+    }
+    return overdue
+}
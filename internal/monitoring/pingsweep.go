@@ -0,0 +1,290 @@
+// internal/monitoring/pingsweep.go - Counting the addresses that answer ping in a subnet
+package monitoring
+
+import (
+    "context"
+    "encoding/binary"
+    "fmt"
+    "net"
+    "os/exec"
+    "strings"
+    "sync"
+    "time"
+
+    "raven2/internal/database"
+)
+
+// maxSweepAddresses caps how many addresses one sweep may ping (a /20)
+const maxSweepAddresses = 4096
+
+// Ping sweep option defaults and limits
+const (
+    defaultSweepConcurrency = 32
+    maxSweepConcurrency     = 256
+    defaultSweepHostTimeout = time.Second
+)
+
+// sweepSettings are a ping sweep check's parsed options
+type sweepSettings struct {
+    network     *net.IPNet
+    minAlive    int // -1 = no lower bound
+    maxAlive    int // -1 = no upper bound
+    concurrency int
+    hostTimeout time.Duration
+}
+
+// PingSweepPlugin pings every address in an IPv4 subnet and checks how many
+// answer, to catch a whole segment going dark or filling up
+type PingSweepPlugin struct {
+    mu       sync.Mutex
+    previous map[string][]string // Addresses alive on the last sweep, by host ID and subnet
+}
+
+func (p *PingSweepPlugin) Name() string {
+    return "pingsweep"
+}
+
+func (p *PingSweepPlugin) Init(options map[string]interface{}) error {
+    return nil
+}
+
+// Probe looks for the ping binary on PATH
+func (p *PingSweepPlugin) Probe() (bool, string) {
+    path, err := exec.LookPath("ping")
+    if err != nil {
+        return false, "ping not found on PATH"
+    }
+    return true, path
+}
+
+func (p *PingSweepPlugin) Description() string {
+    return "Pings every address in an IPv4 subnet; critical when fewer than min_alive answer, warning when more than max_alive do"
+}
+
+func (p *PingSweepPlugin) ExampleOptions() map[string]interface{} {
+    return map[string]interface{}{
+        "cidr":      "10.0.5.0/24",
+        "min_alive": 40,
+        "max_alive": 60,
+    }
+}
+
+func (p *PingSweepPlugin) OptionsSchema() OptionsSchema {
+    return OptionsSchema{
+        "cidr":         {Type: OptionString, Required: true, Description: fmt.Sprintf("IPv4 subnet to sweep, at most %d addresses", maxSweepAddresses)},
+        "min_alive":    {Type: OptionInt, Description: "Critical when fewer addresses than this answer"},
+        "max_alive":    {Type: OptionInt, Description: "Warning when more addresses than this answer"},
+        "concurrency":  {Type: OptionInt, Description: fmt.Sprintf("Pings in flight at once (default %d, at most %d)", defaultSweepConcurrency, maxSweepConcurrency)},
+        "host_timeout": {Type: OptionString, Description: fmt.Sprintf("How long to wait for each address (default %s)", defaultSweepHostTimeout)},
+    }
+}
+
+// parseSweepOptions reads and checks a ping sweep's options; validation and
+// Execute share it
+func parseSweepOptions(options map[string]interface{}) (*sweepSettings, error) {
+    cidr, _ := options["cidr"].(string)
+    ip, network, err := net.ParseCIDR(cidr)
+    if err != nil {
+        return nil, fmt.Errorf("invalid cidr %q", cidr)
+    }
+    if ip.To4() == nil {
+        return nil, fmt.Errorf("cidr %s is not an IPv4 subnet", cidr)
+    }
+    if ones, bits := network.Mask.Size(); bits-ones > 12 {
+        return nil, fmt.Errorf("cidr %s covers more than %d addresses", cidr, maxSweepAddresses)
+    }
+
+    settings := &sweepSettings{
+        network:     network,
+        minAlive:    -1,
+        maxAlive:    -1,
+        concurrency: defaultSweepConcurrency,
+        hostTimeout: defaultSweepHostTimeout,
+    }
+    for name, target := range map[string]*int{
+        "min_alive":   &settings.minAlive,
+        "max_alive":   &settings.maxAlive,
+        "concurrency": &settings.concurrency,
+    } {
+        if value, ok := options[name]; ok {
+            n, ok := optionInt(value)
+            if !ok || n < 0 {
+                return nil, fmt.Errorf("invalid %s %v", name, value)
+            }
+            *target = n
+        }
+    }
+    if settings.concurrency < 1 || settings.concurrency > maxSweepConcurrency {
+        return nil, fmt.Errorf("concurrency must be 1-%d", maxSweepConcurrency)
+    }
+    if settings.minAlive >= 0 && settings.maxAlive >= 0 && settings.minAlive > settings.maxAlive {
+        return nil, fmt.Errorf("min_alive %d is above max_alive %d", settings.minAlive, settings.maxAlive)
+    }
+
+    if value, ok := options["host_timeout"].(string); ok {
+        timeout, err := time.ParseDuration(value)
+        if err != nil || timeout <= 0 {
+            return nil, fmt.Errorf("invalid host_timeout %q", value)
+        }
+        settings.hostTimeout = timeout
+    }
+
+    return settings, nil
+}
+
+// sweepAddresses lists a subnet's host addresses in order, leaving out the
+// network and broadcast addresses unless it is a /31 or /32
+func sweepAddresses(network *net.IPNet) []string {
+    ones, bits := network.Mask.Size()
+    start := binary.BigEndian.Uint32(network.IP.To4())
+    size := uint32(1) << uint(bits-ones)
+
+    first, last := start, start+size-1
+    if size > 2 {
+        first, last = first+1, last-1
+    }
+
+    addresses := make([]string, 0, last-first+1)
+    ip := make(net.IP, 4)
+    for n := first; n <= last; n++ {
+        binary.BigEndian.PutUint32(ip, n)
+        addresses = append(addresses, ip.String())
+    }
+    return addresses
+}
+
+func (p *PingSweepPlugin) Execute(ctx context.Context, host *database.Host, options map[string]interface{}) (*CheckResult, error) {
+    settings, err := parseSweepOptions(options)
+    if err != nil {
+        return &CheckResult{ExitCode: database.StateUnknown, Output: "Invalid options: " + err.Error()}, nil
+    }
+
+    addresses := sweepAddresses(settings.network)
+    alive := p.sweep(ctx, addresses, settings)
+    if ctx.Err() == context.DeadlineExceeded {
+        return nil, &TimeoutError{PartialOutput: fmt.Sprintf("%d addresses had answered when the sweep of %s was stopped", len(alive), settings.network)}
+    }
+
+    appeared, disappeared, first := p.compare(host.ID+" "+settings.network.String(), alive)
+
+    exitCode := database.StateOK
+    status := "OK"
+    switch {
+    case settings.minAlive >= 0 && len(alive) < settings.minAlive:
+        exitCode, status = database.StateCritical, "CRITICAL"
+    case settings.maxAlive >= 0 && len(alive) > settings.maxAlive:
+        exitCode, status = database.StateWarning, "WARNING"
+    }
+
+    output := fmt.Sprintf("PINGSWEEP %s - %d/%d addresses alive in %s%s", status, len(alive), len(addresses), settings.network, settings.expected())
+    if len(appeared) > 0 || len(disappeared) > 0 {
+        output += fmt.Sprintf(", %d appeared, %d disappeared", len(appeared), len(disappeared))
+    }
+
+    var long []string
+    if first {
+        long = append(long, "First sweep since startup")
+    } else {
+        long = append(long, "Appeared: "+joinOrNone(appeared), "Disappeared: "+joinOrNone(disappeared))
+    }
+    long = append(long, "Alive: "+joinOrNone(alive))
+
+    return &CheckResult{
+        ExitCode:   exitCode,
+        Output:     output,
+        PerfData:   fmt.Sprintf("alive_count=%d;;;0;%d", len(alive), len(addresses)),
+        LongOutput: strings.Join(long, "\n"),
+    }, nil
+}
+
+// expected describes the configured alive range for the output
+func (s *sweepSettings) expected() string {
+    switch {
+    case s.minAlive >= 0 && s.maxAlive >= 0:
+        return fmt.Sprintf(" (expected %d-%d)", s.minAlive, s.maxAlive)
+    case s.minAlive >= 0:
+        return fmt.Sprintf(" (expected at least %d)", s.minAlive)
+    case s.maxAlive >= 0:
+        return fmt.Sprintf(" (expected at most %d)", s.maxAlive)
+    }
+    return ""
+}
+
+// sweep pings the addresses, at most settings.concurrency at a time, and
+// returns those that answered in address order. It stops starting pings
+// once ctx is done.
+func (p *PingSweepPlugin) sweep(ctx context.Context, addresses []string, settings *sweepSettings) []string {
+    answered := make([]bool, len(addresses))
+    slots := make(chan struct{}, settings.concurrency)
+    var wg sync.WaitGroup
+
+sweep:
+    for i, address := range addresses {
+        select {
+        case <-ctx.Done():
+            break sweep
+        case slots <- struct{}{}:
+        }
+
+        wg.Add(1)
+        go func(i int, address string) {
+            defer wg.Done()
+            defer func() { <-slots }()
+
+            pingCtx, cancel := context.WithTimeout(ctx, settings.hostTimeout)
+            defer cancel()
+            answered[i] = exec.CommandContext(pingCtx, "ping", "-c", "1", address).Run() == nil
+        }(i, address)
+    }
+    wg.Wait()
+
+    var alive []string
+    for i, ok := range answered {
+        if ok {
+            alive = append(alive, addresses[i])
+        }
+    }
+    return alive
+}
+
+// compare records this sweep's alive addresses and returns how they differ
+// from the previous sweep of the same host and subnet. first is true when
+// there was none since startup.
+func (p *PingSweepPlugin) compare(key string, alive []string) (appeared, disappeared []string, first bool) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if p.previous == nil {
+        p.previous = make(map[string][]string)
+    }
+    previous, seen := p.previous[key]
+    p.previous[key] = alive
+    if !seen {
+        return nil, nil, true
+    }
+
+    before := make(map[string]bool, len(previous))
+    for _, address := range previous {
+        before[address] = true
+    }
+    now := make(map[string]bool, len(alive))
+    for _, address := range alive {
+        now[address] = true
+        if !before[address] {
+            appeared = append(appeared, address)
+        }
+    }
+    for _, address := range previous {
+        if !now[address] {
+            disappeared = append(disappeared, address)
+        }
+    }
+    return appeared, disappeared, false
+}
+
+func joinOrNone(addresses []string) string {
+    if len(addresses) == 0 {
+        return "none"
+    }
+    return strings.Join(addresses, ", ")
+}
@@ -0,0 +1,96 @@
+// internal/monitoring/plugin_registry.go - Lookup and discovery of registered check plugins
+package monitoring
+
+import (
+    "sort"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+// PluginDescriber is implemented by plugins that can explain themselves
+// to operators in GET /api/plugins
+type PluginDescriber interface {
+    Description() string
+    ExampleOptions() map[string]interface{}
+}
+
+// PluginProber is implemented by plugins that depend on something outside
+// Raven, such as a system binary. Probe runs once, when the plugin is
+// registered at startup.
+type PluginProber interface {
+    Probe() (available bool, detail string)
+}
+
+// PluginAvailability is the cached result of probing a plugin's dependency.
+// Plugins without one are always available.
+type PluginAvailability struct {
+    Available bool       `json:"available"`
+    Detail    string     `json:"availability_detail,omitempty"` // What was found, or what is missing
+    ProbedAt  *time.Time `json:"probed_at,omitempty"`
+}
+
+// PluginInfo describes a registered check type
+type PluginInfo struct {
+    Name           string                 `json:"name"`
+    Description    string                 `json:"description,omitempty"`
+    Options        OptionsSchema          `json:"options,omitempty"`
+    ExampleOptions map[string]interface{} `json:"example_options,omitempty"`
+    PluginAvailability
+}
+
+// GetPlugin returns the plugin registered for a check type
+func (e *Engine) GetPlugin(checkType string) (Plugin, bool) {
+    e.pluginsMu.RLock()
+    defer e.pluginsMu.RUnlock()
+
+    plugin, exists := e.plugins[checkType]
+    return plugin, exists
+}
+
+// GetPlugins describes every registered plugin, sorted by name
+func (e *Engine) GetPlugins() []PluginInfo {
+    e.pluginsMu.RLock()
+    defer e.pluginsMu.RUnlock()
+
+    plugins := make([]PluginInfo, 0, len(e.plugins))
+    for name, plugin := range e.plugins {
+        info := PluginInfo{Name: name, PluginAvailability: e.pluginAvailability[name]}
+        if describer, ok := plugin.(PluginDescriber); ok {
+            info.Description = describer.Description()
+            info.ExampleOptions = describer.ExampleOptions()
+        }
+        if provider, ok := plugin.(OptionsSchemaProvider); ok {
+            info.Options = provider.OptionsSchema()
+        }
+        plugins = append(plugins, info)
+    }
+
+    sort.Slice(plugins, func(i, j int) bool {
+        return plugins[i].Name < plugins[j].Name
+    })
+    return plugins
+}
+
+// registerPlugin adds a plugin under its check type name, probing its
+// dependency if it has one
+func (e *Engine) registerPlugin(plugin Plugin) {
+    availability := PluginAvailability{Available: true}
+    if prober, ok := plugin.(PluginProber); ok {
+        now := time.Now().UTC()
+        availability.Available, availability.Detail = prober.Probe()
+        availability.ProbedAt = &now
+        if !availability.Available {
+            logrus.WithFields(logrus.Fields{
+                "plugin": plugin.Name(),
+                "detail": availability.Detail,
+            }).Warn("Plugin dependency not found")
+        }
+    }
+
+    e.pluginsMu.Lock()
+    defer e.pluginsMu.Unlock()
+
+    e.plugins[plugin.Name()] = plugin
+    e.pluginAvailability[plugin.Name()] = availability
+}
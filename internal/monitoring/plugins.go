@@ -4,9 +4,11 @@ package monitoring
 import (
     "context"
     "fmt"
+    "os"
     "os/exec"
     "regexp"
     "strconv"
+    "strings"
 
     "raven2/internal/database"
 )
@@ -22,23 +24,42 @@ func (p *PingPlugin) Init(options map[string]interface{}) error {
     return nil
 }
 
-func (p *PingPlugin) Execute(ctx context.Context, host *database.Host) (*CheckResult, error) {
-    target := host.IPv4
-    if target == "" {
-        target = host.Hostname
+// Probe looks for the ping binary on PATH
+func (p *PingPlugin) Probe() (bool, string) {
+    path, err := exec.LookPath("ping")
+    if err != nil {
+        return false, "ping not found on PATH"
     }
-    if target == "" {
-        return &CheckResult{
-            ExitCode:   3,
-            Output:     "No IP address or hostname configured",
-            PerfData:   "",
-            LongOutput: "",
-        }, nil
+    return true, path
+}
+
+func (p *PingPlugin) Description() string {
+    return "ICMP echo to the host's IPv4 address or hostname (see the target option); warns on loss or slow round trips"
+}
+
+func (p *PingPlugin) ExampleOptions() map[string]interface{} {
+    return map[string]interface{}{"count": 3}
+}
+
+func (p *PingPlugin) OptionsSchema() OptionsSchema {
+    return OptionsSchema{
+        "count":  {Type: OptionInt, Description: "Number of echo requests to send"},
+        "target": targetOptionSpec,
+    }
+}
+
+func (p *PingPlugin) Execute(ctx context.Context, host *database.Host, options map[string]interface{}) (*CheckResult, error) {
+    target, unknown := resolveTarget(host, options)
+    if unknown != nil {
+        return unknown, nil
     }
 
     cmd := exec.CommandContext(ctx, "ping", "-c", "3", target)
-    output, err := cmd.Output()
+    output, err := commandOutput(ctx, cmd)
 
+    if _, timedOut := err.(*TimeoutError); timedOut {
+        return nil, err
+    }
     if err != nil {
         return &CheckResult{
             ExitCode:   2,
@@ -91,7 +112,9 @@ func (p *PingPlugin) Execute(ctx context.Context, host *database.Host) (*CheckRe
 }
 
 // NagiosPlugin executes Nagios-compatible check plugins
-type NagiosPlugin struct{}
+type NagiosPlugin struct {
+    pluginDir string // server.plugin_dir, probed at startup
+}
 
 func (p *NagiosPlugin) Name() string {
     return "nagios"
@@ -101,13 +124,90 @@ func (p *NagiosPlugin) Init(options map[string]interface{}) error {
     return nil
 }
 
-func (p *NagiosPlugin) Execute(ctx context.Context, host *database.Host) (*CheckResult, error) {
-    // This would be implemented based on your existing nagios plugin logic
-    // For now, return a placeholder
+// Probe checks that the plugin directory (server.plugin_dir, or the usual
+// /usr/lib/nagios/plugins) can be read. Programs can live anywhere, so each
+// check's program is checked separately in GET /api/plugins.
+func (p *NagiosPlugin) Probe() (bool, string) {
+    dir := p.pluginDir
+    if dir == "" {
+        dir = "/usr/lib/nagios/plugins"
+    }
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return false, fmt.Sprintf("plugin directory %s is not readable: %v", dir, err)
+    }
+    return true, fmt.Sprintf("%s (%d entries)", dir, len(entries))
+}
+
+func (p *NagiosPlugin) Description() string {
+    return "Runs a Nagios-compatible plugin and maps its exit code to the check state"
+}
+
+func (p *NagiosPlugin) ExampleOptions() map[string]interface{} {
+    return map[string]interface{}{
+        "program": "/usr/lib/nagios/plugins/check_disk",
+        "options": []string{"-w", "20%", "-c", "10%"},
+    }
+}
+
+func (p *NagiosPlugin) OptionsSchema() OptionsSchema {
+    return OptionsSchema{
+        "program": {Type: OptionString, Required: true, Description: "Path to the Nagios plugin executable"},
+        "options": {Type: OptionList, Description: "Arguments passed to the plugin"},
+        "target":  targetOptionSpec,
+        "env":     envOptionSpec,
+        "cwd":     cwdOptionSpec,
+        "extract": extractOptionSpec,
+    }
+}
+
+func (p *NagiosPlugin) Execute(ctx context.Context, host *database.Host, options map[string]interface{}) (*CheckResult, error) {
+    target, unknown := resolveTarget(host, options)
+    if unknown != nil {
+        return unknown, nil
+    }
+
+    program, _ := options["program"].(string)
+    if program == "" {
+        return &CheckResult{ExitCode: database.StateUnknown, Output: "No plugin program configured"}, nil
+    }
+
+    // $HOSTADDRESS$ and $HOSTNAME$ expand as they would under Nagios
+    macros := strings.NewReplacer("$HOSTADDRESS$", target, "$HOSTNAME$", host.Name)
+    var args []string
+    switch list := options["options"].(type) {
+    case []interface{}:
+        for _, arg := range list {
+            args = append(args, macros.Replace(fmt.Sprint(arg)))
+        }
+    case []string:
+        for _, arg := range list {
+            args = append(args, macros.Replace(arg))
+        }
+    }
+
+    cmd := pluginCommand(ctx, program, args, options)
+    output, err := commandOutput(ctx, cmd)
+    if _, timedOut := err.(*TimeoutError); timedOut {
+        return nil, err
+    }
+
+    exitCode, err := pluginExitCode(err)
+    if err != nil {
+        return &CheckResult{
+            ExitCode: database.StateUnknown,
+            Output:   fmt.Sprintf("Failed to run %s: %v", program, err),
+        }, nil
+    }
+
+    text, perfData, longOutput := parsePluginOutput(string(output))
+    if text == "" {
+        text = fmt.Sprintf("%s returned no output", program)
+    }
     return &CheckResult{
-        ExitCode:   0,
-        Output:     "Nagios check OK",
-        PerfData:   "",
-        LongOutput: "Nagios plugin executed successfully",
+        ExitCode:   exitCode,
+        Output:     text,
+        PerfData:   perfData,
+        LongOutput: longOutput,
     }, nil
 }
@@ -0,0 +1,146 @@
+// internal/monitoring/reconfigure.go - Resetting tracked state when a check starts checking something else
+package monitoring
+
+import (
+    "encoding/json"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// What changed about a reconfigured check
+const (
+    ChangedType         = "type"
+    ChangedOptions      = "options"
+    ChangedHostsRemoved = "hosts removed"
+)
+
+// CheckReconfigured is passed to OnCheckReconfigured listeners when a
+// check's tracked state is reset because what it checks changed
+type CheckReconfigured struct {
+    CheckID      string    `json:"check_id"`
+    CheckName    string    `json:"check_name"`
+    Changes      []string  `json:"changes"`                 // ChangedType, ChangedOptions, ChangedHostsRemoved
+    ResetHosts   []string  `json:"reset_hosts,omitempty"`   // Back to unknown, waiting for a new first result
+    RemovedHosts []string  `json:"removed_hosts,omitempty"` // No longer listed; their state is dropped
+    Timestamp    time.Time `json:"timestamp"`
+}
+
+// MaterialChanges compares a check before and after an update and returns
+// the changes that make its earlier results meaningless: a new type or new
+// options, which reset every host's state, and hosts it no longer lists,
+// whose state is dropped. Name, interval, threshold and other tuning keep
+// state. The API, inventory import and config sync all go through here.
+func MaterialChanges(before, after *database.Check) (changes []string, removedHosts []string) {
+    if before.Type != after.Type {
+        changes = append(changes, ChangedType)
+    }
+    if !sameOptions(before.Options, after.Options) {
+        changes = append(changes, ChangedOptions)
+    }
+
+    listed := make(map[string]bool, len(after.Hosts))
+    for _, hostID := range after.Hosts {
+        listed[hostID] = true
+    }
+    for _, hostID := range before.Hosts {
+        if !listed[hostID] {
+            removedHosts = append(removedHosts, hostID)
+        }
+    }
+    if len(removedHosts) > 0 {
+        changes = append(changes, ChangedHostsRemoved)
+    }
+    return changes, removedHosts
+}
+
+// sameOptions compares options by their JSON form, so a 5 read from YAML
+// matches the 5.0 the store decodes
+func sameOptions(a, b map[string]interface{}) bool {
+    if len(a) == 0 && len(b) == 0 {
+        return true
+    }
+    aJSON, aErr := json.Marshal(a)
+    bJSON, bErr := json.Marshal(b)
+    return aErr == nil && bErr == nil && string(aJSON) == string(bJSON)
+}
+
+// CheckChanged applies a check update to the scheduler's tracked state. On
+// a material change (see MaterialChanges) the check's hosts go back to
+// unknown with their soft fail counters cleared and run straight away, and
+// removed hosts are forgotten; otherwise state is kept as is.
+func (e *Engine) CheckChanged(before, after *database.Check) {
+    changes, removedHosts := MaterialChanges(before, after)
+    if len(changes) == 0 {
+        return
+    }
+
+    now := time.Now().UTC()
+    tracker := e.scheduler.stateTracker
+    event := &CheckReconfigured{
+        CheckID:      after.ID,
+        CheckName:    after.Name,
+        Changes:      changes,
+        RemovedHosts: removedHosts,
+        Timestamp:    now,
+    }
+
+    // Removed hosts alone leave the remaining hosts' state alone
+    resetAll := false
+    for _, change := range changes {
+        resetAll = resetAll || change != ChangedHostsRemoved
+    }
+
+    tracker.mu.Lock()
+    for _, hostID := range removedHosts {
+        delete(tracker.states, hostID+":"+after.ID)
+    }
+    if resetAll {
+        for _, hostID := range after.Hosts {
+            if info, exists := tracker.states[hostID+":"+after.ID]; exists {
+                info.reset(now)
+                event.ResetHosts = append(event.ResetHosts, hostID)
+            }
+        }
+    }
+    tracker.mu.Unlock()
+
+    logrus.WithFields(logrus.Fields{
+        "check":         after.ID,
+        "changes":       changes,
+        "reset_hosts":   len(event.ResetHosts),
+        "removed_hosts": len(removedHosts),
+    }).Info("Check reconfigured")
+
+    if len(event.ResetHosts) > 0 && after.Enabled {
+        e.RunSoon("", after.ID)
+    }
+
+    e.listenersMu.RLock()
+    defer e.listenersMu.RUnlock()
+    for _, listener := range e.reconfiguredListeners {
+        listener(event)
+    }
+}
+
+// reset puts a tracked state back to unknown, as if the check had never
+// reported, keeping its soft fail settings. Callers must hold the tracker
+// lock.
+func (info *StateInfo) reset(now time.Time) {
+    info.CurrentState = database.StateUnknown
+    info.PendingState = database.StateUnknown
+    info.ConsecutiveCount = 0
+    info.PendingSince = now
+    info.LastStateChange = now
+    info.OutputHash = ""
+}
+
+// OnCheckReconfigured registers a function called when a check's state is
+// reset by a material change. Listeners must not block.
+func (e *Engine) OnCheckReconfigured(listener func(*CheckReconfigured)) {
+    e.listenersMu.Lock()
+    defer e.listenersMu.Unlock()
+
+    e.reconfiguredListeners = append(e.reconfiguredListeners, listener)
+}
@@ -0,0 +1,100 @@
+// internal/monitoring/reload.go - Switching to a config re-read from disk
+package monitoring
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+// ReloadFromDisk re-reads the file the running config was loaded from,
+// along with its includes, and switches to it with ReloadConfig
+func (e *Engine) ReloadFromDisk() (*config.Changes, error) {
+    path := e.Config().Path()
+    if path == "" {
+        return nil, fmt.Errorf("the running configuration was not loaded from a file")
+    }
+
+    cfg, err := config.Load(path)
+    if err != nil {
+        return nil, err
+    }
+    return e.ReloadConfig(cfg)
+}
+
+// ReloadConfig switches the engine to a freshly loaded config. Hosts, checks
+// and groups dropped from it are deleted, then everything else is synced as
+// on startup. The web server and alert manager read the config through
+// Config, so they see the new one too.
+func (e *Engine) ReloadConfig(cfg *config.Config) (*config.Changes, error) {
+    for _, check := range cfg.Checks {
+        if err := e.ValidateCheckOptions(check.Type, check.Options); err != nil {
+            return nil, fmt.Errorf("check '%s' has invalid options: %w", check.ID, err)
+        }
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    ctx := context.Background()
+    changes := config.Diff(e.Config(), cfg)
+    e.warnDroppedIDs(ctx, changes.Hosts.Removed, changes.Checks.Removed)
+
+    // Checks before hosts so nothing is left pointing at a deleted host.
+    // Their results, tracked state and metric series go with them.
+    for _, id := range changes.Checks.Removed {
+        if err := e.DeleteCheck(ctx, id); err != nil {
+            return changes, fmt.Errorf("failed to delete check %s: %w", id, err)
+        }
+    }
+    for _, id := range changes.Hosts.Removed {
+        if _, err := e.DeleteHost(ctx, id); err != nil {
+            return changes, fmt.Errorf("failed to delete host %s: %w", id, err)
+        }
+    }
+    if len(changes.Hosts.Removed) > 0 {
+        // Checks added through the API may still list them
+        if _, err := e.store.RemoveHostsFromChecks(ctx, changes.Hosts.Removed); err != nil {
+            return changes, fmt.Errorf("failed to remove deleted hosts from checks: %w", err)
+        }
+    }
+
+    e.config.Store(cfg)
+    e.store.SetDedupWindow(cfg.Database.DedupWindow)
+    e.store.SetHistoryDedupe(cfg.Database.HistoryDedupe)
+    e.store.SetMaxOutputBytes(cfg.Monitoring.MaxOutputBytes)
+
+    if err := e.syncConfig(); err != nil {
+        return changes, err
+    }
+
+    // Hosts added through the API may still use a group the file dropped
+    for _, id := range changes.Groups.Removed {
+        hosts, err := e.store.GetHosts(ctx, database.HostFilters{Group: id})
+        if err != nil {
+            return changes, fmt.Errorf("failed to get hosts for group %s: %w", id, err)
+        }
+        if len(hosts) > 0 {
+            logrus.WithField("group", id).Info("Keeping group removed from config, it still has hosts")
+            continue
+        }
+        if err := e.store.DeleteGroup(ctx, id); err != nil && err.Error() != "group not found" {
+            return changes, fmt.Errorf("failed to delete group %s: %w", id, err)
+        }
+    }
+
+    if len(changes.Hosts.Removed) > 0 || len(changes.Checks.Removed) > 0 {
+        e.PruneMetricSeries(ctx)
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "hosts":            len(cfg.Hosts),
+        "checks":           len(cfg.Checks),
+        "restart_required": changes.RestartRequired,
+    }).Info("Reloaded configuration")
+
+    return changes, nil
+}
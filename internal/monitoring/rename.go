@@ -0,0 +1,134 @@
+// internal/monitoring/rename.go - Carrying records and history over when a configured ID changes
+package monitoring
+
+import (
+    "context"
+    "strings"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// migrateRenamedIDs moves hosts and checks stored under one of their
+// previous_ids to their configured ID, along with their results and tracked
+// state. Nothing moves when the configured ID already exists, so a rename is
+// applied once and stale previous_ids are harmless.
+func (e *Engine) migrateRenamedIDs(ctx context.Context) {
+    for _, hostCfg := range e.Config().Hosts {
+        for _, oldID := range hostCfg.PreviousIDs {
+            if _, err := e.store.GetHost(ctx, oldID); err != nil {
+                continue
+            }
+            logger := logrus.WithFields(logrus.Fields{"from": oldID, "to": hostCfg.ID})
+            if _, err := e.store.GetHost(ctx, hostCfg.ID); err == nil {
+                logger.Warn("Not renaming host, the new ID already exists; the old one is kept until it is purged")
+                continue
+            }
+            if err := e.store.RenameHost(ctx, oldID, hostCfg.ID); err != nil {
+                logger.WithError(err).Error("Failed to rename host")
+                continue
+            }
+            e.scheduler.stateTracker.renameHost(oldID, hostCfg.ID)
+            logger.Info("Renamed host")
+            break
+        }
+    }
+
+    for _, checkCfg := range e.Config().Checks {
+        for _, oldID := range checkCfg.PreviousIDs {
+            if _, err := e.store.GetCheck(ctx, oldID); err != nil {
+                continue
+            }
+            logger := logrus.WithFields(logrus.Fields{"from": oldID, "to": checkCfg.ID})
+            if _, err := e.store.GetCheck(ctx, checkCfg.ID); err == nil {
+                logger.Warn("Not renaming check, the new ID already exists; the old one is kept until it is purged")
+                continue
+            }
+            if err := e.store.RenameCheck(ctx, oldID, checkCfg.ID); err != nil {
+                logger.WithError(err).Error("Failed to rename check")
+                continue
+            }
+            e.scheduler.stateTracker.renameCheck(oldID, checkCfg.ID)
+            logger.Info("Renamed check")
+            break
+        }
+    }
+}
+
+// warnDroppedIDs logs the hosts and checks among the given IDs that still
+// have results. Without a previous_ids entry pointing at them, a new ID
+// starts with an empty history and theirs goes when they are deleted.
+func (e *Engine) warnDroppedIDs(ctx context.Context, hostIDs, checkIDs []string) {
+    for _, id := range hostIDs {
+        statuses, err := e.store.GetStatus(ctx, database.StatusFilters{HostID: id, Limit: 1})
+        if err == nil && len(statuses) > 0 {
+            logrus.WithField("host", id).Warn("Host was removed from the configuration but has history; list it in previous_ids if it was renamed")
+        }
+    }
+    for _, id := range checkIDs {
+        statuses, err := e.store.GetStatus(ctx, database.StatusFilters{CheckID: id, Limit: 1})
+        if err == nil && len(statuses) > 0 {
+            logrus.WithField("check", id).Warn("Check was removed from the configuration but has history; list it in previous_ids if it was renamed")
+        }
+    }
+}
+
+// unconfiguredIDs returns the stored hosts and checks that no configured
+// entry, previous_ids list or self check accounts for
+func (e *Engine) unconfiguredIDs(ctx context.Context) (hostIDs, checkIDs []string) {
+    known := e.Config().ConfiguredIDs()
+    if e.Config().Monitoring.SelfChecks.Enabled {
+        known.Hosts[SelfHostID] = true
+    }
+    for _, id := range selfCheckIDs(e.Config().Monitoring.SelfChecks) {
+        known.Checks[id] = true
+    }
+
+    if hosts, err := e.store.GetHosts(ctx, database.HostFilters{}); err == nil {
+        for _, host := range hosts {
+            if !known.Hosts[host.ID] {
+                hostIDs = append(hostIDs, host.ID)
+            }
+        }
+    }
+    if checks, err := e.store.GetChecks(ctx); err == nil {
+        for _, check := range checks {
+            if !known.Checks[check.ID] {
+                checkIDs = append(checkIDs, check.ID)
+            }
+        }
+    }
+    return hostIDs, checkIDs
+}
+
+// renameHost re-keys tracked states after a host's ID changed
+func (st *StateTracker) renameHost(oldID, newID string) {
+    st.rekey(func(key string) (string, bool) {
+        checkID, ok := strings.CutPrefix(key, oldID+":")
+        return newID + ":" + checkID, ok
+    })
+}
+
+// renameCheck re-keys tracked states after a check's ID changed
+func (st *StateTracker) renameCheck(oldID, newID string) {
+    st.rekey(func(key string) (string, bool) {
+        hostID, ok := strings.CutSuffix(key, ":"+oldID)
+        return hostID + ":" + newID, ok
+    })
+}
+
+func (st *StateTracker) rekey(rename func(key string) (string, bool)) {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    renamed := make(map[string]*StateInfo)
+    for key, info := range st.states {
+        if newKey, ok := rename(key); ok {
+            delete(st.states, key)
+            renamed[newKey] = info
+        }
+    }
+    for key, info := range renamed {
+        st.states[key] = info
+    }
+}
@@ -3,8 +3,11 @@ package monitoring
 
 import (
     "context"
+    "hash/fnv"
     "math/rand"
+    "strings"
     "sync"
+    "sync/atomic"
     "time"
     "fmt"
 
@@ -14,12 +17,25 @@ import (
 
 type Scheduler struct {
     engine       *Engine
-    jobQueue     chan *Job
+    jobQueue     *JobQueue
     resultQueue  chan *JobResult
+    resultsDone  chan struct{} // Closed once processResults has drained resultQueue
+    listeners    *listenerQueue // Runs status and soft fail averted listeners
     workers      []*Worker
     running      bool
     mu           sync.RWMutex
     stateTracker *StateTracker // Track state changes for soft fails
+    batcher      *database.StatusBatcher // Optional write-behind status batching
+    recordMu     sync.Mutex              // Held while a result is recorded, so deletes can't interleave
+    jobsDeferred atomic.Uint64           // Total jobs deferred because the queue was full
+    wake         chan struct{}           // Runs the next scheduling pass straight away
+    dueHosts     map[string]bool         // Hosts whose checks run on the next pass, guarded by mu
+    dueChecks    map[string]bool         // Checks that run on the next pass, guarded by mu
+    duePairs     map[string]bool         // "hostID:checkID" pairs that run on the next pass, guarded by mu
+    startedAt    time.Time               // When Start last ran, guarded by mu
+    lastPass     time.Time               // When the last scheduling pass finished, guarded by mu
+    lastPassTook time.Duration           // How long that pass took, guarded by mu
+    lastWrite    atomic.Int64            // Unix nanoseconds of the last status the store accepted
 }
 
 type Job struct {
@@ -32,6 +48,8 @@ type Job struct {
     Retries  int
     State    int // Current reported state (0=OK, 1=Warning, 2=Critical, 3=Unknown)
     StateAge int // How many consecutive checks have returned this state
+    Priority int       // Higher runs first when workers are busy
+    QueuedAt time.Time // Set when the job enters the queue
 }
 
 type JobResult struct {
@@ -43,9 +61,12 @@ type JobResult struct {
 type Worker struct {
     id      int
     engine  *Engine
-    jobs    chan *Job
+    jobs    *JobQueue
     results chan *JobResult
     quit    chan bool
+
+    mu       sync.Mutex
+    activity workerActivity // Current job and recent history, for /api/debug/workers
 }
 
 // StateTracker manages soft fail logic for host/check combinations
@@ -58,18 +79,54 @@ type StateInfo struct {
     CurrentState     int       // The state we're reporting (what's stored in DB)
     PendingState     int       // The state we're seeing in checks
     ConsecutiveCount int       // How many consecutive times we've seen the pending state
+    PendingSince     time.Time // When we first saw the pending state
     LastStateChange  time.Time // When we last changed the current state
     LastCheckTime    time.Time // When we last ran this check
     SoftFailEnabled  bool      // Whether soft fail is enabled for this check
+    Symmetric        bool      // Recovery to OK also waits for the threshold
     Threshold        int       // How many consecutive failures needed to change state
+    NextRun          time.Time // Smeared first run for checks with no recent result (zero once run)
+    Queued           bool      // A job is waiting for or running on a worker
+    OutputHash       string    // Fingerprint of the last result's output
+}
+
+// StateDetail is an API-friendly copy of a tracked host/check state
+type StateDetail struct {
+    CurrentState     int       `json:"current_state"`
+    PendingState     int       `json:"pending_state"`
+    ConsecutiveCount int       `json:"consecutive_count"`
+    PendingSince     time.Time `json:"pending_since"`
+    Threshold        int       `json:"threshold"`
+    SoftFailEnabled  bool      `json:"soft_fail_enabled"`
+    SoftFailMode     string    `json:"soft_fail_mode"`
+    IsSoft           bool      `json:"is_soft"`
+    StateType        string    `json:"state_type"` // "soft" or "hard"
+    LastStateChange  time.Time `json:"last_state_change"`
+    LastCheckTime    time.Time `json:"last_check_time"`
 }
 
+// scheduleTick is how often the scheduler looks for due checks
+const scheduleTick = 30 * time.Second
+
+// resultQueueSize is how many finished jobs can wait to be recorded before
+// workers block
+const resultQueueSize = 1000
+
 func NewScheduler(engine *Engine) *Scheduler {
+    queueSize := engine.Config().Server.JobQueueSize
+    if queueSize <= 0 {
+        queueSize = 1000
+    }
+
     return &Scheduler{
         engine:       engine,
-        jobQueue:     make(chan *Job, 1000),
-        resultQueue:  make(chan *JobResult, 1000),
+        jobQueue:     NewJobQueue(queueSize, engine.Config().Server.JobAging),
+        resultQueue:  make(chan *JobResult, resultQueueSize),
         stateTracker: NewStateTracker(),
+        wake:         make(chan struct{}, 1),
+        dueHosts:     make(map[string]bool),
+        dueChecks:    make(map[string]bool),
+        duePairs:     make(map[string]bool),
     }
 }
 
@@ -79,6 +136,82 @@ func NewStateTracker() *StateTracker {
     }
 }
 
+// Get returns a copy of the tracked state for a host/check key
+func (st *StateTracker) Get(key string) (*StateDetail, bool) {
+    st.mu.RLock()
+    defer st.mu.RUnlock()
+
+    info, exists := st.states[key]
+    if !exists {
+        return nil, false
+    }
+    return info.detail(), true
+}
+
+// forgetHost drops the tracked states of every check on a host
+func (st *StateTracker) forgetHost(hostID string) {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    for key := range st.states {
+        if strings.HasPrefix(key, hostID+":") {
+            delete(st.states, key)
+        }
+    }
+}
+
+// forgetCheck drops the tracked states of a check on every host
+func (st *StateTracker) forgetCheck(checkID string) {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    for key := range st.states {
+        if strings.HasSuffix(key, ":"+checkID) {
+            delete(st.states, key)
+        }
+    }
+}
+
+// Snapshot returns copies of all tracked states keyed by "hostID:checkID"
+func (st *StateTracker) Snapshot() map[string]*StateDetail {
+    st.mu.RLock()
+    defer st.mu.RUnlock()
+
+    snapshot := make(map[string]*StateDetail, len(st.states))
+    for key, info := range st.states {
+        snapshot[key] = info.detail()
+    }
+    return snapshot
+}
+
+// detail converts the internal state info into its API representation.
+// Callers must hold the tracker lock.
+func (info *StateInfo) detail() *StateDetail {
+    isSoft := info.SoftFailEnabled && info.PendingState != info.CurrentState
+    stateType := "hard"
+    if isSoft {
+        stateType = "soft"
+    }
+    mode := SoftFailFailures
+    if info.Symmetric {
+        mode = SoftFailSymmetric
+    }
+
+    return &StateDetail{
+        CurrentState:     info.CurrentState,
+        PendingState:     info.PendingState,
+        ConsecutiveCount: info.ConsecutiveCount,
+        PendingSince:     info.PendingSince,
+        Threshold:        info.Threshold,
+        SoftFailEnabled:  info.SoftFailEnabled,
+        SoftFailMode:     mode,
+        IsSoft:           isSoft,
+        StateType:        stateType,
+        LastStateChange:  info.LastStateChange,
+        LastCheckTime:    info.LastCheckTime,
+    }
+}
+
 func (s *Scheduler) Start(ctx context.Context) error {
     s.mu.Lock()
     defer s.mu.Unlock()
@@ -88,6 +221,7 @@ func (s *Scheduler) Start(ctx context.Context) error {
     }
 
     s.running = true
+    s.startedAt = time.Now()
     logrus.Info("Starting scheduler with soft fail support")
 
     // Initialize state tracker from existing database states
@@ -95,8 +229,23 @@ func (s *Scheduler) Start(ctx context.Context) error {
         logrus.WithError(err).Warn("Failed to initialize state tracker from database")
     }
 
+    // Start write batching if enabled
+    if s.engine.Config().Database.BatchWrites {
+        s.batcher = database.NewStatusBatcher(
+            s.engine.store,
+            s.engine.Config().Database.BatchWindow,
+            s.engine.Config().Database.BatchSize,
+        )
+        s.batcher.Start(ctx)
+    }
+
+    // A stopped scheduler closed the old result queue
+    s.resultQueue = make(chan *JobResult, resultQueueSize)
+    s.resultsDone = make(chan struct{})
+    s.listeners = newListenerQueue(s.engine.metrics.UpdateListenerQueueDepth)
+
     // Start workers
-    workerCount := s.engine.config.Server.Workers
+    workerCount := s.engine.Config().Server.Workers
     s.workers = make([]*Worker, workerCount)
     
     for i := 0; i < workerCount; i++ {
@@ -113,7 +262,7 @@ func (s *Scheduler) Start(ctx context.Context) error {
     }
 
     // Start result processor
-    go s.processResults()
+    go s.processResults(s.resultQueue, s.resultsDone)
 
     // Start job scheduler
     go s.scheduleJobs(ctx)
@@ -132,10 +281,22 @@ func (s *Scheduler) Stop() {
     logrus.Info("Stopping scheduler")
     s.running = false
 
-    // Stop workers
+    // Stop workers; each finishes and reports its current job first
     for _, worker := range s.workers {
         worker.stop()
     }
+
+    // Record every result the workers produced before the store goes away
+    close(s.resultQueue)
+    <-s.resultsDone
+
+    // Flush any pending status writes
+    if s.batcher != nil {
+        s.batcher.Close()
+    }
+
+    // Statuses are safe; give listeners a moment but don't wait on them
+    s.listeners.close(listenerDrainTimeout)
 }
 
 func (s *Scheduler) initializeStateTracker() error {
@@ -144,33 +305,44 @@ func (s *Scheduler) initializeStateTracker() error {
         return fmt.Errorf("failed to get checks: %w", err)
     }
 
+    now := time.Now()
+
     for _, check := range checks {
         for _, hostID := range check.Hosts {
             key := fmt.Sprintf("%s:%s", hostID, check.ID)
             
             // Get current status from database
-            statuses, _ := s.engine.store.GetStatus(context.Background(), database.StatusFilters{
-                HostID:  hostID,
-                CheckID: check.ID,
-                Limit:   1,
-            })
+            latest, err := s.engine.store.GetLatestStatus(context.Background(), hostID, check.ID)
 
-            threshold := s.getThreshold(&check)
-            
             stateInfo := &StateInfo{
                 CurrentState:     3, // Unknown by default
                 PendingState:     3,
                 ConsecutiveCount: 0,
-                LastStateChange:  time.Now(),
-                LastCheckTime:    time.Now(),
-                SoftFailEnabled:  s.isSoftFailEnabled(&check),
-                Threshold:        threshold,
+                PendingSince:     time.Now().UTC(),
+                LastStateChange:  time.Now().UTC(),
+                LastCheckTime:    time.Now().UTC(),
+            }
+            stateInfo.applySoftFail(s.engine.HostSoftFailDecision(&check, hostID))
+
+            if err == nil {
+                stateInfo.CurrentState = latest.ExitCode
+                stateInfo.PendingState = latest.ExitCode
+                stateInfo.PendingSince = latest.Timestamp
+                stateInfo.LastCheckTime = latest.Timestamp
+                stateInfo.OutputHash = latest.OutputHash
+                // Statuses stored before the state change was persisted
+                // only tell us the state held as of their timestamp
+                stateInfo.LastStateChange = latest.LastStateChange
+                if stateInfo.LastStateChange.IsZero() {
+                    stateInfo.LastStateChange = latest.Timestamp
+                }
             }
 
-            if len(statuses) > 0 {
-                stateInfo.CurrentState = statuses[0].ExitCode
-                stateInfo.PendingState = statuses[0].ExitCode
-                stateInfo.LastCheckTime = statuses[0].Timestamp
+            // Checks that are already due would all fire on the first pass;
+            // spread them across their interval instead
+            interval := s.checkInterval(&check, stateInfo)
+            if err != nil || stateInfo.LastCheckTime.Add(interval).Before(now) {
+                stateInfo.NextRun = now.Add(smearOffset(key, interval))
             }
 
             s.stateTracker.states[key] = stateInfo
@@ -181,26 +353,16 @@ func (s *Scheduler) initializeStateTracker() error {
     return nil
 }
 
-func (s *Scheduler) getThreshold(check *database.Check) int {
-    // Check if threshold is specified in check configuration
-    if check.Threshold > 0 {
-        return check.Threshold
-    }
-    
-    // Fall back to default from monitoring config
-    return s.engine.config.Monitoring.DefaultThreshold
-}
-
-func (s *Scheduler) isSoftFailEnabled(check *database.Check) bool {
-    // For database checks, we don't have the SoftFailEnabled field from config
-    // So we use the threshold to determine if soft fail should be enabled
-    // and rely on the global setting
-    threshold := s.getThreshold(check)
-    return s.engine.config.Monitoring.SoftFailEnabled && threshold > 1
+// applySoftFail copies a check's resolved soft fail settings into its
+// tracked state. Callers must hold the tracker lock for existing states.
+func (info *StateInfo) applySoftFail(decision SoftFailDecision) {
+    info.SoftFailEnabled = decision.Enabled
+    info.Symmetric = decision.Mode == SoftFailSymmetric
+    info.Threshold = decision.Threshold
 }
 
 func (s *Scheduler) scheduleJobs(ctx context.Context) {
-    ticker := time.NewTicker(30 * time.Second)
+    ticker := time.NewTicker(scheduleTick)
     defer ticker.Stop()
 
     for {
@@ -209,10 +371,34 @@ func (s *Scheduler) scheduleJobs(ctx context.Context) {
             return
         case <-ticker.C:
             s.processSchedule()
+            s.WorkerDebugInfo() // Refresh the stuck-worker gauge
+        case <-s.wake:
+            s.processSchedule()
         }
     }
 }
 
+// runSoon makes every check on a host, every host of a check, or one host's
+// check when both are given, due on a scheduling pass run straight away
+// rather than after its interval
+func (s *Scheduler) runSoon(hostID, checkID string) {
+    s.mu.Lock()
+    switch {
+    case hostID != "" && checkID != "":
+        s.duePairs[hostID+":"+checkID] = true
+    case hostID != "":
+        s.dueHosts[hostID] = true
+    case checkID != "":
+        s.dueChecks[checkID] = true
+    }
+    s.mu.Unlock()
+
+    select {
+    case s.wake <- struct{}{}:
+    default: // A pass is already pending
+    }
+}
+
 func (s *Scheduler) processSchedule() {
     checks, err := s.engine.store.GetChecks(context.Background())
     if err != nil {
@@ -222,12 +408,25 @@ func (s *Scheduler) processSchedule() {
 
     now := time.Now()
     scheduled := 0
+    deferred := 0
 
-    for _, check := range checks {
+    s.mu.Lock()
+    dueHosts, dueChecks, duePairs := s.dueHosts, s.dueChecks, s.duePairs
+    s.dueHosts, s.dueChecks, s.duePairs = make(map[string]bool), make(map[string]bool), make(map[string]bool)
+    s.mu.Unlock()
+
+    for i := range checks {
+        check := &checks[i] // Jobs keep this pointer, so it must not be the loop variable
         if !check.Enabled {
             continue
         }
 
+        // Outside its periods a check keeps its last state until the next
+        // window opens
+        if !check.InPeriod(now) {
+            continue
+        }
+
         for _, hostID := range check.Hosts {
             host, err := s.engine.store.GetHost(context.Background(), hostID)
             if err != nil || !host.Enabled {
@@ -242,70 +441,70 @@ func (s *Scheduler) processSchedule() {
             
             if !exists {
                 // Initialize state info for this host/check combination
-                threshold := s.getThreshold(&check)
                 stateInfo = &StateInfo{
                     CurrentState:     3, // Unknown
                     PendingState:     3,
                     ConsecutiveCount: 0,
+                    PendingSince:     now,
                     LastStateChange:  now,
                     LastCheckTime:    now,
-                    SoftFailEnabled:  s.isSoftFailEnabled(&check),
-                    Threshold:        threshold,
                 }
+                stateInfo.applySoftFail(s.engine.HostSoftFailDecision(check, hostID))
+                // New host/check (e.g. after RefreshConfig): first run is
+                // smeared across the interval rather than waiting a full one
+                stateInfo.NextRun = now.Add(smearOffset(key, s.checkInterval(check, stateInfo)))
                 
                 s.stateTracker.mu.Lock()
                 s.stateTracker.states[key] = stateInfo
                 s.stateTracker.mu.Unlock()
+            } else {
+                // Pick up soft fail changes made since the state was created
+                s.stateTracker.mu.Lock()
+                stateInfo.applySoftFail(s.engine.HostSoftFailDecision(check, hostID))
+                s.stateTracker.mu.Unlock()
             }
 
-            // Determine interval based on current reported state (not pending state)
-            var interval time.Duration
-            switch stateInfo.CurrentState {
-            case 0:
-                interval = check.Interval["ok"]
-            case 1:
-                interval = check.Interval["warning"]
-            case 2:
-                interval = check.Interval["critical"]
-            default:
-                interval = check.Interval["unknown"]
-            }
+            s.stateTracker.mu.RLock()
+            queued := stateInfo.Queued
+            nextRun := stateInfo.NextRun
+            lastCheck := stateInfo.LastCheckTime
+            s.stateTracker.mu.RUnlock()
 
-            if interval == 0 {
-                interval = s.engine.config.Monitoring.DefaultInterval
+            // Don't queue a second job while one is still pending
+            if queued {
+                continue
             }
 
-            // If we're in a pending state change, check more frequently
-            if stateInfo.SoftFailEnabled && stateInfo.PendingState != stateInfo.CurrentState {
-                // Use a shorter interval for pending state verification
-                interval = interval / 3
-                if interval < 30*time.Second {
-                    interval = 30 * time.Second
+            if nextRun.IsZero() {
+                interval := s.checkInterval(check, stateInfo)
+                nextRun = lastCheck.Add(interval)
+
+                // Add some jitter to prevent thundering herd
+                if maxJitter := int(interval.Seconds() * 0.1); maxJitter > 0 {
+                    nextRun = nextRun.Add(time.Duration(rand.Intn(maxJitter)) * time.Second)
                 }
             }
 
-            nextRun := stateInfo.LastCheckTime.Add(interval)
-            
-            // Add some jitter to prevent thundering herd
-            jitter := time.Duration(rand.Intn(int(interval.Seconds()*0.1))) * time.Second
-            nextRun = nextRun.Add(jitter)
-
-            if nextRun.Before(now) {
+            if nextRun.Before(now) || dueHosts[hostID] || dueChecks[check.ID] || duePairs[key] {
                 job := &Job{
-                    ID:      key,
-                    HostID:  hostID,
-                    CheckID: check.ID,
-                    Host:    host,
-                    Check:   &check,
-                    NextRun: now,
-                    State:   stateInfo.CurrentState,
+                    ID:       key,
+                    HostID:   hostID,
+                    CheckID:  check.ID,
+                    Host:     host,
+                    Check:    check,
+                    NextRun:  now,
+                    State:    stateInfo.CurrentState,
+                    Priority: check.Priority,
                 }
 
-                select {
-                case s.jobQueue <- job:
+                if s.jobQueue.Push(job) {
+                    s.stateTracker.mu.Lock()
+                    stateInfo.Queued = true
+                    s.stateTracker.mu.Unlock()
                     scheduled++
-                default:
-                    logrus.Warn("Job queue full, dropping job")
+                } else {
+                    // Still due, so it is picked up again on the next pass
+                    deferred++
                 }
             }
         }
@@ -314,17 +513,76 @@ func (s *Scheduler) processSchedule() {
     if scheduled > 0 {
         logrus.WithField("count", scheduled).Debug("Scheduled jobs")
     }
+    if deferred > 0 {
+        s.jobsDeferred.Add(uint64(deferred))
+        s.engine.metrics.RecordJobsDeferred(deferred)
+        logrus.WithField("count", deferred).Warn("Job queue full, deferring jobs to the next pass")
+    }
+
+    s.mu.Lock()
+    s.lastPass = time.Now()
+    s.lastPassTook = s.lastPass.Sub(now)
+    s.mu.Unlock()
 }
 
-func (s *Scheduler) processResults() {
-    for result := range s.resultQueue {
+// checkInterval returns how often a check should run given its current
+// reported state, shortened while a soft-fail state change is pending
+func (s *Scheduler) checkInterval(check *database.Check, stateInfo *StateInfo) time.Duration {
+    interval := check.Interval[database.StateName(stateInfo.CurrentState)]
+
+    if interval == 0 {
+        interval = s.engine.Config().Monitoring.IntervalFor(check.Type)
+    }
+
+    // If we're in a pending state change, check more frequently
+    if stateInfo.SoftFailEnabled && stateInfo.PendingState != stateInfo.CurrentState {
+        // Use a shorter interval for pending state verification
+        interval = interval / 3
+        if interval < 30*time.Second {
+            interval = 30 * time.Second
+        }
+    }
+
+    return interval
+}
+
+// smearOffset deterministically spreads first runs across the interval
+// based on the host:check key, so restarts don't cause a thundering herd
+func smearOffset(key string, interval time.Duration) time.Duration {
+    if interval <= 0 {
+        return 0
+    }
+    h := fnv.New64a()
+    h.Write([]byte(key))
+    return time.Duration(h.Sum64() % uint64(interval))
+}
+
+func (s *Scheduler) processResults(results <-chan *JobResult, done chan<- struct{}) {
+    defer close(done)
+
+    for result := range results {
+        s.engine.metrics.UpdateResultQueueDepth(len(results))
         s.handleResult(result)
     }
+    s.engine.metrics.UpdateResultQueueDepth(0)
 }
 
 func (s *Scheduler) handleResult(result *JobResult) {
     ctx := context.Background()
     key := fmt.Sprintf("%s:%s", result.Job.HostID, result.Job.CheckID)
+
+    s.recordMu.Lock()
+    defer s.recordMu.Unlock()
+
+    // The host or check may have been deleted, or the host taken off the
+    // check, while it ran
+    if !s.engine.coverage.covers(result.Job.HostID, result.Job.CheckID) {
+        logrus.WithFields(logrus.Fields{
+            "host":  result.Job.HostID,
+            "check": result.Job.CheckID,
+        }).Debug("Dropping result for a host the check no longer lists")
+        return
+    }
     
     if result.Error != nil {
         logrus.WithError(result.Error).
@@ -341,66 +599,145 @@ func (s *Scheduler) handleResult(result *JobResult) {
             LongOutput: result.Error.Error(),
             Duration:   0,
         }
+
+        // Keep whatever a timed out plugin managed to say
+        if timeoutErr, ok := result.Error.(*TimeoutError); ok {
+            result.Result.Output = fmt.Sprintf("UNKNOWN - check timed out after %s", timeoutErr.Timeout)
+            result.Result.Duration = timeoutErr.Timeout
+            if timeoutErr.PartialOutput != "" {
+                result.Result.LongOutput = "Output before the timeout:\n" + timeoutErr.PartialOutput
+            } else {
+                result.Result.LongOutput = "No output before the timeout"
+            }
+        }
     }
 
+    // Plugin output can come from the remote end (page titles, banners),
+    // so optionally drop anything that could drive a terminal or log viewer
+    if s.engine.Config().Monitoring.StripControl {
+        result.Result.Output = database.StripControl(result.Result.Output)
+        result.Result.LongOutput = database.StripControl(result.Result.LongOutput)
+        result.Result.PerfData = database.StripControl(result.Result.PerfData)
+    }
+
+    // Apply any per-check exit code remapping before soft fail handling
+    exitCode := result.Job.Check.RemapExitCode(result.Result.ExitCode)
+
     // Update state tracker with new result
-    reportedState := s.updateStateTracker(key, result.Result.ExitCode)
+    reportedState, averted := s.updateStateTracker(key, exitCode)
     
+    // Fingerprint what the plugin said, before any soft fail wording is added
+    outputHash := database.OutputFingerprint(result.Result.Output, result.Result.LongOutput)
+
     // Get state info for logging
-    s.stateTracker.mu.RLock()
+    s.stateTracker.mu.Lock()
     stateInfo := s.stateTracker.states[key]
-    s.stateTracker.mu.RUnlock()
+    lastStateChange := stateInfo.LastStateChange
+    prevOutputHash := stateInfo.OutputHash
+    stateInfo.OutputHash = outputHash
+    s.stateTracker.mu.Unlock()
 
     // Store result with the reported state (may be different from actual result due to soft fail)
     status := &database.Status{
-        HostID:     result.Job.HostID,
-        CheckID:    result.Job.CheckID,
-        ExitCode:   reportedState,
-        Output:     result.Result.Output,
-        PerfData:   result.Result.PerfData,
-        LongOutput: result.Result.LongOutput,
-        Duration:   result.Result.Duration.Seconds() * 1000, // Convert to milliseconds
-        Timestamp:  time.Now(),
+        HostID:          result.Job.HostID,
+        CheckID:         result.Job.CheckID,
+        ExitCode:        reportedState,
+        RawExitCode:     result.Result.ExitCode,
+        Output:          result.Result.Output,
+        PerfData:        result.Result.PerfData,
+        LongOutput:      result.Result.LongOutput,
+        Duration:        result.Result.Duration.Seconds() * 1000, // Convert to milliseconds
+        Timestamp:       time.Now().UTC(),
+        LastStateChange: lastStateChange,
+        OutputHash:      outputHash,
+        Extracted:       extractValues(result.Job.CheckID, result.Job.Check.Options, result.Result.Output, result.Result.LongOutput),
+    }
+    if prevOutputHash != "" && prevOutputHash != outputHash {
+        status.PrevOutputHash = prevOutputHash
     }
 
     // If we're in soft fail mode and states don't match, add soft fail info to output
-    if stateInfo.SoftFailEnabled && result.Result.ExitCode != reportedState {
+    if stateInfo.SoftFailEnabled && exitCode != reportedState {
         status.Output = fmt.Sprintf("SOFT FAIL (%d/%d) - %s", 
             stateInfo.ConsecutiveCount, stateInfo.Threshold, result.Result.Output)
         
-        status.LongOutput = fmt.Sprintf("Soft fail protection active. Consecutive non-OK results: %d/%d required.\nOriginal output: %s\nOriginal long output: %s",
-            stateInfo.ConsecutiveCount, stateInfo.Threshold, result.Result.Output, result.Result.LongOutput)
+        // In symmetric mode a recovery is held back too
+        pending := "non-OK"
+        if stateInfo.PendingState == database.StateOK {
+            pending = "OK"
+        }
+        status.LongOutput = fmt.Sprintf("Soft fail protection active. Consecutive %s results: %d/%d required.\nOriginal output: %s\nOriginal long output: %s",
+            pending, stateInfo.ConsecutiveCount, stateInfo.Threshold, result.Result.Output, result.Result.LongOutput)
     }
 
-    if err := s.engine.store.UpdateStatus(ctx, status); err != nil {
-        logrus.WithError(err).Error("Failed to store status")
-        return
+    if s.batcher != nil {
+        s.batcher.Add(status)
+    } else {
+        if err := s.engine.store.UpdateStatus(ctx, status); err != nil {
+            logrus.WithError(err).Error("Failed to store status")
+            return
+        }
+        s.lastWrite.Store(time.Now().UnixNano())
+    }
+
+    // Listeners get their own copy, as the store may still be writing this one
+    published := *status
+    s.listeners.add(func() { s.engine.notifyStatus(&published) })
+    if reportedState == 0 {
+        s.engine.snoozeRecovered(result.Job.HostID, result.Job.CheckID)
     }
 
     // Record metrics using the reported state
     s.engine.metrics.RecordCheckResult(
-        result.Job.Host.Name,
+        result.Job.Host,
         result.Job.Check.Type,
         reportedState,
         result.Result.Duration,
     )
 
     s.engine.metrics.UpdateHostStatus(
-        result.Job.Host.Name,
-        result.Job.Host.Group,
+        result.Job.Host,
         result.Job.Check.Type,
         reportedState,
     )
 
+    // Staleness gauges use the actual result, not the soft-fail reported state
+    s.engine.metrics.RecordCheckRun(
+        result.Job.Host,
+        result.Job.CheckID,
+        exitCode,
+        status.Timestamp,
+    )
+
+    if status.Extracted != nil {
+        s.engine.metrics.RecordExtractedValues(result.Job.Host, result.Job.CheckID, status.Extracted)
+    }
+
+    if averted != nil {
+        averted.HostID = result.Job.HostID
+        averted.CheckID = result.Job.CheckID
+        logrus.WithFields(logrus.Fields{
+            "host":      result.Job.Host.Name,
+            "check":     result.Job.Check.Name,
+            "state":     database.StateName(averted.State),
+            "failures":  averted.Failures,
+            "threshold": averted.Threshold,
+            "span":      averted.RecoveredAt.Sub(averted.FirstFailure).Round(time.Second),
+        }).Info("Soft fail averted, check recovered before the threshold")
+        s.engine.metrics.RecordSoftFailAverted(result.Job.Host, result.Job.CheckID)
+        s.listeners.add(func() { s.engine.notifySoftFailAverted(averted) })
+    }
+
     logFields := logrus.Fields{
         "host":     result.Job.Host.Name,
         "check":    result.Job.Check.Name,
-        "exit":     result.Result.ExitCode,
+        "exit":     exitCode,
+        "raw_exit": result.Result.ExitCode,
         "reported": reportedState,
         "duration": result.Result.Duration,
     }
 
-    if stateInfo.SoftFailEnabled && result.Result.ExitCode != reportedState {
+    if stateInfo.SoftFailEnabled && exitCode != reportedState {
         logFields["soft_fail"] = true
         logFields["consecutive"] = stateInfo.ConsecutiveCount
         logFields["threshold"] = stateInfo.Threshold
@@ -409,7 +746,30 @@ func (s *Scheduler) handleResult(result *JobResult) {
     logrus.WithFields(logFields).Debug("Check completed")
 }
 
-func (s *Scheduler) updateStateTracker(key string, newExitCode int) int {
+// stopRecording runs forget, which takes hosts or checks out of coverage,
+// between results, then discards statuses still waiting to be written that
+// match. Once it returns nothing more is recorded for them, so their stored
+// statuses can be deleted for good.
+func (s *Scheduler) stopRecording(forget func(), match func(*database.Status) bool) {
+    // Not under recordMu: Stop holds mu while it waits for results
+    s.mu.RLock()
+    batcher := s.batcher
+    s.mu.RUnlock()
+
+    s.recordMu.Lock()
+    defer s.recordMu.Unlock()
+
+    forget()
+    if batcher != nil {
+        if dropped := batcher.Discard(match); dropped > 0 {
+            logrus.WithField("count", dropped).Debug("Discarded queued statuses of deleted hosts or checks")
+        }
+    }
+}
+
+// updateStateTracker applies a result to the tracked state and returns the
+// state to report, and a near miss if a pending failure just recovered
+func (s *Scheduler) updateStateTracker(key string, newExitCode int) (int, *SoftFailAverted) {
     s.stateTracker.mu.Lock()
     defer s.stateTracker.mu.Unlock()
     
@@ -420,35 +780,57 @@ func (s *Scheduler) updateStateTracker(key string, newExitCode int) int {
             CurrentState:     newExitCode,
             PendingState:     newExitCode,
             ConsecutiveCount: 1,
-            LastStateChange:  time.Now(),
-            LastCheckTime:    time.Now(),
+            PendingSince:     time.Now().UTC(),
+            LastStateChange:  time.Now().UTC(),
+            LastCheckTime:    time.Now().UTC(),
             SoftFailEnabled:  false,
             Threshold:        1,
         }
         s.stateTracker.states[key] = stateInfo
-        return newExitCode
+        return newExitCode, nil
     }
 
-    stateInfo.LastCheckTime = time.Now()
+    lastCheckTime := stateInfo.LastCheckTime
+    stateInfo.LastCheckTime = time.Now().UTC()
+    stateInfo.NextRun = time.Time{}
+    stateInfo.Queued = false
     
     // If soft fail is not enabled, just update and return the new state
     if !stateInfo.SoftFailEnabled {
         if stateInfo.CurrentState != newExitCode {
-            stateInfo.LastStateChange = time.Now()
+            stateInfo.LastStateChange = time.Now().UTC()
+        }
+        if stateInfo.PendingState != newExitCode {
+            stateInfo.PendingSince = time.Now().UTC()
+            stateInfo.ConsecutiveCount = 0
         }
         stateInfo.CurrentState = newExitCode
         stateInfo.PendingState = newExitCode
-        stateInfo.ConsecutiveCount = 1
-        return newExitCode
+        stateInfo.ConsecutiveCount++
+        return newExitCode, nil
     }
 
     // Soft fail logic
+    var averted *SoftFailAverted
     if newExitCode == stateInfo.PendingState {
         // Same state as before, increment counter
         stateInfo.ConsecutiveCount++
     } else {
+        // A failure that clears before the threshold never changed the
+        // reported state; keep a record of the near miss
+        if newExitCode == 0 && stateInfo.CurrentState == 0 && stateInfo.PendingState != 0 {
+            averted = &SoftFailAverted{
+                State:        stateInfo.PendingState,
+                Failures:     stateInfo.ConsecutiveCount,
+                Threshold:    stateInfo.Threshold,
+                FirstFailure: stateInfo.PendingSince,
+                LastFailure:  lastCheckTime,
+                RecoveredAt:  stateInfo.LastCheckTime,
+            }
+        }
         // Different state, reset counter
         stateInfo.PendingState = newExitCode
+        stateInfo.PendingSince = time.Now().UTC()
         stateInfo.ConsecutiveCount = 1
     }
 
@@ -456,8 +838,8 @@ func (s *Scheduler) updateStateTracker(key string, newExitCode int) int {
     shouldChangeState := false
     
     if newExitCode == 0 {
-        // Recovery to OK state - immediate transition
-        shouldChangeState = true
+        // Recovery to OK state - immediate unless soft fail is symmetric
+        shouldChangeState = !stateInfo.Symmetric || stateInfo.ConsecutiveCount >= stateInfo.Threshold
     } else if stateInfo.CurrentState == 0 && newExitCode != 0 {
         // Transitioning from OK to non-OK - apply soft fail logic
         shouldChangeState = stateInfo.ConsecutiveCount >= stateInfo.Threshold
@@ -472,7 +854,7 @@ func (s *Scheduler) updateStateTracker(key string, newExitCode int) int {
 
     if shouldChangeState {
         if stateInfo.CurrentState != newExitCode {
-            stateInfo.LastStateChange = time.Now()
+            stateInfo.LastStateChange = time.Now().UTC()
             logrus.WithFields(logrus.Fields{
                 "key":              key,
                 "old_state":        stateInfo.CurrentState,
@@ -485,17 +867,17 @@ func (s *Scheduler) updateStateTracker(key string, newExitCode int) int {
         stateInfo.ConsecutiveCount = 1 // Reset counter after state change
     }
 
-    return stateInfo.CurrentState
+    return stateInfo.CurrentState, averted
 }
 
 func (w *Worker) start() {
     for {
-        select {
-        case job := <-w.jobs:
-            w.executeJob(job)
-        case <-w.quit:
+        job, ok := w.jobs.Pop(w.quit)
+        if !ok {
             return
         }
+        w.engine.metrics.RecordJobQueueWait(job.Priority, time.Since(job.QueuedAt))
+        w.executeJob(job)
     }
 }
 
@@ -505,9 +887,11 @@ func (w *Worker) stop() {
 
 func (w *Worker) executeJob(job *Job) {
     start := time.Now()
+    w.beginJob(job, start)
     
-    plugin, exists := w.engine.plugins[job.Check.Type]
+    plugin, exists := w.engine.GetPlugin(job.Check.Type)
     if !exists {
+        w.finishJob(job, start, nil, fmt.Errorf("unknown check type: %s", job.Check.Type))
         w.results <- &JobResult{
             Job:    job,
             Result: nil,
@@ -519,10 +903,20 @@ func (w *Worker) executeJob(job *Job) {
     ctx, cancel := context.WithTimeout(context.Background(), job.Check.Timeout)
     defer cancel()
 
-    result, err := plugin.Execute(ctx, job.Host)
+    result, err := plugin.Execute(ctx, job.Host, job.Check.Options)
     if result != nil {
         result.Duration = time.Since(start)
+    } else if ctx.Err() == context.DeadlineExceeded {
+        // Exec-based plugins hand back what the command wrote before it
+        // was killed; the rest only know the deadline passed
+        timeoutErr, ok := err.(*TimeoutError)
+        if !ok {
+            timeoutErr = &TimeoutError{}
+        }
+        timeoutErr.Timeout = job.Check.Timeout
+        err = timeoutErr
     }
+    w.finishJob(job, start, result, err)
 
     w.results <- &JobResult{
         Job:    job,
@@ -0,0 +1,245 @@
+// internal/monitoring/self_checks.go - Checks on Raven's own health
+package monitoring
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "runtime"
+    "sync"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+const (
+    // SelfHostID is the reserved host that self checks run against
+    SelfHostID = "raven-self"
+
+    // InternalPluginName is the check type of self checks
+    InternalPluginName = "raven-internal"
+)
+
+// selfCheckMetrics are the self checks created when monitoring.self_checks
+// is enabled, one per metric the internal plugin reports
+var selfCheckMetrics = []struct {
+    metric string
+    name   string
+}{
+    {"queue", "Raven Job Queue"},
+    {"deferred", "Raven Deferred Jobs"},
+    {"database_size", "Raven Database Size"},
+    {"goroutines", "Raven Goroutines"},
+}
+
+func selfCheckID(metric string) string {
+    return SelfHostID + "-" + metric
+}
+
+// InternalPlugin reports on the engine itself rather than a remote host
+type InternalPlugin struct {
+    engine *Engine
+
+    mu           sync.Mutex
+    lastDeferred uint64 // Deferred job total at the previous "deferred" run
+}
+
+func (p *InternalPlugin) Name() string {
+    return InternalPluginName
+}
+
+func (p *InternalPlugin) Init(options map[string]interface{}) error {
+    return nil
+}
+
+func (p *InternalPlugin) Description() string {
+    return "Raven's own health: job queue fill, deferred jobs, database size and goroutine count"
+}
+
+func (p *InternalPlugin) ExampleOptions() map[string]interface{} {
+    return map[string]interface{}{"metric": "queue"}
+}
+
+func (p *InternalPlugin) OptionsSchema() OptionsSchema {
+    return OptionsSchema{
+        "metric": {Type: OptionString, Required: true, Description: "queue, deferred, database_size or goroutines"},
+    }
+}
+
+func (p *InternalPlugin) Execute(ctx context.Context, host *database.Host, options map[string]interface{}) (*CheckResult, error) {
+    sc := p.engine.Config().Monitoring.SelfChecks
+    metric, _ := options["metric"].(string)
+
+    switch metric {
+    case "queue":
+        queue := p.engine.scheduler.jobQueue
+        queued, capacity := queue.Len(), queue.Cap()
+        percent := float64(queued) * 100 / float64(capacity)
+        state := thresholdState(percent, sc.QueueWarning, sc.QueueCritical)
+        return &CheckResult{
+            ExitCode: state,
+            Output:   fmt.Sprintf("QUEUE %s - %d/%d jobs queued (%.1f%%)", stateLabel(state), queued, capacity, percent),
+            PerfData: fmt.Sprintf("queue=%.1f%%;%g;%g;0;100", percent, sc.QueueWarning, sc.QueueCritical),
+        }, nil
+
+    case "deferred":
+        total := p.engine.scheduler.jobsDeferred.Load()
+        p.mu.Lock()
+        deferred := total - p.lastDeferred
+        p.lastDeferred = total
+        p.mu.Unlock()
+
+        state := thresholdState(float64(deferred), float64(sc.DeferredWarning), float64(sc.DeferredCritical))
+        return &CheckResult{
+            ExitCode: state,
+            Output:   fmt.Sprintf("DEFERRED %s - %d jobs deferred since the last check", stateLabel(state), deferred),
+            PerfData: fmt.Sprintf("deferred=%d;%d;%d;0", deferred, sc.DeferredWarning, sc.DeferredCritical),
+        }, nil
+
+    case "database_size":
+        info, err := os.Stat(p.engine.Config().Database.Path)
+        if err != nil {
+            return &CheckResult{
+                ExitCode: database.StateUnknown,
+                Output:   "DATABASE UNKNOWN - " + err.Error(),
+            }, nil
+        }
+        sizeMB := float64(info.Size()) / (1024 * 1024)
+        state := thresholdState(sizeMB, float64(sc.DatabaseSizeWarning), float64(sc.DatabaseSizeCritical))
+        return &CheckResult{
+            ExitCode: state,
+            Output:   fmt.Sprintf("DATABASE %s - %.1f MB", stateLabel(state), sizeMB),
+            PerfData: fmt.Sprintf("size=%.1fMB;%d;%d;0", sizeMB, sc.DatabaseSizeWarning, sc.DatabaseSizeCritical),
+        }, nil
+
+    case "goroutines":
+        count := runtime.NumGoroutine()
+        state := thresholdState(float64(count), float64(sc.GoroutineWarning), float64(sc.GoroutineCritical))
+        return &CheckResult{
+            ExitCode: state,
+            Output:   fmt.Sprintf("GOROUTINES %s - %d running", stateLabel(state), count),
+            PerfData: fmt.Sprintf("goroutines=%d;%d;%d;0", count, sc.GoroutineWarning, sc.GoroutineCritical),
+        }, nil
+    }
+
+    return &CheckResult{
+        ExitCode: database.StateUnknown,
+        Output:   fmt.Sprintf("Unknown raven-internal metric: %q", metric),
+    }, nil
+}
+
+// thresholdState compares a value against warning and critical limits;
+// a limit of 0 is not checked
+func thresholdState(value, warning, critical float64) int {
+    if critical > 0 && value >= critical {
+        return database.StateCritical
+    }
+    if warning > 0 && value >= warning {
+        return database.StateWarning
+    }
+    return database.StateOK
+}
+
+func stateLabel(state int) string {
+    switch state {
+    case database.StateOK:
+        return "OK"
+    case database.StateWarning:
+        return "WARNING"
+    case database.StateCritical:
+        return "CRITICAL"
+    }
+    return "UNKNOWN"
+}
+
+// selfCheckIDs returns the self check IDs, or none when self checks are
+// disabled, so the purge treats them like checks defined in the config
+func selfCheckIDs(sc config.SelfChecksConfig) []string {
+    if !sc.Enabled {
+        return nil
+    }
+    ids := make([]string, 0, len(selfCheckMetrics))
+    for _, selfCheck := range selfCheckMetrics {
+        ids = append(ids, selfCheckID(selfCheck.metric))
+    }
+    return ids
+}
+
+// syncSelfChecks creates or updates the raven-self host and its checks when
+// self checks are enabled. Once disabled, the orphan purge removes them.
+func (e *Engine) syncSelfChecks() {
+    ctx := context.Background()
+    sc := e.Config().Monitoring.SelfChecks
+
+    if !sc.Enabled {
+        return
+    }
+
+    host := &database.Host{
+        ID:          SelfHostID,
+        Name:        SelfHostID,
+        DisplayName: "Raven",
+        Hostname:    "localhost",
+        Group:       "raven",
+        Enabled:     true,
+    }
+    if err := e.ApplyGroup(ctx, host); err != nil {
+        logrus.WithError(err).Error("Failed to create self check group")
+    }
+    if existing, err := e.store.GetHost(ctx, SelfHostID); err != nil {
+        host.CreatedAt = time.Now().UTC()
+        host.UpdatedAt = time.Now().UTC()
+        if err := e.store.CreateHost(ctx, host); err != nil {
+            logrus.WithError(err).Error("Failed to create self check host")
+            return
+        }
+    } else if !existing.Enabled {
+        existing.Enabled = true
+        existing.UpdatedAt = time.Now().UTC()
+        if err := e.store.UpdateHost(ctx, existing); err != nil {
+            logrus.WithError(err).Error("Failed to enable self check host")
+        }
+    }
+
+    interval := make(map[string]time.Duration)
+    for _, state := range config.IntervalStates {
+        interval[state] = sc.Interval
+    }
+
+    for _, selfCheck := range selfCheckMetrics {
+        check := &database.Check{
+            ID:       selfCheckID(selfCheck.metric),
+            Name:     selfCheck.name,
+            Type:     InternalPluginName,
+            Hosts:    []string{SelfHostID},
+            Interval: interval,
+            Timeout:  10 * time.Second,
+            Enabled:  true,
+            Options:  map[string]interface{}{"metric": selfCheck.metric},
+        }
+
+        existing, err := e.store.GetCheck(ctx, check.ID)
+        if err != nil {
+            check.CreatedAt = time.Now().UTC()
+            check.UpdatedAt = time.Now().UTC()
+            if err := e.store.CreateCheck(ctx, check); err != nil {
+                logrus.WithError(err).WithField("check", check.ID).Error("Failed to create self check")
+            }
+            continue
+        }
+
+        existing.Name = check.Name
+        existing.Type = check.Type
+        existing.Hosts = check.Hosts
+        existing.Interval = check.Interval
+        existing.Timeout = check.Timeout
+        existing.Enabled = check.Enabled
+        existing.Options = check.Options
+        existing.UpdatedAt = time.Now().UTC()
+        if err := e.store.UpdateCheck(ctx, existing); err != nil {
+            logrus.WithError(err).WithField("check", check.ID).Error("Failed to update self check")
+        }
+    }
+}
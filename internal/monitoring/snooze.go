@@ -0,0 +1,168 @@
+// internal/monitoring/snooze.go - Snoozing a host/check alert until it recovers or a deadline passes
+package monitoring
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// Why a snooze ended
+const (
+    SnoozeRecovered = "recovered" // The check reported OK
+    SnoozeExpired   = "expired"   // Its deadline passed and the purge removed it
+    SnoozeRemoved   = "removed"   // Cleared through the API
+)
+
+// SnoozeCleared is passed to OnSnoozeCleared listeners when a snooze ends
+type SnoozeCleared struct {
+    HostID  string    `json:"host_id"`
+    CheckID string    `json:"check_id"`
+    Until   time.Time `json:"until"`
+    Reason  string    `json:"reason"`
+}
+
+// snoozeSet mirrors the stored snoozes so results can be checked against
+// them without a database read
+type snoozeSet struct {
+    mu      sync.RWMutex
+    snoozes map[string]database.Snooze // "hostID:checkID"
+}
+
+func newSnoozeSet() *snoozeSet {
+    return &snoozeSet{snoozes: make(map[string]database.Snooze)}
+}
+
+// loadSnoozes fills the set from the store
+func (e *Engine) loadSnoozes(ctx context.Context) {
+    snoozes, err := e.store.GetSnoozes(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to load snoozes")
+        return
+    }
+
+    e.snoozes.mu.Lock()
+    defer e.snoozes.mu.Unlock()
+    for _, snooze := range snoozes {
+        e.snoozes.snoozes[snooze.HostID+":"+snooze.CheckID] = snooze
+    }
+}
+
+// Snooze quiets a host/check alert until it recovers or until passes,
+// replacing any snooze already set on it
+func (e *Engine) Snooze(ctx context.Context, hostID, checkID string, until time.Time) (*database.Snooze, error) {
+    snooze := &database.Snooze{
+        HostID:    hostID,
+        CheckID:   checkID,
+        Until:     until.UTC(),
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := e.store.SetSnooze(ctx, snooze); err != nil {
+        return nil, err
+    }
+
+    e.snoozes.mu.Lock()
+    e.snoozes.snoozes[hostID+":"+checkID] = *snooze
+    e.snoozes.mu.Unlock()
+    return snooze, nil
+}
+
+// Unsnooze clears the snooze on a host/check
+func (e *Engine) Unsnooze(ctx context.Context, hostID, checkID string) error {
+    if err := e.store.DeleteSnooze(ctx, hostID, checkID); err != nil {
+        return err
+    }
+    e.clearSnooze(hostID, checkID, SnoozeRemoved)
+    return nil
+}
+
+// SnoozedUntil returns when the snooze on a host/check ends. A snooze past
+// its deadline no longer counts, even before the purge removes it.
+func (e *Engine) SnoozedUntil(hostID, checkID string) (time.Time, bool) {
+    e.snoozes.mu.RLock()
+    defer e.snoozes.mu.RUnlock()
+
+    snooze, exists := e.snoozes.snoozes[hostID+":"+checkID]
+    if !exists || !snooze.Until.After(time.Now()) {
+        return time.Time{}, false
+    }
+    return snooze.Until, true
+}
+
+// HostSnoozes returns when each snoozed check on a host is snoozed until,
+// keyed by check ID
+func (e *Engine) HostSnoozes(hostID string) map[string]time.Time {
+    now := time.Now()
+    snoozed := make(map[string]time.Time)
+
+    e.snoozes.mu.RLock()
+    defer e.snoozes.mu.RUnlock()
+    for _, snooze := range e.snoozes.snoozes {
+        if snooze.HostID == hostID && snooze.Until.After(now) {
+            snoozed[snooze.CheckID] = snooze.Until
+        }
+    }
+    return snoozed
+}
+
+// snoozeRecovered ends the snooze on a host/check that reported OK
+func (e *Engine) snoozeRecovered(hostID, checkID string) {
+    e.snoozes.mu.RLock()
+    _, exists := e.snoozes.snoozes[hostID+":"+checkID]
+    e.snoozes.mu.RUnlock()
+    if !exists {
+        return
+    }
+
+    if err := e.store.DeleteSnooze(context.Background(), hostID, checkID); err != nil && err.Error() != "snooze not found" {
+        logrus.WithError(err).WithFields(logrus.Fields{"host": hostID, "check": checkID}).Error("Failed to clear snooze")
+        return
+    }
+    e.clearSnooze(hostID, checkID, SnoozeRecovered)
+}
+
+// snoozesExpired drops snoozes the purge removed from the store
+func (e *Engine) snoozesExpired(snoozes []database.Snooze) {
+    for _, snooze := range snoozes {
+        e.clearSnooze(snooze.HostID, snooze.CheckID, SnoozeExpired)
+    }
+}
+
+// clearSnooze drops a snooze from the set and tells listeners, unless it
+// was already gone
+func (e *Engine) clearSnooze(hostID, checkID, reason string) {
+    key := hostID + ":" + checkID
+
+    e.snoozes.mu.Lock()
+    snooze, exists := e.snoozes.snoozes[key]
+    delete(e.snoozes.snoozes, key)
+    e.snoozes.mu.Unlock()
+    if !exists {
+        return
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "host":   hostID,
+        "check":  checkID,
+        "reason": reason,
+    }).Info("Snooze cleared")
+
+    cleared := &SnoozeCleared{HostID: hostID, CheckID: checkID, Until: snooze.Until, Reason: reason}
+    e.listenersMu.RLock()
+    defer e.listenersMu.RUnlock()
+    for _, listener := range e.snoozeListeners {
+        listener(cleared)
+    }
+}
+
+// OnSnoozeCleared registers a function called when a snooze ends. Listeners
+// may run on the result goroutine and must not block.
+func (e *Engine) OnSnoozeCleared(listener func(*SnoozeCleared)) {
+    e.listenersMu.Lock()
+    defer e.listenersMu.Unlock()
+
+    e.snoozeListeners = append(e.snoozeListeners, listener)
+}
@@ -0,0 +1,111 @@
+// internal/monitoring/softfail.go - Effective soft fail settings for a check
+package monitoring
+
+import (
+    "fmt"
+    "time"
+
+    "raven2/internal/database"
+)
+
+// Soft fail modes
+const (
+    SoftFailFailures  = "failures"  // Only failures wait for the threshold; recovery is immediate
+    SoftFailSymmetric = "symmetric" // Recovery to OK waits for the threshold too
+)
+
+// SoftFailDecision is the soft fail behaviour the scheduler actually applies
+// to a check, after resolving per-check overrides against the global settings
+type SoftFailDecision struct {
+    Enabled      bool   `json:"enabled"`
+    Threshold    int    `json:"threshold"`
+    HostOverride bool   `json:"host_override,omitempty"` // Threshold comes from the check's host_thresholds
+    Mode         string `json:"mode"`
+    Reason       string `json:"reason"`
+}
+
+// SoftFailAverted records a near miss: a check that failed, then returned
+// to OK before reaching its soft fail threshold, so its reported state never
+// changed
+type SoftFailAverted struct {
+    HostID       string    `json:"host_id"`
+    CheckID      string    `json:"check_id"`
+    State        int       `json:"state"`     // The failure that was pending
+    Failures     int       `json:"failures"`  // Consecutive failures seen
+    Threshold    int       `json:"threshold"` // Failures that would have confirmed it
+    FirstFailure time.Time `json:"first_failure"`
+    LastFailure  time.Time `json:"last_failure"`
+    RecoveredAt  time.Time `json:"recovered_at"`
+}
+
+// SoftFailDecision resolves a check's soft fail settings. Soft fail is on
+// when the check (or, without an override, monitoring.soft_fail_enabled)
+// enables it and the effective threshold is at least 2: with a threshold of
+// 1 every result is confirmed straight away.
+func (e *Engine) SoftFailDecision(check *database.Check) SoftFailDecision {
+    return e.softFailDecision(check, e.effectiveThreshold(check.Threshold))
+}
+
+// HostSoftFailDecision resolves a check's soft fail settings on one host,
+// where the check's host_thresholds entry for the host, if any, replaces its
+// threshold. An override of 1 turns soft fail off for that host.
+func (e *Engine) HostSoftFailDecision(check *database.Check, hostID string) SoftFailDecision {
+    threshold, exists := check.HostThresholds[hostID]
+    if !exists {
+        return e.SoftFailDecision(check)
+    }
+
+    decision := e.softFailDecision(check, threshold)
+    decision.HostOverride = true
+    return decision
+}
+
+func (e *Engine) softFailDecision(check *database.Check, threshold int) SoftFailDecision {
+    decision := SoftFailDecision{
+        Threshold: threshold,
+        Mode:      check.SoftFailMode,
+    }
+    if decision.Mode == "" {
+        decision.Mode = SoftFailFailures
+    }
+
+    switch {
+    case check.SoftFailEnabled != nil && !*check.SoftFailEnabled:
+        decision.Reason = "disabled for this check"
+    case check.SoftFailEnabled == nil && !e.Config().Monitoring.SoftFailEnabled:
+        decision.Reason = "disabled globally (monitoring.soft_fail_enabled)"
+    case threshold < 2:
+        decision.Reason = "threshold is 1, so every result is confirmed immediately"
+    case check.SoftFailEnabled != nil:
+        decision.Enabled = true
+        decision.Reason = "enabled for this check"
+    default:
+        decision.Enabled = true
+        decision.Reason = "enabled globally (monitoring.soft_fail_enabled)"
+    }
+
+    return decision
+}
+
+// ValidateSoftFail rejects an unknown soft fail mode, and a check that
+// explicitly enables soft fail with a threshold that can never delay a state
+// change
+func (e *Engine) ValidateSoftFail(enabled *bool, mode string, threshold int) error {
+    switch mode {
+    case "", SoftFailFailures, SoftFailSymmetric:
+    default:
+        return fmt.Errorf("invalid soft_fail_mode %q (must be %s or %s)", mode, SoftFailFailures, SoftFailSymmetric)
+    }
+
+    if enabled != nil && *enabled && e.effectiveThreshold(threshold) < 2 {
+        return fmt.Errorf("soft_fail_enabled needs a threshold of at least 2")
+    }
+    return nil
+}
+
+func (e *Engine) effectiveThreshold(threshold int) int {
+    if threshold > 0 {
+        return threshold
+    }
+    return e.Config().Monitoring.DefaultThreshold
+}
@@ -0,0 +1,64 @@
+// internal/monitoring/target.go - Choosing which host address a check runs against
+package monitoring
+
+import (
+    "fmt"
+
+    "raven2/internal/database"
+)
+
+// Values of the "target" check option
+const (
+    TargetAuto     = "auto"     // IPv4 address, falling back to the hostname
+    TargetIP       = "ip"       // Always the IPv4 address, bypassing DNS
+    TargetHostname = "hostname" // Always the hostname, e.g. for TLS SNI or vhosts
+)
+
+// targetOptionSpec is shared by every built-in plugin's options schema
+var targetOptionSpec = OptionSpec{
+    Type:        OptionString,
+    Description: "Address to check: ip, hostname or auto (IPv4, falling back to hostname)",
+}
+
+// validateTargetOption rejects an unknown "target" option value
+func validateTargetOption(options map[string]interface{}) error {
+    value, ok := options["target"]
+    if !ok {
+        return nil
+    }
+
+    switch value {
+    case TargetAuto, TargetIP, TargetHostname:
+        return nil
+    }
+    return fmt.Errorf("invalid target %v (must be %s, %s or %s)", value, TargetIP, TargetHostname, TargetAuto)
+}
+
+// resolveTarget returns the address a check should run against, honouring
+// the "target" option. When the requested kind of address isn't configured
+// on the host it returns an UNKNOWN result for the plugin to report instead.
+func resolveTarget(host *database.Host, options map[string]interface{}) (string, *CheckResult) {
+    kind, _ := options["target"].(string)
+
+    var target, missing string
+    switch kind {
+    case TargetIP:
+        target, missing = host.IPv4, "No IPv4 address configured (check target is ip)"
+    case TargetHostname:
+        target, missing = host.Hostname, "No hostname configured (check target is hostname)"
+    default:
+        target = host.IPv4
+        if target == "" {
+            target = host.Hostname
+        }
+        missing = "No IP address or hostname configured"
+    }
+
+    if target == "" {
+        return "", &CheckResult{
+            ExitCode: database.StateUnknown,
+            Output:   missing,
+        }
+    }
+    return target, nil
+}
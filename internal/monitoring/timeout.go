@@ -0,0 +1,37 @@
+// internal/monitoring/timeout.go - Reporting checks that run past their timeout
+package monitoring
+
+import (
+    "context"
+    "fmt"
+    "os/exec"
+    "time"
+)
+
+// maxPartialOutput caps how much of a timed out command's output is kept
+const maxPartialOutput = 64 * 1024
+
+// TimeoutError reports a check stopped at its timeout, with whatever the
+// plugin had written by then
+type TimeoutError struct {
+    Timeout       time.Duration
+    PartialOutput string
+}
+
+func (e *TimeoutError) Error() string {
+    return fmt.Sprintf("check timed out after %s", e.Timeout)
+}
+
+// commandOutput runs cmd and returns its standard output. If ctx expires
+// first, the output written so far is returned in a *TimeoutError.
+func commandOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+    output, err := cmd.Output()
+    if ctx.Err() == context.DeadlineExceeded {
+        partial := string(output)
+        if len(partial) > maxPartialOutput {
+            partial = partial[:maxPartialOutput]
+        }
+        return output, &TimeoutError{PartialOutput: partial}
+    }
+    return output, err
+}
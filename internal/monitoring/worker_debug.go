@@ -0,0 +1,161 @@
+// internal/monitoring/worker_debug.go - Per-worker execution history for debugging stuck workers
+package monitoring
+
+import (
+    "strconv"
+    "time"
+
+    "raven2/internal/database"
+)
+
+// workerHistorySize is how many finished jobs each worker remembers
+const workerHistorySize = 20
+
+// WorkerJobRecord describes a single job executed by a worker
+type WorkerJobRecord struct {
+    HostID    string    `json:"host_id"`
+    HostName  string    `json:"host_name"`
+    CheckID   string    `json:"check_id"`
+    CheckName string    `json:"check_name"`
+    StartTime time.Time `json:"start_time"`
+    Duration  float64   `json:"duration_ms"`
+    ExitCode  int       `json:"exit_code"`
+    Error     string    `json:"error,omitempty"`
+}
+
+// WorkerCurrentJob describes the job a worker is executing right now
+type WorkerCurrentJob struct {
+    HostID    string    `json:"host_id"`
+    HostName  string    `json:"host_name"`
+    CheckID   string    `json:"check_id"`
+    CheckName string    `json:"check_name"`
+    StartTime time.Time `json:"start_time"`
+    Running   float64   `json:"running_ms"`
+    Timeout   float64   `json:"timeout_ms"`
+}
+
+// WorkerDebugInfo is a snapshot of a worker's activity
+type WorkerDebugInfo struct {
+    ID           int               `json:"id"`
+    JobsExecuted int64             `json:"jobs_executed"`
+    Current      *WorkerCurrentJob `json:"current,omitempty"`
+    Stuck        bool              `json:"stuck"` // Current job has run for more than twice its timeout
+    History      []WorkerJobRecord `json:"history"`
+}
+
+// workerActivity tracks what a worker is doing, guarded by Worker.mu
+type workerActivity struct {
+    current      *Job
+    currentStart time.Time
+    history      []WorkerJobRecord
+    historyNext  int
+    executed     int64
+}
+
+func (w *Worker) beginJob(job *Job, start time.Time) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    w.activity.current = job
+    w.activity.currentStart = start
+}
+
+func (w *Worker) finishJob(job *Job, start time.Time, result *CheckResult, err error) {
+    record := WorkerJobRecord{
+        HostID:    job.HostID,
+        CheckID:   job.CheckID,
+        StartTime: start.UTC(),
+        Duration:  float64(time.Since(start).Microseconds()) / 1000,
+        ExitCode:  database.StateUnknown,
+    }
+    if job.Host != nil {
+        record.HostName = job.Host.Name
+    }
+    if job.Check != nil {
+        record.CheckName = job.Check.Name
+    }
+    if result != nil {
+        record.ExitCode = result.ExitCode
+    }
+    if err != nil {
+        record.Error = err.Error()
+    }
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    a := &w.activity
+    if len(a.history) < workerHistorySize {
+        a.history = append(a.history, record)
+    } else {
+        a.history[a.historyNext] = record
+    }
+    a.historyNext = (a.historyNext + 1) % workerHistorySize
+    a.executed++
+    a.current = nil
+}
+
+// busy reports whether the worker is running a job
+func (w *Worker) busy() bool {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.activity.current != nil
+}
+
+func (w *Worker) debugInfo(now time.Time) WorkerDebugInfo {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    a := &w.activity
+    info := WorkerDebugInfo{
+        ID:           w.id,
+        JobsExecuted: a.executed,
+        History:      make([]WorkerJobRecord, 0, len(a.history)),
+    }
+
+    // Newest first
+    for i := 1; i <= len(a.history); i++ {
+        idx := (a.historyNext - i + workerHistorySize) % workerHistorySize
+        if idx < len(a.history) {
+            info.History = append(info.History, a.history[idx])
+        }
+    }
+
+    if job := a.current; job != nil {
+        running := now.Sub(a.currentStart)
+        current := &WorkerCurrentJob{
+            HostID:    job.HostID,
+            CheckID:   job.CheckID,
+            StartTime: a.currentStart.UTC(),
+            Running:   float64(running.Milliseconds()),
+        }
+        if job.Host != nil {
+            current.HostName = job.Host.Name
+        }
+        if job.Check != nil {
+            current.CheckName = job.Check.Name
+            current.Timeout = float64(job.Check.Timeout.Milliseconds())
+            info.Stuck = job.Check.Timeout > 0 && running > 2*job.Check.Timeout
+        }
+        info.Current = current
+    }
+
+    return info
+}
+
+// WorkerDebugInfo returns a snapshot of every worker and publishes the
+// stuck-worker gauge
+func (s *Scheduler) WorkerDebugInfo() []WorkerDebugInfo {
+    s.mu.RLock()
+    workers := s.workers
+    s.mu.RUnlock()
+
+    now := time.Now()
+    infos := make([]WorkerDebugInfo, 0, len(workers))
+    for _, worker := range workers {
+        info := worker.debugInfo(now)
+        s.engine.metrics.UpdateWorkerStuck(strconv.Itoa(info.ID), info.Stuck)
+        infos = append(infos, info)
+    }
+    return infos
+}
@@ -0,0 +1,99 @@
+// internal/web/annotation_handlers.go - Notes attached to status history entries
+package web
+
+import (
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// AnnotationRequest is the body for adding an annotation to a status entry
+type AnnotationRequest struct {
+    Author string `json:"author" binding:"required"`
+    Text   string `json:"text" binding:"required"`
+}
+
+// StatusHistoryEntry is a history entry with any annotations attached to it.
+// An entry that folded in repeated results covers the run from Start to End.
+type StatusHistoryEntry struct {
+    database.Status
+    Annotations     []database.Annotation `json:"annotations,omitempty"`
+    AnnotationCount int                   `json:"annotation_count"`
+    Start           time.Time             `json:"start"` // First result of the run
+    End             time.Time             `json:"end"`   // Last result folded in
+    Count           int                   `json:"count"` // Results the entry stands for
+}
+
+// GET /api/status/:id/annotations - Annotations on a status entry
+func (s *Server) getAnnotations(c *gin.Context) {
+    annotations, err := s.store.GetAnnotations(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get annotations")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get annotations"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  annotations,
+        "count": len(annotations),
+    })
+}
+
+// POST /api/status/:id/annotations - Attach a note to a status entry
+func (s *Server) createAnnotation(c *gin.Context) {
+    var req AnnotationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if strings.TrimSpace(req.Text) == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Annotation text is required"})
+        return
+    }
+
+    annotation := &database.Annotation{
+        StatusID:  c.Param("id"),
+        Author:    req.Author,
+        Text:      req.Text,
+        Timestamp: time.Now().UTC(),
+    }
+
+    if err := s.store.AddAnnotation(c.Request.Context(), annotation); err != nil {
+        if err.Error() == "status not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Status not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to create annotation")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create annotation"})
+        return
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "status_id": annotation.StatusID,
+        "author":    annotation.Author,
+    }).Info("Added status annotation")
+
+    c.JSON(http.StatusCreated, gin.H{"data": annotation})
+}
+
+// DELETE /api/status/:id/annotations/:annotation_id - Remove an annotation.
+// There are no roles yet, so this is as open as the rest of the API.
+func (s *Server) deleteAnnotation(c *gin.Context) {
+    err := s.store.DeleteAnnotation(c.Request.Context(), c.Param("id"), c.Param("annotation_id"))
+    if err != nil {
+        if err.Error() == "annotation not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Annotation not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to delete annotation")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete annotation"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted successfully"})
+}
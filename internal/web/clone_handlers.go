@@ -0,0 +1,191 @@
+// internal/web/clone_handlers.go - Duplicate existing hosts and checks
+package web
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// CloneHostRequest holds the fields that differ from the source host
+type CloneHostRequest struct {
+    Name     string `json:"name" binding:"required"`
+    IPv4     string `json:"ipv4"`
+    Hostname string `json:"hostname"`
+}
+
+// CloneCheckRequest holds the fields that differ from the source check.
+// Hosts replaces the source's host list; options are merged over the source's.
+type CloneCheckRequest struct {
+    Name    string                 `json:"name" binding:"required"`
+    Hosts   []string               `json:"hosts"`
+    Options map[string]interface{} `json:"options"`
+}
+
+// POST /api/hosts/:id/clone - Copy a host's group, tags and check membership
+func (s *Server) cloneHost(c *gin.Context) {
+    ctx := c.Request.Context()
+
+    var req CloneHostRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    source, err := s.store.GetHost(ctx, c.Param("id"))
+    if err != nil {
+        if err.Error() == "host not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Host not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to get host")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get host"})
+        return
+    }
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checks"})
+        return
+    }
+
+    var checkIDs []string
+    for _, check := range checks {
+        for _, hostID := range check.Hosts {
+            if hostID == source.ID {
+                checkIDs = append(checkIDs, check.ID)
+                break
+            }
+        }
+    }
+
+    tags := make(map[string]string, len(source.Tags))
+    for k, v := range source.Tags {
+        tags[k] = v
+    }
+
+    host := &database.Host{
+        ID:       uuid.New().String(),
+        Name:     req.Name,
+        IPv4:     req.IPv4,
+        Hostname: req.Hostname,
+        Group:    source.Group,
+        Enabled:  source.Enabled,
+        Tags:     tags,
+    }
+
+    updated, err := s.store.CreateHostInChecks(ctx, host, checkIDs)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to clone host")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone host"})
+        return
+    }
+    if updated == nil {
+        updated = []string{}
+    }
+
+    s.engine.RefreshConfig()
+
+    logrus.WithFields(logrus.Fields{
+        "source": source.ID,
+        "host":   host.ID,
+        "checks": len(updated),
+    }).Info("Cloned host")
+
+    s.broadcast(WSMessage{
+        Type: "host_cloned",
+        Data: gin.H{"source_id": source.ID, "host": host, "checks": updated},
+    })
+
+    c.JSON(http.StatusCreated, gin.H{
+        "data":   host,
+        "checks": updated,
+    })
+}
+
+// POST /api/checks/:id/clone - Duplicate a check, optionally with other hosts or options
+func (s *Server) cloneCheck(c *gin.Context) {
+    ctx := c.Request.Context()
+
+    var req CloneCheckRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    source, err := s.store.GetCheck(ctx, c.Param("id"))
+    if err != nil {
+        if err.Error() == "check not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Check not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to get check")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get check"})
+        return
+    }
+
+    check := *source
+    check.ID = uuid.New().String()
+    check.Name = req.Name
+
+    check.Hosts = append([]string{}, source.Hosts...)
+    if req.Hosts != nil {
+        for _, hostID := range req.Hosts {
+            if _, err := s.store.GetHost(ctx, hostID); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown host: " + hostID})
+                return
+            }
+        }
+        check.Hosts = req.Hosts
+    }
+
+    // Keep the source's threshold overrides for the hosts the clone targets
+    check.HostThresholds = make(map[string]int)
+    for _, hostID := range check.Hosts {
+        if threshold, exists := source.HostThresholds[hostID]; exists {
+            check.HostThresholds[hostID] = threshold
+        }
+    }
+
+    check.Options = make(map[string]interface{}, len(source.Options)+len(req.Options))
+    for k, v := range source.Options {
+        check.Options[k] = v
+    }
+    for k, v := range req.Options {
+        check.Options[k] = v
+    }
+    s.keepSecrets(check.Options, source.Options)
+
+    if err := s.engine.ValidateCheckOptions(check.Type, check.Options); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid options: " + err.Error()})
+        return
+    }
+
+    check.CreatedAt = time.Now().UTC()
+    check.UpdatedAt = time.Now().UTC()
+
+    if err := s.store.CreateCheck(ctx, &check); err != nil {
+        logrus.WithError(err).Error("Failed to clone check")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone check"})
+        return
+    }
+
+    s.engine.RefreshConfig()
+
+    logrus.WithFields(logrus.Fields{
+        "source": source.ID,
+        "check":  check.ID,
+    }).Info("Cloned check")
+
+    s.broadcast(WSMessage{
+        Type: "check_cloned",
+        Data: gin.H{"source_id": source.ID, "check": check},
+    })
+
+    c.JSON(http.StatusCreated, gin.H{"data": s.redactedCheck(&check)})
+}
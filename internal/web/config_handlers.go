@@ -0,0 +1,401 @@
+// internal/web/config_handlers.go - Export/import of the host and check inventory
+package web
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "gopkg.in/yaml.v3"
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+// ImportSummary lists the IDs touched by an import
+type ImportSummary struct {
+    Created []string `json:"created"`
+    Updated []string `json:"updated"`
+    Deleted []string `json:"deleted"`
+}
+
+func newImportSummary() *ImportSummary {
+    return &ImportSummary{
+        Created: []string{},
+        Updated: []string{},
+        Deleted: []string{},
+    }
+}
+
+// GET /api/config/export - Export the live hosts and checks as an include-compatible config
+func (s *Server) exportConfig(c *gin.Context) {
+    ctx := c.Request.Context()
+
+    hosts, err := s.store.GetHosts(ctx, database.HostFilters{})
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get hosts for export")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hosts"})
+        return
+    }
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks for export")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checks"})
+        return
+    }
+
+    inventory := config.PartialConfig{
+        Hosts:  make([]config.HostConfig, 0, len(hosts)),
+        Checks: make([]config.CheckConfig, 0, len(checks)),
+    }
+    for _, host := range hosts {
+        inventory.Hosts = append(inventory.Hosts, hostToConfig(&host))
+    }
+    for _, check := range checks {
+        inventory.Checks = append(inventory.Checks, checkToConfig(&check))
+    }
+
+    var document yaml.Node
+    if err := document.Encode(inventory); err != nil {
+        logrus.WithError(err).Error("Failed to encode configuration export")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode configuration"})
+        return
+    }
+
+    // Never hand resolved secrets back out. Values are redacted before
+    // formatting, so the placeholder is quoted rather than read as an alias.
+    s.redactNode(&document)
+
+    if c.DefaultQuery("format", "yaml") == "json" {
+        // Go through YAML so keys and durations match the config format
+        var generic interface{}
+        if err := document.Decode(&generic); err != nil {
+            logrus.WithError(err).Error("Failed to convert configuration export")
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode configuration"})
+            return
+        }
+        c.JSON(http.StatusOK, generic)
+        return
+    }
+
+    data, err := yaml.Marshal(&document)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to encode configuration export")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode configuration"})
+        return
+    }
+
+    c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+}
+
+// POST /api/config/import - Replace the host and check inventory with the posted config
+func (s *Server) importConfig(c *gin.Context) {
+    body, err := io.ReadAll(c.Request.Body)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+        return
+    }
+
+    if strings.Contains(string(body), config.RedactedValue) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Import contains redacted secret values; replace " + config.RedactedValue + " before importing"})
+        return
+    }
+
+    // JSON is valid YAML, so both export formats are accepted here
+    var inventory config.PartialConfig
+    if err := yaml.Unmarshal(body, &inventory); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration: " + err.Error()})
+        return
+    }
+
+    if err := validateImportIDs(&inventory); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := s.config().ValidateInventory(inventory.Hosts, inventory.Checks); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    for _, check := range inventory.Checks {
+        if err := s.engine.ValidateCheckOptions(check.Type, check.Options); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("check '%s' has invalid options: %s", check.ID, err)})
+            return
+        }
+    }
+
+    hostSummary, checkSummary, err := s.applyInventory(c.Request.Context(), &inventory)
+    s.engine.RebuildCoverage(c.Request.Context())
+    if err != nil {
+        logrus.WithError(err).Error("Failed to apply imported configuration")
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error":  "Failed to apply imported configuration",
+            "hosts":  hostSummary,
+            "checks": checkSummary,
+        })
+        return
+    }
+
+    // The scheduler reads checks from the store on every cycle, so there is
+    // no need for RefreshConfig here - it would re-apply the YAML on top of
+    // the imported inventory. The coverage index was rebuilt above.
+    s.pruneMetricSeries(c.Request.Context())
+
+    logrus.WithFields(logrus.Fields{
+        "hosts":  len(inventory.Hosts),
+        "checks": len(inventory.Checks),
+    }).Info("Imported configuration")
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":   "Configuration imported successfully",
+        "hosts":     hostSummary,
+        "checks":    checkSummary,
+        "timestamp": time.Now().UTC(),
+    })
+}
+
+// POST /api/config/reload - Re-read the config file and apply what changed,
+// for tools that manage the file and can call in after writing it
+func (s *Server) reloadConfig(c *gin.Context) {
+    changes, err := s.engine.ReloadFromDisk()
+    if err != nil {
+        if changes == nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to load configuration: " + err.Error()})
+            return
+        }
+        logrus.WithError(err).Error("Failed to apply reloaded configuration")
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error":   "Failed to apply reloaded configuration",
+            "changes": changes,
+        })
+        return
+    }
+
+    s.pruneMetricSeries(c.Request.Context())
+
+    // The config is shared with the engine, so this is the reloaded one
+    warnings := s.config().Warnings()
+    for _, warning := range warnings {
+        logrus.Warnf("Config: %s", warning)
+    }
+
+    includeFiles := s.config().IncludeFiles()
+    if includeFiles == nil {
+        includeFiles = []string{}
+    }
+    if warnings == nil {
+        warnings = []string{}
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":       "Configuration reloaded successfully",
+        "config_file":   s.config().Path(),
+        "include_files": includeFiles,
+        "changed":       !changes.Empty(),
+        "changes":       changes,
+        "warnings":      warnings,
+        "timestamp":     time.Now().UTC(),
+    })
+}
+
+// applyInventory creates, updates and deletes hosts and checks so the store
+// matches the imported inventory
+func (s *Server) applyInventory(ctx context.Context, inventory *config.PartialConfig) (*ImportSummary, *ImportSummary, error) {
+    hostSummary := newImportSummary()
+    checkSummary := newImportSummary()
+
+    existingHosts, err := s.store.GetHosts(ctx, database.HostFilters{})
+    if err != nil {
+        return hostSummary, checkSummary, fmt.Errorf("failed to get hosts: %w", err)
+    }
+    existingChecks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        return hostSummary, checkSummary, fmt.Errorf("failed to get checks: %w", err)
+    }
+
+    hostsByID := make(map[string]database.Host)
+    for _, host := range existingHosts {
+        hostsByID[host.ID] = host
+    }
+    checksByID := make(map[string]database.Check)
+    for _, check := range existingChecks {
+        checksByID[check.ID] = check
+    }
+
+    now := time.Now().UTC()
+
+    // Hosts first so imported checks never reference a missing host
+    wantedHosts := make(map[string]bool)
+    for _, hostCfg := range inventory.Hosts {
+        wantedHosts[hostCfg.ID] = true
+        host := configToHost(hostCfg)
+        host.UpdatedAt = now
+        if err := s.engine.ApplyGroup(ctx, host); err != nil {
+            return hostSummary, checkSummary, err
+        }
+
+        if existing, ok := hostsByID[host.ID]; ok {
+            host.CreatedAt = existing.CreatedAt
+            if err := s.store.UpdateHost(ctx, host); err != nil {
+                return hostSummary, checkSummary, fmt.Errorf("failed to update host %s: %w", host.ID, err)
+            }
+            hostSummary.Updated = append(hostSummary.Updated, host.ID)
+        } else {
+            host.CreatedAt = now
+            if err := s.store.CreateHost(ctx, host); err != nil {
+                return hostSummary, checkSummary, fmt.Errorf("failed to create host %s: %w", host.ID, err)
+            }
+            hostSummary.Created = append(hostSummary.Created, host.ID)
+        }
+    }
+
+    wantedChecks := make(map[string]bool)
+    for _, checkCfg := range inventory.Checks {
+        wantedChecks[checkCfg.ID] = true
+        check := configToCheck(checkCfg)
+        check.UpdatedAt = now
+
+        if existing, ok := checksByID[check.ID]; ok {
+            check.CreatedAt = existing.CreatedAt
+            if err := s.store.UpdateCheck(ctx, check); err != nil {
+                return hostSummary, checkSummary, fmt.Errorf("failed to update check %s: %w", check.ID, err)
+            }
+            s.engine.CheckChanged(&existing, check)
+            checkSummary.Updated = append(checkSummary.Updated, check.ID)
+        } else {
+            check.CreatedAt = now
+            if err := s.store.CreateCheck(ctx, check); err != nil {
+                return hostSummary, checkSummary, fmt.Errorf("failed to create check %s: %w", check.ID, err)
+            }
+            checkSummary.Created = append(checkSummary.Created, check.ID)
+        }
+    }
+
+    // Remove checks before hosts so nothing is left pointing at a deleted host
+    for _, check := range existingChecks {
+        if wantedChecks[check.ID] {
+            continue
+        }
+        if err := s.store.DeleteCheck(ctx, check.ID); err != nil {
+            return hostSummary, checkSummary, fmt.Errorf("failed to delete check %s: %w", check.ID, err)
+        }
+        checkSummary.Deleted = append(checkSummary.Deleted, check.ID)
+    }
+
+    for _, host := range existingHosts {
+        if wantedHosts[host.ID] {
+            continue
+        }
+        if err := s.store.DeleteHost(ctx, host.ID); err != nil {
+            return hostSummary, checkSummary, fmt.Errorf("failed to delete host %s: %w", host.ID, err)
+        }
+        hostSummary.Deleted = append(hostSummary.Deleted, host.ID)
+    }
+
+    return hostSummary, checkSummary, nil
+}
+
+// validateImportIDs catches missing and duplicate IDs, which validate()
+// leaves to the YAML author
+func validateImportIDs(inventory *config.PartialConfig) error {
+    for i, host := range inventory.Hosts {
+        if host.ID == "" {
+            return fmt.Errorf("hosts[%d] is missing an id", i)
+        }
+    }
+
+    checkIDs := make(map[string]bool)
+    for i, check := range inventory.Checks {
+        if check.ID == "" {
+            return fmt.Errorf("checks[%d] is missing an id", i)
+        }
+        if checkIDs[check.ID] {
+            return fmt.Errorf("duplicate check ID: %s", check.ID)
+        }
+        checkIDs[check.ID] = true
+    }
+
+    return nil
+}
+
+func hostToConfig(host *database.Host) config.HostConfig {
+    return config.HostConfig{
+        ID:          host.ID,
+        Name:        host.Name,
+        DisplayName: host.DisplayName,
+        IPv4:        host.IPv4,
+        Hostname:    host.Hostname,
+        Group:       host.Group,
+        Enabled:     host.Enabled,
+        Tags:        host.Tags,
+    }
+}
+
+func configToHost(hostCfg config.HostConfig) *database.Host {
+    host := &database.Host{
+        ID:          hostCfg.ID,
+        Name:        hostCfg.Name,
+        DisplayName: hostCfg.DisplayName,
+        IPv4:        hostCfg.IPv4,
+        Hostname:    hostCfg.Hostname,
+        Group:       hostCfg.Group,
+        Enabled:     hostCfg.Enabled,
+        Tags:        hostCfg.Tags,
+    }
+
+    if host.Group == "" {
+        host.Group = "default"
+    }
+    if host.Tags == nil {
+        host.Tags = make(map[string]string)
+    }
+    return host
+}
+
+func checkToConfig(check *database.Check) config.CheckConfig {
+    return config.CheckConfig{
+        ID:              check.ID,
+        Name:            check.Name,
+        Type:            check.Type,
+        Hosts:           check.Hosts,
+        Interval:        check.Interval,
+        Threshold:       check.Threshold,
+        HostThresholds:  check.HostThresholds,
+        SoftFailEnabled: check.SoftFailEnabled,
+        SoftFailMode:    check.SoftFailMode,
+        Timeout:         check.Timeout,
+        Enabled:         check.Enabled,
+        Options:         check.Options,
+        Retention:       check.Retention,
+        ExitCodeMap:     check.ExitCodeMap,
+        Priority:        check.Priority,
+        ObserveOnly:     check.ObserveOnly,
+        Periods:         check.Periods,
+    }
+}
+
+func configToCheck(checkCfg config.CheckConfig) *database.Check {
+    return &database.Check{
+        ID:              checkCfg.ID,
+        Name:            checkCfg.Name,
+        Type:            checkCfg.Type,
+        Hosts:           checkCfg.Hosts,
+        Interval:        checkCfg.Interval,
+        Threshold:       checkCfg.Threshold,
+        HostThresholds:  checkCfg.HostThresholds,
+        SoftFailEnabled: checkCfg.SoftFailEnabled,
+        SoftFailMode:    checkCfg.SoftFailMode,
+        Timeout:         checkCfg.Timeout,
+        Enabled:         checkCfg.Enabled,
+        Options:         checkCfg.Options,
+        Retention:       checkCfg.Retention,
+        ExitCodeMap:     checkCfg.ExitCodeMap,
+        Priority:        checkCfg.Priority,
+        ObserveOnly:     checkCfg.ObserveOnly,
+        Periods:         checkCfg.Periods,
+    }
+}
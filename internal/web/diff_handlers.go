@@ -0,0 +1,298 @@
+// internal/web/diff_handlers.go - Output diffs between status history entries
+package web
+
+import (
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+const (
+    maxDiffLines = 1000      // Lines compared per side; the rest is left out
+    maxDiffBytes = 32 * 1024 // Longest diff returned
+    diffContext  = 3         // Unchanged lines shown around each change
+)
+
+// DiffEntry identifies one side of an output diff
+type DiffEntry struct {
+    ID         string    `json:"id"`
+    Timestamp  time.Time `json:"timestamp"`
+    ExitCode   int       `json:"exit_code"`
+    OutputHash string    `json:"output_hash"`
+}
+
+// OutputDiff is the unified diff between the output of two results
+type OutputDiff struct {
+    HostID    string    `json:"host_id"`
+    CheckID   string    `json:"check_id"`
+    From      DiffEntry `json:"from"`
+    To        DiffEntry `json:"to"`
+    Changed   bool      `json:"changed"`
+    Binary    bool      `json:"binary"`    // Output isn't text, so no diff is given
+    Truncated bool      `json:"truncated"` // Input or diff was cut to the size limits
+    Diff      string    `json:"diff"`
+}
+
+// diffURL is where the alerts view finds the output diff for a failing check
+func diffURL(hostID, checkID string) string {
+    return fmt.Sprintf("/api/status/diff/%s/%s", url.PathEscape(hostID), url.PathEscape(checkID))
+}
+
+// GET /api/status/diff/:host/:check - Diff the output of the history entries
+// given by from and to (status IDs). Without them, the last OK result is
+// compared with the failure that followed it.
+func (s *Server) getStatusDiff(c *gin.Context) {
+    hostID := c.Param("host")
+    checkID := c.Param("check")
+    fromID, toID := c.Query("from"), c.Query("to")
+
+    if (fromID == "") != (toID == "") {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be given together"})
+        return
+    }
+
+    history, err := s.store.GetStatusHistory(c.Request.Context(), hostID, checkID, time.Time{})
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get status history")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status history"})
+        return
+    }
+
+    var from, to *database.Status
+    if fromID != "" {
+        for i := range history {
+            switch history[i].ID {
+            case fromID:
+                from = &history[i]
+            case toID:
+                to = &history[i]
+            }
+        }
+        if from == nil || to == nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Status entry not found in history"})
+            return
+        }
+    } else {
+        for i := len(history) - 1; i > 0; i-- {
+            if history[i].ExitCode != database.StateOK && history[i-1].ExitCode == database.StateOK {
+                from, to = &history[i-1], &history[i]
+                break
+            }
+        }
+        if from == nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "No change from OK to a failure in history"})
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{"data": outputDiff(hostID, checkID, from, to)})
+}
+
+func outputDiff(hostID, checkID string, from, to *database.Status) *OutputDiff {
+    result := &OutputDiff{
+        HostID:  hostID,
+        CheckID: checkID,
+        From:    diffEntry(from),
+        To:      diffEntry(to),
+    }
+
+    fromLines := database.OutputLines(from.Output, from.LongOutput)
+    toLines := database.OutputLines(to.Output, to.LongOutput)
+    result.Changed = strings.Join(fromLines, "\n") != strings.Join(toLines, "\n")
+
+    if isBinary(fromLines) || isBinary(toLines) {
+        result.Binary = true
+        return result
+    }
+
+    if len(fromLines) > maxDiffLines {
+        fromLines = fromLines[:maxDiffLines]
+        result.Truncated = true
+    }
+    if len(toLines) > maxDiffLines {
+        toLines = toLines[:maxDiffLines]
+        result.Truncated = true
+    }
+
+    result.Diff = unifiedDiff(from.ID, to.ID, fromLines, toLines)
+    if len(result.Diff) > maxDiffBytes {
+        cut := strings.LastIndex(result.Diff[:maxDiffBytes], "\n")
+        result.Diff = result.Diff[:cut+1]
+        result.Truncated = true
+    }
+
+    return result
+}
+
+func diffEntry(status *database.Status) DiffEntry {
+    hash := status.OutputHash
+    if hash == "" {
+        // Stored before fingerprints were kept
+        hash = database.OutputFingerprint(status.Output, status.LongOutput)
+    }
+    return DiffEntry{
+        ID:         status.ID,
+        Timestamp:  status.Timestamp,
+        ExitCode:   status.ExitCode,
+        OutputHash: hash,
+    }
+}
+
+// isBinary reports output that can't be shown as text
+func isBinary(lines []string) bool {
+    for _, line := range lines {
+        if !utf8.ValidString(line) || strings.ContainsRune(line, 0) {
+            return true
+        }
+    }
+    return false
+}
+
+type diffOp struct {
+    kind byte // ' ' kept, '-' removed or '+' added
+    text string
+}
+
+// diffLines returns the shortest edit turning a into b, from a longest
+// common subsequence of the lines that differ
+func diffLines(a, b []string) []diffOp {
+    prefix := 0
+    for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+        prefix++
+    }
+    suffix := 0
+    for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+        suffix++
+    }
+    ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+    // lcs[i][j] is the common subsequence length of ma[i:] and mb[j:]
+    lcs := make([][]int32, len(ma)+1)
+    for i := range lcs {
+        lcs[i] = make([]int32, len(mb)+1)
+    }
+    for i := len(ma) - 1; i >= 0; i-- {
+        for j := len(mb) - 1; j >= 0; j-- {
+            switch {
+            case ma[i] == mb[j]:
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            case lcs[i+1][j] >= lcs[i][j+1]:
+                lcs[i][j] = lcs[i+1][j]
+            default:
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+
+    ops := make([]diffOp, 0, len(a)+len(b))
+    for _, line := range a[:prefix] {
+        ops = append(ops, diffOp{' ', line})
+    }
+    i, j := 0, 0
+    for i < len(ma) || j < len(mb) {
+        switch {
+        case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+            ops = append(ops, diffOp{' ', ma[i]})
+            i++
+            j++
+        case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+            ops = append(ops, diffOp{'-', ma[i]})
+            i++
+        default:
+            ops = append(ops, diffOp{'+', mb[j]})
+            j++
+        }
+    }
+    for _, line := range a[len(a)-suffix:] {
+        ops = append(ops, diffOp{' ', line})
+    }
+
+    return ops
+}
+
+// unifiedDiff formats the changes from a to b as a unified diff, or returns
+// "" if there are none
+func unifiedDiff(fromName, toName string, a, b []string) string {
+    ops := diffLines(a, b)
+
+    // Lines of a and b before each op, for hunk headers
+    aPos := make([]int, len(ops)+1)
+    bPos := make([]int, len(ops)+1)
+    for k, op := range ops {
+        aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+        if op.kind != '+' {
+            aPos[k+1]++
+        }
+        if op.kind != '-' {
+            bPos[k+1]++
+        }
+    }
+
+    var out strings.Builder
+    for k := 0; k < len(ops); k++ {
+        if ops[k].kind == ' ' {
+            continue
+        }
+
+        // Extend the hunk while the next change is within reach of its context
+        start := k - diffContext
+        if start < 0 {
+            start = 0
+        }
+        end := k
+        for end < len(ops) {
+            next := end
+            for next < len(ops) && ops[next].kind != ' ' {
+                next++
+            }
+            gap := next
+            for gap < len(ops) && ops[gap].kind == ' ' {
+                gap++
+            }
+            end = next
+            if gap == len(ops) || gap-next > 2*diffContext {
+                break
+            }
+            end = gap
+        }
+        stop := end + diffContext
+        if stop > len(ops) {
+            stop = len(ops)
+        }
+
+        if out.Len() == 0 {
+            fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+        }
+        fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+            hunkRange(aPos[start], aPos[stop]-aPos[start]),
+            hunkRange(bPos[start], bPos[stop]-bPos[start]))
+        for _, op := range ops[start:stop] {
+            out.WriteByte(op.kind)
+            out.WriteString(op.text)
+            out.WriteByte('\n')
+        }
+
+        k = stop - 1
+    }
+
+    return out.String()
+}
+
+// hunkRange formats a hunk's start line and length; an empty range names the
+// line before it
+func hunkRange(before, count int) string {
+    if count == 0 {
+        return fmt.Sprintf("%d,0", before)
+    }
+    if count == 1 {
+        return fmt.Sprintf("%d", before+1)
+    }
+    return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -0,0 +1,76 @@
+// internal/web/events.go - Server-Sent Events status stream
+package web
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+)
+
+// sseKeepalive is how often an idle stream gets a comment line, so proxies
+// don't close it
+const sseKeepalive = 30 * time.Second
+
+// GET /api/events - The WebSocket status stream as Server-Sent Events, for
+// clients that only need to listen. Takes the same group, host_id and
+// min_severity filters and starts with the same snapshot; each message's
+// type is the event name.
+func (s *Server) streamEvents(c *gin.Context) {
+    sub, err := subscriptionFromQuery(c.Request.URL.Query())
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    // The stream outlives server.write_timeout
+    if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+        logrus.WithError(err).Debug("Failed to clear write deadline for event stream")
+    }
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+    c.Status(http.StatusOK)
+    c.Writer.Flush()
+
+    client := newSubscriber(sub, false)
+    s.addSubscriber(client)
+    defer s.removeClient(client)
+
+    s.sendSnapshot(c.Request.Context(), client)
+
+    keepalive := time.NewTicker(sseKeepalive)
+    defer keepalive.Stop()
+
+    for {
+        select {
+        case message, ok := <-client.send:
+            if !ok {
+                return // Dropped for falling behind
+            }
+            data, err := json.Marshal(message.Data)
+            if err != nil {
+                logrus.WithError(err).Error("Failed to encode event")
+                continue
+            }
+            if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", message.Type, data); err != nil {
+                return
+            }
+            c.Writer.Flush()
+
+        case <-keepalive.C:
+            if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+                return
+            }
+            c.Writer.Flush()
+
+        case <-c.Request.Context().Done():
+            return
+        }
+    }
+}
@@ -0,0 +1,195 @@
+// internal/web/group_handlers.go - Host group metadata and per-group summaries
+package web
+
+import (
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// GroupRequest is the body for creating or updating a group. ID is only read
+// on create; on update the ID comes from the URL.
+type GroupRequest struct {
+    ID          string            `json:"id"`
+    DisplayName string            `json:"display_name"`
+    Description string            `json:"description"`
+    DefaultTags map[string]string `json:"default_tags"`
+    SortOrder   int               `json:"sort_order"`
+}
+
+// GroupSummary joins a group's metadata with the state of its hosts
+type GroupSummary struct {
+    database.Group
+    Hosts        int            `json:"hosts"`
+    EnabledHosts int            `json:"enabled_hosts"`
+    States       map[string]int `json:"states"` // Host count per state
+}
+
+// GET /api/groups - Groups in sort order with host counts and states.
+// Disabled hosts count as parked unless include_disabled=true is passed.
+func (s *Server) getGroups(c *gin.Context) {
+    ctx := c.Request.Context()
+    includeDisabled := c.Query("include_disabled") == "true"
+
+    groups, err := s.store.GetGroups(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get groups")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get groups"})
+        return
+    }
+
+    hosts, err := s.store.GetHosts(ctx, database.HostFilters{})
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get hosts")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hosts"})
+        return
+    }
+
+    summaries := make([]GroupSummary, len(groups))
+    byID := make(map[string]*GroupSummary, len(groups))
+    for i, group := range groups {
+        summaries[i] = GroupSummary{Group: group, States: make(map[string]int)}
+        byID[group.ID] = &summaries[i]
+    }
+
+    checks := s.loadCheckSet(ctx)
+
+    for _, host := range hosts {
+        summary, exists := byID[host.Group]
+        if !exists {
+            continue
+        }
+        summary.Hosts++
+        if host.Enabled {
+            summary.EnabledHosts++
+        } else if !includeDisabled {
+            summary.States[ParkedState]++
+            continue
+        }
+        state, _ := s.getHostStatus(ctx, host.ID, checks)
+        summary.States[state]++
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  summaries,
+        "count": len(summaries),
+    })
+}
+
+// GET /api/groups/:id
+func (s *Server) getGroup(c *gin.Context) {
+    group, err := s.store.GetGroup(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if err.Error() == "group not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to get group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get group"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"data": group})
+}
+
+// POST /api/groups
+func (s *Server) createGroup(c *gin.Context) {
+    var req GroupRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if req.ID == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+        return
+    }
+
+    if _, err := s.store.GetGroup(c.Request.Context(), req.ID); err == nil {
+        c.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+        return
+    }
+
+    group := groupFromRequest(req.ID, &req)
+    if err := s.store.SaveGroup(c.Request.Context(), group); err != nil {
+        logrus.WithError(err).Error("Failed to create group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{"data": group})
+}
+
+// PUT /api/groups/:id - Groups defined in the config are reset to it when
+// the config is next synced
+func (s *Server) updateGroup(c *gin.Context) {
+    id := c.Param("id")
+
+    var req GroupRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if _, err := s.store.GetGroup(c.Request.Context(), id); err != nil {
+        if err.Error() == "group not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get group"})
+        return
+    }
+
+    group := groupFromRequest(id, &req)
+    if err := s.store.SaveGroup(c.Request.Context(), group); err != nil {
+        logrus.WithError(err).Error("Failed to update group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"data": group})
+}
+
+// DELETE /api/groups/:id - Only groups no host belongs to can be deleted
+func (s *Server) deleteGroup(c *gin.Context) {
+    ctx := c.Request.Context()
+    id := c.Param("id")
+
+    hosts, err := s.store.GetHosts(ctx, database.HostFilters{Group: id})
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get hosts")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hosts"})
+        return
+    }
+    if len(hosts) > 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Group still has hosts", "hosts": len(hosts)})
+        return
+    }
+
+    if err := s.store.DeleteGroup(ctx, id); err != nil {
+        if err.Error() == "group not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to delete group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+func groupFromRequest(id string, req *GroupRequest) *database.Group {
+    group := &database.Group{
+        ID:          id,
+        DisplayName: req.DisplayName,
+        Description: req.Description,
+        DefaultTags: req.DefaultTags,
+        SortOrder:   req.SortOrder,
+    }
+    if group.DisplayName == "" {
+        group.DisplayName = id
+    }
+    return group
+}
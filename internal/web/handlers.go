@@ -3,15 +3,19 @@ package web
 
 import (
     "context"
+    "encoding/json"
     "fmt"
     "net/http"
+    "sort"
     "strconv"
     "time"
 
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
     "github.com/sirupsen/logrus"
+    "raven2/internal/config"
     "raven2/internal/database"
+    "raven2/internal/monitoring"
 )
 
 type HostRequest struct {
@@ -27,16 +31,22 @@ type HostRequest struct {
 // Enhanced HostResponse with IP check status and additional fields
 type HostResponse struct {
     *database.Host
-    Status        string                     `json:"status"`
-    LastCheck     time.Time                  `json:"last_check"`
-    NextCheck     time.Time                  `json:"next_check"`
-    CheckCount    int                        `json:"check_count"`
-    IPAddressOK   bool                       `json:"ip_address_ok"`
-    IPLastChecked time.Time                  `json:"ip_last_checked"`
-    SoftFailInfo  map[string]*SoftFailStatus `json:"soft_fail_info,omitempty"`
-    OKDuration    map[string]*OKDurationInfo `json:"ok_duration,omitempty"`
+    Status            string                     `json:"status"`
+    LastCheck         time.Time                  `json:"last_check"`
+    NextCheck         time.Time                  `json:"next_check"`
+    CheckCount        int                        `json:"check_count"`
+    IPAddressOK       bool                       `json:"ip_address_ok"`       // Reachable over either protocol (true until probed)
+    IPLastChecked     *time.Time                 `json:"ip_last_checked"`     // Null until probed
+    IPReachability    IPReachability             `json:"ip_reachability"`
+    SoftFailInfo      map[string]*SoftFailStatus `json:"soft_fail_info,omitempty"`
+    OKDuration        map[string]*OKDurationInfo `json:"ok_duration,omitempty"`
     // NEW: Add check names mapping for frontend display
-    CheckNames    map[string]string          `json:"check_names,omitempty"`
+    CheckNames        map[string]string          `json:"check_names,omitempty"`
+    LastStateChange   time.Time                  `json:"last_state_change"`   // When the host's reported state began
+    StateDuration     int64                      `json:"state_duration"`      // milliseconds
+    StateDurationText string                     `json:"state_duration_text"` // e.g. "3h 12m"
+    SnoozedUntil      map[string]time.Time       `json:"snoozed_until,omitempty"` // By check ID
+    OverdueSince      map[string]time.Time       `json:"overdue_since,omitempty"` // By check ID, for checks that haven't run when expected
 }
 
 // SoftFailStatus tracks consecutive failures for a check - ENHANCED with check name
@@ -50,10 +60,11 @@ type SoftFailStatus struct {
 
 // OKDurationInfo tracks how long a check has been OK - ENHANCED with check name
 type OKDurationInfo struct {
-    CheckName  string    `json:"check_name"`  // NEW: Add check name
-    OKSince    time.Time `json:"ok_since"`
-    Duration   string    `json:"duration"`
-    CheckCount int       `json:"check_count"`
+    CheckName       string    `json:"check_name"`       // NEW: Add check name
+    OKSince         time.Time `json:"ok_since"`
+    Duration        string    `json:"duration"`         // e.g. "3h 12m"
+    DurationSeconds int64     `json:"duration_seconds"` // For sorting
+    CheckCount      int       `json:"check_count"`      // OK results in a row the scheduler has seen
 }
 
 // Enhanced status response with additional context
@@ -63,33 +74,69 @@ type StatusResponse struct {
     OKInfo        *OKDurationInfo `json:"ok_info,omitempty"`
     CheckName     string          `json:"check_name"`
     HostName      string          `json:"host_name"`
+    // State detail as tracked by the scheduler (soft vs hard state)
+    StateDetail   *monitoring.StateDetail `json:"state_detail,omitempty"`
+    // Check is dark-launched: recorded but not alerted on
+    ObserveOnly   bool                    `json:"observe_only,omitempty"`
+    // Check is outside its run periods: the last result is kept but not alerted on
+    OutOfPeriod   bool                    `json:"out_of_period,omitempty"`
+    NextInPeriod  *time.Time              `json:"next_in_period,omitempty"`
+    // No result for longer than monitoring.overdue_factor expected intervals:
+    // the status may be stale even if it is OK
+    Overdue       bool                    `json:"overdue,omitempty"`
+    OverdueSince  *time.Time              `json:"overdue_since,omitempty"`
 }
 
 // CheckRequest represents the request body for creating/updating checks
 type CheckRequest struct {
-    Name      string                   `json:"name" binding:"required"`
-    Type      string                   `json:"type" binding:"required"`
-    Hosts     []string                 `json:"hosts" binding:"required"`
-    Interval  map[string]string        `json:"interval"`
-    Threshold int                      `json:"threshold"`
-    Timeout   string                   `json:"timeout"`
-    Enabled   bool                     `json:"enabled"`
-    Options   map[string]interface{}   `json:"options"`
+    Name            string                 `json:"name" binding:"required"`
+    Type            string                 `json:"type" binding:"required"`
+    Hosts           []string               `json:"hosts" binding:"required"`
+    Interval        map[string]string      `json:"interval"`
+    Threshold       int                    `json:"threshold"`
+    HostThresholds  map[string]int         `json:"host_thresholds"`
+    SoftFailEnabled *bool                  `json:"soft_fail_enabled"`
+    SoftFailMode    string                 `json:"soft_fail_mode"`
+    Timeout         string                 `json:"timeout"`
+    Enabled         bool                   `json:"enabled"`
+    Options         map[string]interface{} `json:"options"`
+    Retention       string                 `json:"retention"`
+    ExitCodeMap     map[int]int            `json:"exit_code_map"`
+    Priority        int                    `json:"priority"`
+    ObserveOnly     bool                   `json:"observe_only"`
+    Periods         []database.Period      `json:"periods"`
 }
 
 // Alert represents an alert derived from status data
 type Alert struct {
-    ID        string    `json:"id"`
-    Timestamp time.Time `json:"timestamp"`
-    Severity  string    `json:"severity"`
-    Host      string    `json:"host"`
-    Check     string    `json:"check"`
-    Message   string    `json:"message"`
-    Duration  int64     `json:"duration"` // milliseconds
+    ID                string     `json:"id"`
+    Timestamp         time.Time  `json:"timestamp"`
+    Severity          string     `json:"severity"`
+    Host              string     `json:"host"`
+    Check             string     `json:"check"`
+    Message           string     `json:"message"`
+    Duration          int64      `json:"duration"`            // milliseconds in the current state
+    LastStateChange   time.Time  `json:"last_state_change"`   // When the reported state began
+    StateDuration     int64      `json:"state_duration"`      // milliseconds
+    StateDurationText string     `json:"state_duration_text"` // e.g. "3h 12m"
+    OutputHash        string     `json:"output_hash,omitempty"`
+    DiffURL           string     `json:"diff_url"`            // Output diff from the last OK result
+    SnoozedUntil      *time.Time `json:"snoozed_until"`       // Null unless snoozed
 }
 
-// GET /api/hosts - Enhanced to include IP checks and soft fail info with CHECK NAMES
+// Hosts returned per page of GET /api/hosts unless ?limit= or ?all=true
+const (
+    defaultHostPageSize = 200
+    maxHostPageSize     = 1000
+)
+
+// GET /api/hosts?limit=&offset=&all= - Hosts with their state and
+// reachability, a page at a time (200 by default). The per-check soft fail,
+// OK duration and check name maps are only in GET /api/hosts/:id. Hosts are
+// encoded as they are built, so a large inventory isn't held in memory as
+// one response.
 func (s *Server) getHosts(c *gin.Context) {
+    ctx := c.Request.Context()
     group := c.Query("group")
     enabledStr := c.Query("enabled")
     
@@ -102,64 +149,111 @@ func (s *Server) getHosts(c *gin.Context) {
         filters.Enabled = &enabled
     }
 
-    hosts, err := s.store.GetHosts(c.Request.Context(), filters)
+    limit := defaultHostPageSize
+    if value := c.Query("limit"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 || parsed > maxHostPageSize {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit: %s (1-%d)", value, maxHostPageSize)})
+            return
+        }
+        limit = parsed
+    }
+    offset := 0
+    if value := c.Query("offset"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset: " + value})
+            return
+        }
+        offset = parsed
+    }
+    all := c.Query("all") == "true"
+
+    hosts, err := s.store.GetHosts(ctx, filters)
     if err != nil {
         logrus.WithError(err).Error("Failed to get hosts")
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hosts"})
         return
     }
 
-    // Enhance with comprehensive status information
-    response := make([]HostResponse, 0, len(hosts))
-    for i := range hosts {
-        host := hosts[i]
-        
-        // Get overall status for this specific host
-        status := s.getHostStatus(c.Request.Context(), host.ID)
-        
-        // Get latest status timestamp for this host
-        statuses, err := s.store.GetStatus(c.Request.Context(), database.StatusFilters{
-            HostID: host.ID,
-            Limit:  1,
-        })
-        
-        var lastCheck time.Time
-        if err == nil && len(statuses) > 0 {
-            lastCheck = statuses[0].Timestamp
-        }
+    total := len(hosts)
+    if all {
+        offset, limit = 0, total
+    }
+    page := hosts[min(offset, total):min(offset+limit, total)]
 
-        // Check IP address connectivity
-        ipOK, ipLastChecked := s.checkIPAddress(host.IPv4, host.Hostname)
+    checks := s.loadCheckSet(ctx)
 
-        // CHANGE: Use NEW functions with names
-        softFailInfo := s.getSoftFailInfoWithNames(c.Request.Context(), host.ID)
-        okDuration := s.getOKDurationInfoWithNames(c.Request.Context(), host.ID)
-        checkNames := s.getCheckNamesForHost(c.Request.Context(), host.ID)
+    c.Header("Content-Type", "application/json; charset=utf-8")
+    c.Status(http.StatusOK)
+    fmt.Fprintf(c.Writer, `{"count":%d,"total":%d,"offset":%d,"limit":%d,"data":[`, len(page), total, offset, limit)
 
-        hostResp := HostResponse{
-            Host:          &host,
-            Status:        status,
-            LastCheck:     lastCheck,
-            NextCheck:     time.Time{}, // TODO: Calculate from scheduler
-            CheckCount:    0,           // TODO: Count active checks for this host
-            IPAddressOK:   ipOK,
-            IPLastChecked: ipLastChecked,
-            SoftFailInfo:  softFailInfo,
-            OKDuration:    okDuration,
-            CheckNames:    checkNames,    // NEW: Add this line
+    encoder := json.NewEncoder(c.Writer)
+    for i := range page {
+        if i > 0 {
+            c.Writer.WriteString(",")
+        }
+        if err := encoder.Encode(s.hostResponse(ctx, &page[i], false, checks)); err != nil {
+            logrus.WithError(err).Warn("Failed to stream host list")
+            return
         }
-        response = append(response, hostResp)
     }
+    c.Writer.WriteString("]}")
+}
 
-    c.JSON(http.StatusOK, gin.H{
-        "data":  response,
-        "count": len(response),
-    })
+// hostResponse adds a host's state, last check and reachability. With
+// details it also carries the per-check soft fail, OK duration and name maps.
+// checks is loaded once by the caller, however many hosts it lists.
+func (s *Server) hostResponse(ctx context.Context, host *database.Host, details bool, checks *checkSet) HostResponse {
+    // Get overall status for this specific host
+    status, stateSince := s.getHostStatus(ctx, host.ID, checks)
+    var stateDuration int64
+    if !stateSince.IsZero() {
+        stateDuration = time.Since(stateSince).Milliseconds()
+    }
+    
+    // Get latest status timestamp for this host
+    statuses, err := s.store.GetLatestStatuses(ctx, host.ID)
+    
+    var lastCheck time.Time
+    if err == nil && len(statuses) > 0 {
+        lastCheck = statuses[0].Timestamp
+    }
+
+    // Check IP address connectivity
+    ipReach := s.checkIPAddress(host)
+
+    response := HostResponse{
+        Host:              host,
+        Status:            status,
+        LastCheck:         lastCheck,
+        NextCheck:         time.Time{}, // TODO: Calculate from scheduler
+        CheckCount:        s.engine.Coverage().EnabledCheckCount(host.ID),
+        IPAddressOK:       ipReach.OK(),
+        IPLastChecked:     ipReach.CheckedAt,
+        IPReachability:    ipReach,
+        LastStateChange:   stateSince,
+        StateDuration:     stateDuration,
+        StateDurationText: formatDuration(time.Duration(stateDuration) * time.Millisecond),
+    }
+    if snoozed := s.engine.HostSnoozes(host.ID); len(snoozed) > 0 {
+        response.SnoozedUntil = snoozed
+    }
+    if overdue := s.engine.HostOverdue(host, checks.byID); len(overdue) > 0 {
+        response.OverdueSince = overdue
+    }
+    if details {
+        response.SoftFailInfo = s.getSoftFailInfoWithNames(ctx, host.ID, checks)
+        response.OKDuration = s.getOKDurationInfoWithNames(ctx, host.ID, checks)
+        response.CheckNames = s.engine.Coverage().ChecksForHost(host.ID)
+    }
+    return response
 }
 
-// checkIPAddress performs a basic connectivity test to the host's IP or hostname
-func (s *Server) checkIPAddress(ipv4, hostname string) (bool, time.Time) {
-    return true, time.Now()
+// checkIPAddress reports the host's IPv4 and IPv6 reachability from the
+// probe cache, refreshing stale results in the background
+func (s *Server) checkIPAddress(host *database.Host) IPReachability {
+    return s.ipProber.reachability(host)
 }
 
 // LEGACY: Keep original functions for backward compatibility, but mark as deprecated
@@ -167,7 +261,7 @@ func (s *Server) checkIPAddress(ipv4, hostname string) (bool, time.Time) {
 // DEPRECATED: Use getSoftFailInfoWithNames instead
 func (s *Server) getSoftFailInfo(ctx context.Context, hostID string) map[string]*SoftFailStatus {
     // Convert new format to old format (without check names)
-    newFormat := s.getSoftFailInfoWithNames(ctx, hostID)
+    newFormat := s.getSoftFailInfoWithNames(ctx, hostID, s.loadCheckSet(ctx))
     oldFormat := make(map[string]*SoftFailStatus)
     
     for checkID, failInfo := range newFormat {
@@ -187,14 +281,15 @@ func (s *Server) getSoftFailInfo(ctx context.Context, hostID string) map[string]
 // DEPRECATED: Use getOKDurationInfoWithNames instead
 func (s *Server) getOKDurationInfo(ctx context.Context, hostID string) map[string]*OKDurationInfo {
     // Convert new format to old format (without check names)
-    newFormat := s.getOKDurationInfoWithNames(ctx, hostID)
+    newFormat := s.getOKDurationInfoWithNames(ctx, hostID, s.loadCheckSet(ctx))
     oldFormat := make(map[string]*OKDurationInfo)
     
     for checkID, okInfo := range newFormat {
         oldFormat[checkID] = &OKDurationInfo{
-            OKSince:    okInfo.OKSince,
-            Duration:   okInfo.Duration,
-            CheckCount: okInfo.CheckCount,
+            OKSince:         okInfo.OKSince,
+            Duration:        okInfo.Duration,
+            DurationSeconds: okInfo.DurationSeconds,
+            CheckCount:      okInfo.CheckCount,
             // Don't include CheckName for backward compatibility
         }
     }
@@ -218,6 +313,11 @@ func (s *Server) getStatus(c *gin.Context) {
             filters.ExitCode = &exitCode
         }
     }
+    if sinceStr := c.Query("since"); sinceStr != "" {
+        if since, err := time.Parse(time.RFC3339, sinceStr); err == nil {
+            filters.Since = &since
+        }
+    }
 
     statuses, err := s.store.GetStatus(c.Request.Context(), filters)
     if err != nil {
@@ -228,19 +328,25 @@ func (s *Server) getStatus(c *gin.Context) {
 
     // Enhance statuses with additional context
     enhancedStatuses := make([]StatusResponse, 0, len(statuses))
+    now := time.Now()
     
     for i := range statuses {
         status := statuses[i]
         
         // Get check name
         checkName := status.CheckID
-        if check, err := s.store.GetCheck(c.Request.Context(), status.CheckID); err == nil {
+        observeOnly := false
+        var check *database.Check
+        if found, err := s.store.GetCheck(c.Request.Context(), status.CheckID); err == nil {
+            check = found
             checkName = check.Name
+            observeOnly = check.ObserveOnly
         }
 
         // Get host name
         hostName := status.HostID
-        if host, err := s.store.GetHost(c.Request.Context(), status.HostID); err == nil {
+        host, err := s.store.GetHost(c.Request.Context(), status.HostID)
+        if err == nil {
             if host.DisplayName != "" {
                 hostName = host.DisplayName
             } else {
@@ -249,27 +355,37 @@ func (s *Server) getStatus(c *gin.Context) {
         }
 
         enhancedStatus := StatusResponse{
-            Status:    &status,
-            CheckName: checkName,
-            HostName:  hostName,
+            Status:      &status,
+            CheckName:   checkName,
+            HostName:    hostName,
+            ObserveOnly: observeOnly,
         }
 
-        // Add soft fail info for non-OK statuses WITH check names
-        if status.ExitCode != 0 {
-            softFailInfo := s.getSoftFailInfoWithNames(c.Request.Context(), status.HostID)
-            if info, exists := softFailInfo[status.CheckID]; exists {
-                enhancedStatus.SoftFailsInfo = info
+        if check != nil && host != nil {
+            if since, overdue := s.engine.OverdueSince(check, host, now); overdue {
+                enhancedStatus.Overdue = true
+                enhancedStatus.OverdueSince = &since
             }
         }
 
-        // Add OK duration info for OK statuses WITH check names
-        if status.ExitCode == 0 {
-            okDurationInfo := s.getOKDurationInfoWithNames(c.Request.Context(), status.HostID)
-            if info, exists := okDurationInfo[status.CheckID]; exists {
-                enhancedStatus.OKInfo = info
+        if check != nil && !check.InPeriod(now) {
+            enhancedStatus.OutOfPeriod = true
+            if next := check.NextPeriodStart(now); !next.IsZero() {
+                next = next.UTC()
+                enhancedStatus.NextInPeriod = &next
             }
         }
 
+        if detail, exists := s.engine.GetStateDetail(status.HostID, status.CheckID); exists {
+            enhancedStatus.StateDetail = detail
+
+            // Add soft fail info WITH check names
+            enhancedStatus.SoftFailsInfo = s.softFailStatus(status.HostID, check, detail)
+        }
+
+        // Add OK duration info for OK statuses WITH check names
+        enhancedStatus.OKInfo = s.okDurationInfo(&status, checkName)
+
         enhancedStatuses = append(enhancedStatuses, enhancedStatus)
     }
 
@@ -279,6 +395,75 @@ func (s *Server) getStatus(c *gin.Context) {
     })
 }
 
+// maxStatusBatchSize caps how many pairs a single batch status request may ask for
+const maxStatusBatchSize = 500
+
+// StatusBatchRequest lists the host/check pairs to fetch
+type StatusBatchRequest struct {
+    Pairs []StatusBatchPair `json:"pairs" binding:"required"`
+}
+
+type StatusBatchPair struct {
+    HostID  string `json:"host_id"`
+    CheckID string `json:"check_id"`
+}
+
+// StatusBatchResult is the latest status for one requested pair, or nil if
+// the check has not reported for that host yet
+type StatusBatchResult struct {
+    HostID  string           `json:"host_id"`
+    CheckID string           `json:"check_id"`
+    Status  *database.Status `json:"status"`
+}
+
+// POST /api/status/batch - Latest status for many host/check pairs in one call
+func (s *Server) getStatusBatch(c *gin.Context) {
+    var req StatusBatchRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if len(req.Pairs) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "At least one pair is required"})
+        return
+    }
+    if len(req.Pairs) > maxStatusBatchSize {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many pairs: %d (max %d)", len(req.Pairs), maxStatusBatchSize)})
+        return
+    }
+
+    pairs := make([]database.HostCheckPair, len(req.Pairs))
+    for i, pair := range req.Pairs {
+        if pair.HostID == "" || pair.CheckID == "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pairs[%d] needs both host_id and check_id", i)})
+            return
+        }
+        pairs[i] = database.HostCheckPair{HostID: pair.HostID, CheckID: pair.CheckID}
+    }
+
+    statuses, err := s.store.GetStatusBatch(c.Request.Context(), pairs)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get status batch")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+        return
+    }
+
+    results := make([]StatusBatchResult, len(pairs))
+    for i, pair := range pairs {
+        results[i] = StatusBatchResult{
+            HostID:  pair.HostID,
+            CheckID: pair.CheckID,
+            Status:  statuses[i],
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  results,
+        "count": len(results),
+    })
+}
+
 // Helper function to format duration in a human-readable way
 func formatDuration(d time.Duration) string {
     if d < time.Minute {
@@ -316,7 +501,8 @@ func (s *Server) getHost(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"data": host})
+    ctx := c.Request.Context()
+    c.JSON(http.StatusOK, gin.H{"data": s.hostResponse(ctx, host, true, s.loadCheckSet(ctx))})
 }
 
 func (s *Server) createHost(c *gin.Context) {
@@ -335,8 +521,8 @@ func (s *Server) createHost(c *gin.Context) {
         Group:       req.Group,
         Enabled:     req.Enabled,
         Tags:        req.Tags,
-        CreatedAt:   time.Now(),
-        UpdatedAt:   time.Now(),
+        CreatedAt:   time.Now().UTC(),
+        UpdatedAt:   time.Now().UTC(),
     }
 
     if host.Group == "" {
@@ -345,6 +531,11 @@ func (s *Server) createHost(c *gin.Context) {
     if host.Tags == nil {
         host.Tags = make(map[string]string)
     }
+    if err := s.engine.ApplyGroup(c.Request.Context(), host); err != nil {
+        logrus.WithError(err).Error("Failed to apply host group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create host"})
+        return
+    }
 
     if err := s.store.CreateHost(c.Request.Context(), host); err != nil {
         logrus.WithError(err).Error("Failed to create host")
@@ -378,6 +569,7 @@ func (s *Server) updateHost(c *gin.Context) {
     }
 
     // Update fields
+    reenabled := !host.Enabled && req.Enabled
     host.Name = req.Name
     host.DisplayName = req.DisplayName
     host.IPv4 = req.IPv4
@@ -385,7 +577,12 @@ func (s *Server) updateHost(c *gin.Context) {
     host.Group = req.Group
     host.Enabled = req.Enabled
     host.Tags = req.Tags
-    host.UpdatedAt = time.Now()
+    host.UpdatedAt = time.Now().UTC()
+    if err := s.engine.ApplyGroup(c.Request.Context(), host); err != nil {
+        logrus.WithError(err).Error("Failed to apply host group")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update host"})
+        return
+    }
 
     if err := s.store.UpdateHost(c.Request.Context(), host); err != nil {
         logrus.WithError(err).Error("Failed to update host")
@@ -395,60 +592,143 @@ func (s *Server) updateHost(c *gin.Context) {
 
     // Notify monitoring engine of host change
     s.engine.RefreshConfig()
+    s.pruneMetricSeries(c.Request.Context())
+    if reenabled {
+        s.engine.RunSoon(host.ID, "") // Replace the parked results straight away
+    }
 
     c.JSON(http.StatusOK, gin.H{"data": host})
 }
 
 func (s *Server) deleteHost(c *gin.Context) {
+    ctx := c.Request.Context()
     id := c.Param("id")
-    
-    if err := s.store.DeleteHost(c.Request.Context(), id); err != nil {
+
+    if _, err := s.engine.DeleteHost(ctx, id); err != nil {
         logrus.WithError(err).Error("Failed to delete host")
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete host"})
         return
     }
+    if _, err := s.store.RemoveHostsFromChecks(ctx, []string{id}); err != nil {
+        logrus.WithError(err).Error("Failed to remove deleted host from checks")
+    }
 
     // Notify monitoring engine
     s.engine.RefreshConfig()
+    s.pruneMetricSeries(c.Request.Context())
 
     c.JSON(http.StatusOK, gin.H{"message": "Host deleted successfully"})
 }
 
-func (s *Server) getHostStatus(ctx context.Context, hostID string) string {
-    // Get latest status for host
-    statuses, err := s.store.GetStatus(ctx, database.StatusFilters{
-        HostID: hostID,
-        Limit:  1,
-    })
+// getHostStatus returns the host's state, taken from its most recent
+// counted check result, and when that check's reported state last changed
+func (s *Server) getHostStatus(ctx context.Context, hostID string, checks *checkSet) (string, time.Time) {
+    // Current status of each check on the host, newest first
+    statuses, err := s.store.GetLatestStatuses(ctx, hostID)
     
-    if err != nil || len(statuses) == 0 {
-        return "unknown"
+    if err != nil {
+        return database.StateName(database.StateUnknown), time.Time{}
     }
 
-    switch statuses[0].ExitCode {
-    case 0:
-        return "ok"
-    case 1:
-        return "warning"
-    case 2:
-        return "critical"
-    default:
-        return "unknown"
+    // Observe-only and disabled checks don't count towards the host's state
+    for i := range statuses {
+        if !checks.uncounted[statuses[i].CheckID] {
+            return database.StateName(statuses[i].ExitCode), s.stateChangedAt(&statuses[i])
+        }
+    }
+
+    return database.StateName(database.StateUnknown), time.Time{}
+}
+
+// stateChangedAt returns when a status's reported state began. The
+// scheduler's tracker is preferred; the value persisted with the status
+// covers results the tracker has moved on from, and records stored before
+// it was persisted fall back to their own timestamp.
+func (s *Server) stateChangedAt(status *database.Status) time.Time {
+    if detail, ok := s.engine.GetStateDetail(status.HostID, status.CheckID); ok &&
+        detail.CurrentState == status.ExitCode && !detail.LastStateChange.IsZero() {
+        return detail.LastStateChange
     }
+    if !status.LastStateChange.IsZero() {
+        return status.LastStateChange
+    }
+    return status.Timestamp
 }
 
-// Helper function to convert exit codes to status names
-func getStatusName(exitCode int) string {
-    switch exitCode {
-    case 0:
-        return "ok"
-    case 1:
-        return "warning"
-    case 2:
-        return "critical"
-    default:
-        return "unknown"
+// getObserveOnlyChecks returns the IDs of dark-launched checks, which are
+// left out of alerts, alert counts and host state rollups
+func (s *Server) getObserveOnlyChecks(ctx context.Context) map[string]bool {
+    observeOnly := make(map[string]bool)
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        return observeOnly
+    }
+
+    for _, check := range checks {
+        if check.ObserveOnly {
+            observeOnly[check.ID] = true
+        }
     }
+    return observeOnly
+}
+
+// checkSet is every check, read once by a request that looks at many hosts
+// rather than once per host
+type checkSet struct {
+    byID      map[string]*database.Check
+    uncounted map[string]bool // Observe-only and disabled checks, whose results don't count towards their hosts' state
+}
+
+// name returns a check's name, or its ID if it has none or is gone
+func (cs *checkSet) name(checkID string) string {
+    if check, ok := cs.byID[checkID]; ok && check.Name != "" {
+        return check.Name
+    }
+    return checkID
+}
+
+func (s *Server) loadCheckSet(ctx context.Context) *checkSet {
+    set := &checkSet{
+        byID:      make(map[string]*database.Check),
+        uncounted: make(map[string]bool),
+    }
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        return set
+    }
+
+    for i := range checks {
+        check := &checks[i]
+        set.byID[check.ID] = check
+        if check.ObserveOnly || !check.Enabled {
+            set.uncounted[check.ID] = true
+        }
+    }
+    return set
+}
+
+// getOutOfPeriodChecks returns the IDs of checks outside their run periods
+// at now. Their last result stands until the next period, so it is left out
+// of alerts and alert counts rather than alerted on for hours.
+func (s *Server) getOutOfPeriodChecks(ctx context.Context, now time.Time) map[string]bool {
+    outOfPeriod := make(map[string]bool)
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        return outOfPeriod
+    }
+
+    for _, check := range checks {
+        if !check.InPeriod(now) {
+            outOfPeriod[check.ID] = true
+        }
+    }
+    return outOfPeriod
 }
 
 
@@ -470,6 +750,12 @@ func (s *Server) createCheck(c *gin.Context) {
             return
         }
     }
+    normalized, err := config.NormalizeIntervals(intervalDurations, s.config().Monitoring.IntervalFor(req.Type))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval: " + err.Error()})
+        return
+    }
+    intervalDurations = normalized
 
     // Parse timeout
     var timeout time.Duration
@@ -482,18 +768,67 @@ func (s *Server) createCheck(c *gin.Context) {
         }
     }
 
+    // Parse retention
+    var retention time.Duration
+    if req.Retention != "" {
+        if r, err := time.ParseDuration(req.Retention); err == nil && r >= 0 {
+            retention = r
+        } else {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention format: " + req.Retention})
+            return
+        }
+    }
+
+    if err := database.ValidateExitCodeMap(req.ExitCodeMap); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if err := database.ValidatePeriods(req.Periods); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + err.Error()})
+        return
+    }
+
+    if err := database.ValidateHostThresholds(req.Hosts, req.HostThresholds); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + err.Error()})
+        return
+    }
+
+    if err := s.engine.ValidateSoftFail(req.SoftFailEnabled, req.SoftFailMode, req.Threshold); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if _, exists := s.engine.GetPlugin(req.Type); !exists {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Unknown check type: " + req.Type + " (see /api/plugins)"})
+        return
+    }
+
+    if err := s.engine.ValidateCheckOptions(req.Type, req.Options); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid options: " + err.Error()})
+        return
+    }
+
     check := &database.Check{
-        ID:        uuid.New().String(),
-        Name:      req.Name,
-        Type:      req.Type,
-        Hosts:     req.Hosts,
-        Interval:  intervalDurations,
-        Threshold: req.Threshold,
-        Timeout:   timeout,
-        Enabled:   req.Enabled,
-        Options:   req.Options,
-        CreatedAt: time.Now(),
-        UpdatedAt: time.Now(),
+        ID:              uuid.New().String(),
+        Name:            req.Name,
+        Type:            req.Type,
+        Hosts:           req.Hosts,
+        Interval:        intervalDurations,
+        Threshold:       req.Threshold,
+        HostThresholds:  req.HostThresholds,
+        SoftFailEnabled: req.SoftFailEnabled,
+        SoftFailMode:    req.SoftFailMode,
+        Timeout:         timeout,
+        Enabled:         req.Enabled,
+        Options:         req.Options,
+        Retention:       retention,
+        ExitCodeMap:     req.ExitCodeMap,
+        Priority:        req.Priority,
+        ObserveOnly:     req.ObserveOnly,
+        Periods:         req.Periods,
+        CreatedAt:       time.Now().UTC(),
+        UpdatedAt:       time.Now().UTC(),
     }
 
     if err := s.store.CreateCheck(c.Request.Context(), check); err != nil {
@@ -501,9 +836,10 @@ func (s *Server) createCheck(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create check"})
         return
     }
+    s.engine.CheckSaved(check)
 
     s.engine.RefreshConfig()
-    c.JSON(http.StatusCreated, gin.H{"data": check})
+    c.JSON(http.StatusCreated, gin.H{"data": s.redactedCheck(check)})
 }
 
 // PUT /api/checks/:id - Update existing check
@@ -537,6 +873,12 @@ func (s *Server) updateCheck(c *gin.Context) {
             return
         }
     }
+    normalized, err := config.NormalizeIntervals(intervalDurations, s.config().Monitoring.IntervalFor(req.Type))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval: " + err.Error()})
+        return
+    }
+    intervalDurations = normalized
 
     // Parse timeout
     var timeout time.Duration
@@ -549,27 +891,85 @@ func (s *Server) updateCheck(c *gin.Context) {
         }
     }
 
+    // Parse retention
+    var retention time.Duration
+    if req.Retention != "" {
+        if r, err := time.ParseDuration(req.Retention); err == nil && r >= 0 {
+            retention = r
+        } else {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention format: " + req.Retention})
+            return
+        }
+    }
+
+    if err := database.ValidateExitCodeMap(req.ExitCodeMap); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if err := database.ValidatePeriods(req.Periods); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + err.Error()})
+        return
+    }
+
+    if err := database.ValidateHostThresholds(req.Hosts, req.HostThresholds); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + err.Error()})
+        return
+    }
+
+    if err := s.engine.ValidateSoftFail(req.SoftFailEnabled, req.SoftFailMode, req.Threshold); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if _, exists := s.engine.GetPlugin(req.Type); !exists {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Unknown check type: " + req.Type + " (see /api/plugins)"})
+        return
+    }
+
+    s.keepSecrets(req.Options, check.Options)
+    if err := s.engine.ValidateCheckOptions(req.Type, req.Options); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid options: " + err.Error()})
+        return
+    }
+
     // Update check fields
+    before := *check
+    reenabled := !check.Enabled && req.Enabled
     check.Name = req.Name
     check.Type = req.Type
     check.Hosts = req.Hosts
     check.Interval = intervalDurations
     check.Threshold = req.Threshold
+    check.HostThresholds = req.HostThresholds
+    check.SoftFailEnabled = req.SoftFailEnabled
+    check.SoftFailMode = req.SoftFailMode
     check.Timeout = timeout
     check.Enabled = req.Enabled
     check.Options = req.Options
-    check.UpdatedAt = time.Now()
+    check.Retention = retention
+    check.ExitCodeMap = req.ExitCodeMap
+    check.Priority = req.Priority
+    check.ObserveOnly = req.ObserveOnly
+    check.Periods = req.Periods
+    check.UpdatedAt = time.Now().UTC()
 
     if err := s.store.UpdateCheck(c.Request.Context(), check); err != nil {
         logrus.WithError(err).Error("Failed to update check")
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update check"})
         return
     }
+    s.engine.CheckSaved(check)
+    s.engine.CheckChanged(&before, check)
 
     // Notify monitoring engine of check change
     s.engine.RefreshConfig()
+    s.pruneMetricSeries(c.Request.Context())
+    if reenabled {
+        s.engine.RunSoon("", check.ID) // Replace the parked results straight away
+    }
 
-    c.JSON(http.StatusOK, gin.H{"data": check})
+    c.JSON(http.StatusOK, gin.H{"data": s.redactedCheck(check)})
 }
 
 // DELETE /api/checks/:id - Delete existing check
@@ -587,7 +987,7 @@ func (s *Server) deleteCheck(c *gin.Context) {
         return
     }
 
-    if err := s.store.DeleteCheck(c.Request.Context(), id); err != nil {
+    if err := s.engine.DeleteCheck(c.Request.Context(), id); err != nil {
         logrus.WithError(err).Error("Failed to delete check")
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete check"})
         return
@@ -595,21 +995,78 @@ func (s *Server) deleteCheck(c *gin.Context) {
 
     // Notify monitoring engine
     s.engine.RefreshConfig()
+    s.pruneMetricSeries(c.Request.Context())
 
     c.JSON(http.StatusOK, gin.H{"message": "Check deleted successfully"})
 }
 
-// GET /api/alerts - Get current alerts
+// listedPairs answers whether a check still lists a host, caching the
+// coverage index's per-host lookups for one request. Results for a pair no
+// check lists any more are kept as history but aren't current.
+type listedPairs struct {
+    coverage monitoring.CheckCoverage
+    hosts    map[string]map[string]string
+}
+
+func (s *Server) newListedPairs() *listedPairs {
+    return &listedPairs{coverage: s.engine.Coverage(), hosts: make(map[string]map[string]string)}
+}
+
+func (l *listedPairs) listed(hostID, checkID string) bool {
+    checks, exists := l.hosts[hostID]
+    if !exists {
+        checks = l.coverage.ChecksForHost(hostID)
+        l.hosts[hostID] = checks
+    }
+    _, listed := checks[checkID]
+    return listed
+}
+
+// getActiveProblems returns the current non-OK result of every host/check
+// pair that can alert. The status bucket holds only the latest result of
+// each pair, so each problem appears once however long it has lasted.
+func (s *Server) getActiveProblems(c *gin.Context, now time.Time) ([]database.Status, error) {
+    ctx := c.Request.Context()
+    statuses, err := s.store.GetStatus(ctx, database.StatusFilters{})
+    if err != nil {
+        return nil, err
+    }
+
+    observeOnly := s.getObserveOnlyChecks(ctx)
+    outOfPeriod := s.getOutOfPeriodChecks(ctx, now)
+    parked := s.getParked(c)
+    listed := s.newListedPairs()
+
+    problems := statuses[:0]
+    for _, status := range statuses {
+        switch {
+        case status.ExitCode == 0:
+            continue
+        case observeOnly[status.CheckID]:
+            continue // Dark-launched checks never alert
+        case parked.parked(status.HostID, status.CheckID):
+            continue // Left over from before the host or check was disabled
+        case outOfPeriod[status.CheckID]:
+            continue // Not due to run until its next period
+        case !listed.listed(status.HostID, status.CheckID):
+            continue // The check no longer runs on the host
+        }
+        problems = append(problems, status)
+    }
+    return problems, nil
+}
+
+// GET /api/alerts - Get current alerts, one per failing host/check, longest
+// standing first. limit (default 100, 0 for all) applies after filtering;
+// total counts every matching alert.
 func (s *Server) getAlerts(c *gin.Context) {
     limitStr := c.DefaultQuery("limit", "100")
     limit, _ := strconv.Atoi(limitStr)
     
     severityFilter := c.Query("severity") // optional: critical, warning, unknown
 
-    // Get recent status entries that indicate problems
-    statuses, err := s.store.GetStatus(c.Request.Context(), database.StatusFilters{
-        Limit: limit,
-    })
+    now := time.Now()
+    statuses, err := s.getActiveProblems(c, now)
     if err != nil {
         logrus.WithError(err).Error("Failed to get status for alerts")
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alerts"})
@@ -617,45 +1074,60 @@ func (s *Server) getAlerts(c *gin.Context) {
     }
 
     // Convert problematic statuses to alerts
-    var alerts []Alert
-    now := time.Now()
-    
-    for _, status := range statuses {
-        if status.ExitCode == 0 {
-            continue // Skip OK statuses
-        }
-
-        severity := getStatusName(status.ExitCode)
+    alerts := []Alert{}
+    for i := range statuses {
+        status := &statuses[i]
+        severity := database.StateName(status.ExitCode)
         
         // Apply severity filter if specified
         if severityFilter != "" && severity != severityFilter {
             continue
         }
 
+        stateSince := s.stateChangedAt(status)
+        stateDuration := now.Sub(stateSince).Milliseconds()
+
         alert := Alert{
-            ID:        status.ID,
-            Timestamp: status.Timestamp,
-            Severity:  severity,
-            Host:      status.HostID,
-            Check:     status.CheckID,
-            Message:   status.Output,
-            Duration:  now.Sub(status.Timestamp).Milliseconds(),
+            ID:                status.ID,
+            Timestamp:         status.Timestamp,
+            Severity:          severity,
+            Host:              status.HostID,
+            Check:             status.CheckID,
+            Message:           status.Output,
+            Duration:          stateDuration,
+            LastStateChange:   stateSince,
+            StateDuration:     stateDuration,
+            StateDurationText: formatDuration(time.Duration(stateDuration) * time.Millisecond),
+            OutputHash:        status.OutputHash,
+            DiffURL:           diffURL(status.HostID, status.CheckID),
+        }
+        if until, snoozed := s.engine.SnoozedUntil(status.HostID, status.CheckID); snoozed {
+            alert.SnoozedUntil = &until
         }
         
         alerts = append(alerts, alert)
     }
 
+    // Longest-standing problems first
+    sort.SliceStable(alerts, func(i, j int) bool {
+        return alerts[i].StateDuration > alerts[j].StateDuration
+    })
+
+    total := len(alerts)
+    if limit > 0 && len(alerts) > limit {
+        alerts = alerts[:limit]
+    }
+
     c.JSON(http.StatusOK, gin.H{
         "data":  alerts,
         "count": len(alerts),
+        "total": total,
     })
 }
 
-// GET /api/alerts/summary - Get alert summary statistics
+// GET /api/alerts/summary - Count current alerts, one per failing host/check
 func (s *Server) getAlertsSummary(c *gin.Context) {
-    statuses, err := s.store.GetStatus(c.Request.Context(), database.StatusFilters{
-        Limit: 1000, // Get more data for accurate summary
-    })
+    statuses, err := s.getActiveProblems(c, time.Now())
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alert summary"})
         return
@@ -669,187 +1141,97 @@ func (s *Server) getAlertsSummary(c *gin.Context) {
     }
 
     for _, status := range statuses {
-        if status.ExitCode > 0 {
-            summary["active"]++
-            
-            switch status.ExitCode {
-            case 1:
-                summary["warning"]++
-            case 2:
-                summary["critical"]++
-            case 3:
-                summary["unknown"]++
-            }
-        }
+        summary["active"]++
+        summary[database.StateName(status.ExitCode)]++
     }
 
     c.JSON(http.StatusOK, gin.H{"data": summary})
 }
 
-// getCheckNamesForHost returns a mapping of check IDs to check names for a specific host
-func (s *Server) getCheckNamesForHost(ctx context.Context, hostID string) map[string]string {
-    checkNames := make(map[string]string)
-
-    // Get all checks that include this host
-    checks, err := s.store.GetChecks(ctx)
-    if err != nil {
-        logrus.WithError(err).Error("Failed to get checks for host")
-        return checkNames
-    }
+// getSoftFailInfoWithNames reports the checks on a host that are failing
+// but not yet confirmed by the soft fail threshold, WITH check names.
+// The scheduler's state tracker is the source of truth: stored statuses already
+// carry the soft-fail adjusted exit code, so re-deriving counts from history
+// would disagree with what the scheduler is actually tracking.
+func (s *Server) getSoftFailInfoWithNames(ctx context.Context, hostID string, checks *checkSet) map[string]*SoftFailStatus {
+    softFailInfo := make(map[string]*SoftFailStatus)
 
-    for _, check := range checks {
-        for _, checkHostID := range check.Hosts {
-            if checkHostID == hostID {
-                checkNames[check.ID] = check.Name
-                break
-            }
+    for checkID, detail := range s.engine.GetHostStateDetails(hostID) {
+        if info := s.softFailStatus(hostID, checks.byID[checkID], detail); info != nil {
+            softFailInfo[checkID] = info
         }
     }
 
-    return checkNames
+    return softFailInfo
 }
 
-// getSoftFailInfoWithNames retrieves soft failure information WITH check names
-func (s *Server) getSoftFailInfoWithNames(ctx context.Context, hostID string) map[string]*SoftFailStatus {
-    softFailInfo := make(map[string]*SoftFailStatus)
-
-    // Get recent statuses for this host to analyze failure patterns
-    statuses, err := s.store.GetStatus(ctx, database.StatusFilters{
-        HostID: hostID,
-        Limit:  100, // Get enough history to analyze patterns
-    })
-    
-    if err != nil {
-        logrus.WithError(err).Error("Failed to get status for soft fail analysis")
-        return softFailInfo
+// softFailStatus reports a check's pending soft failure on a host, or nil
+// if the tracker isn't holding one back: the state has to be soft, pending
+// a failure rather than a recovery, and soft fail still enabled for the
+// check on this host
+func (s *Server) softFailStatus(hostID string, check *database.Check, detail *monitoring.StateDetail) *SoftFailStatus {
+    if check == nil || detail == nil || !detail.IsSoft || detail.PendingState == database.StateOK {
+        return nil
     }
-
-    // Group statuses by check_id and analyze failure patterns
-    checkStatuses := make(map[string][]*database.Status)
-    for i := range statuses {
-        checkID := statuses[i].CheckID
-        if checkStatuses[checkID] == nil {
-            checkStatuses[checkID] = make([]*database.Status, 0)
-        }
-        checkStatuses[checkID] = append(checkStatuses[checkID], &statuses[i])
+    if !s.engine.HostSoftFailDecision(check, hostID).Enabled {
+        return nil
     }
 
-    // Analyze each check's failure pattern
-    for checkID, statusList := range checkStatuses {
-        if len(statusList) == 0 {
-            continue
-        }
-
-        // Look for consecutive failures at the start of the list (most recent)
-        consecutiveFails := 0
-        var firstFailTime, lastFailTime time.Time
-        
-        for _, status := range statusList {
-            if status.ExitCode != 0 { // Non-OK status
-                consecutiveFails++
-                lastFailTime = status.Timestamp
-                if firstFailTime.IsZero() {
-                    firstFailTime = status.Timestamp
-                }
-            } else {
-                break // Stop at first OK status
-            }
-        }
-
-        // Only include if there are current failures
-        if consecutiveFails > 0 {
-            // Get the check details including name and threshold
-            check, err := s.store.GetCheck(ctx, checkID)
-            threshold := 3
-            checkName := checkID // fallback to ID if name not found
-            
-            if err == nil {
-                if check.Threshold > 0 {
-                    threshold = check.Threshold
-                }
-                if check.Name != "" {
-                    checkName = check.Name  // Use actual check name
-                }
-            }
-
-            softFailInfo[checkID] = &SoftFailStatus{
-                CheckName:     checkName,     // IMPORTANT: Include check name
-                CurrentFails:  consecutiveFails,
-                ThresholdMax:  threshold,
-                FirstFailTime: firstFailTime,
-                LastFailTime:  lastFailTime,
-            }
-        }
+    checkName := check.Name
+    if checkName == "" {
+        checkName = check.ID
+    }
+    return &SoftFailStatus{
+        CheckName:     checkName,
+        CurrentFails:  detail.ConsecutiveCount,
+        ThresholdMax:  detail.Threshold,
+        FirstFailTime: detail.PendingSince,
+        LastFailTime:  detail.LastCheckTime,
     }
-
-    return softFailInfo
 }
 
-// getOKDurationInfoWithNames retrieves information about how long checks have been OK WITH check names
-func (s *Server) getOKDurationInfoWithNames(ctx context.Context, hostID string) map[string]*OKDurationInfo {
+// getOKDurationInfoWithNames reports how long each check on a host whose
+// current result is OK has been OK, WITH check names
+func (s *Server) getOKDurationInfoWithNames(ctx context.Context, hostID string, checks *checkSet) map[string]*OKDurationInfo {
     okDurationInfo := make(map[string]*OKDurationInfo)
 
-    // Get recent statuses for this host
-    statuses, err := s.store.GetStatus(ctx, database.StatusFilters{
-        HostID: hostID,
-        Limit:  1000, // Get more history for OK duration analysis
-    })
-    
+    // Current status of each check on this host
+    statuses, err := s.store.GetLatestStatuses(ctx, hostID)
     if err != nil {
         logrus.WithError(err).Error("Failed to get status for OK duration analysis")
         return okDurationInfo
     }
 
-    // Group statuses by check_id
-    checkStatuses := make(map[string][]*database.Status)
     for i := range statuses {
-        checkID := statuses[i].CheckID
-        if checkStatuses[checkID] == nil {
-            checkStatuses[checkID] = make([]*database.Status, 0)
+        if info := s.okDurationInfo(&statuses[i], checks.name(statuses[i].CheckID)); info != nil {
+            okDurationInfo[statuses[i].CheckID] = info
         }
-        checkStatuses[checkID] = append(checkStatuses[checkID], &statuses[i])
     }
+    return okDurationInfo
+}
 
-    // Analyze OK duration for each check
-    for checkID, statusList := range checkStatuses {
-        if len(statusList) == 0 {
-            continue
-        }
-
-        // Check if the most recent status is OK
-        if statusList[0].ExitCode == 0 {
-            okSince := statusList[0].Timestamp
-            okCount := 1
-
-            // Count consecutive OK statuses
-            for i := 1; i < len(statusList); i++ {
-                if statusList[i].ExitCode == 0 {
-                    okSince = statusList[i].Timestamp
-                    okCount++
-                } else {
-                    break // Stop at first non-OK status
-                }
-            }
-
-            duration := time.Since(okSince)
-            durationStr := formatDuration(duration)
-
-            // Get check name
-            check, err := s.store.GetCheck(ctx, checkID)
-            checkName := checkID // fallback to ID if name not found
-            
-            if err == nil && check.Name != "" {
-                checkName = check.Name  // Use actual check name
-            }
+// okDurationInfo reports how long a current OK status has been OK, or nil
+// if it isn't OK. The run starts when the reported state last changed (see
+// stateChangedAt), and the count is how many OK results in a row the
+// scheduler has seen.
+func (s *Server) okDurationInfo(status *database.Status, checkName string) *OKDurationInfo {
+    if status.ExitCode != database.StateOK {
+        return nil
+    }
 
-            okDurationInfo[checkID] = &OKDurationInfo{
-                CheckName:  checkName,  // IMPORTANT: Include check name
-                OKSince:    okSince,
-                Duration:   durationStr,
-                CheckCount: okCount,
-            }
-        }
+    okSince := s.stateChangedAt(status)
+    okCount := 1
+    if detail, ok := s.engine.GetStateDetail(status.HostID, status.CheckID); ok &&
+        detail.PendingState == database.StateOK && detail.ConsecutiveCount > okCount {
+        okCount = detail.ConsecutiveCount
     }
 
-    return okDurationInfo
+    duration := time.Since(okSince)
+    return &OKDurationInfo{
+        CheckName:       checkName,
+        OKSince:         okSince,
+        Duration:        formatDuration(duration),
+        DurationSeconds: int64(duration.Seconds()),
+        CheckCount:      okCount,
+    }
 }
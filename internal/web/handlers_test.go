@@ -0,0 +1,140 @@
+// internal/web/handlers_test.go - The serialized form of host state and OK durations
+package web
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const handlersTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "default"
+    enabled: true
+checks:
+  - id: "ping-check"
+    name: "Ping"
+    type: "ping"
+    hosts: ["web-01"]
+    enabled: true
+`
+
+func TestOKDurationSerialization(t *testing.T) {
+    s := newTestServer(t, handlersTestConfig)
+
+    // Stored with an offset, as older records were
+    zone := time.FixedZone("UTC+5", 5*60*60)
+    now := time.Now().In(zone)
+    status := &database.Status{
+        HostID:          "web-01",
+        CheckID:         "ping-check",
+        ExitCode:        database.StateOK,
+        Output:          "PING OK",
+        Timestamp:       now.Add(-time.Minute),
+        LastStateChange: now.Add(-(3*time.Hour + 12*time.Minute)),
+    }
+    if err := s.store.UpdateStatus(context.Background(), status); err != nil {
+        t.Fatal(err)
+    }
+
+    code, body := s.get(t, "/api/hosts/web-01")
+    if code != http.StatusOK {
+        t.Fatalf("GET /api/hosts/web-01 = %d: %s", code, body)
+    }
+
+    var response struct {
+        Data struct {
+            LastCheck         string                     `json:"last_check"`
+            LastStateChange   string                     `json:"last_state_change"`
+            StateDuration     json.Number                `json:"state_duration"`
+            StateDurationText string                     `json:"state_duration_text"`
+            OKDuration        map[string]json.RawMessage `json:"ok_duration"`
+        } `json:"data"`
+    }
+    if err := json.Unmarshal([]byte(body), &response); err != nil {
+        t.Fatalf("decoding response: %v", err)
+    }
+    host := response.Data
+
+    for name, value := range map[string]string{"last_check": host.LastCheck, "last_state_change": host.LastStateChange} {
+        if _, err := time.Parse(time.RFC3339, value); err != nil || !strings.HasSuffix(value, "Z") {
+            t.Errorf("%s = %q, want UTC RFC3339", name, value)
+        }
+    }
+    if _, err := host.StateDuration.Int64(); err != nil {
+        t.Errorf("state_duration = %q, want whole milliseconds", host.StateDuration)
+    }
+    if host.StateDurationText != "3h 12m" {
+        t.Errorf("state_duration_text = %q, want \"3h 12m\"", host.StateDurationText)
+    }
+
+    raw, ok := host.OKDuration["ping-check"]
+    if !ok {
+        t.Fatalf("ok_duration = %v, want an entry for ping-check", host.OKDuration)
+    }
+    var fields map[string]interface{}
+    if err := json.Unmarshal(raw, &fields); err != nil {
+        t.Fatal(err)
+    }
+    for _, field := range []string{"check_name", "ok_since", "duration", "duration_seconds", "check_count"} {
+        if _, ok := fields[field]; !ok {
+            t.Errorf("ok_duration entry %s is missing %s", raw, field)
+        }
+    }
+
+    var info OKDurationInfo
+    if err := json.Unmarshal(raw, &info); err != nil {
+        t.Fatalf("ok_duration entry %s: %v", raw, err)
+    }
+    if info.CheckName != "Ping" {
+        t.Errorf("check_name = %q, want Ping", info.CheckName)
+    }
+    if okSince, _ := fields["ok_since"].(string); !strings.HasSuffix(okSince, "Z") {
+        t.Errorf("ok_since = %q, want UTC", okSince)
+    }
+    if _, isNumber := fields["duration_seconds"].(float64); !isNumber {
+        t.Errorf("duration_seconds = %v, want a number", fields["duration_seconds"])
+    }
+    if elapsed := int64(time.Since(info.OKSince).Seconds()); elapsed-info.DurationSeconds > 2 || info.DurationSeconds > elapsed {
+        t.Errorf("duration_seconds = %d, want the %ds since ok_since", info.DurationSeconds, elapsed)
+    }
+    if info.Duration != formatDuration(time.Duration(info.DurationSeconds)*time.Second) {
+        t.Errorf("duration = %q, doesn't match duration_seconds %d", info.Duration, info.DurationSeconds)
+    }
+
+    // OK since the state last changed, not since the last result
+    if !info.OKSince.Equal(status.LastStateChange) {
+        t.Errorf("ok_since = %s, want the state change at %s", info.OKSince, status.LastStateChange.UTC())
+    }
+    if info.Duration != "3h 12m" {
+        t.Errorf("duration = %q, want \"3h 12m\"", info.Duration)
+    }
+}
+
+func TestFormatDuration(t *testing.T) {
+    tests := []struct {
+        in   time.Duration
+        want string
+    }{
+        {0, "0s"},
+        {42 * time.Second, "42s"},
+        {5 * time.Minute, "5m"},
+        {time.Hour, "1h"},
+        {3*time.Hour + 12*time.Minute, "3h 12m"},
+        {24 * time.Hour, "1d"},
+        {50 * time.Hour, "2d 2h"},
+    }
+    for _, tt := range tests {
+        if got := formatDuration(tt.in); got != tt.want {
+            t.Errorf("formatDuration(%s) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,81 @@
+// internal/web/helpers_test.go - A server over a temporary database for handler tests
+package web
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/config"
+    "raven2/internal/database"
+    "raven2/internal/metrics"
+    "raven2/internal/monitoring"
+)
+
+// newTestServer loads configYAML, with the database pointed at a temporary
+// directory, and builds a server over it. The engine is synced but not
+// started, so nothing runs checks.
+func newTestServer(t testing.TB, configYAML string) *Server {
+    t.Helper()
+
+    dir := t.TempDir()
+    path := filepath.Join(dir, "config.yaml")
+    document := fmt.Sprintf("database:\n  path: %q\nlogging:\n  level: error\n%s", filepath.Join(dir, "raven.db"), configYAML)
+    if err := os.WriteFile(path, []byte(document), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    cfg, err := config.Load(path)
+    if err != nil {
+        t.Fatalf("config.Load: %v", err)
+    }
+    store, err := database.NewExtendedBoltStore(cfg.Database.Path, cfg.Database.Files())
+    if err != nil {
+        t.Fatalf("NewExtendedBoltStore: %v", err)
+    }
+    t.Cleanup(func() { store.Close() })
+
+    // Keep the request and engine logs out of test output
+    gin.DefaultWriter = io.Discard
+    logrus.SetLevel(logrus.ErrorLevel)
+
+    collector := metrics.NewCollector(store, cfg.Prometheus)
+    engine, err := monitoring.NewEngine(cfg, store, collector)
+    if err != nil {
+        t.Fatalf("NewEngine: %v", err)
+    }
+    if err := engine.RefreshConfig(); err != nil {
+        t.Fatalf("RefreshConfig: %v", err)
+    }
+    return NewServer(cfg, store, engine, collector)
+}
+
+// get performs a request against the server's router and returns the status
+// code and body
+func (s *Server) get(t *testing.T, path string) (int, string) {
+    t.Helper()
+    return s.request(t, http.MethodGet, path, "")
+}
+
+func (s *Server) request(t *testing.T, method, path, body string) (int, string) {
+    t.Helper()
+
+    var reader io.Reader
+    if body != "" {
+        reader = strings.NewReader(body)
+    }
+    req := httptest.NewRequest(method, path, reader)
+    if body != "" {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    recorder := httptest.NewRecorder()
+    s.router.ServeHTTP(recorder, req)
+    return recorder.Code, recorder.Body.String()
+}
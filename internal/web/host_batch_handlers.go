@@ -0,0 +1,114 @@
+// internal/web/host_batch_handlers.go - Deleting hosts with their results, one or many per request
+package web
+
+import (
+    "fmt"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+)
+
+// maxHostBatchSize caps how many hosts a single batch delete may name
+const maxHostBatchSize = 500
+
+// HostBatchDeleteRequest lists the hosts to delete
+type HostBatchDeleteRequest struct {
+    IDs []string `json:"ids" binding:"required"`
+}
+
+// HostBatchDeleteResult reports what happened to one requested host
+type HostBatchDeleteResult struct {
+    ID              string `json:"id"`
+    Deleted         bool   `json:"deleted"`
+    StatusesRemoved int    `json:"statuses_removed"`
+    Error           string `json:"error,omitempty"`
+}
+
+// POST /api/hosts/batch-delete - Delete many hosts with their status and
+// check membership, refreshing the engine once at the end
+func (s *Server) batchDeleteHosts(c *gin.Context) {
+    ctx := c.Request.Context()
+
+    var req HostBatchDeleteRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if len(req.IDs) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "At least one ID is required"})
+        return
+    }
+    if len(req.IDs) > maxHostBatchSize {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many IDs: %d (max %d)", len(req.IDs), maxHostBatchSize)})
+        return
+    }
+
+    results := make([]HostBatchDeleteResult, len(req.IDs))
+    seen := make(map[string]bool, len(req.IDs))
+    var deleted []string
+
+    for i, id := range req.IDs {
+        results[i].ID = id
+        if seen[id] {
+            results[i].Error = "Duplicate ID"
+            continue
+        }
+        seen[id] = true
+
+        if _, err := s.store.GetHost(ctx, id); err != nil {
+            if err.Error() == "host not found" {
+                results[i].Error = "Host not found"
+            } else {
+                logrus.WithError(err).WithField("host", id).Error("Failed to get host")
+                results[i].Error = "Failed to get host"
+            }
+            continue
+        }
+
+        removed, err := s.engine.DeleteHost(ctx, id)
+        results[i].StatusesRemoved = removed
+        if err != nil {
+            logrus.WithError(err).WithField("host", id).Error("Failed to delete host")
+            results[i].Error = "Failed to delete host"
+            continue
+        }
+        results[i].Deleted = true
+        deleted = append(deleted, id)
+    }
+
+    // Checks left without any host are reported for cleanup, not deleted
+    emptyChecks := []string{}
+    if len(deleted) > 0 {
+        updated, err := s.store.RemoveHostsFromChecks(ctx, deleted)
+        if err != nil {
+            logrus.WithError(err).Error("Failed to remove deleted hosts from checks")
+        }
+        for _, check := range updated {
+            if len(check.Hosts) == 0 {
+                emptyChecks = append(emptyChecks, check.ID)
+            }
+        }
+
+        s.engine.RefreshConfig()
+        s.pruneMetricSeries(ctx)
+
+        s.broadcast(WSMessage{
+            Type: "hosts_deleted",
+            Data: gin.H{"hosts": deleted, "empty_checks": emptyChecks},
+        })
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "requested":    len(req.IDs),
+        "deleted":      len(deleted),
+        "empty_checks": len(emptyChecks),
+    }).Info("Batch deleted hosts")
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":         results,
+        "count":        len(results),
+        "deleted":      len(deleted),
+        "empty_checks": emptyChecks,
+    })
+}
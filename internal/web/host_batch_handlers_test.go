@@ -0,0 +1,91 @@
+// internal/web/host_batch_handlers_test.go - Host deletes leave no results behind
+package web
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const hostDeleteTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "default"
+    enabled: true
+  - id: "db-01"
+    name: "db-01"
+    ipv4: "127.0.0.2"
+    group: "default"
+    enabled: true
+checks:
+  - id: "ping-check"
+    name: "Ping"
+    type: "ping"
+    hosts: ["web-01", "db-01"]
+    enabled: true
+`
+
+// recordResults stores a few distinct results for a host/check, so it has
+// a current status and history
+func recordResults(t *testing.T, store database.Store, hostID, checkID string) {
+    t.Helper()
+    for i := 0; i < 3; i++ {
+        status := &database.Status{
+            HostID:    hostID,
+            CheckID:   checkID,
+            ExitCode:  i % 2,
+            Output:    fmt.Sprintf("result %d", i),
+            Timestamp: time.Now().UTC().Add(time.Duration(i-3) * time.Minute),
+        }
+        if err := store.UpdateStatus(context.Background(), status); err != nil {
+            t.Fatalf("UpdateStatus: %v", err)
+        }
+    }
+}
+
+func TestDeleteHostLeavesNoStatuses(t *testing.T) {
+    s := newTestServer(t, hostDeleteTestConfig)
+    ctx := context.Background()
+    since := time.Now().Add(-time.Hour)
+
+    recordResults(t, s.store, "web-01", "ping-check")
+    recordResults(t, s.store, "db-01", "ping-check")
+
+    if code, body := s.request(t, http.MethodDelete, "/api/hosts/web-01", ""); code != http.StatusOK {
+        t.Fatalf("DELETE /api/hosts/web-01 = %d: %s", code, body)
+    }
+
+    latest, err := s.store.GetLatestStatuses(ctx, "web-01")
+    if err != nil || len(latest) != 0 {
+        t.Errorf("GetLatestStatuses(web-01) = %d statuses, %v; want none", len(latest), err)
+    }
+    current, err := s.store.GetStatus(ctx, database.StatusFilters{HostID: "web-01"})
+    if err != nil || len(current) != 0 {
+        t.Errorf("GetStatus(web-01) = %d statuses, %v; want none", len(current), err)
+    }
+    history, err := s.store.GetStatusHistory(ctx, "web-01", "ping-check", since)
+    if err != nil || len(history) != 0 {
+        t.Errorf("GetStatusHistory(web-01) = %d entries, %v; want none", len(history), err)
+    }
+
+    // The time index only has the other host's entry left
+    indexed, err := s.store.GetStatus(ctx, database.StatusFilters{Since: &since})
+    if err != nil {
+        t.Fatalf("GetStatus(since): %v", err)
+    }
+    if len(indexed) != 1 || indexed[0].HostID != "db-01" {
+        t.Errorf("GetStatus(since) = %+v, want only db-01", indexed)
+    }
+
+    // The other host on the same check is untouched
+    history, err = s.store.GetStatusHistory(ctx, "db-01", "ping-check", since)
+    if err != nil || len(history) != 3 {
+        t.Errorf("GetStatusHistory(db-01) = %d entries, %v; want 3", len(history), err)
+    }
+}
@@ -0,0 +1,163 @@
+// internal/web/host_list_test.go - The host list pages without per-check details
+package web
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "runtime"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "raven2/internal/database"
+)
+
+func TestHostListLeavesOutDetails(t *testing.T) {
+    s := newTestServer(t, handlersTestConfig)
+    recordResults(t, s.store, "web-01", "ping-check")
+
+    detailFields := []string{"soft_fail_info", "ok_duration", "check_names"}
+    for _, path := range []string{"/api/hosts", "/api/hosts?all=true", "/api/hosts?include=details"} {
+        code, body := s.get(t, path)
+        if code != http.StatusOK {
+            t.Fatalf("GET %s = %d: %s", path, code, body)
+        }
+        var response struct {
+            Data []map[string]json.RawMessage `json:"data"`
+        }
+        if err := json.Unmarshal([]byte(body), &response); err != nil {
+            t.Fatalf("GET %s: %v", path, err)
+        }
+        if len(response.Data) != 1 {
+            t.Fatalf("GET %s listed %d hosts, want 1", path, len(response.Data))
+        }
+        for _, field := range detailFields {
+            if value, ok := response.Data[0][field]; ok && string(value) != "null" {
+                t.Errorf("GET %s includes %s: %s", path, field, value)
+            }
+        }
+    }
+
+    // The detail view still has them
+    _, body := s.get(t, "/api/hosts/web-01")
+    if !strings.Contains(body, `"check_names":{"ping-check":"Ping"}`) {
+        t.Errorf("GET /api/hosts/web-01 is missing check_names: %s", body)
+    }
+}
+
+// BenchmarkHostList measures a dashboard's worth of host listing over 5,000
+// hosts: the default page, every host streamed, and every host with its
+// details built into one slice and marshalled, as the list used to be. B/op
+// and peak-heap-MB show how much memory each holds.
+//
+//    go test ./internal/web -run '^$' -bench HostList -benchtime 5x
+func BenchmarkHostList(b *testing.B) {
+    const hosts = 5000
+    s := newTestServer(b, handlersTestConfig)
+    ctx := context.Background()
+
+    check, err := s.store.GetCheck(ctx, "ping-check")
+    if err != nil {
+        b.Fatal(err)
+    }
+    statuses := make([]*database.Status, 0, hosts)
+    for i := 0; i < hosts; i++ {
+        host := &database.Host{
+            ID:      fmt.Sprintf("host-%04d", i),
+            Name:    fmt.Sprintf("host-%04d", i),
+            Group:   "default",
+            Enabled: true,
+        }
+        if err := s.store.CreateHost(ctx, host); err != nil {
+            b.Fatal(err)
+        }
+        check.Hosts = append(check.Hosts, host.ID)
+        statuses = append(statuses, &database.Status{
+            HostID:          host.ID,
+            CheckID:         check.ID,
+            ExitCode:        database.StateOK,
+            Output:          "PING OK - Packet loss = 0%, RTA = 0.80 ms",
+            Timestamp:       time.Now().UTC(),
+            LastStateChange: time.Now().Add(-time.Hour).UTC(),
+        })
+    }
+    if err := s.store.UpdateCheck(ctx, check); err != nil {
+        b.Fatal(err)
+    }
+    s.engine.CheckSaved(check)
+    if err := s.store.UpdateStatusBatch(ctx, statuses); err != nil {
+        b.Fatal(err)
+    }
+
+    serve := func(path string) func() {
+        return func() {
+            recorder := httptest.NewRecorder()
+            s.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+            if recorder.Code != http.StatusOK {
+                b.Fatalf("GET %s = %d", path, recorder.Code)
+            }
+        }
+    }
+    inMemory := func() {
+        list, err := s.store.GetHosts(ctx, database.HostFilters{})
+        if err != nil {
+            b.Fatal(err)
+        }
+        checks := s.loadCheckSet(ctx)
+        responses := make([]HostResponse, 0, len(list))
+        for i := range list {
+            responses = append(responses, s.hostResponse(ctx, &list[i], true, checks))
+        }
+        if _, err := json.Marshal(gin.H{"data": responses, "count": len(responses)}); err != nil {
+            b.Fatal(err)
+        }
+    }
+
+    for _, bench := range []struct {
+        name string
+        run  func()
+    }{
+        {"page", serve("/api/hosts")},
+        {"all streamed", serve("/api/hosts?all=true")},
+        {"all with details in memory", inMemory},
+    } {
+        b.Run(bench.name, func(b *testing.B) {
+            b.ReportAllocs()
+            peak := uint64(0)
+            for i := 0; i < b.N; i++ {
+                runtime.GC()
+                var before runtime.MemStats
+                runtime.ReadMemStats(&before)
+
+                done := make(chan struct{})
+                sampled := make(chan uint64)
+                go func() {
+                    high := uint64(0)
+                    var stats runtime.MemStats
+                    for {
+                        runtime.ReadMemStats(&stats)
+                        if stats.HeapAlloc > high {
+                            high = stats.HeapAlloc
+                        }
+                        select {
+                        case <-done:
+                            sampled <- high
+                            return
+                        case <-time.After(time.Millisecond):
+                        }
+                    }
+                }()
+                bench.run()
+                close(done)
+                if high := <-sampled; high > before.HeapAlloc && high-before.HeapAlloc > peak {
+                    peak = high - before.HeapAlloc
+                }
+            }
+            b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+        })
+    }
+}
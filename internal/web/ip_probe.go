@@ -0,0 +1,197 @@
+// internal/web/ip_probe.go - Background IPv4/IPv6 reachability probes for the host list
+package web
+
+import (
+    "context"
+    "net"
+    "os/exec"
+    "regexp"
+    "strconv"
+    "sync"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const (
+    ipProbeTTL       = time.Minute     // How long a probe result is reused
+    ipProbeTimeout   = 3 * time.Second // Per protocol, name lookup included
+    maxIPProbesInUse = 8               // Probes running at once; more wait for the next request
+)
+
+var pingTimePattern = regexp.MustCompile(`time[=<]([\d.]+) ?ms`)
+
+// IPReachability reports whether a host answers a ping over each protocol.
+// A protocol is null when the host has no address for it or it hasn't been
+// probed yet. Results are cached per protocol and refreshed in the
+// background, so listing hosts never waits on a probe.
+type IPReachability struct {
+    V4OK      *bool      `json:"v4_ok"`
+    V6OK      *bool      `json:"v6_ok"`
+    LatencyMs *float64   `json:"latency_ms"` // Fastest protocol that answered
+    CheckedAt *time.Time `json:"checked_at"`
+}
+
+// OK reports whether any protocol answered. Hosts not probed yet count as
+// reachable, as before probing existed.
+func (r IPReachability) OK() bool {
+    if r.V4OK == nil && r.V6OK == nil {
+        return true
+    }
+    return (r.V4OK != nil && *r.V4OK) || (r.V6OK != nil && *r.V6OK)
+}
+
+// ipProbeResult is one protocol's cached outcome for an address
+type ipProbeResult struct {
+    hasAddress bool
+    ok         bool
+    latency    time.Duration
+    checkedAt  time.Time
+}
+
+// ipProber caches probe results by protocol and address
+type ipProber struct {
+    mu      sync.Mutex
+    results map[string]ipProbeResult // "4|address" or "6|address"
+    pending map[string]bool
+    slots   chan struct{}
+}
+
+func newIPProber() *ipProber {
+    return &ipProber{
+        results: make(map[string]ipProbeResult),
+        pending: make(map[string]bool),
+        slots:   make(chan struct{}, maxIPProbesInUse),
+    }
+}
+
+// probeTargets picks the address each protocol is probed at. An IP literal
+// in ipv4 (either family) is used as is; otherwise the hostname is resolved
+// for that protocol when probed.
+func probeTargets(host *database.Host) (v4, v6 string) {
+    if ip := net.ParseIP(host.IPv4); ip != nil {
+        if ip.To4() != nil {
+            v4 = host.IPv4
+        } else {
+            v6 = host.IPv4
+        }
+    }
+    if v4 == "" {
+        v4 = host.Hostname
+    }
+    if v6 == "" {
+        v6 = host.Hostname
+    }
+    return v4, v6
+}
+
+// reachability returns the cached results for a host, starting probes for
+// protocols whose result is missing or stale
+func (p *ipProber) reachability(host *database.Host) IPReachability {
+    v4, v6 := probeTargets(host)
+
+    var reach IPReachability
+    for _, probe := range []struct {
+        protocol string
+        address  string
+        ok       **bool
+    }{{"4", v4, &reach.V4OK}, {"6", v6, &reach.V6OK}} {
+        if probe.address == "" {
+            continue
+        }
+        result, fresh := p.cached(probe.protocol, probe.address)
+        if !fresh {
+            p.start(probe.protocol, probe.address)
+        }
+        if result.checkedAt.IsZero() || !result.hasAddress {
+            continue
+        }
+
+        ok := result.ok
+        *probe.ok = &ok
+        if reach.CheckedAt == nil || result.checkedAt.After(*reach.CheckedAt) {
+            checkedAt := result.checkedAt
+            reach.CheckedAt = &checkedAt
+        }
+        if ok {
+            latency := float64(result.latency.Microseconds()) / 1000
+            if reach.LatencyMs == nil || latency < *reach.LatencyMs {
+                reach.LatencyMs = &latency
+            }
+        }
+    }
+    return reach
+}
+
+func (p *ipProber) cached(protocol, address string) (ipProbeResult, bool) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    result, exists := p.results[protocol+"|"+address]
+    return result, exists && time.Since(result.checkedAt) < ipProbeTTL
+}
+
+// start probes an address in the background unless a probe for it is
+// already running or every slot is busy
+func (p *ipProber) start(protocol, address string) {
+    key := protocol + "|" + address
+
+    p.mu.Lock()
+    if p.pending[key] {
+        p.mu.Unlock()
+        return
+    }
+    select {
+    case p.slots <- struct{}{}:
+    default:
+        p.mu.Unlock()
+        return
+    }
+    p.pending[key] = true
+    p.mu.Unlock()
+
+    go func() {
+        result := probeAddress(protocol, address)
+
+        p.mu.Lock()
+        p.results[key] = result
+        delete(p.pending, key)
+        p.mu.Unlock()
+        <-p.slots
+    }()
+}
+
+// probeAddress sends one ping over the given protocol. A hostname without
+// an address for that protocol gives a result with hasAddress false.
+func probeAddress(protocol, address string) ipProbeResult {
+    ctx, cancel := context.WithTimeout(context.Background(), ipProbeTimeout)
+    defer cancel()
+
+    result := ipProbeResult{hasAddress: true}
+    target := address
+    if net.ParseIP(address) == nil {
+        ips, err := net.DefaultResolver.LookupIP(ctx, "ip"+protocol, address)
+        if err != nil || len(ips) == 0 {
+            result.hasAddress = false
+            result.checkedAt = time.Now().UTC()
+            return result
+        }
+        target = ips[0].String()
+    }
+
+    started := time.Now()
+    output, err := exec.CommandContext(ctx, "ping", "-"+protocol, "-c", "1", "-W", "2", target).Output()
+    result.checkedAt = time.Now().UTC()
+    if err != nil {
+        return result
+    }
+
+    result.ok = true
+    result.latency = result.checkedAt.Sub(started)
+    if match := pingTimePattern.FindSubmatch(output); match != nil {
+        if ms, err := strconv.ParseFloat(string(match[1]), 64); err == nil {
+            result.latency = time.Duration(ms * float64(time.Millisecond))
+        }
+    }
+    return result
+}
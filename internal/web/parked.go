@@ -0,0 +1,64 @@
+// internal/web/parked.go - Keeping the stale results of disabled hosts and checks out of rollups
+package web
+
+import (
+    "context"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+)
+
+// ParkedState is reported for disabled hosts in group state counts
+const ParkedState = "parked"
+
+// parkedSet holds the disabled hosts and checks. Their last results are
+// kept, but parked: stats, alerts and group states leave them out unless
+// the request passes include_disabled=true.
+type parkedSet struct {
+    hosts  map[string]bool
+    checks map[string]bool
+}
+
+// parked reports whether a host/check result belongs to a disabled host or
+// check
+func (p parkedSet) parked(hostID, checkID string) bool {
+    return p.hosts[hostID] || p.checks[checkID]
+}
+
+// getParked returns the disabled hosts and checks, or an empty set when the
+// request asked for include_disabled=true
+func (s *Server) getParked(c *gin.Context) parkedSet {
+    if c.Query("include_disabled") == "true" {
+        return parkedSet{}
+    }
+    return s.loadParked(c.Request.Context())
+}
+
+func (s *Server) loadParked(ctx context.Context) parkedSet {
+    parked := parkedSet{
+        hosts:  make(map[string]bool),
+        checks: make(map[string]bool),
+    }
+
+    hosts, err := s.store.GetHosts(ctx, database.HostFilters{})
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get hosts")
+    }
+    for _, host := range hosts {
+        if !host.Enabled {
+            parked.hosts[host.ID] = true
+        }
+    }
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+    }
+    for _, check := range checks {
+        if !check.Enabled {
+            parked.checks[check.ID] = true
+        }
+    }
+    return parked
+}
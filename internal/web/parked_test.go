@@ -0,0 +1,132 @@
+// internal/web/parked_test.go - Disabled checks left out of host and group states
+package web
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+const parkedTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "web"
+    enabled: true
+  - id: "web-02"
+    name: "web-02"
+    ipv4: "127.0.0.2"
+    group: "web"
+    enabled: true
+  - id: "web-03"
+    name: "web-03"
+    ipv4: "127.0.0.3"
+    group: "web"
+    enabled: true
+checks:
+  - id: "ping-check"
+    name: "Ping"
+    type: "ping"
+    hosts: ["web-01", "web-02", "web-03"]
+    enabled: true
+  - id: "old-check"
+    name: "Retired"
+    type: "ping"
+    hosts: ["web-01", "web-02", "web-03"]
+    enabled: false
+`
+
+// countingStore counts full check reads
+type countingStore struct {
+    database.Store
+    getChecks atomic.Int64
+}
+
+func (s *countingStore) GetChecks(ctx context.Context) ([]database.Check, error) {
+    s.getChecks.Add(1)
+    return s.Store.GetChecks(ctx)
+}
+
+func TestDisabledChecksDontDecideHostState(t *testing.T) {
+    s := newTestServer(t, parkedTestConfig)
+    ctx := context.Background()
+
+    // The disabled check's last result is newer, and critical
+    for i := 1; i <= 3; i++ {
+        hostID := fmt.Sprintf("web-0%d", i)
+        for checkID, exitCode := range map[string]int{"ping-check": database.StateOK, "old-check": database.StateCritical} {
+            status := &database.Status{HostID: hostID, CheckID: checkID, ExitCode: exitCode, Timestamp: time.Now().UTC()}
+            if checkID == "ping-check" {
+                status.Timestamp = status.Timestamp.Add(-time.Minute)
+            }
+            if err := s.store.UpdateStatus(ctx, status); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+
+    store := &countingStore{Store: s.store}
+    s.store = store
+
+    code, body := s.get(t, "/api/hosts?all=true")
+    if code != http.StatusOK {
+        t.Fatalf("GET /api/hosts = %d: %s", code, body)
+    }
+    var hosts struct {
+        Data []struct {
+            ID     string `json:"id"`
+            Status string `json:"status"`
+        } `json:"data"`
+    }
+    if err := json.Unmarshal([]byte(body), &hosts); err != nil {
+        t.Fatal(err)
+    }
+    if len(hosts.Data) != 3 {
+        t.Fatalf("GET /api/hosts returned %d hosts, want 3", len(hosts.Data))
+    }
+    for _, host := range hosts.Data {
+        if host.Status != "ok" {
+            t.Errorf("%s state = %s, want ok with the disabled check left out", host.ID, host.Status)
+        }
+    }
+    if reads := store.getChecks.Load(); reads != 1 {
+        t.Errorf("GET /api/hosts read every check %d times, want once for all hosts", reads)
+    }
+
+    store.getChecks.Store(0)
+    code, body = s.get(t, "/api/groups")
+    if code != http.StatusOK {
+        t.Fatalf("GET /api/groups = %d: %s", code, body)
+    }
+    var groups struct {
+        Data []struct {
+            ID     string         `json:"id"`
+            States map[string]int `json:"states"`
+        } `json:"data"`
+    }
+    if err := json.Unmarshal([]byte(body), &groups); err != nil {
+        t.Fatal(err)
+    }
+    found := false
+    for _, group := range groups.Data {
+        if group.ID == "web" {
+            found = true
+            if group.States["ok"] != 3 {
+                t.Errorf("web group states = %v, want 3 ok", group.States)
+            }
+        }
+    }
+    if !found {
+        t.Errorf("GET /api/groups has no web group: %s", body)
+    }
+    if reads := store.getChecks.Load(); reads != 1 {
+        t.Errorf("GET /api/groups read every check %d times, want once for all hosts", reads)
+    }
+}
@@ -10,6 +10,7 @@ import (
 
     "github.com/gin-gonic/gin"
     "github.com/sirupsen/logrus"
+    "raven2/internal/database"
 )
 
 // Add these methods to your existing Server struct
@@ -28,10 +29,19 @@ func (s *Server) setupPurgeRoutes() {
         alerts.DELETE("/purge/all", s.purgeAllStaleData)
     }
     
+    // Database maintenance endpoints
+    db := api.Group("/database")
+    {
+        db.GET("/stats", s.getDatabaseStats)
+    }
+    
     // Enhanced configuration endpoints
     config := api.Group("/config")
     {
         config.POST("/refresh", s.refreshConfigWithPurge)
+        config.GET("/export", s.exportConfig)
+        config.POST("/import", s.importConfig)
+        config.POST("/reload", s.reloadConfig)
     }
 }
 
@@ -51,7 +61,7 @@ func (s *Server) purgeStaleAlerts(c *gin.Context) {
     
     c.JSON(http.StatusOK, gin.H{
         "message": "Stale alerts purged successfully",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
     })
 }
 
@@ -70,7 +80,7 @@ func (s *Server) purgeOrphanedHosts(c *gin.Context) {
     
     c.JSON(http.StatusOK, gin.H{
         "message": "Orphaned hosts purged successfully",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
     })
 }
 
@@ -89,7 +99,7 @@ func (s *Server) purgeOrphanedChecks(c *gin.Context) {
     
     c.JSON(http.StatusOK, gin.H{
         "message": "Orphaned checks purged successfully",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
     })
 }
 
@@ -108,10 +118,28 @@ func (s *Server) purgeAllStaleData(c *gin.Context) {
     
     c.JSON(http.StatusOK, gin.H{
         "message": "All stale data purged successfully",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
     })
 }
 
+// GET /api/database/stats - Database size and per-check history counts
+func (s *Server) getDatabaseStats(c *gin.Context) {
+    extStore, ok := s.store.(database.ExtendedStore)
+    if !ok {
+        c.JSON(http.StatusNotImplemented, gin.H{"error": "Database statistics not supported by store"})
+        return
+    }
+    
+    stats, err := extStore.GetDatabaseStats(c.Request.Context())
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get database stats")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get database stats"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
 // POST /api/config/refresh - Refresh configuration with purge
 func (s *Server) refreshConfigWithPurge(c *gin.Context) {
     logrus.Info("Configuration refresh with purge requested")
@@ -124,6 +152,6 @@ func (s *Server) refreshConfigWithPurge(c *gin.Context) {
     
     c.JSON(http.StatusOK, gin.H{
         "message": "Configuration refreshed and stale data purged successfully",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
     })
 }
@@ -0,0 +1,130 @@
+// internal/web/redact.go - Keeping secrets out of API responses and request logs
+package web
+
+import (
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "gopkg.in/yaml.v3"
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+// Query parameters whose values never appear in the request log
+var sensitiveParams = []string{
+    "token", "api_token", "api_key", "user_key", "password", "secret", "auth", "authorization",
+}
+
+// redactedCheck returns a copy of a check for a response, with option
+// values resolved from the environment or secret files replaced. Check
+// options are where configured secrets end up; everything that serializes a
+// check goes through here.
+func (s *Server) redactedCheck(check *database.Check) *database.Check {
+    redacted := *check
+    redacted.Options = s.config().RedactOptions(check.Options)
+    return &redacted
+}
+
+// keepSecrets puts back option values a client sent as the redaction
+// placeholder, so saving a check it was shown doesn't overwrite a secret.
+// Nested maps and lists are walked the way RedactOptions redacts them, with
+// list items matched by position.
+func (s *Server) keepSecrets(options, previous map[string]interface{}) {
+    cfg := s.config()
+    for key, value := range options {
+        options[key] = keepSecret(cfg, value, previous[key])
+    }
+}
+
+func keepSecret(cfg *config.Config, value, previous interface{}) interface{} {
+    switch v := value.(type) {
+    case string:
+        if old, ok := previous.(string); ok && v == config.RedactedValue && cfg.IsSecret(old) {
+            return old
+        }
+    case map[string]interface{}:
+        old, _ := previous.(map[string]interface{})
+        for key, elem := range v {
+            v[key] = keepSecret(cfg, elem, old[key])
+        }
+    case []interface{}:
+        old, _ := previous.([]interface{})
+        for i, elem := range v {
+            var oldElem interface{}
+            if i < len(old) {
+                oldElem = old[i]
+            }
+            v[i] = keepSecret(cfg, elem, oldElem)
+        }
+    }
+    return value
+}
+
+// redactNode replaces scalars of a YAML document that were resolved from
+// secrets
+func (s *Server) redactNode(node *yaml.Node) {
+    cfg := s.config()
+    var walk func(node *yaml.Node)
+    walk = func(node *yaml.Node) {
+        if node.Kind == yaml.ScalarNode {
+            if cfg.IsSecret(node.Value) {
+                node.Value = config.RedactedValue
+                node.Style = yaml.DoubleQuotedStyle
+            }
+            return
+        }
+        for _, child := range node.Content {
+            walk(child)
+        }
+    }
+    walk(node)
+}
+
+// requestLogger is gin's request log with sensitive query parameters and
+// resolved secrets scrubbed from the path. Headers and bodies aren't logged.
+func (s *Server) requestLogger() gin.HandlerFunc {
+    return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+        if param.Latency > time.Minute {
+            param.Latency = param.Latency.Truncate(time.Second)
+        }
+        return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+            param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+            param.StatusCode,
+            param.Latency,
+            param.ClientIP,
+            param.Method,
+            s.config().Redact(scrubQuery(param.Path)),
+            param.ErrorMessage,
+        )
+    })
+}
+
+// scrubQuery replaces the values of sensitive query parameters in path
+func scrubQuery(path string) string {
+    base, rawQuery, found := strings.Cut(path, "?")
+    if !found {
+        return path
+    }
+
+    query, err := url.ParseQuery(rawQuery)
+    if err != nil {
+        return base + "?" + config.RedactedValue
+    }
+
+    scrubbed := false
+    for key := range query {
+        for _, name := range sensitiveParams {
+            if strings.EqualFold(key, name) {
+                query[key] = []string{config.RedactedValue}
+                scrubbed = true
+            }
+        }
+    }
+    if !scrubbed {
+        return path
+    }
+    return base + "?" + query.Encode()
+}
@@ -0,0 +1,121 @@
+// internal/web/redact_test.go - No resolved secret in any API response
+package web
+
+import (
+    "context"
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+const redactTestConfig = `
+hosts:
+  - id: "web-01"
+    name: "web-01"
+    ipv4: "127.0.0.1"
+    group: "default"
+    enabled: true
+checks:
+  - id: "login"
+    name: "Login"
+    type: "nagios"
+    hosts: ["web-01"]
+    enabled: true
+    options:
+      program: "/bin/true"
+      options: ["-u", "admin", "-p", "${RAVEN_TEST_PASSWORD}"]
+      env:
+        API_TOKEN: "${RAVEN_TEST_TOKEN}"
+`
+
+// Route parameters filled in when walking the API
+var redactTestParams = map[string]string{
+    ":host":  "web-01",
+    ":check": "login",
+}
+
+func TestAPIResponsesNeverContainSecrets(t *testing.T) {
+    // One secret contains another, and one needs escaping in JSON
+    secrets := []string{"hunter2-long-password", `tok"en<\hunter2`}
+    t.Setenv("RAVEN_TEST_PASSWORD", secrets[0])
+    t.Setenv("RAVEN_TEST_TOKEN", secrets[1])
+
+    s := newTestServer(t, redactTestConfig)
+
+    checked := 0
+    for _, route := range s.router.Routes() {
+        if route.Method != "GET" || !strings.HasPrefix(route.Path, "/api/") || route.Path == "/api/events" {
+            continue
+        }
+
+        path := route.Path
+        for _, segment := range strings.Split(route.Path, "/") {
+            if !strings.HasPrefix(segment, ":") {
+                continue
+            }
+            value, ok := redactTestParams[segment]
+            if !ok {
+                // :id means whichever resource the route is under
+                value = map[bool]string{true: "login", false: "web-01"}[strings.HasPrefix(route.Path, "/api/checks")]
+            }
+            path = strings.Replace(path, segment, value, 1)
+        }
+
+        for _, query := range []string{"", "?format=json"} {
+            _, body := s.get(t, path+query)
+            for _, secret := range secrets {
+                escaped, _ := json.Marshal(secret)
+                if strings.Contains(body, secret) || strings.Contains(body, strings.Trim(string(escaped), `"`)) {
+                    t.Errorf("GET %s%s contains secret %q", path, query, secret)
+                }
+            }
+        }
+        checked++
+    }
+    if checked < 20 {
+        t.Fatalf("only walked %d routes", checked)
+    }
+
+    // The placeholder is shown where the secrets were
+    _, body := s.get(t, "/api/checks/login")
+    if !strings.Contains(body, `"***set***"`) {
+        t.Errorf("check options don't show the redaction placeholder: %s", body)
+    }
+}
+
+func TestSavingRedactedCheckKeepsSecret(t *testing.T) {
+    t.Setenv("RAVEN_TEST_PASSWORD", "hunter2-long-password")
+    t.Setenv("RAVEN_TEST_TOKEN", "token-value")
+
+    s := newTestServer(t, redactTestConfig)
+
+    // Options the client echoes back as the placeholder keep the old value
+    previous := map[string]interface{}{"password": "hunter2-long-password", "user": "admin"}
+    options := map[string]interface{}{"password": "***set***", "user": "***set***"}
+    s.keepSecrets(options, previous)
+    if options["password"] != "hunter2-long-password" {
+        t.Errorf("password = %v, want the secret kept", options["password"])
+    }
+    if options["user"] != "***set***" {
+        t.Errorf("user = %v, a value that isn't a secret must not be restored", options["user"])
+    }
+
+    // Secrets nested in the env map and the argument list come back too
+    stored, err := s.store.GetCheck(context.Background(), "login")
+    if err != nil {
+        t.Fatal(err)
+    }
+    options = s.redactedCheck(stored).Options
+    if env, _ := options["env"].(map[string]interface{}); env["API_TOKEN"] != "***set***" {
+        t.Fatalf("env = %v, want API_TOKEN shown as the placeholder", options["env"])
+    }
+    s.keepSecrets(options, stored.Options)
+    if env, _ := options["env"].(map[string]interface{}); env["API_TOKEN"] != "token-value" {
+        t.Errorf("env = %v, want API_TOKEN kept", options["env"])
+    }
+    args, _ := options["options"].([]interface{})
+    if want := []interface{}{"-u", "admin", "-p", "hunter2-long-password"}; !reflect.DeepEqual(args, want) {
+        t.Errorf("options = %v, want %v", args, want)
+    }
+}
@@ -0,0 +1,274 @@
+// internal/web/report_handlers.go - Per-host report card over a period
+package web
+
+import (
+    "math"
+    "net/http"
+    "sort"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+    "raven2/internal/monitoring"
+)
+
+// Outage is a run of non-OK results
+type Outage struct {
+    Start           time.Time `json:"start"`
+    End             time.Time `json:"end"`
+    DurationSeconds float64   `json:"duration_seconds"`
+    Ongoing         bool      `json:"ongoing"` // Still failing at the end of the period
+}
+
+// CheckReport summarizes one check on a host over a report period
+type CheckReport struct {
+    CheckID         string                      `json:"check_id"`
+    CheckName       string                      `json:"check_name"`
+    Type            string                      `json:"type"`
+    Results         int                         `json:"results"`
+    ObservedFrom    *time.Time                  `json:"observed_from"`    // First result in the period; availability counts from here
+    ObservedSeconds float64                     `json:"observed_seconds"`
+    Availability    *float64                    `json:"availability"`     // Percentage of observed time in OK, null without results
+    StateChanges    int                         `json:"state_changes"`
+    DowntimeSeconds float64                     `json:"downtime_seconds"` // Time in any non-OK state
+    LongestOutage   *Outage                     `json:"longest_outage"`
+    MeanDurationMs  float64                     `json:"mean_duration_ms"`
+    P95DurationMs   float64                     `json:"p95_duration_ms"`
+    CurrentState    string                      `json:"current_state"`
+    Config          *database.Check             `json:"config"`
+    SoftFail        monitoring.SoftFailDecision `json:"soft_fail"`
+}
+
+// HostReport is the report card for a host
+type HostReport struct {
+    Host   *database.Host `json:"host"`
+    Since  time.Time      `json:"since"`
+    Until  time.Time      `json:"until"`
+    Checks []CheckReport  `json:"checks"`
+}
+
+// GET /api/hosts/:id/report?since=&until= - Availability, state changes,
+// outages and check durations for each check on a host over a period
+// (default the last 24 hours). Results are reported state, after soft fail.
+func (s *Server) getHostReport(c *gin.Context) {
+    ctx := c.Request.Context()
+    id := c.Param("id")
+
+    until := time.Now().UTC()
+    if value := c.Query("until"); value != "" {
+        parsed, err := time.Parse(time.RFC3339, value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + value})
+            return
+        }
+        until = parsed.UTC()
+    }
+    since := until.Add(-24 * time.Hour)
+    if value := c.Query("since"); value != "" {
+        parsed, err := time.Parse(time.RFC3339, value)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + value})
+            return
+        }
+        since = parsed.UTC()
+    }
+    if !until.After(since) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "until must be after since"})
+        return
+    }
+
+    host, err := s.store.GetHost(ctx, id)
+    if err != nil {
+        if err.Error() == "host not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Host not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to get host")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get host"})
+        return
+    }
+
+    checks, err := s.store.GetChecks(ctx)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checks"})
+        return
+    }
+
+    report := HostReport{
+        Host:   host,
+        Since:  since,
+        Until:  until,
+        Checks: []CheckReport{},
+    }
+
+    for i := range checks {
+        check := &checks[i]
+        if !contains(check.Hosts, id) {
+            continue
+        }
+
+        // From the start, for the result in effect when the period begins
+        history, err := s.store.GetStatusHistory(ctx, id, check.ID, time.Time{})
+        if err != nil {
+            logrus.WithError(err).Error("Failed to get status history")
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status history"})
+            return
+        }
+
+        checkReport := summarizeHistory(periodHistory(history, since, until), since, until)
+        checkReport.CheckID = check.ID
+        checkReport.CheckName = check.Name
+        checkReport.Type = check.Type
+        checkReport.Config = s.redactedCheck(check)
+        checkReport.SoftFail = s.engine.HostSoftFailDecision(check, id)
+        report.Checks = append(report.Checks, checkReport)
+    }
+
+    sort.Slice(report.Checks, func(i, j int) bool {
+        return report.Checks[i].CheckName < report.Checks[j].CheckName
+    })
+
+    c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// periodHistory trims a check's history, oldest first, to the entries that
+// bear on a period: those inside it and the one in effect when it starts,
+// which may be a folded run that began before it
+func periodHistory(history []database.Status, since, until time.Time) []database.Status {
+    start := sort.Search(len(history), func(i int) bool { return history[i].Timestamp.After(since) })
+    if start > 0 {
+        start--
+    }
+    end := sort.Search(len(history), func(i int) bool { return history[i].Timestamp.After(until) })
+    if end < start {
+        return nil
+    }
+    return history[start:end]
+}
+
+// summarizeHistory works out a check's figures from its results for the
+// period, oldest first, as trimmed by periodHistory. Each result's state is
+// taken to hold until the next one, and the last until the end of the
+// period; a result from before the period counts from its start. A check
+// that only started reporting during the period is measured from its first
+// result, so it isn't counted as down before it existed.
+func summarizeHistory(history []database.Status, since, until time.Time) CheckReport {
+    report := CheckReport{
+        CurrentState: database.StateName(database.StateUnknown),
+    }
+    if len(history) == 0 {
+        return report
+    }
+
+    first := history[0].Timestamp
+    if first.Before(since) {
+        first = since
+    }
+    report.ObservedFrom = &first
+    report.ObservedSeconds = until.Sub(first).Seconds()
+    report.CurrentState = database.StateName(history[len(history)-1].ExitCode)
+
+    var okTime, downTime time.Duration
+    var outage *Outage
+    durations := make([]float64, 0, len(history))
+    totalDuration := 0.0
+
+    for i, status := range history {
+        start := status.Timestamp
+        if start.Before(since) {
+            start = since
+        }
+        end := until
+        if i+1 < len(history) {
+            end = history[i+1].Timestamp
+        }
+        span := end.Sub(start)
+
+        if i > 0 && status.ExitCode != history[i-1].ExitCode {
+            report.StateChanges++
+        }
+
+        if status.ExitCode == database.StateOK {
+            okTime += span
+            if outage != nil {
+                outage.End = status.Timestamp
+                report.LongestOutage = longerOutage(report.LongestOutage, outage)
+                outage = nil
+            }
+        } else {
+            downTime += span
+            if outage == nil {
+                outage = &Outage{Start: start}
+            }
+        }
+
+        if samples := samplesBetween(&status, since, until); samples > 0 {
+            report.Results += samples
+            durations = append(durations, status.Duration)
+            totalDuration += status.Duration
+        }
+    }
+    if outage != nil {
+        outage.End = until
+        outage.Ongoing = true
+        report.LongestOutage = longerOutage(report.LongestOutage, outage)
+    }
+
+    if observed := okTime + downTime; observed > 0 {
+        availability := float64(okTime) / float64(observed) * 100
+        report.Availability = &availability
+    }
+    report.DowntimeSeconds = downTime.Seconds()
+
+    if len(durations) > 0 {
+        report.MeanDurationMs = totalDuration / float64(len(durations))
+        sort.Float64s(durations)
+        report.P95DurationMs = durations[(len(durations)*95+99)/100-1]
+    }
+
+    return report
+}
+
+// samplesBetween counts the results a history entry stands for that came in
+// between since and until. The repeats folded into an entry are taken as
+// evenly spaced between its timestamp and LastSeen.
+func samplesBetween(status *database.Status, since, until time.Time) int {
+    samples := 1 + status.Repeats
+    first := status.Timestamp
+    last := first
+    if status.LastSeen != nil && samples > 1 && status.LastSeen.After(first) {
+        last = *status.LastSeen
+    }
+    if last.Before(since) || first.After(until) {
+        return 0
+    }
+    if last.Equal(first) {
+        return samples
+    }
+
+    // Index of each sample's time along the run
+    step := float64(last.Sub(first)) / float64(samples-1)
+    from, to := 0, samples-1
+    if first.Before(since) {
+        from = int(math.Ceil(float64(since.Sub(first)) / step))
+    }
+    if last.After(until) {
+        to = int(math.Floor(float64(until.Sub(first)) / step))
+    }
+    if to < from {
+        return 0
+    }
+    return to - from + 1
+}
+
+// longerOutage returns whichever outage lasted longer, filling in its
+// duration
+func longerOutage(current, candidate *Outage) *Outage {
+    candidate.DurationSeconds = candidate.End.Sub(candidate.Start).Seconds()
+    if current == nil || candidate.DurationSeconds > current.DurationSeconds {
+        return candidate
+    }
+    return current
+}
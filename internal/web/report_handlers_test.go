@@ -0,0 +1,213 @@
+// internal/web/report_handlers_test.go - Report card figures at the edges of the period
+package web
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "testing"
+    "time"
+
+    "raven2/internal/database"
+)
+
+var reportSince = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// result is a status minutes into the report period
+func result(minutes, exitCode int) database.Status {
+    return database.Status{
+        HostID:    "web-01",
+        CheckID:   "ping-check",
+        ExitCode:  exitCode,
+        Duration:  float64(10 * (minutes + 1)),
+        Timestamp: reportSince.Add(time.Duration(minutes) * time.Minute),
+    }
+}
+
+// folded is an OK history entry minutes into the report period folding
+// repeats more results, every minutes apart
+func folded(minutes, repeats, every int) database.Status {
+    status := result(minutes, database.StateOK)
+    lastSeen := status.Timestamp.Add(time.Duration(repeats*every) * time.Minute)
+    status.Repeats = repeats
+    status.LastSeen = &lastSeen
+    return status
+}
+
+func TestSummarizeHistoryBoundaries(t *testing.T) {
+    until := reportSince.Add(100 * time.Minute)
+
+    tests := []struct {
+        name         string
+        history      []database.Status
+        results      int
+        observedFrom int     // Minutes into the period, -1 for none
+        availability float64 // -1 for none
+        downtime     float64 // Minutes
+        stateChanges int
+        longest      [2]int // Start and end minutes, zero for none
+        ongoing      bool
+        current      string
+    }{
+        {
+            name:         "no results",
+            results:      0, observedFrom: -1, availability: -1, current: "unknown",
+        },
+        {
+            name:         "result on the period start",
+            history:      []database.Status{result(0, database.StateOK), result(50, database.StateOK)},
+            results:      2, observedFrom: 0, availability: 100, current: "ok",
+        },
+        {
+            name:         "check added mid-period",
+            history:      []database.Status{result(60, database.StateCritical), result(80, database.StateOK)},
+            results:      2, observedFrom: 60, availability: 50, downtime: 20, stateChanges: 1,
+            longest: [2]int{60, 80}, current: "ok",
+        },
+        {
+            name:         "outage still open at the period end",
+            history:      []database.Status{result(0, database.StateOK), result(75, database.StateWarning), result(90, database.StateCritical)},
+            results:      3, observedFrom: 0, availability: 75, downtime: 25, stateChanges: 2,
+            longest: [2]int{75, 100}, ongoing: true, current: "critical",
+        },
+        {
+            name:         "result on the period end",
+            history:      []database.Status{result(0, database.StateOK), result(100, database.StateCritical)},
+            results:      2, observedFrom: 0, availability: 100, stateChanges: 1,
+            longest: [2]int{100, 100}, ongoing: true, current: "critical",
+        },
+        {
+            name:         "longest of several outages",
+            history:      []database.Status{result(0, database.StateCritical), result(10, database.StateOK), result(40, database.StateCritical), result(70, database.StateOK)},
+            results:      4, observedFrom: 0, availability: 60, downtime: 40, stateChanges: 3,
+            longest: [2]int{40, 70}, current: "ok",
+        },
+        {
+            name:         "outage from before the period start",
+            history:      []database.Status{result(-30, database.StateCritical), result(20, database.StateOK)},
+            results:      1, observedFrom: 0, availability: 80, downtime: 20, stateChanges: 1,
+            longest: [2]int{0, 20}, current: "ok",
+        },
+        {
+            name:         "folded run across the period start",
+            history:      []database.Status{folded(-20, 4, 10), result(50, database.StateCritical)},
+            results:      4, observedFrom: 0, availability: 50, downtime: 50, stateChanges: 1,
+            longest: [2]int{50, 100}, ongoing: true, current: "critical",
+        },
+        {
+            name:         "folded run across the period end",
+            history:      []database.Status{result(0, database.StateCritical), folded(80, 5, 10)},
+            results:      4, observedFrom: 0, availability: 20, downtime: 80, stateChanges: 1,
+            longest: [2]int{0, 80}, current: "ok",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            report := summarizeHistory(tt.history, reportSince, until)
+
+            if tt.observedFrom < 0 {
+                if report.ObservedFrom != nil {
+                    t.Errorf("observed_from = %s, want none", report.ObservedFrom)
+                }
+            } else if report.ObservedFrom == nil || !report.ObservedFrom.Equal(reportSince.Add(time.Duration(tt.observedFrom)*time.Minute)) {
+                t.Errorf("observed_from = %v, want minute %d", report.ObservedFrom, tt.observedFrom)
+            }
+
+            if tt.availability < 0 {
+                if report.Availability != nil {
+                    t.Errorf("availability = %.2f, want none", *report.Availability)
+                }
+            } else if report.Availability == nil || !nearlyEqual(*report.Availability, tt.availability) {
+                t.Errorf("availability = %v, want %.2f", report.Availability, tt.availability)
+            }
+
+            if !nearlyEqual(report.DowntimeSeconds, tt.downtime*60) {
+                t.Errorf("downtime_seconds = %.0f, want %.0f", report.DowntimeSeconds, tt.downtime*60)
+            }
+            if report.Results != tt.results {
+                t.Errorf("results = %d, want %d", report.Results, tt.results)
+            }
+            if report.StateChanges != tt.stateChanges {
+                t.Errorf("state_changes = %d, want %d", report.StateChanges, tt.stateChanges)
+            }
+            if report.CurrentState != tt.current {
+                t.Errorf("current_state = %s, want %s", report.CurrentState, tt.current)
+            }
+
+            if tt.longest == [2]int{} {
+                if report.LongestOutage != nil {
+                    t.Errorf("longest_outage = %+v, want none", report.LongestOutage)
+                }
+                return
+            }
+            outage := report.LongestOutage
+            start := reportSince.Add(time.Duration(tt.longest[0]) * time.Minute)
+            end := reportSince.Add(time.Duration(tt.longest[1]) * time.Minute)
+            if outage == nil || !outage.Start.Equal(start) || !outage.End.Equal(end) || outage.Ongoing != tt.ongoing {
+                t.Errorf("longest_outage = %+v, want minutes %d-%d ongoing=%t", outage, tt.longest[0], tt.longest[1], tt.ongoing)
+            }
+        })
+    }
+}
+
+func nearlyEqual(a, b float64) bool {
+    return a-b < 1e-6 && b-a < 1e-6
+}
+
+func TestHostReportPeriod(t *testing.T) {
+    s := newTestServer(t, handlersTestConfig)
+    ctx := context.Background()
+
+    // Results either side of both ends of the period
+    since := time.Now().UTC().Truncate(time.Minute).Add(-3 * time.Hour)
+    until := since.Add(time.Hour)
+    for _, minutes := range []int{-10, 0, 30, 60, 70} {
+        status := &database.Status{
+            HostID:    "web-01",
+            CheckID:   "ping-check",
+            ExitCode:  database.StateOK,
+            Output:    "PING OK",
+            Timestamp: since.Add(time.Duration(minutes) * time.Minute),
+        }
+        if minutes == 30 {
+            status.ExitCode = database.StateCritical
+        }
+        if err := s.store.UpdateStatus(ctx, status); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    query := url.Values{"since": {since.Format(time.RFC3339)}, "until": {until.Format(time.RFC3339)}}
+    code, body := s.get(t, "/api/hosts/web-01/report?"+query.Encode())
+    if code != http.StatusOK {
+        t.Fatalf("GET report = %d: %s", code, body)
+    }
+    var response struct {
+        Data HostReport `json:"data"`
+    }
+    if err := json.Unmarshal([]byte(body), &response); err != nil {
+        t.Fatal(err)
+    }
+    if len(response.Data.Checks) != 1 {
+        t.Fatalf("report has %d checks, want 1", len(response.Data.Checks))
+    }
+    report := response.Data.Checks[0]
+
+    // The results at the start, middle and end count; those outside don't
+    if report.Results != 3 {
+        t.Errorf("results = %d, want the 3 inside the period", report.Results)
+    }
+    if report.ObservedFrom == nil || !report.ObservedFrom.Equal(since) {
+        t.Errorf("observed_from = %v, want the period start %s", report.ObservedFrom, since)
+    }
+    if report.Availability == nil || !nearlyEqual(*report.Availability, 50) {
+        t.Errorf("availability = %v, want 50", report.Availability)
+    }
+
+    query.Set("until", since.Format(time.RFC3339))
+    if code, body := s.get(t, "/api/hosts/web-01/report?"+query.Encode()); code != http.StatusBadRequest {
+        t.Errorf("GET report with until == since = %d: %s, want 400", code, body)
+    }
+}
@@ -9,10 +9,13 @@ import (
     "os"
     "strings"
     "fmt"
+    "html"
     "mime"
+    "sort"
+    "strconv"
+    "sync"
 
     "github.com/gin-gonic/gin"
-    "github.com/prometheus/client_golang/prometheus/promhttp"
     "github.com/sirupsen/logrus"
     "raven2/internal/config"
     "raven2/internal/database"
@@ -21,13 +24,23 @@ import (
 )
 
 type Server struct {
-    config    *config.Config
     store     database.Store
     engine    *monitoring.Engine
     metrics   *metrics.Collector
     router    *gin.Engine
-    wsClients map[*WSClient]bool
     server    *http.Server
+
+    // WebSocket and SSE status stream clients
+    subscribers   map[*subscriber]bool
+    subscribersMu sync.Mutex
+
+    // Host ID to group, for group-filtered streams
+    hostGroups       map[string]string
+    hostGroupsLoaded time.Time
+    hostGroupsMu     sync.Mutex
+
+    // Cached host reachability for the host list
+    ipProber *ipProber
 }
 
 func NewServer(cfg *config.Config, store database.Store, engine *monitoring.Engine, metricsCollector *metrics.Collector) *Server {
@@ -36,32 +49,46 @@ func NewServer(cfg *config.Config, store database.Store, engine *monitoring.Engi
     }
 
     router := gin.New()
-    router.Use(gin.Logger())
-    router.Use(gin.Recovery())
-    router.Use(corsMiddleware())
 
     server := &Server{
-        config:    cfg,
-        store:     store,
-        engine:    engine,
-        metrics:   metricsCollector,
-        router:    router,
-        wsClients: make(map[*WSClient]bool),
+        store:       store,
+        engine:      engine,
+        metrics:     metricsCollector,
+        router:      router,
+        subscribers: make(map[*subscriber]bool),
+        ipProber:    newIPProber(),
     }
 
+    router.Use(server.requestLogger())
+    router.Use(gin.Recovery())
+    router.Use(corsMiddleware())
+
     server.setupRoutes()
+
+    // Push check results to WebSocket and SSE clients as they arrive
+    engine.OnStatus(server.broadcastStatus)
+    engine.OnSoftFailAverted(server.broadcastSoftFailAverted)
+    engine.OnSnoozeCleared(server.broadcastSnoozeCleared)
+    engine.OnCheckReconfigured(server.broadcastCheckReconfigured)
+
     return server
 }
 
+// config returns the engine's running configuration, which a reload
+// replaces rather than changes
+func (s *Server) config() *config.Config {
+    return s.engine.Config()
+}
+
 func (s *Server) Start(ctx context.Context) error {
     s.server = &http.Server{
-        Addr:         s.config.Server.Port,
+        Addr:         s.config().Server.Port,
         Handler:      s.router,
-        ReadTimeout:  s.config.Server.ReadTimeout,
-        WriteTimeout: s.config.Server.WriteTimeout,
+        ReadTimeout:  s.config().Server.ReadTimeout,
+        WriteTimeout: s.config().Server.WriteTimeout,
     }
 
-    logrus.WithField("port", s.config.Server.Port).Info("Starting web server")
+    logrus.WithField("port", s.config().Server.Port).Info("Starting web server")
 
     // Start metrics update routine
     go s.updateMetricsRoutine(ctx)
@@ -85,16 +112,16 @@ func (s *Server) Stop(ctx context.Context) error {
 
 func (s *Server) setupRoutes() {
     // Configure static file serving based on config
-    if s.config.Web.ServeStatic {
+    if s.config().Web.ServeStatic {
         var staticDir string
         
         // Determine static directory
-        if s.config.Web.AssetsDir != "" {
+        if s.config().Web.AssetsDir != "" {
             // Use configured assets directory
-            if filepath.IsAbs(s.config.Web.StaticDir) {
-                staticDir = s.config.Web.StaticDir
+            if filepath.IsAbs(s.config().Web.StaticDir) {
+                staticDir = s.config().Web.StaticDir
             } else {
-                staticDir = filepath.Join(s.config.Web.AssetsDir, s.config.Web.StaticDir)
+                staticDir = filepath.Join(s.config().Web.AssetsDir, s.config().Web.StaticDir)
             }
         } else {
             // Auto-detect static directory
@@ -132,29 +159,52 @@ func (s *Server) setupRoutes() {
         // Host endpoints
         api.GET("/hosts", s.getHosts)
         api.GET("/hosts/:id", s.getHost)
+        api.GET("/hosts/:id/report", s.getHostReport)
         api.POST("/hosts", s.createHost)
         api.PUT("/hosts/:id", s.updateHost)
         api.DELETE("/hosts/:id", s.deleteHost)
+        api.POST("/hosts/batch-delete", s.batchDeleteHosts)
+        api.POST("/hosts/:id/clone", s.cloneHost)
+
+        // Group endpoints
+        api.GET("/groups", s.getGroups)
+        api.GET("/groups/:id", s.getGroup)
+        api.POST("/groups", s.createGroup)
+        api.PUT("/groups/:id", s.updateGroup)
+        api.DELETE("/groups/:id", s.deleteGroup)
 
         // Check endpoints
         api.GET("/checks", s.getChecks)
+        api.GET("/checks/summary", s.getChecksSummary)
         api.GET("/checks/:id", s.getCheck)
         api.POST("/checks", s.createCheck)
         api.PUT("/checks/:id", s.updateCheck)
         api.DELETE("/checks/:id", s.deleteCheck)
+        api.POST("/checks/:id/clone", s.cloneCheck)
+        api.POST("/checks/:id/run", s.runCheck)
 
         // Status endpoints
         api.GET("/status", s.getStatus)
+        api.POST("/status/batch", s.getStatusBatch)
+        api.GET("/status/:id/annotations", s.getAnnotations)
+        api.POST("/status/:id/annotations", s.createAnnotation)
+        api.DELETE("/status/:id/annotations/:annotation_id", s.deleteAnnotation)
         api.GET("/status/history/:host/:check", s.getStatusHistory)
+        api.GET("/status/diff/:host/:check", s.getStatusDiff)
+        api.GET("/events", s.streamEvents)
 
         // Alert endpoints
         api.GET("/alerts", s.getAlerts)
         api.GET("/alerts/summary", s.getAlertsSummary)
+        api.POST("/alerts/:host/:check/snooze", s.snoozeAlert)
+        api.DELETE("/alerts/:host/:check/snooze", s.unsnoozeAlert)
 
         // System endpoints
         api.GET("/stats", s.getStats)
         api.GET("/health", s.healthCheck)
         api.GET("/diagnostics/web", s.webDiagnostics)
+        api.GET("/debug/workers", s.getWorkerDebugInfo)
+        api.GET("/plugins", s.getPlugins)
         api.GET("/build-info", s.getBuildInfo)
 
         // web-config endpoints
@@ -168,15 +218,15 @@ func (s *Server) setupRoutes() {
     s.setupPurgeRoutes()
 
     // Prometheus metrics
-    if s.config.Prometheus.Enabled {
-        s.router.GET(s.config.Prometheus.MetricsPath, gin.WrapH(promhttp.Handler()))
+    if s.config().Prometheus.Enabled {
+        s.router.GET(s.config().Prometheus.MetricsPath, gin.WrapH(s.metrics.Handler()))
     }
 }
 
 // setupFileRoutes configures routes for files specified in the config
 func (s *Server) setupFileRoutes() {
     // Root route (either configured or default to index.html)
-    rootFile := s.config.Web.Root
+    rootFile := s.config().Web.Root
     if rootFile == "" {
         rootFile = "index.html"
     }
@@ -187,8 +237,8 @@ func (s *Server) setupFileRoutes() {
     })
 
     // If files are specified in config, create routes for each
-    if len(s.config.Web.Files) > 0 {
-        for _, filename := range s.config.Web.Files {
+    if len(s.config().Web.Files) > 0 {
+        for _, filename := range s.config().Web.Files {
             // Create a closure to capture the filename
             filename := filename // Important: capture the loop variable
             
@@ -251,8 +301,8 @@ func (s *Server) findAssetFile(filename string) string {
     var searchPaths []string
     
     // If assets directory is configured, try that first
-    if s.config.Web.AssetsDir != "" {
-        configuredPath := filepath.Join(s.config.Web.AssetsDir, filename)
+    if s.config().Web.AssetsDir != "" {
+        configuredPath := filepath.Join(s.config().Web.AssetsDir, filename)
         searchPaths = append(searchPaths, configuredPath)
     }
     
@@ -323,7 +373,8 @@ func (s *Server) setFileHeaders(c *gin.Context, filename string) {
     }
 }
 
-// serveFileNotFoundError serves a helpful error page when a configured file is not found
+// serveFileNotFoundError serves a helpful error page when a configured file
+// is not found. Everything interpolated into it is HTML-escaped.
 func (s *Server) serveFileNotFoundError(c *gin.Context, filename string) {
     c.Header("Content-Type", "text/html; charset=utf-8")
     c.String(http.StatusNotFound, `
@@ -386,18 +437,18 @@ func (s *Server) serveFileNotFoundError(c *gin.Context, filename string) {
     </div>
 </body>
 </html>`, 
-        filename,
-        s.config.Web.AssetsDir,
-        s.config.Web.Files,
+        html.EscapeString(filename),
+        html.EscapeString(s.config().Web.AssetsDir),
+        html.EscapeString(fmt.Sprint(s.config().Web.Files)),
         func() string {
-            if s.config.Web.Root != "" {
-                return s.config.Web.Root
+            if s.config().Web.Root != "" {
+                return html.EscapeString(s.config().Web.Root)
             }
             return "index.html (default)"
         }(),
         s.generateSearchPathsList(filename),
-        s.config.Web.AssetsDir,
-        filepath.Join(s.config.Web.AssetsDir, filename),
+        html.EscapeString(s.config().Web.AssetsDir),
+        html.EscapeString(filepath.Join(s.config().Web.AssetsDir, filename)),
     )
 }
 
@@ -405,8 +456,8 @@ func (s *Server) serveFileNotFoundError(c *gin.Context, filename string) {
 func (s *Server) generateSearchPathsList(filename string) string {
     var searchPaths []string
     
-    if s.config.Web.AssetsDir != "" {
-        searchPaths = append(searchPaths, filepath.Join(s.config.Web.AssetsDir, filename))
+    if s.config().Web.AssetsDir != "" {
+        searchPaths = append(searchPaths, filepath.Join(s.config().Web.AssetsDir, filename))
     }
     
     fallbackPaths := []string{
@@ -420,9 +471,9 @@ func (s *Server) generateSearchPathsList(filename string) string {
     var listItems strings.Builder
     for _, path := range searchPaths {
         if _, err := os.Stat(path); err == nil {
-            listItems.WriteString(fmt.Sprintf("<li><code>%s</code> ✅ (exists but not accessible)</li>", path))
+            listItems.WriteString(fmt.Sprintf("<li><code>%s</code> ✅ (exists but not accessible)</li>", html.EscapeString(path)))
         } else {
-            listItems.WriteString(fmt.Sprintf("<li><code>%s</code> ❌ (not found)</li>", path))
+            listItems.WriteString(fmt.Sprintf("<li><code>%s</code> ❌ (not found)</li>", html.EscapeString(path)))
         }
     }
     
@@ -432,7 +483,7 @@ func (s *Server) generateSearchPathsList(filename string) string {
 // Legacy methods for backward compatibility - these now use the new configurable system
 
 func (s *Server) serveSPA(c *gin.Context) {
-    rootFile := s.config.Web.Root
+    rootFile := s.config().Web.Root
     if rootFile == "" {
         rootFile = "index.html"
     }
@@ -453,10 +504,22 @@ func (s *Server) serveFaviconICO(c *gin.Context) {
 
 // Rest of the methods remain the same...
 
+// GET /api/stats - Count current results by state. By default each
+// host/check pair the checks currently list is counted once, in the state of
+// its latest result; ?by=host counts each host once instead, in the state
+// the hosts API reports for it (its most recent counted result, unknown if
+// it has none).
 func (s *Server) getStats(c *gin.Context) {
-    statuses, err := s.store.GetStatus(c.Request.Context(), database.StatusFilters{
-        Limit: 1000,
-    })
+    ctx := c.Request.Context()
+
+    by := c.DefaultQuery("by", "check")
+    if by != "check" && by != "host" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid by: " + by + " (must be check or host)"})
+        return
+    }
+
+    // The current status bucket holds only the latest result per host/check
+    statuses, err := s.store.GetStatus(ctx, database.StatusFilters{})
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
         return
@@ -469,20 +532,51 @@ func (s *Server) getStats(c *gin.Context) {
         "unknown":  0,
     }
 
-    for _, status := range statuses {
-        switch status.ExitCode {
-        case 0:
-            stats["ok"]++
-        case 1:
-            stats["warning"]++
-        case 2:
-            stats["critical"]++
-        default:
-            stats["unknown"]++
+    uncounted := s.getObserveOnlyChecks(ctx)
+    if by == "host" {
+        uncounted = s.loadCheckSet(ctx).uncounted
+    }
+    parked := s.getParked(c)
+    listed := s.newListedPairs()
+    hostLatest := make(map[string]*database.Status)
+
+    for i := range statuses {
+        status := &statuses[i]
+        if uncounted[status.CheckID] || parked.parked(status.HostID, status.CheckID) {
+            continue
+        }
+
+        if !listed.listed(status.HostID, status.CheckID) {
+            continue
+        }
+
+        if by == "check" {
+            stats[database.StateName(status.ExitCode)]++
+        } else if latest := hostLatest[status.HostID]; latest == nil || status.Timestamp.After(latest.Timestamp) {
+            hostLatest[status.HostID] = status
+        }
+    }
+
+    if by == "host" {
+        hosts, err := s.store.GetHosts(ctx, database.HostFilters{})
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hosts"})
+            return
+        }
+        for _, host := range hosts {
+            if parked.hosts[host.ID] {
+                continue
+            }
+            // A host without a counted result is unknown, as in the hosts API
+            state := database.StateUnknown
+            if latest := hostLatest[host.ID]; latest != nil {
+                state = latest.ExitCode
+            }
+            stats[database.StateName(state)]++
         }
     }
 
-    c.JSON(http.StatusOK, gin.H{"data": stats})
+    c.JSON(http.StatusOK, gin.H{"data": stats, "by": by})
 }
 
 func (s *Server) getChecks(c *gin.Context) {
@@ -493,9 +587,14 @@ func (s *Server) getChecks(c *gin.Context) {
         return
     }
 
+    response := make([]CheckResponse, 0, len(checks))
+    for i := range checks {
+        response = append(response, s.newCheckResponse(&checks[i]))
+    }
+
     c.JSON(http.StatusOK, gin.H{
-        "data":  checks,
-        "count": len(checks),
+        "data":  response,
+        "count": len(response),
     })
 }
 
@@ -512,15 +611,145 @@ func (s *Server) getCheck(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"data": check})
+    c.JSON(http.StatusOK, gin.H{"data": s.newCheckResponse(check)})
+}
+
+// POST /api/checks/:id/run?host= - Run a check on all its hosts, or on one,
+// without waiting for its next interval. The results arrive as usual.
+func (s *Server) runCheck(c *gin.Context) {
+    check, err := s.store.GetCheck(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if err.Error() == "check not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Check not found"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get check"})
+        return
+    }
+    if !check.Enabled {
+        c.JSON(http.StatusConflict, gin.H{"error": "Check is disabled"})
+        return
+    }
+
+    hostID := c.Query("host")
+    if hostID != "" && !contains(check.Hosts, hostID) {
+        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Check does not run on host %s", hostID)})
+        return
+    }
+    s.engine.RunSoon(hostID, check.ID)
+
+    hosts := check.Hosts
+    if hostID != "" {
+        hosts = []string{hostID}
+    }
+    c.JSON(http.StatusAccepted, gin.H{
+        "message": "Check queued",
+        "check":   check.ID,
+        "hosts":   hosts,
+    })
+}
+
+// CheckResponse adds effective (resolved) settings to a check
+type CheckResponse struct {
+    *database.Check
+    EffectiveRetention time.Duration               `json:"effective_retention"` // 0 = kept forever
+    SoftFail           monitoring.SoftFailDecision `json:"soft_fail"`
+    InPeriod           bool                        `json:"in_period"`
+    NextPeriodStart    *time.Time                  `json:"next_period_start,omitempty"`
+}
+
+func (s *Server) newCheckResponse(check *database.Check) CheckResponse {
+    now := time.Now()
+    response := CheckResponse{
+        Check:              s.redactedCheck(check),
+        EffectiveRetention: check.EffectiveRetention(s.config().Database.HistoryRetention),
+        SoftFail:           s.engine.SoftFailDecision(check),
+        InPeriod:           check.InPeriod(now),
+    }
+    if next := check.NextPeriodStart(now); !next.IsZero() {
+        next = next.UTC()
+        response.NextPeriodStart = &next
+    }
+    return response
+}
+
+// CheckTypeSummary counts the checks of one type and how many are failing
+type CheckTypeSummary struct {
+    Type      string `json:"type"`
+    Checks    int    `json:"checks"`
+    Instances int    `json:"instances"` // host/check combinations
+    Failing   int    `json:"failing"`   // instances whose latest result is not OK
+}
+
+// GET /api/checks/summary - Checks grouped by type. Disabled and
+// observe-only checks, and disabled hosts, are counted but never as failing,
+// matching alerts.
+func (s *Server) getChecksSummary(c *gin.Context) {
+    checks, err := s.store.GetChecks(c.Request.Context())
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checks"})
+        return
+    }
+
+    parked := s.loadParked(c.Request.Context())
+    summaries := make(map[string]*CheckTypeSummary)
+    var pairs []database.HostCheckPair
+    var pairTypes []string
+
+    for _, check := range checks {
+        summary, ok := summaries[check.Type]
+        if !ok {
+            summary = &CheckTypeSummary{Type: check.Type}
+            summaries[check.Type] = summary
+        }
+        summary.Checks++
+        summary.Instances += len(check.Hosts)
+
+        if !check.Enabled || check.ObserveOnly {
+            continue
+        }
+        for _, hostID := range check.Hosts {
+            if parked.hosts[hostID] {
+                continue
+            }
+            pairs = append(pairs, database.HostCheckPair{HostID: hostID, CheckID: check.ID})
+            pairTypes = append(pairTypes, check.Type)
+        }
+    }
+
+    statuses, err := s.store.GetStatusBatch(c.Request.Context(), pairs)
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get latest statuses")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get check summary"})
+        return
+    }
+    for i, status := range statuses {
+        if status != nil && status.ExitCode != database.StateOK {
+            summaries[pairTypes[i]].Failing++
+        }
+    }
+
+    response := make([]CheckTypeSummary, 0, len(summaries))
+    for _, summary := range summaries {
+        response = append(response, *summary)
+    }
+    sort.Slice(response, func(i, j int) bool {
+        return response[i].Type < response[j].Type
+    })
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  response,
+        "count": len(response),
+    })
 }
 
 // getWebConfig returns web configuration for the frontend
 func (s *Server) getWebConfig(c *gin.Context) {
     config := gin.H{
-        "header_link": s.config.Web.HeaderLink,
-        "serve_static": s.config.Web.ServeStatic,
-        "root": s.config.Web.Root,
+        "header_link": s.config().Web.HeaderLink,
+        "serve_static": s.config().Web.ServeStatic,
+        "root": s.config().Web.Root,
     }
     
     c.JSON(http.StatusOK, gin.H{"data": config})
@@ -537,18 +766,215 @@ func (s *Server) getStatusHistory(c *gin.Context) {
         }
     }
 
+    maxPoints := s.config().Web.HistoryMaxPoints
+    if limitStr := c.Query("limit"); limitStr != "" {
+        limit, err := strconv.Atoi(limitStr)
+        if err != nil || limit < 1 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: " + limitStr})
+            return
+        }
+        maxPoints = limit
+    }
+
     history, err := s.store.GetStatusHistory(c.Request.Context(), hostID, checkID, since)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status history"})
         return
     }
+    total := len(history)
+    results := 0
+    for _, status := range history {
+        results += 1 + status.Repeats
+    }
+
+    // Entries exactly as stored, repeats folded, for export
+    if c.Query("raw") == "true" {
+        c.JSON(http.StatusOK, gin.H{
+            "data":       history,
+            "count":      total,
+            "results":    results,
+            "compressed": results > total,
+        })
+        return
+    }
+    history = downsampleHistory(history, maxPoints)
+
+    statusIDs := make([]string, len(history))
+    for i, status := range history {
+        statusIDs[i] = status.ID
+    }
+    annotations, err := s.store.GetAnnotationsForStatuses(c.Request.Context(), statusIDs)
+    if err != nil {
+        logrus.WithError(err).Warn("Failed to get annotations for status history")
+    }
+
+    entries := make([]StatusHistoryEntry, len(history))
+    for i, status := range history {
+        end := status.Timestamp
+        if status.LastSeen != nil {
+            end = *status.LastSeen
+        }
+        entries[i] = StatusHistoryEntry{
+            Status:          status,
+            Annotations:     annotations[status.ID],
+            AnnotationCount: len(annotations[status.ID]),
+            Start:           status.Timestamp,
+            End:             end,
+            Count:           1 + status.Repeats,
+        }
+    }
 
     c.JSON(http.StatusOK, gin.H{
-        "data":  history,
-        "count": len(history),
+        "data":        entries,
+        "count":       len(entries),
+        "total":       total,
+        "results":     results,
+        "downsampled": len(entries) < total,
+        "archive":     s.getArchivedHistory(c.Request.Context(), hostID, checkID, since, history),
     })
 }
 
+// downsampleHistory picks at most maxPoints entries spread evenly across
+// history, always keeping the first and last, so a long range keeps its
+// shape instead of being cut off
+func downsampleHistory(history []database.Status, maxPoints int) []database.Status {
+    if maxPoints <= 0 || len(history) <= maxPoints {
+        return history
+    }
+    if maxPoints == 1 {
+        return history[len(history)-1:]
+    }
+
+    sampled := make([]database.Status, maxPoints)
+    for i := range sampled {
+        sampled[i] = history[i*(len(history)-1)/(maxPoints-1)]
+    }
+    return sampled
+}
+
+// PluginResponse adds how many configured checks use a plugin, and which of
+// them name a program that can't be run
+type PluginResponse struct {
+    monitoring.PluginInfo
+    InUse           bool                `json:"in_use"`
+    CheckCount      int                 `json:"check_count"`
+    ProgramProblems []CheckProgramIssue `json:"program_problems,omitempty"`
+}
+
+// CheckProgramIssue is a check whose program is missing or not executable,
+// which makes it report UNKNOWN on every run
+type CheckProgramIssue struct {
+    CheckID string `json:"check_id"`
+    Program string `json:"program"`
+    Problem string `json:"problem"`
+}
+
+// GET /api/plugins - Check types supported by the running engine, with
+// whether their dependencies were found (?available=true lists only the
+// types that can be used)
+func (s *Server) getPlugins(c *gin.Context) {
+    checks, err := s.store.GetChecks(c.Request.Context())
+    if err != nil {
+        logrus.WithError(err).Error("Failed to get checks")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get checks"})
+        return
+    }
+
+    checksByType := make(map[string]int)
+    problemsByType := make(map[string][]CheckProgramIssue)
+    for _, check := range checks {
+        checksByType[check.Type]++
+        if program, ok := check.Options["program"].(string); ok {
+            if problem := monitoring.ProgramProblem(program); problem != "" {
+                problemsByType[check.Type] = append(problemsByType[check.Type], CheckProgramIssue{
+                    CheckID: check.ID,
+                    Program: program,
+                    Problem: problem,
+                })
+            }
+        }
+    }
+
+    availableOnly := c.Query("available") == "true"
+    plugins := s.engine.GetPlugins()
+    response := make([]PluginResponse, 0, len(plugins))
+    for _, plugin := range plugins {
+        if availableOnly && !plugin.Available {
+            continue
+        }
+        response = append(response, PluginResponse{
+            PluginInfo:      plugin,
+            InUse:           checksByType[plugin.Name] > 0,
+            CheckCount:      checksByType[plugin.Name],
+            ProgramProblems: problemsByType[plugin.Name],
+        })
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  response,
+        "count": len(response),
+    })
+}
+
+// getArchivedHistory returns hourly rollups covering the part of the
+// requested range that is older than the raw history still on disk
+func (s *Server) getArchivedHistory(ctx context.Context, hostID, checkID string, since time.Time, history []database.Status) []database.StatusRollup {
+    rollups := []database.StatusRollup{}
+
+    extStore, ok := s.store.(database.ExtendedStore)
+    if !ok {
+        return rollups
+    }
+
+    // Raw history is complete from its oldest entry onwards
+    rawStart := time.Now()
+    for _, status := range history {
+        if status.Timestamp.Before(rawStart) {
+            rawStart = status.Timestamp
+        }
+    }
+    if !since.Before(rawStart) {
+        return rollups
+    }
+
+    archived, err := extStore.GetStatusArchive(ctx, hostID, checkID, since)
+    if err != nil {
+        logrus.WithError(err).Warn("Failed to get archived history")
+        return rollups
+    }
+
+    for _, rollup := range archived {
+        if rollup.Hour.Before(rawStart) {
+            rollups = append(rollups, rollup)
+        }
+    }
+    return rollups
+}
+
+// GET /api/debug/workers - Current and recent jobs for each worker
+func (s *Server) getWorkerDebugInfo(c *gin.Context) {
+    workers := s.engine.GetWorkerDebugInfo()
+
+    stuck := 0
+    for _, worker := range workers {
+        if worker.Stuck {
+            stuck++
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "data":  workers,
+        "count": len(workers),
+        "stuck": stuck,
+    })
+}
+
+// pruneMetricSeries drops metric series for hosts/checks that were just
+// removed or disabled instead of waiting for the next metrics update
+func (s *Server) pruneMetricSeries(ctx context.Context) {
+    s.engine.PruneMetricSeries(ctx)
+}
+
 func (s *Server) updateMetricsRoutine(ctx context.Context) {
     ticker := time.NewTicker(30 * time.Second)
     defer ticker.Stop()
@@ -568,7 +994,7 @@ func (s *Server) updateMetricsRoutine(ctx context.Context) {
 func (s *Server) healthCheck(c *gin.Context) {
     health := gin.H{
         "status":    "healthy",
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
         "version":   Version,
         "services":  gin.H{},
     }
@@ -593,7 +1019,7 @@ func (s *Server) healthCheck(c *gin.Context) {
     missingFiles := []string{}
     foundFiles := []string{}
     
-    filesToCheck := s.config.Web.Files
+    filesToCheck := s.config().Web.Files
     if len(filesToCheck) == 0 {
         // Check default files if none configured
         filesToCheck = []string{"index.html", "styles.css", "favicon.ico"}
@@ -625,10 +1051,15 @@ func (s *Server) healthCheck(c *gin.Context) {
     
     services["websocket"] = gin.H{
         "status":         "healthy", 
-        "active_clients": len(s.wsClients),
+        "active_clients": s.websocketClients(),
     }
     
-    services["monitoring"] = gin.H{"status": "healthy"}
+    // The engine says whether it is keeping up, not just that it exists
+    engineHealth := s.engine.Health()
+    services["monitoring"] = engineHealth
+    if engineHealth.Status != monitoring.HealthHealthy {
+        health["status"] = "degraded"
+    }
     
     httpStatus := http.StatusOK
     if health["status"] == "degraded" {
@@ -640,19 +1071,19 @@ func (s *Server) healthCheck(c *gin.Context) {
 
 func (s *Server) webDiagnostics(c *gin.Context) {
     diagnostics := gin.H{
-        "timestamp": time.Now(),
+        "timestamp": time.Now().UTC(),
         "configuration": gin.H{
-            "assets_dir":    s.config.Web.AssetsDir,
-            "static_dir":    s.config.Web.StaticDir,
-            "serve_static":  s.config.Web.ServeStatic,
-            "root":          s.config.Web.Root,
-            "files":         s.config.Web.Files,
+            "assets_dir":    s.config().Web.AssetsDir,
+            "static_dir":    s.config().Web.StaticDir,
+            "serve_static":  s.config().Web.ServeStatic,
+            "root":          s.config().Web.Root,
+            "files":         s.config().Web.Files,
         },
         "web_assets": gin.H{},
     }
 
     // Check all configured files
-    filesToCheck := s.config.Web.Files
+    filesToCheck := s.config().Web.Files
     if len(filesToCheck) == 0 {
         filesToCheck = []string{"index.html", "styles.css", "favicon.ico", "favicon.svg"}
     }
@@ -662,8 +1093,8 @@ func (s *Server) webDiagnostics(c *gin.Context) {
     for _, filename := range filesToCheck {
         var searchPaths []string
         
-        if s.config.Web.AssetsDir != "" {
-            configuredPath := filepath.Join(s.config.Web.AssetsDir, filename)
+        if s.config().Web.AssetsDir != "" {
+            configuredPath := filepath.Join(s.config().Web.AssetsDir, filename)
             searchPaths = append(searchPaths, configuredPath)
         }
         
@@ -683,7 +1114,7 @@ func (s *Server) webDiagnostics(c *gin.Context) {
                 "priority": i + 1,
             }
             
-            if i == 0 && s.config.Web.AssetsDir != "" {
+            if i == 0 && s.config().Web.AssetsDir != "" {
                 result["source"] = "configured"
             } else {
                 result["source"] = "default"
@@ -703,7 +1134,7 @@ func (s *Server) webDiagnostics(c *gin.Context) {
                             content := string(buffer[:n])
                             result["looks_like_html"] = strings.Contains(strings.ToLower(content), "<!doctype html") || 
                                                        strings.Contains(strings.ToLower(content), "<html")
-                            result["preview"] = content
+                            result["preview"] = s.config().Redact(content)
                         }
                         file.Close()
                     }
@@ -718,7 +1149,7 @@ func (s *Server) webDiagnostics(c *gin.Context) {
         
         assetResults[filename] = gin.H{
             "paths": pathResults,
-            "configured": contains(s.config.Web.Files, filename),
+            "configured": contains(s.config().Web.Files, filename),
         }
     }
     
@@ -0,0 +1,114 @@
+// internal/web/snooze_handlers.go - Snoozing an alert until it recovers or a deadline passes
+package web
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sirupsen/logrus"
+    "raven2/internal/monitoring"
+)
+
+// maxSnooze caps how long an alert may be snoozed for
+const maxSnooze = 7 * 24 * time.Hour
+
+// SnoozeRequest is the body for snoozing an alert
+type SnoozeRequest struct {
+    Duration string `json:"duration" binding:"required"` // e.g. "2h"
+}
+
+// POST /api/alerts/:host/:check/snooze - Quiet an alert until it recovers or
+// the duration passes, whichever is first
+func (s *Server) snoozeAlert(c *gin.Context) {
+    ctx := c.Request.Context()
+    hostID, checkID := c.Param("host"), c.Param("check")
+
+    var req SnoozeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    duration, err := time.ParseDuration(req.Duration)
+    if err != nil || duration <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration: %q", req.Duration)})
+        return
+    }
+    if duration > maxSnooze {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Duration %s is longer than the maximum of %s", duration, maxSnooze)})
+        return
+    }
+
+    // Only a current problem can be snoozed; an OK check would clear it on
+    // its next result anyway
+    status, err := s.store.GetLatestStatus(ctx, hostID, checkID)
+    if err != nil || status == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No status for this host and check"})
+        return
+    }
+    if status.ExitCode == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Check is OK, there is no alert to snooze"})
+        return
+    }
+
+    snooze, err := s.engine.Snooze(ctx, hostID, checkID, time.Now().Add(duration))
+    if err != nil {
+        logrus.WithError(err).Error("Failed to snooze alert")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to snooze alert"})
+        return
+    }
+
+    logrus.WithFields(logrus.Fields{
+        "host":  hostID,
+        "check": checkID,
+        "until": snooze.Until,
+    }).Info("Snoozed alert")
+
+    s.broadcastSnooze("alert_snoozed", hostID, status.ExitCode, snooze)
+    c.JSON(http.StatusOK, gin.H{"data": snooze})
+}
+
+// DELETE /api/alerts/:host/:check/snooze - Clear a snooze early
+func (s *Server) unsnoozeAlert(c *gin.Context) {
+    if err := s.engine.Unsnooze(c.Request.Context(), c.Param("host"), c.Param("check")); err != nil {
+        if err.Error() == "snooze not found" {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Snooze not found"})
+            return
+        }
+        logrus.WithError(err).Error("Failed to clear snooze")
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear snooze"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Snooze cleared successfully"})
+}
+
+// broadcastSnoozeCleared tells clients watching the host that a snooze
+// ended, whether by recovery, expiry or through the API
+func (s *Server) broadcastSnoozeCleared(cleared *monitoring.SnoozeCleared) {
+    state := 0
+    if cleared.Reason != monitoring.SnoozeRecovered {
+        if status, err := s.store.GetLatestStatus(context.Background(), cleared.HostID, cleared.CheckID); err == nil && status != nil {
+            state = status.ExitCode
+        }
+    }
+    s.broadcastSnooze("snooze_cleared", cleared.HostID, state, cleared)
+}
+
+// broadcastSnooze sends a snooze event to the clients whose filter matches
+// the host and the check's current state
+func (s *Server) broadcastSnooze(messageType, hostID string, state int, data interface{}) {
+    message := WSMessage{Type: messageType, Data: data}
+    group := s.hostGroup(hostID)
+
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    for client := range s.subscribers {
+        if client.wants(hostID, group, state) {
+            s.queueLocked(client, message)
+        }
+    }
+}
@@ -0,0 +1,327 @@
+// internal/web/subscriptions.go - Filtered status streams shared by WebSocket and SSE clients
+package web
+
+import (
+    "context"
+    "fmt"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/sirupsen/logrus"
+    "raven2/internal/database"
+    "raven2/internal/monitoring"
+)
+
+// hostGroupsTTL bounds how stale the host-to-group map used for group
+// filters may get
+const hostGroupsTTL = 30 * time.Second
+
+// SubscriptionRequest selects which status updates a client receives. Empty
+// fields don't filter.
+type SubscriptionRequest struct {
+    Group       string   `json:"group,omitempty"`
+    HostIDs     []string `json:"host_ids,omitempty"`
+    MinSeverity string   `json:"min_severity,omitempty"` // ok, unknown, warning or critical
+}
+
+// subscription is a SubscriptionRequest compiled for cheap per-message checks
+type subscription struct {
+    request     SubscriptionRequest
+    hostIDs     map[string]bool // nil = all hosts
+    minSeverity int             // database.StateSeverity rank
+}
+
+// compile validates a request and precomputes its host set and severity rank
+func (r SubscriptionRequest) compile() (subscription, error) {
+    sub := subscription{request: r}
+
+    if len(r.HostIDs) > 0 {
+        sub.hostIDs = make(map[string]bool, len(r.HostIDs))
+        for _, id := range r.HostIDs {
+            sub.hostIDs[id] = true
+        }
+    }
+
+    if r.MinSeverity != "" {
+        severity, ok := severityByName(r.MinSeverity)
+        if !ok {
+            return subscription{}, fmt.Errorf("invalid min_severity %q (must be ok, unknown, warning or critical)", r.MinSeverity)
+        }
+        sub.minSeverity = severity
+    }
+
+    return sub, nil
+}
+
+func severityByName(name string) (int, bool) {
+    for _, code := range []int{database.StateOK, database.StateWarning, database.StateCritical, database.StateUnknown} {
+        if database.StateName(code) == name {
+            return database.StateSeverity(code), true
+        }
+    }
+    return 0, false
+}
+
+// matches reports whether a status for hostID (in group) passes the filter
+func (sub *subscription) matches(hostID, group string, exitCode int) bool {
+    if sub.hostIDs != nil && !sub.hostIDs[hostID] {
+        return false
+    }
+    if sub.request.Group != "" && sub.request.Group != group {
+        return false
+    }
+    return database.StateSeverity(exitCode) >= sub.minSeverity
+}
+
+// subscriptionFromQuery reads group, host_id (repeated or comma-separated)
+// and min_severity query parameters
+func subscriptionFromQuery(query url.Values) (subscription, error) {
+    req := SubscriptionRequest{
+        Group:       query.Get("group"),
+        MinSeverity: query.Get("min_severity"),
+    }
+    for _, value := range query["host_id"] {
+        for _, id := range strings.Split(value, ",") {
+            if id = strings.TrimSpace(id); id != "" {
+                req.HostIDs = append(req.HostIDs, id)
+            }
+        }
+    }
+    return req.compile()
+}
+
+// subscriber is one client of the status stream, over WebSocket or SSE
+type subscriber struct {
+    send      chan WSMessage
+    websocket bool // Counted as a WebSocket connection in metrics and health
+
+    mu  sync.Mutex
+    sub subscription
+}
+
+func newSubscriber(sub subscription, websocket bool) *subscriber {
+    return &subscriber{
+        send:      make(chan WSMessage, 256),
+        websocket: websocket,
+        sub:       sub,
+    }
+}
+
+func (c *subscriber) subscription() subscription {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.sub
+}
+
+func (c *subscriber) setSubscription(sub subscription) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.sub = sub
+}
+
+// wants reports whether a status update should go to this client
+func (c *subscriber) wants(hostID, group string, exitCode int) bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.sub.matches(hostID, group, exitCode)
+}
+
+// addSubscriber registers a client with the hub
+func (s *Server) addSubscriber(client *subscriber) {
+    s.subscribersMu.Lock()
+    s.subscribers[client] = true
+    s.subscribersMu.Unlock()
+
+    if client.websocket {
+        s.metrics.RecordWebSocketConnection(1)
+    }
+}
+
+// sendSnapshot queues the current statuses matching the client's filter, so
+// it can render before the next update arrives
+func (s *Server) sendSnapshot(ctx context.Context, client *subscriber) {
+    sub := client.subscription()
+
+    statuses, err := s.snapshot(ctx, &sub)
+    if err != nil {
+        logrus.WithError(err).Warn("Failed to build status snapshot")
+        return
+    }
+
+    s.sendTo(client, WSMessage{
+        Type: "snapshot",
+        Data: map[string]interface{}{
+            "filter":   sub.request,
+            "statuses": statuses,
+        },
+    })
+}
+
+// snapshot returns the latest status of every host/check pair matching sub
+func (s *Server) snapshot(ctx context.Context, sub *subscription) ([]database.Status, error) {
+    hosts, err := s.store.GetHosts(ctx, database.HostFilters{Group: sub.request.Group})
+    if err != nil {
+        return nil, err
+    }
+
+    statuses := []database.Status{}
+    for _, host := range hosts {
+        if sub.hostIDs != nil && !sub.hostIDs[host.ID] {
+            continue
+        }
+
+        latest, err := s.store.GetLatestStatuses(ctx, host.ID)
+        if err != nil {
+            return nil, err
+        }
+        for _, status := range latest {
+            if sub.matches(host.ID, host.Group, status.ExitCode) {
+                statuses = append(statuses, status)
+            }
+        }
+    }
+
+    return statuses, nil
+}
+
+// hostGroup returns a host's group for filtering broadcasts, from a map
+// reloaded at most every hostGroupsTTL rather than a store read per update
+func (s *Server) hostGroup(hostID string) string {
+    s.hostGroupsMu.Lock()
+    defer s.hostGroupsMu.Unlock()
+
+    if time.Since(s.hostGroupsLoaded) > hostGroupsTTL {
+        hosts, err := s.store.GetHosts(context.Background(), database.HostFilters{})
+        if err != nil {
+            logrus.WithError(err).Warn("Failed to load host groups")
+        } else {
+            s.hostGroups = make(map[string]string, len(hosts))
+            for _, host := range hosts {
+                s.hostGroups[host.ID] = host.Group
+            }
+            s.hostGroupsLoaded = time.Now()
+        }
+    }
+
+    return s.hostGroups[hostID]
+}
+
+func (s *Server) broadcast(message WSMessage) {
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    for client := range s.subscribers {
+        s.queueLocked(client, message)
+    }
+}
+
+// broadcastStatus sends a check result to every client whose filter it matches
+func (s *Server) broadcastStatus(status *database.Status) {
+    message := WSMessage{Type: "status_update", Data: status}
+    group := s.hostGroup(status.HostID)
+
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    for client := range s.subscribers {
+        if client.wants(status.HostID, group, status.ExitCode) {
+            s.queueLocked(client, message)
+        }
+    }
+}
+
+// broadcastSoftFailAverted tells clients watching the host about a failure
+// that recovered before soft fail confirmed it. The severity filter applies
+// to the failure that was pending.
+func (s *Server) broadcastSoftFailAverted(averted *monitoring.SoftFailAverted) {
+    message := WSMessage{Type: "softfail_averted", Data: averted}
+    group := s.hostGroup(averted.HostID)
+
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    for client := range s.subscribers {
+        if client.wants(averted.HostID, group, averted.State) {
+            s.queueLocked(client, message)
+        }
+    }
+}
+
+// broadcastCheckReconfigured tells clients watching any of the check's hosts
+// that its state was reset
+func (s *Server) broadcastCheckReconfigured(event *monitoring.CheckReconfigured) {
+    message := WSMessage{Type: "check_reconfigured", Data: event}
+    hostIDs := append(append([]string{}, event.ResetHosts...), event.RemovedHosts...)
+    groups := make(map[string]string, len(hostIDs))
+    for _, hostID := range hostIDs {
+        groups[hostID] = s.hostGroup(hostID)
+    }
+
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    for client := range s.subscribers {
+        for _, hostID := range hostIDs {
+            if client.wants(hostID, groups[hostID], database.StateUnknown) {
+                s.queueLocked(client, message)
+                break
+            }
+        }
+    }
+}
+
+// sendTo queues a message for a single client, if it is still connected
+func (s *Server) sendTo(client *subscriber, message WSMessage) {
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    if s.subscribers[client] {
+        s.queueLocked(client, message)
+    }
+}
+
+// queueLocked queues a message, dropping clients that can't keep up.
+// Callers must hold subscribersMu.
+func (s *Server) queueLocked(client *subscriber, message WSMessage) {
+    select {
+    case client.send <- message:
+    default:
+        close(client.send)
+        s.deleteLocked(client)
+    }
+}
+
+func (s *Server) removeClient(client *subscriber) {
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    if s.subscribers[client] {
+        s.deleteLocked(client)
+    }
+}
+
+func (s *Server) deleteLocked(client *subscriber) {
+    delete(s.subscribers, client)
+    if client.websocket {
+        s.metrics.RecordWebSocketConnection(-1)
+    }
+}
+
+// websocketClients counts connected WebSocket clients
+func (s *Server) websocketClients() int {
+    s.subscribersMu.Lock()
+    defer s.subscribersMu.Unlock()
+
+    count := 0
+    for client := range s.subscribers {
+        if client.websocket {
+            count++
+        }
+    }
+    return count
+}
@@ -2,12 +2,15 @@
 package web
 
 import (
+    "context"
+    "encoding/json"
     "net/http"
     "time"
 
     "github.com/gin-gonic/gin"
     "github.com/gorilla/websocket"
     "github.com/sirupsen/logrus"
+    "raven2/internal/database"
 )
 
 var upgrader = websocket.Upgrader{
@@ -21,13 +24,33 @@ type WSMessage struct {
     Data interface{} `json:"data"`
 }
 
+// WSRequest is a message sent by the client, e.g.
+// {"type": "subscribe", "group": "web", "min_severity": "warning"},
+// {"type": "watch_host", "host_id": "router"} or {"type": "unwatch_host"}
+type WSRequest struct {
+    Type        string   `json:"type"`
+    HostID      string   `json:"host_id"`
+    Group       string   `json:"group"`
+    HostIDs     []string `json:"host_ids"`
+    MinSeverity string   `json:"min_severity"`
+}
+
 type WSClient struct {
+    *subscriber
     conn   *websocket.Conn
-    send   chan WSMessage
     server *Server
 }
 
+// GET /ws - Status stream. The group, host_id and min_severity query
+// parameters set the initial filter; a snapshot of the matching statuses is
+// sent first.
 func (s *Server) handleWebSocket(c *gin.Context) {
+    sub, err := subscriptionFromQuery(c.Request.URL.Query())
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
     conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
     if err != nil {
         logrus.WithError(err).Error("Failed to upgrade websocket")
@@ -35,12 +58,13 @@ func (s *Server) handleWebSocket(c *gin.Context) {
     }
 
     client := &WSClient{
-        conn:   conn,
-        send:   make(chan WSMessage, 256),
-        server: s,
+        subscriber: newSubscriber(sub, true),
+        conn:       conn,
+        server:     s,
     }
 
-    s.wsClients[client] = true
+    s.addSubscriber(client.subscriber)
+    s.sendSnapshot(context.Background(), client.subscriber)
 
     go client.writePump()
     go client.readPump()
@@ -51,7 +75,7 @@ func (c *WSClient) writePump() {
     defer func() {
         ticker.Stop()
         c.conn.Close()
-        delete(c.server.wsClients, c)
+        c.server.removeClient(c.subscriber)
     }()
 
     for {
@@ -77,9 +101,12 @@ func (c *WSClient) writePump() {
 }
 
 func (c *WSClient) readPump() {
-    defer c.conn.Close()
+    defer func() {
+        c.conn.Close()
+        c.server.removeClient(c.subscriber)
+    }()
 
-    c.conn.SetReadLimit(512)
+    c.conn.SetReadLimit(4096)
     c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
     c.conn.SetPongHandler(func(string) error {
         c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -87,20 +114,66 @@ func (c *WSClient) readPump() {
     })
 
     for {
-        _, _, err := c.conn.ReadMessage()
+        _, data, err := c.conn.ReadMessage()
         if err != nil {
             break
         }
+
+        var req WSRequest
+        if err := json.Unmarshal(data, &req); err != nil {
+            continue // Ignore anything that isn't a request
+        }
+        c.handleRequest(&req)
     }
 }
 
-func (s *Server) broadcast(message WSMessage) {
-    for client := range s.wsClients {
-        select {
-        case client.send <- message:
-        default:
-            close(client.send)
-            delete(s.wsClients, client)
+func (c *WSClient) handleRequest(req *WSRequest) {
+    switch req.Type {
+    case "subscribe":
+        // Replaces the whole filter, then resends the snapshot for it
+        sub, err := SubscriptionRequest{
+            Group:       req.Group,
+            HostIDs:     req.HostIDs,
+            MinSeverity: req.MinSeverity,
+        }.compile()
+        if err != nil {
+            c.server.sendTo(c.subscriber, WSMessage{Type: "error", Data: gin.H{"error": err.Error()}})
+            return
+        }
+        c.setSubscription(sub)
+        c.server.sendSnapshot(context.Background(), c.subscriber)
+
+    case "watch_host":
+        if req.HostID == "" {
+            return
+        }
+        c.setHostIDs([]string{req.HostID})
+
+        // Current state first, then only this host's updates
+        statuses, err := c.server.store.GetLatestStatuses(context.Background(), req.HostID)
+        if err != nil {
+            logrus.WithError(err).WithField("host", req.HostID).Warn("Failed to get host snapshot")
+            return
         }
+        if statuses == nil {
+            statuses = []database.Status{}
+        }
+        c.server.sendTo(c.subscriber, WSMessage{
+            Type: "host_snapshot",
+            Data: gin.H{"host_id": req.HostID, "statuses": statuses},
+        })
+
+    case "unwatch_host":
+        c.setHostIDs(nil)
     }
 }
+
+// setHostIDs replaces the host filter, keeping the group and severity
+func (c *WSClient) setHostIDs(hostIDs []string) {
+    request := c.subscription().request
+    request.HostIDs = hostIDs
+
+    // Only the host list changed, and host IDs can't fail to compile
+    sub, _ := request.compile()
+    c.setSubscription(sub)
+}
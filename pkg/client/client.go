@@ -0,0 +1,122 @@
+// pkg/client/client.go - HTTP client for the Raven API
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// DefaultURL is where a Raven server listens unless configured otherwise
+const DefaultURL = "http://localhost:8000"
+
+// Client calls a Raven server's /api endpoints
+type Client struct {
+    BaseURL string // e.g. "http://raven:8000"
+    Token   string // Sent as a bearer token when set, for servers behind an authenticating proxy
+    HTTP    *http.Client
+}
+
+// New returns a client for the server at baseURL
+func New(baseURL, token string) *Client {
+    return &Client{
+        BaseURL: strings.TrimRight(baseURL, "/"),
+        Token:   token,
+        HTTP:    &http.Client{Timeout: 30 * time.Second},
+    }
+}
+
+// APIError is a response outside 2xx, with the server's error message
+type APIError struct {
+    StatusCode int
+    Message    string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// envelope is the {"data": ...} wrapper most responses use
+type envelope struct {
+    Data json.RawMessage `json:"data"`
+}
+
+// do sends a request and decodes the response body into out, if given
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+    target := c.BaseURL + "/api" + path
+    if len(query) > 0 {
+        target += "?" + query.Encode()
+    }
+
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return fmt.Errorf("failed to encode request: %w", err)
+        }
+        reader = bytes.NewReader(data)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, target, reader)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", "application/json")
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    if c.Token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.Token)
+    }
+
+    resp, err := c.HTTP.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return fmt.Errorf("failed to read response: %w", err)
+    }
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        var failure struct {
+            Error string `json:"error"`
+        }
+        message := strings.TrimSpace(string(data))
+        if json.Unmarshal(data, &failure) == nil && failure.Error != "" {
+            message = failure.Error
+        }
+        if message == "" {
+            message = http.StatusText(resp.StatusCode)
+        }
+        return &APIError{StatusCode: resp.StatusCode, Message: message}
+    }
+
+    if out == nil {
+        return nil
+    }
+    if err := json.Unmarshal(data, out); err != nil {
+        return fmt.Errorf("failed to decode response from %s: %w", path, err)
+    }
+    return nil
+}
+
+// get sends a GET and decodes the response's data field into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+    var response envelope
+    if err := c.do(ctx, http.MethodGet, path, query, nil, &response); err != nil {
+        return err
+    }
+    if err := json.Unmarshal(response.Data, out); err != nil {
+        return fmt.Errorf("failed to decode response from %s: %w", path, err)
+    }
+    return nil
+}
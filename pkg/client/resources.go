@@ -0,0 +1,236 @@
+// pkg/client/resources.go - Typed calls for hosts, checks, statuses, alerts and config
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "time"
+
+    "raven2/internal/config"
+    "raven2/internal/database"
+)
+
+// Host is a host as the API returns it: the stored host with its current
+// state
+type Host struct {
+    database.Host
+    HostState
+}
+
+// HostState is the current state the API adds to a stored host
+type HostState struct {
+    Status            string    `json:"status"`
+    LastCheck         time.Time `json:"last_check"`
+    NextCheck         time.Time `json:"next_check"`
+    CheckCount        int       `json:"check_count"`
+    StateDurationText string    `json:"state_duration_text"` // e.g. "3h 12m"
+
+    OverdueSince map[string]time.Time `json:"overdue_since,omitempty"` // By check ID, for checks that haven't run when expected
+}
+
+// HostRequest is the body for creating or updating a host
+type HostRequest struct {
+    Name        string            `json:"name"`
+    DisplayName string            `json:"display_name"`
+    IPv4        string            `json:"ipv4"`
+    Hostname    string            `json:"hostname"`
+    Group       string            `json:"group"`
+    Enabled     bool              `json:"enabled"`
+    Tags        map[string]string `json:"tags"`
+}
+
+// Check is a check as the API returns it
+type Check struct {
+    database.Check
+    CheckState
+}
+
+// CheckState is what the API adds to a stored check
+type CheckState struct {
+    InPeriod bool `json:"in_period"`
+}
+
+// Status is a host/check's latest result as the API returns it
+type Status struct {
+    database.Status
+    StatusContext
+}
+
+// StatusContext is what the API adds to a stored status
+type StatusContext struct {
+    CheckName   string `json:"check_name"`
+    HostName    string `json:"host_name"`
+    ObserveOnly bool   `json:"observe_only,omitempty"`
+    OutOfPeriod bool   `json:"out_of_period,omitempty"`
+
+    // No result for longer than the server's overdue_factor allows
+    Overdue      bool       `json:"overdue,omitempty"`
+    OverdueSince *time.Time `json:"overdue_since,omitempty"`
+}
+
+// Alert is a current problem as GET /api/alerts returns it
+type Alert struct {
+    ID                string     `json:"id"`
+    Timestamp         time.Time  `json:"timestamp"`
+    Severity          string     `json:"severity"`
+    Host              string     `json:"host"`
+    Check             string     `json:"check"`
+    Message           string     `json:"message"`
+    Duration          int64      `json:"duration"`            // milliseconds in the current state
+    LastStateChange   time.Time  `json:"last_state_change"`
+    StateDuration     int64      `json:"state_duration"`      // milliseconds
+    StateDurationText string     `json:"state_duration_text"` // e.g. "3h 12m"
+    DiffURL           string     `json:"diff_url"`
+    SnoozedUntil      *time.Time `json:"snoozed_until"`       // Nil unless snoozed
+}
+
+// The stored types convert their timestamps in UnmarshalJSON, which would
+// otherwise be promoted and leave the added fields unset
+func (h *Host) UnmarshalJSON(data []byte) error {
+    return decodeParts(data, &h.Host, &h.HostState)
+}
+
+func (c *Check) UnmarshalJSON(data []byte) error {
+    return decodeParts(data, &c.Check, &c.CheckState)
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+    return decodeParts(data, &s.Status, &s.StatusContext)
+}
+
+// decodeParts decodes the same object into each part
+func decodeParts(data []byte, parts ...interface{}) error {
+    for _, part := range parts {
+        if err := json.Unmarshal(data, part); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// ReloadResult is what POST /api/config/reload reports
+type ReloadResult struct {
+    Message      string          `json:"message"`
+    ConfigFile   string          `json:"config_file"`
+    IncludeFiles []string        `json:"include_files"`
+    Changed      bool            `json:"changed"`
+    Changes      *config.Changes `json:"changes"`
+    Warnings     []string        `json:"warnings"`
+}
+
+// Hosts returns every host
+func (c *Client) Hosts(ctx context.Context) ([]Host, error) {
+    var hosts []Host
+    err := c.get(ctx, "/hosts", url.Values{"all": {"true"}}, &hosts)
+    return hosts, err
+}
+
+// Host returns one host
+func (c *Client) Host(ctx context.Context, id string) (*Host, error) {
+    var host Host
+    if err := c.get(ctx, "/hosts/"+url.PathEscape(id), nil, &host); err != nil {
+        return nil, err
+    }
+    return &host, nil
+}
+
+// SetHostEnabled enables or disables a host, keeping its other fields
+func (c *Client) SetHostEnabled(ctx context.Context, id string, enabled bool) (*database.Host, error) {
+    host, err := c.Host(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    req := HostRequest{
+        Name:        host.Name,
+        DisplayName: host.DisplayName,
+        IPv4:        host.IPv4,
+        Hostname:    host.Hostname,
+        Group:       host.Group,
+        Enabled:     enabled,
+        Tags:        host.Tags,
+    }
+    var response struct {
+        Data database.Host `json:"data"`
+    }
+    if err := c.do(ctx, http.MethodPut, "/hosts/"+url.PathEscape(id), nil, req, &response); err != nil {
+        return nil, err
+    }
+    return &response.Data, nil
+}
+
+// Checks returns every check
+func (c *Client) Checks(ctx context.Context) ([]Check, error) {
+    var checks []Check
+    err := c.get(ctx, "/checks", nil, &checks)
+    return checks, err
+}
+
+// RunCheck runs a check on all its hosts, or on hostID if set, without
+// waiting for its next interval, and returns the hosts it was queued for
+func (c *Client) RunCheck(ctx context.Context, checkID, hostID string) ([]string, error) {
+    query := url.Values{}
+    if hostID != "" {
+        query.Set("host", hostID)
+    }
+    var response struct {
+        Hosts []string `json:"hosts"`
+    }
+    if err := c.do(ctx, http.MethodPost, "/checks/"+url.PathEscape(checkID)+"/run", query, nil, &response); err != nil {
+        return nil, err
+    }
+    return response.Hosts, nil
+}
+
+// HostStatus returns the latest result of every check on a host
+func (c *Client) HostStatus(ctx context.Context, hostID string) ([]Status, error) {
+    var statuses []Status
+    err := c.get(ctx, "/status", url.Values{"host_id": {hostID}, "limit": {"0"}}, &statuses)
+    return statuses, err
+}
+
+// Alerts returns every current problem, optionally of one severity
+// ("critical", "warning" or "unknown")
+func (c *Client) Alerts(ctx context.Context, severity string) ([]Alert, error) {
+    query := url.Values{"limit": {"0"}}
+    if severity != "" {
+        query.Set("severity", severity)
+    }
+    var alerts []Alert
+    err := c.get(ctx, "/alerts", query, &alerts)
+    return alerts, err
+}
+
+// SnoozeAlert quiets a host/check alert until it recovers or duration
+// passes
+func (c *Client) SnoozeAlert(ctx context.Context, hostID, checkID string, duration time.Duration) (*database.Snooze, error) {
+    var response struct {
+        Data database.Snooze `json:"data"`
+    }
+    body := map[string]string{"duration": duration.String()}
+    if err := c.do(ctx, http.MethodPost, alertPath(hostID, checkID)+"/snooze", nil, body, &response); err != nil {
+        return nil, err
+    }
+    return &response.Data, nil
+}
+
+// UnsnoozeAlert clears the snooze on a host/check
+func (c *Client) UnsnoozeAlert(ctx context.Context, hostID, checkID string) error {
+    return c.do(ctx, http.MethodDelete, alertPath(hostID, checkID)+"/snooze", nil, nil, nil)
+}
+
+// ReloadConfig makes the server read its config files again
+func (c *Client) ReloadConfig(ctx context.Context) (*ReloadResult, error) {
+    var result ReloadResult
+    if err := c.do(ctx, http.MethodPost, "/config/reload", nil, nil, &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+func alertPath(hostID, checkID string) string {
+    return fmt.Sprintf("/alerts/%s/%s", url.PathEscape(hostID), url.PathEscape(checkID))
+}
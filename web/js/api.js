@@ -16,9 +16,18 @@ window.RavenAPI = {
     },
 
     // Host management
+    // The list comes a page at a time and without per-check details
     async loadHosts() {
-        const response = await axios.get('/api/hosts');
-        return response.data.data || [];
+        const pageSize = 200;
+        const hosts = [];
+        for (let offset = 0; ; offset += pageSize) {
+            const response = await axios.get(`/api/hosts?limit=${pageSize}&offset=${offset}`);
+            const page = response.data.data || [];
+            hosts.push(...page);
+            if (page.length < pageSize || hosts.length >= response.data.total) {
+                return hosts;
+            }
+        }
     },
 
     async loadHost(hostId) {
@@ -26,6 +35,28 @@ window.RavenAPI = {
         return response.data.data;
     },
 
+    // Fill in each host's soft_fail_info, ok_duration and check_names from
+    // its detail endpoint, a few hosts at a time
+    async loadHostDetails(hosts) {
+        const concurrency = 8;
+        let next = 0;
+        const worker = async () => {
+            while (next < hosts.length) {
+                const host = hosts[next++];
+                try {
+                    const detail = await this.loadHost(host.id);
+                    host.soft_fail_info = detail.soft_fail_info;
+                    host.ok_duration = detail.ok_duration;
+                    host.check_names = detail.check_names;
+                } catch (error) {
+                    console.error(`Failed to load details for host ${host.id}:`, error);
+                }
+            }
+        };
+        await Promise.all(Array.from({ length: concurrency }, worker));
+        return hosts;
+    },
+
     async createHost(hostData) {
         await axios.post('/api/hosts', hostData);
     },
@@ -61,6 +92,17 @@ window.RavenAPI = {
         await axios.delete(`/api/checks/${id}`);
     },
 
+    // Check types supported by the engine
+    async loadPlugins() {
+        try {
+            const response = await axios.get('/api/plugins');
+            return response.data.data || [];
+        } catch (error) {
+            console.error('Failed to load plugins:', error);
+            return [{ name: 'ping' }, { name: 'nagios' }];
+        }
+    },
+
     // Status and monitoring
     async loadStatus(limit = 100, filters = {}) {
         const params = new URLSearchParams();
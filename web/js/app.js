@@ -96,7 +96,9 @@ createApp({
                 type: '',
                 message: ''
             },
-            websocket: null
+            websocket: null,
+            watchedHostId: null,
+            wsRefreshTimer: null
         }
     },
     computed: {
@@ -326,11 +328,14 @@ createApp({
                 
                 // Find all hosts affected by this specific alert
                 try {
-                    const hostResponse = await axios.get('/api/hosts');
-                    const allHosts = hostResponse.data.data || [];
+                    // Only hosts with a result for this check can be affected
+                    const hostIds = [...new Set(this.alertStatuses.map(status => status.host_id))];
+                    const candidateHosts = await Promise.all(hostIds.map(hostId =>
+                        window.RavenAPI.loadHost(hostId).catch(() => null)
+                    ));
                     
                     // Filter hosts that have this specific alert
-                    this.affectedHosts = allHosts.filter(host => {
+                    this.affectedHosts = candidateHosts.filter(host => host).filter(host => {
                         // Check if host has soft fail info for this check
                         if (host.soft_fail_info) {
                             for (const [checkId, failInfo] of Object.entries(host.soft_fail_info)) {
@@ -425,6 +430,8 @@ createApp({
             this.loading = true;
             try {
                 this.hosts = await window.RavenAPI.loadHosts();
+                // Host cards fill in their check details as they arrive
+                window.RavenAPI.loadHostDetails(this.hosts);
             } catch (error) {
                 console.error('Failed to load hosts:', error);
                 window.RavenUtils.showNotification(this, 'error', 'Failed to load hosts');
@@ -447,13 +454,17 @@ createApp({
 
         async loadStats() {
             try {
-                const statuses = await window.RavenAPI.loadStatus(1000);
+                const [statuses, stats] = await Promise.all([
+                    window.RavenAPI.loadStatus(1000),
+                    window.RavenAPI.loadStats()
+                ]);
                 
+                // Counted server side: one per host/check, without the status limit
                 this.stats = {
-                    ok: statuses.filter(s => s.exit_code === 0).length,
-                    warning: statuses.filter(s => s.exit_code === 1).length,
-                    critical: statuses.filter(s => s.exit_code === 2).length,
-                    unknown: statuses.filter(s => s.exit_code === 3).length
+                    ok: stats.ok || 0,
+                    warning: stats.warning || 0,
+                    critical: stats.critical || 0,
+                    unknown: stats.unknown || 0
                 };
 
                 this.recentActivity = statuses
@@ -622,8 +633,14 @@ createApp({
                 hosts: check.hosts || [],
                 interval: check.interval || {},
                 threshold: check.threshold || 3,
+                host_thresholds: check.host_thresholds || {},
+                soft_fail_enabled: check.soft_fail_enabled,
+                soft_fail_mode: check.soft_fail_mode || '',
                 timeout: check.timeout || '30s',
                 enabled: check.enabled,
+                observe_only: check.observe_only || false,
+                priority: check.priority || 0,
+                exit_code_map: check.exit_code_map || {},
                 options: check.options || {}
             };
             this.showCheckModal = true;
@@ -637,6 +654,14 @@ createApp({
 
         async saveCheck() {
             this.saving = true;
+            // Threshold overrides only apply to hosts the check still targets
+            const hostThresholds = {};
+            for (const hostId of this.checkForm.hosts) {
+                if (this.checkForm.host_thresholds && hostId in this.checkForm.host_thresholds) {
+                    hostThresholds[hostId] = this.checkForm.host_thresholds[hostId];
+                }
+            }
+            this.checkForm.host_thresholds = hostThresholds;
             try {
                 if (this.editingCheck) {
                     await window.RavenAPI.updateCheck(this.editingCheck.id, this.checkForm);
@@ -749,19 +774,25 @@ createApp({
             this.websocket.onopen = () => {
                 this.connected = true;
                 console.log('WebSocket connected');
+                this.watchedHostId = null; // New connection starts unfiltered
+                this.updateHostWatch();
             };
             
             this.websocket.onmessage = (event) => {
                 const message = JSON.parse(event.data);
                 if (message.type === 'status_update') {
-                    this.loadStats();
-                    if (this.currentView === 'hosts') {
-                        this.loadHosts();
-                    } else if (this.currentView === 'host-detail') {
-                        this.refreshHostData();
-                    } else if (this.currentView === 'alert-detail') {
-                        this.refreshAlertData();
-                    }
+                    // Results arrive one check at a time; refresh once per burst
+                    clearTimeout(this.wsRefreshTimer);
+                    this.wsRefreshTimer = setTimeout(() => {
+                        this.loadStats();
+                        if (this.currentView === 'hosts') {
+                            this.loadHosts();
+                        } else if (this.currentView === 'host-detail') {
+                            this.refreshHostData();
+                        } else if (this.currentView === 'alert-detail') {
+                            this.refreshAlertData();
+                        }
+                    }, 2000);
                 }
             };
             
@@ -775,10 +806,28 @@ createApp({
                 console.error('WebSocket error:', error);
                 this.connected = false;
             };
+        },
+
+        // Only stream the open host's updates while a host detail page is shown
+        updateHostWatch() {
+            if (!this.websocket || this.websocket.readyState !== WebSocket.OPEN) return;
+
+            const hostId = this.currentHostDetail ? this.currentHostDetail.id : null;
+            if (hostId === this.watchedHostId) return;
+
+            if (hostId) {
+                this.websocket.send(JSON.stringify({ type: 'watch_host', host_id: hostId }));
+            } else {
+                this.websocket.send(JSON.stringify({ type: 'unwatch_host' }));
+            }
+            this.watchedHostId = hostId;
         }
     },
 
     watch: {
+        currentHostDetail() {
+            this.updateHostWatch();
+        },
         sortingInfo: {
             handler(newInfo, oldInfo) {
                 if (oldInfo && newInfo !== oldInfo) {
@@ -8,6 +8,14 @@ window.CheckModal = {
         saving: Boolean
     },
     emits: ['close', 'save'],
+    data() {
+        return {
+            plugins: []
+        };
+    },
+    async mounted() {
+        this.plugins = await window.RavenAPI.loadPlugins();
+    },
     methods: {
         isValidDuration(duration) {
             return window.RavenUtils.isValidDuration(duration);
@@ -17,6 +25,9 @@ window.CheckModal = {
         },
         getCheckTypeIcon(checkType) {
             return window.RavenUtils.getCheckTypeIcon(checkType);
+        },
+        formatCheckTypeDisplay(checkType) {
+            return window.RavenUtils.formatCheckTypeDisplay(checkType);
         }
     },
     template: `
@@ -44,10 +55,9 @@ window.CheckModal = {
                         <div style="display: flex; align-items: center; gap: 0.5rem;">
                             <i :class="getCheckTypeIcon(form.type)" style="color: var(--primary-color);"></i>
                             <select v-model="form.type" class="form-input" required style="flex: 1;">
-                                <option value="ping">Ping - Network connectivity test</option>
-                                <option value="nagios">Nagios Plugin - Custom monitoring script</option>
-                                <option value="http">HTTP - Web service check</option>
-                                <option value="https">HTTPS - Secure web service check</option>
+                                <option v-for="plugin in plugins" :key="plugin.name" :value="plugin.name" :title="plugin.available === false ? plugin.availability_detail : plugin.description">
+                                    {{ formatCheckTypeDisplay(plugin.name) }}{{ plugin.available === false ? ' (unavailable)' : '' }}
+                                </option>
                             </select>
                         </div>
                     </div>
@@ -132,6 +142,10 @@ window.CheckModal = {
                                 • Failure {{ form.threshold || 3 }}/{{ form.threshold || 3 }}: Status changes to Warning/Critical (hard fail)
                             </div>
                         </div>
+                        <select v-model="form.soft_fail_mode" class="form-input" style="width: 250px; margin-top: 0.5rem;">
+                            <option value="">Failures only (recover immediately)</option>
+                            <option value="symmetric">Failures and recovery</option>
+                        </select>
                     </div>
                     <div class="form-group">
                         <label class="form-label">Timeout</label>
@@ -154,6 +168,15 @@ window.CheckModal = {
                             Disabled checks will not run and won't generate alerts
                         </small>
                     </div>
+                    <div class="form-group">
+                        <label class="form-checkbox">
+                            <input v-model="form.observe_only" type="checkbox">
+                            <span>Observe only</span>
+                        </label>
+                        <small style="color: var(--text-muted); display: block; margin-top: 0.5rem;">
+                            Runs and records results, but is left out of alerts and host status until cleared
+                        </small>
+                    </div>
                     
                     <!-- Show current check statistics if editing -->
                     <div v-if="editing" class="form-group" style="background: var(--light-bg); padding: 1rem; border-radius: 0.5rem; border: 1px solid var(--border-color);">
@@ -179,6 +202,9 @@ window.CheckModal = {
                             <div>
                                 <strong>Current Threshold:</strong> {{ editing.threshold || 3 }} failures
                             </div>
+                            <div v-if="editing.soft_fail" style="grid-column: 1 / -1;">
+                                <strong>Soft Fail:</strong> {{ editing.soft_fail.enabled ? 'on' : 'off' }} ({{ editing.soft_fail.reason }})
+                            </div>
                         </div>
                     </div>
                     
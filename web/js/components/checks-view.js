@@ -84,6 +84,10 @@ window.ChecksView = {
                                     <div class="status-indicator status-unknown"></div>
                                     Disabled
                                 </span>
+                                <span v-if="check.observe_only" class="status-badge status-observe" title="Results are recorded but not alerted on">
+                                    <i class="fas fa-eye"></i>
+                                    Observe only
+                                </span>
                             </td>
                             <td>
                                 <div class="actions">
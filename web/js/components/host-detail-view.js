@@ -393,6 +393,9 @@ window.HostDetailView = {
                                             <div class="status-indicator" :class="'status-' + getStatusName(status.exit_code)"></div>
                                             {{ getStatusName(status.exit_code).toUpperCase() }}
                                         </span>
+                                        <span v-if="status.overdue" class="status-badge status-overdue" :title="'No result since ' + formatTime(status.timestamp) + ', expected by ' + formatTime(status.overdue_since)">
+                                            OVERDUE
+                                        </span>
                                     </td>
                                     <td>{{ formatTime(status.timestamp) }}</td>
                                     <td style="max-width: 300px;">
@@ -72,8 +72,11 @@ window.RavenUtils = {
                 unknown: '1m'
             },
             threshold: 3,
+            host_thresholds: {},
+            soft_fail_mode: '',
             timeout: '30s',
             enabled: true,
+            observe_only: false,
             options: {}
         };
     },
@@ -238,6 +241,7 @@ window.RavenUtils = {
     formatCheckTypeDisplay(checkType) {
         const typeMap = {
             'ping': 'Network Ping',
+            'pingsweep': 'Ping Sweep',
             'http': 'HTTP Check',
             'https': 'HTTPS Check', 
             'nagios': 'Nagios Plugin',
@@ -361,6 +365,7 @@ window.RavenUtils = {
     getCheckTypeIcon(checkType) {
         switch (checkType?.toLowerCase()) {
             case 'ping': return 'fas fa-wifi';
+            case 'pingsweep': return 'fas fa-sitemap';
             case 'http': return 'fab fa-html5';
             case 'https': return 'fas fa-lock';
             case 'nagios': return 'fas fa-cog';